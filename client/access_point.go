@@ -0,0 +1,235 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+)
+
+// AccessPointClient talks to the API on an access point.
+type AccessPointClient struct {
+	*Client
+}
+
+// NewAccessPointClient creates a client for the access point at the given address; see NewClient.
+func NewAccessPointClient(address, password string) *AccessPointClient {
+	return &AccessPointClient{NewClient(address, password)}
+}
+
+// AccessPointStatus represents the JSON status returned by an access point.
+type AccessPointStatus struct {
+	// 5GHz or 6GHz channel number the radio is broadcasting on.
+	Channel int `json:"channel"`
+
+	// Channel bandwidth mode for the radio to use. Valid values are "20MHz", "40MHz", "80MHz" and "160MHz".
+	ChannelBandwidth string `json:"channelBandwidth"`
+
+	// Effective transmit power of the radio in dBm. Zero if it couldn't be determined.
+	TxPower int `json:"txPower"`
+
+	// VLANs to use for the teams of the red alliance. Valid values are "10_20_30", "40_50_60", and "70_80_90".
+	RedVlans string `json:"redVlans"`
+
+	// VLANs to use for the teams of the blue alliance. Valid values are "10_20_30", "40_50_60", and "70_80_90".
+	BlueVlans string `json:"blueVlans"`
+
+	// VLAN IDs used for individual team stations, keyed by station name, overriding the ones given by RedVlans and
+	// BlueVlans.
+	StationVlans map[string]int `json:"stationVlans"`
+
+	// Enum representing the current configuration stage of the radio.
+	Status string `json:"status"`
+
+	// Error encountered while applying the most recent configuration request. Blank if it succeeded.
+	ConfigurationError string `json:"configurationError"`
+
+	// Whether the radio reverted to its previous configuration after the most recent request failed.
+	RolledBack bool `json:"rolledBack"`
+
+	// Map of team station names to their current status. A station is nil if its network is disabled.
+	StationStatuses map[string]*NetworkStatus `json:"stationStatuses"`
+
+	// IP address of the syslog server that logs are sent to.
+	SyslogIpAddress string `json:"syslogIpAddress"`
+
+	// Version of the radio software.
+	Version string `json:"version"`
+}
+
+// AccessPointConfigurationRequest represents a JSON request to configure an access point.
+type AccessPointConfigurationRequest struct {
+	// 5GHz or 6GHz channel number for the radio to use, or AutoChannel to use the least congested valid channel. Set to
+	// 0 to leave unchanged.
+	Channel AccessPointChannel `json:"channel"`
+
+	// Channel bandwidth mode for the radio to use. Valid values are "20MHz", "40MHz", "80MHz" and "160MHz". Set to an
+	// empty string to leave unchanged.
+	ChannelBandwidth string `json:"channelBandwidth"`
+
+	// Transmit power in dBm for the radio to use. The valid range depends on the hardware type. Set to 0 to leave
+	// unchanged, or to TxPowerDefault to restore the driver's default.
+	TxPower int `json:"txPower"`
+
+	// VLANs to use for the teams of the red alliance. Valid values are "10_20_30", "40_50_60", and "70_80_90".
+	RedVlans string `json:"redVlans"`
+
+	// VLANs to use for the teams of the blue alliance. Valid values are "10_20_30", "40_50_60", and "70_80_90".
+	BlueVlans string `json:"blueVlans"`
+
+	// VLAN IDs to use for individual team stations, keyed by station name (e.g. "red1"), overriding the ones given by
+	// RedVlans and BlueVlans. Each must have a matching "vlanN" interface on the access point. Replaces any previous
+	// overrides if non-nil (an empty map removes them all), or leaves them unchanged if nil.
+	StationVlans map[string]int `json:"stationVlans"`
+
+	// SSID and WPA key for each team station, keyed by alliance and number (e.g. "red1", "blue3). If a station is not
+	// included, its network will be disabled by setting its SSID to a placeholder.
+	StationConfigurations map[string]StationConfiguration `json:"stationConfigurations"`
+
+	// IP address of the syslog server to send logs to (via UDP on port 514).
+	SyslogIpAddress string `json:"syslogIpAddress"`
+
+	// Maximum throughput in megabits per second to allow in each direction for every configured station that doesn't
+	// specify its own limit. Set to 0 for no limit.
+	BandwidthLimitMbps float64 `json:"bandwidthLimitMbps"`
+}
+
+// StationConfiguration represents the configuration for a single team station.
+type StationConfiguration struct {
+	// Team-specific SSID for the station, usually equal to the team number as a string.
+	Ssid string `json:"ssid"`
+
+	// Team-specific WPA key for the station. Must be at least eight characters long.
+	WpaKey string `json:"wpaKey"`
+
+	// Maximum throughput in megabits per second to allow in each direction for the station, overriding the limit in
+	// the request as a whole. Set to 0 to fall back to that limit.
+	BandwidthLimitMbps float64 `json:"bandwidthLimitMbps"`
+}
+
+// AccessPointChannel represents the channel requested for an access point, which is sent in JSON either as a number or
+// as the string "auto".
+type AccessPointChannel int
+
+// AutoChannel requests that the access point pick the least congested valid channel.
+const AutoChannel AccessPointChannel = -1
+
+// TxPowerDefault requests that the radio remove any explicitly configured transmit power and restore the Wi-Fi
+// driver's default.
+const TxPowerDefault = -1
+
+// MarshalJSON encodes the channel as a number, or as "auto" for AutoChannel.
+func (channel AccessPointChannel) MarshalJSON() ([]byte, error) {
+	if channel == AutoChannel {
+		return json.Marshal("auto")
+	}
+	return json.Marshal(int(channel))
+}
+
+// UnmarshalJSON decodes the channel from either a number or the string "auto".
+func (channel *AccessPointChannel) UnmarshalJSON(data []byte) error {
+	var number int
+	if err := json.Unmarshal(data, &number); err == nil {
+		*channel = AccessPointChannel(number)
+		return nil
+	}
+	var value string
+	if err := json.Unmarshal(data, &value); err != nil || value != "auto" {
+		return fmt.Errorf("invalid channel %s (expecting a number or \"auto\")", string(data))
+	}
+	*channel = AutoChannel
+	return nil
+}
+
+// ChannelSurvey represents the JSON results of scanning for neighboring networks from an access point.
+type ChannelSurvey struct {
+	// Every network found by the scan, in the order reported by the radio.
+	Networks []SurveyedNetwork `json:"networks"`
+
+	// Congestion of each valid channel for the radio's hardware type, in ascending order of channel number.
+	Channels []ChannelCongestion `json:"channels"`
+
+	// Valid channel having the least congestion, which is the one selected for AutoChannel.
+	LeastCongestedChannel int `json:"leastCongestedChannel"`
+}
+
+// SurveyedNetwork represents a single neighboring network found by scanning.
+type SurveyedNetwork struct {
+	// MAC address of the access point broadcasting the network.
+	MacAddress string `json:"macAddress"`
+
+	// SSID of the network. Blank if hidden.
+	Ssid string `json:"ssid"`
+
+	// Primary channel number of the network.
+	Channel int `json:"channel"`
+
+	// Width of the network's channel, in MHz. 20 unless the network advertises a wider channel.
+	ChannelWidthMhz int `json:"channelWidthMhz"`
+
+	// Signal strength of the network as received by the access point, in decibel-milliwatts.
+	SignalDbm int `json:"signalDbm"`
+
+	// Human-readable description of the network's encryption (e.g. "WPA3 SAE (CCMP)").
+	Encryption string `json:"encryption"`
+}
+
+// ChannelCongestion summarizes the neighboring networks found on a single channel.
+type ChannelCongestion struct {
+	// Channel number.
+	Channel int `json:"channel"`
+
+	// Number of neighboring networks occupying the channel, either as their primary channel or as part of a wider one.
+	NetworkCount int `json:"networkCount"`
+
+	// Signal strength of the strongest neighboring network on the channel, in decibel-milliwatts. Zero if there are
+	// none.
+	StrongestSignalDbm int `json:"strongestSignalDbm"`
+
+	// Combined signal strength of all the neighboring networks occupying the channel, in decibel-milliwatts. Zero if
+	// there are none.
+	TotalSignalDbm int `json:"totalSignalDbm"`
+}
+
+// GetStatus returns the current status of the access point.
+func (client *AccessPointClient) GetStatus(ctx context.Context) (*AccessPointStatus, error) {
+	var status AccessPointStatus
+	if err := client.getJson(ctx, "/status", &status); err != nil {
+		return nil, err
+	}
+	return &status, nil
+}
+
+// GetSurvey scans for neighboring networks from the access point and returns how congested they make each channel.
+func (client *AccessPointClient) GetSurvey(ctx context.Context) (*ChannelSurvey, error) {
+	var survey ChannelSurvey
+	if err := client.getJson(ctx, "/survey", &survey); err != nil {
+		return nil, err
+	}
+	return &survey, nil
+}
+
+// Configure submits the given configuration request to the access point and returns the job tracking it. The request
+// is applied asynchronously; use WaitForConfiguration to wait for it to finish.
+func (client *AccessPointClient) Configure(
+	ctx context.Context, request AccessPointConfigurationRequest,
+) (*ConfigurationJob, error) {
+	return client.configure(ctx, request)
+}
+
+// WaitForConfiguration polls the configuration job with the given ID until it finishes and the access point reports an
+// ACTIVE status, returning the final status. Returns an error if the job fails or is superseded, or if the context is
+// done first.
+func (client *AccessPointClient) WaitForConfiguration(ctx context.Context, jobId string) (*AccessPointStatus, error) {
+	var status *AccessPointStatus
+	err := client.waitForConfiguration(ctx, jobId, func(ctx context.Context) (string, error) {
+		var err error
+		if status, err = client.GetStatus(ctx); err != nil {
+			return "", err
+		}
+		return status.Status, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return status, nil
+}
@@ -0,0 +1,166 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"testing"
+)
+
+func TestAccessPointClient_GetStatus(t *testing.T) {
+	server := newTestServer(t, "mypassword", map[string]http.HandlerFunc{
+		"GET /status": func(w http.ResponseWriter, r *http.Request) {
+			_, _ = fmt.Fprint(
+				w,
+				`{"channel": 93, "channelBandwidth": "HT40", "redVlans": "10_20_30", "blueVlans": "40_50_60",
+				"status": "ACTIVE", "stationStatuses": {"red1": {"ssid": "254", "isLinked": true, "rxRateMbps": 860.3},
+				"blue1": null}, "version": "1.2.3"}`,
+			)
+		},
+	})
+	client := AccessPointClient{newTestClient(server, "mypassword")}
+
+	status, err := client.GetStatus(context.Background())
+	if assert.Nil(t, err) {
+		assert.Equal(t, 93, status.Channel)
+		assert.Equal(t, "HT40", status.ChannelBandwidth)
+		assert.Equal(t, "10_20_30", status.RedVlans)
+		assert.Equal(t, "40_50_60", status.BlueVlans)
+		assert.Equal(t, StatusActive, status.Status)
+		assert.Equal(t, "1.2.3", status.Version)
+		assert.Equal(t, "254", status.StationStatuses["red1"].Ssid)
+		assert.True(t, status.StationStatuses["red1"].IsLinked)
+		assert.Equal(t, 860.3, status.StationStatuses["red1"].RxRateMbps)
+		assert.Nil(t, status.StationStatuses["blue1"])
+	}
+}
+
+func TestAccessPointClient_Configure(t *testing.T) {
+	var receivedRequest AccessPointConfigurationRequest
+	server := newTestServer(t, "", map[string]http.HandlerFunc{
+		"POST /configuration": func(w http.ResponseWriter, r *http.Request) {
+			assert.Equal(t, "application/json", r.Header.Get("Content-Type"))
+			assert.Nil(t, json.NewDecoder(r.Body).Decode(&receivedRequest))
+			w.WriteHeader(http.StatusAccepted)
+			_, _ = fmt.Fprint(w, `{"id": "abc", "status": "QUEUED", "message": "New configuration received."}`)
+		},
+		"GET /configuration/abc": func(w http.ResponseWriter, r *http.Request) {
+			_, _ = fmt.Fprint(w, `{"id": "abc", "status": "SUCCEEDED"}`)
+		},
+		"GET /status": func(w http.ResponseWriter, r *http.Request) {
+			_, _ = fmt.Fprint(w, `{"channel": 5, "status": "ACTIVE"}`)
+		},
+	})
+	client := AccessPointClient{newTestClient(server, "")}
+
+	request := AccessPointConfigurationRequest{
+		Channel:               5,
+		StationConfigurations: map[string]StationConfiguration{"red1": {Ssid: "254", WpaKey: "12345678"}},
+	}
+	job, err := client.Configure(context.Background(), request)
+	if assert.Nil(t, err) {
+		assert.Equal(t, "abc", job.Id)
+		assert.Equal(t, JobQueued, job.Status)
+	}
+	assert.Equal(t, request, receivedRequest)
+
+	status, err := client.WaitForConfiguration(context.Background(), job.Id)
+	if assert.Nil(t, err) {
+		assert.Equal(t, 5, status.Channel)
+		assert.Equal(t, StatusActive, status.Status)
+	}
+}
+
+func TestAccessPointClient_ConfigureError(t *testing.T) {
+	server := newTestServer(t, "", map[string]http.HandlerFunc{
+		"POST /configuration": func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusBadRequest)
+			_, _ = fmt.Fprint(
+				w,
+				`{"code": "INVALID_CHANNEL", "field": "channel", "message": "invalid configuration: invalid channel"}`,
+			)
+		},
+	})
+
+	_, err := NewAccessPointClient(server.URL, "").Configure(
+		context.Background(), AccessPointConfigurationRequest{Channel: 1},
+	)
+	if assert.NotNil(t, err) {
+		assert.Equal(t, "POST /configuration returned status 400: invalid configuration: invalid channel", err.Error())
+		var apiErr *ApiError
+		if assert.True(t, errors.As(err, &apiErr)) {
+			assert.Equal(t, 400, apiErr.StatusCode)
+			assert.Equal(t, "INVALID_CHANNEL", apiErr.Code)
+			assert.Equal(t, "channel", apiErr.Field)
+		}
+	}
+}
+
+func TestAccessPointClient_ConfigureAutoChannel(t *testing.T) {
+	var receivedBody map[string]any
+	server := newTestServer(t, "", map[string]http.HandlerFunc{
+		"POST /configuration": func(w http.ResponseWriter, r *http.Request) {
+			assert.Nil(t, json.NewDecoder(r.Body).Decode(&receivedBody))
+			w.WriteHeader(http.StatusAccepted)
+			_, _ = fmt.Fprint(w, `{"id": "abc", "status": "QUEUED"}`)
+		},
+	})
+
+	_, err := NewAccessPointClient(server.URL, "").Configure(
+		context.Background(), AccessPointConfigurationRequest{Channel: AutoChannel},
+	)
+	assert.Nil(t, err)
+	assert.Equal(t, "auto", receivedBody["channel"])
+
+	var channel AccessPointChannel
+	assert.Nil(t, json.Unmarshal([]byte(`"auto"`), &channel))
+	assert.Equal(t, AutoChannel, channel)
+	assert.Nil(t, json.Unmarshal([]byte("37"), &channel))
+	assert.Equal(t, AccessPointChannel(37), channel)
+	assert.EqualError(
+		t, json.Unmarshal([]byte(`"best"`), &channel), `invalid channel "best" (expecting a number or "auto")`,
+	)
+}
+
+func TestAccessPointClient_GetSurvey(t *testing.T) {
+	server := newTestServer(t, "mypassword", map[string]http.HandlerFunc{
+		"GET /survey": func(w http.ResponseWriter, r *http.Request) {
+			_, _ = fmt.Fprint(
+				w,
+				`{"networks": [{"macAddress": "48:DA:35:B0:10:01", "ssid": "practice-field", "channel": 37,
+				"signalDbm": -58, "encryption": "WPA3 SAE (CCMP)"}], "channels": [{"channel": 5, "networkCount": 0,
+				"strongestSignalDbm": 0, "totalSignalDbm": 0}, {"channel": 37, "networkCount": 1,
+				"strongestSignalDbm": -58, "totalSignalDbm": -58}], "leastCongestedChannel": 5}`,
+			)
+		},
+	})
+	client := AccessPointClient{newTestClient(server, "mypassword")}
+
+	survey, err := client.GetSurvey(context.Background())
+	if assert.Nil(t, err) {
+		assert.Equal(
+			t,
+			[]SurveyedNetwork{
+				{
+					MacAddress: "48:DA:35:B0:10:01",
+					Ssid:       "practice-field",
+					Channel:    37,
+					SignalDbm:  -58,
+					Encryption: "WPA3 SAE (CCMP)",
+				},
+			},
+			survey.Networks,
+		)
+		assert.Equal(t, 2, len(survey.Channels))
+		assert.Equal(
+			t,
+			ChannelCongestion{Channel: 37, NetworkCount: 1, StrongestSignalDbm: -58, TotalSignalDbm: -58},
+			survey.Channels[1],
+		)
+		assert.Equal(t, 5, survey.LeastCongestedChannel)
+	}
+}
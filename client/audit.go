@@ -0,0 +1,54 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"net/url"
+	"time"
+)
+
+// AuditEntry represents a single record in the radio's audit log of mutating requests.
+type AuditEntry struct {
+	// Time at which the action occurred.
+	Time time.Time `json:"time"`
+
+	// Address of the client that requested the action, or the URL of the MQTT broker it was received from.
+	RemoteAddress string `json:"remoteAddress"`
+
+	// Identity of the credential that the action was requested with; one of "anonymous", "password", "mqtt" or
+	// "token [name] ([id])".
+	Identity string `json:"identity"`
+
+	// Description of the action, e.g. "POST /configuration" or "CONFIGURATION_COMPLETED".
+	Action string `json:"action"`
+
+	// Summary of the request, with secrets such as WPA keys redacted.
+	Request json.RawMessage `json:"request,omitempty"`
+
+	// HTTP status code of the response, if the action was requested through the API.
+	StatusCode int `json:"statusCode,omitempty"`
+
+	// Outcome of the action; one of "ACCEPTED", "REJECTED" or "UNAUTHORIZED" for requests, or the final status of the
+	// job for completed configuration jobs.
+	Outcome string `json:"outcome"`
+
+	// Error message explaining why the request was rejected or the configuration failed, if it was.
+	Error string `json:"error,omitempty"`
+
+	// ID of the configuration job that the action queued or completed, if any.
+	JobId string `json:"jobId,omitempty"`
+}
+
+// GetAuditLog returns the entries that the radio recorded in its audit log after the given time, oldest first. A zero
+// time returns all the entries that the radio has retained. Requires the password or a token having the admin scope.
+func (client *Client) GetAuditLog(ctx context.Context, since time.Time) ([]AuditEntry, error) {
+	path := "/audit"
+	if !since.IsZero() {
+		path += "?" + url.Values{"since": {since.Format(time.RFC3339Nano)}}.Encode()
+	}
+	var entries []AuditEntry
+	if err := client.getJson(ctx, path, &entries); err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
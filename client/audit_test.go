@@ -0,0 +1,51 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestClient_GetAuditLog(t *testing.T) {
+	var query string
+	server := newTestServer(t, "mypassword", map[string]http.HandlerFunc{
+		"GET /audit": func(w http.ResponseWriter, r *http.Request) {
+			query = r.URL.RawQuery
+			_, _ = fmt.Fprint(
+				w,
+				`[{"time": "2024-04-20T12:00:00Z", "remoteAddress": "10.0.100.5:51234", "identity": "password", `+
+					`"action": "POST /configuration", "request": {"channel": 93}, "statusCode": 202, `+
+					`"outcome": "ACCEPTED", "jobId": "1"}]`,
+			)
+		},
+	})
+	client := newTestClient(server, "mypassword")
+
+	entries, err := client.GetAuditLog(context.Background(), time.Time{})
+	assert.Nil(t, err)
+	assert.Equal(t, "", query)
+	assert.Equal(
+		t,
+		[]AuditEntry{
+			{
+				Time:          time.Date(2024, 4, 20, 12, 0, 0, 0, time.UTC),
+				RemoteAddress: "10.0.100.5:51234",
+				Identity:      "password",
+				Action:        "POST /configuration",
+				Request:       json.RawMessage(`{"channel": 93}`),
+				StatusCode:    202,
+				Outcome:       "ACCEPTED",
+				JobId:         "1",
+			},
+		},
+		entries,
+	)
+
+	_, err = client.GetAuditLog(context.Background(), time.Date(2024, 4, 20, 12, 0, 0, 500, time.UTC))
+	assert.Nil(t, err)
+	assert.Equal(t, "since=2024-04-20T12%3A00%3A00.0000005Z", query)
+}
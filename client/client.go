@@ -0,0 +1,329 @@
+// Package client provides a Go client for the API that runs on the FRC access point and robot radios.
+//
+// This package mirrors the JSON types of the access point and robot radio rather than importing them from the radio
+// package, so that clients don't depend on the server's internals. Use AccessPointClient or RobotRadioClient for the
+// endpoints whose types differ between the two, or Client for the ones they have in common.
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const (
+	// Default number of times to retry a request that failed due to a network error or a server-side error.
+	DefaultMaxRetries = 2
+
+	// Default interval to wait before retrying a failed request.
+	DefaultRetryInterval = 500 * time.Millisecond
+
+	// Timeout for individual HTTP requests to the radio, other than firmware uploads.
+	requestTimeout = 10 * time.Second
+
+	// Timeout for firmware uploads, which can be large and go over a slow link.
+	firmwareUploadTimeout = 5 * time.Minute
+
+	// Interval between polls of the radio while waiting for a configuration request to be applied.
+	configurationPollInterval = 500 * time.Millisecond
+)
+
+// RadioType identifies which of the two flavors of the API a radio is running.
+type RadioType string
+
+const (
+	TypeAccessPoint RadioType = "ACCESS_POINT"
+	TypeRobotRadio  RadioType = "ROBOT_RADIO"
+)
+
+// Client talks to the endpoints that are common to the API on both the access point and the robot radio.
+type Client struct {
+	// HTTP client used to send requests. Defaults to one with no timeout of its own, since each request is bounded by
+	// its context and a per-request timeout.
+	HttpClient *http.Client
+
+	// Number of times to retry a request that failed due to a network error or a 5xx response. Firmware uploads are
+	// never retried.
+	MaxRetries int
+
+	// Interval to wait before retrying a failed request.
+	RetryInterval time.Duration
+
+	// Base URL of the API, e.g. "http://10.0.100.2:8081".
+	baseUrl string
+
+	// Password or API token secret for authorizing requests to the API. If blank, no Authorization header is sent.
+	password string
+
+	// Interval between polls while waiting for a configuration request to be applied.
+	pollInterval time.Duration
+}
+
+// NewClient creates a client for the radio at the given address, which may be a bare host and port (e.g.
+// "10.0.100.2:8081") or a full URL. The password (or the secret of an API token) is sent as a bearer token and may be
+// blank if the radio doesn't require authorization.
+func NewClient(address, password string) *Client {
+	baseUrl := strings.TrimRight(address, "/")
+	if !strings.Contains(baseUrl, "://") {
+		baseUrl = "http://" + baseUrl
+	}
+	return &Client{
+		HttpClient:    &http.Client{},
+		MaxRetries:    DefaultMaxRetries,
+		RetryInterval: DefaultRetryInterval,
+		baseUrl:       baseUrl,
+		password:      password,
+		pollInterval:  configurationPollInterval,
+	}
+}
+
+// Health returns nil if the API on the radio is up and responding.
+func (client *Client) Health(ctx context.Context) error {
+	_, err := client.do(ctx, http.MethodGet, "/health", "", nil, requestTimeout, true)
+	return err
+}
+
+// DetectRadioType determines whether the radio is an access point or a robot radio from the shape of its status.
+func (client *Client) DetectRadioType(ctx context.Context) (RadioType, error) {
+	var status map[string]json.RawMessage
+	if err := client.getJson(ctx, "/status", &status); err != nil {
+		return "", err
+	}
+	if _, ok := status["stationStatuses"]; ok {
+		return TypeAccessPoint, nil
+	}
+	if _, ok := status["networkStatus6"]; ok {
+		return TypeRobotRadio, nil
+	}
+	return "", errors.New("unable to determine radio type from its status")
+}
+
+// GetConfigurationJob returns the current state of the configuration job with the given ID.
+func (client *Client) GetConfigurationJob(ctx context.Context, id string) (*ConfigurationJob, error) {
+	var job ConfigurationJob
+	if err := client.getJson(ctx, "/configuration/"+id, &job); err != nil {
+		return nil, err
+	}
+	return &job, nil
+}
+
+// GetLinkHistory returns the samples of the given network's link metrics that the radio took after the given time,
+// oldest first. The network is the name of a team station on the access point, or "6GHz" or "2.4GHz" on the robot
+// radio. A zero time returns all the samples that the radio has retained.
+func (client *Client) GetLinkHistory(ctx context.Context, network string, since time.Time) ([]LinkSample, error) {
+	query := url.Values{"station": {network}}
+	if !since.IsZero() {
+		query.Set("since", since.Format(time.RFC3339))
+	}
+	var samples []LinkSample
+	if err := client.getJson(ctx, "/status/history?"+query.Encode(), &samples); err != nil {
+		return nil, err
+	}
+	return samples, nil
+}
+
+// GetLinkEvents returns the link events that the radio recorded after the given time, oldest first. If the network is
+// not blank, only its events are returned. A zero time returns all the events that the radio has retained.
+func (client *Client) GetLinkEvents(ctx context.Context, network string, since time.Time) ([]LinkEvent, error) {
+	query := url.Values{}
+	if network != "" {
+		query.Set("station", network)
+	}
+	if !since.IsZero() {
+		query.Set("since", since.Format(time.RFC3339))
+	}
+	path := "/events"
+	if len(query) > 0 {
+		path += "?" + query.Encode()
+	}
+	var events []LinkEvent
+	if err := client.getJson(ctx, path, &events); err != nil {
+		return nil, err
+	}
+	return events, nil
+}
+
+// UpdateFirmware uploads the given firmware file to the radio, which will flash it and reboot. The checksum is the
+// hexadecimal-encoded SHA-256 hash of the unencrypted firmware (see FirmwareChecksum), and the file may optionally have
+// been encrypted using EncryptFirmware. Returns the message sent back by the radio.
+func (client *Client) UpdateFirmware(ctx context.Context, file io.Reader, checksum string) (string, error) {
+	var body bytes.Buffer
+	writer := multipart.NewWriter(&body)
+	part, err := writer.CreateFormFile("file", "firmware")
+	if err != nil {
+		return "", err
+	}
+	if _, err = io.Copy(part, file); err != nil {
+		return "", err
+	}
+	if err = writer.WriteField("checksum", checksum); err != nil {
+		return "", err
+	}
+	if err = writer.Close(); err != nil {
+		return "", err
+	}
+
+	response, err := client.do(
+		ctx, http.MethodPost, "/firmware", writer.FormDataContentType(), body.Bytes(), firmwareUploadTimeout, false,
+	)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(response)), nil
+}
+
+// configure submits the given configuration request to the radio and returns the job tracking it.
+func (client *Client) configure(ctx context.Context, request any) (*ConfigurationJob, error) {
+	body, err := json.Marshal(request)
+	if err != nil {
+		return nil, err
+	}
+
+	// Resending a configuration request is harmless since the radio only ever applies the newest one, so it is safe
+	// to retry.
+	response, err := client.do(ctx, http.MethodPost, "/configuration", "application/json", body, requestTimeout, true)
+	if err != nil {
+		return nil, err
+	}
+	var job ConfigurationJob
+	if err = json.Unmarshal(response, &job); err != nil {
+		return nil, fmt.Errorf("invalid configuration response: %v", err)
+	}
+	return &job, nil
+}
+
+// waitForConfiguration polls the configuration job with the given ID until it finishes and the given function reports
+// that the radio is ACTIVE. Returns an error if the job fails or is superseded, or if the context is done first.
+func (client *Client) waitForConfiguration(
+	ctx context.Context, jobId string, getStatus func(ctx context.Context) (string, error),
+) error {
+	jobStatus := "unknown"
+	for {
+		job, err := client.GetConfigurationJob(ctx, jobId)
+		if err != nil {
+			if ctx.Err() != nil {
+				break
+			}
+			return err
+		}
+		jobStatus = job.Status
+		switch job.Status {
+		case JobFailed:
+			if job.RollbackError != "" {
+				return fmt.Errorf("configuration failed: %s; rollback also failed: %s", job.Error, job.RollbackError)
+			}
+			return fmt.Errorf("configuration failed: %s", job.Error)
+		case JobSuperseded:
+			return errors.New("configuration was superseded by a newer request")
+		case JobSucceeded:
+			status, err := getStatus(ctx)
+			if err != nil {
+				return err
+			}
+			if status == StatusActive {
+				return nil
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+		case <-time.After(client.pollInterval):
+		}
+		if ctx.Err() != nil {
+			break
+		}
+	}
+	return fmt.Errorf("gave up waiting for configuration to be applied; job is %s: %w", jobStatus, ctx.Err())
+}
+
+// getJson sends a GET request for the given path and decodes the JSON response into the given value.
+func (client *Client) getJson(ctx context.Context, path string, value any) error {
+	response, err := client.do(ctx, http.MethodGet, path, "", nil, requestTimeout, true)
+	if err != nil {
+		return err
+	}
+	if err = json.Unmarshal(response, value); err != nil {
+		return fmt.Errorf("invalid response from %s: %v", path, err)
+	}
+	return nil
+}
+
+// do sends a request to the given path and returns the response body, or an error if the request failed or the radio
+// responded with an error status (in which case it is an *ApiError). If the request is retryable, it is retried after
+// network errors and 5xx responses.
+func (client *Client) do(
+	ctx context.Context, method, path, contentType string, body []byte, timeout time.Duration, retryable bool,
+) ([]byte, error) {
+	maxAttempts := 1
+	if retryable && client.MaxRetries > 0 {
+		maxAttempts += client.MaxRetries
+	}
+
+	var err error
+	for attempt := 1; ; attempt++ {
+		var responseBody []byte
+		var statusCode int
+		responseBody, statusCode, err = client.doOnce(ctx, method, path, contentType, body, timeout)
+		if err == nil && statusCode >= 200 && statusCode < 300 {
+			return responseBody, nil
+		}
+		if err == nil {
+			err = newApiError(method, path, statusCode, responseBody)
+			if statusCode < 500 {
+				// The request itself was at fault, so there is no point retrying it.
+				return nil, err
+			}
+		}
+		if ctx.Err() != nil || attempt >= maxAttempts {
+			return nil, err
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, err
+		case <-time.After(client.RetryInterval):
+		}
+	}
+}
+
+// doOnce makes a single attempt at sending a request and returns the response body and status code.
+func (client *Client) doOnce(
+	ctx context.Context, method, path, contentType string, body []byte, timeout time.Duration,
+) ([]byte, int, error) {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	var bodyReader io.Reader
+	if body != nil {
+		bodyReader = bytes.NewReader(body)
+	}
+	request, err := http.NewRequestWithContext(ctx, method, client.baseUrl+path, bodyReader)
+	if err != nil {
+		return nil, 0, err
+	}
+	if contentType != "" {
+		request.Header.Set("Content-Type", contentType)
+	}
+	if client.password != "" {
+		request.Header.Set("Authorization", "Bearer "+client.password)
+	}
+
+	response, err := client.HttpClient.Do(request)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer response.Body.Close()
+	responseBody, err := io.ReadAll(response.Body)
+	if err != nil {
+		return nil, 0, err
+	}
+	return responseBody, response.StatusCode, nil
+}
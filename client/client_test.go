@@ -0,0 +1,336 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// newTestServer starts a server that checks for the given password and serves the given handlers by method and path.
+func newTestServer(t *testing.T, password string, handlers map[string]http.HandlerFunc) *httptest.Server {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if password != "" && r.Header.Get("Authorization") != "Bearer "+password {
+			http.Error(w, "HTTP request error 401: not authorized", http.StatusUnauthorized)
+			return
+		}
+		handler, ok := handlers[r.Method+" "+r.URL.Path]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		handler(w, r)
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+// newTestClient creates a client for the given server that doesn't wait around between retries and polls.
+func newTestClient(server *httptest.Server, password string) *Client {
+	client := NewClient(server.URL, password)
+	client.RetryInterval = time.Millisecond
+	client.pollInterval = time.Millisecond
+	return client
+}
+
+func TestNewClient(t *testing.T) {
+	assert.Equal(t, "http://10.0.100.2:8081", NewClient("10.0.100.2:8081", "").baseUrl)
+	assert.Equal(t, "https://radio.local:8081", NewClient("https://radio.local:8081/", "").baseUrl)
+	assert.Equal(t, DefaultMaxRetries, NewClient("10.0.100.2:8081", "").MaxRetries)
+}
+
+func TestClient_Health(t *testing.T) {
+	server := newTestServer(t, "mypassword", map[string]http.HandlerFunc{
+		"GET /health": func(w http.ResponseWriter, r *http.Request) {
+			_, _ = fmt.Fprintln(w, "OK")
+		},
+	})
+
+	assert.Nil(t, newTestClient(server, "mypassword").Health(context.Background()))
+
+	err := newTestClient(server, "wrongpassword").Health(context.Background())
+	if assert.NotNil(t, err) {
+		assert.Equal(t, "GET /health returned status 401: HTTP request error 401: not authorized", err.Error())
+	}
+
+	client := NewClient("127.0.0.1:1", "")
+	client.RetryInterval = time.Millisecond
+	assert.NotNil(t, client.Health(context.Background()))
+}
+
+func TestClient_Retries(t *testing.T) {
+	requestCount := 0
+	statusCodes := []int{}
+	server := newTestServer(t, "", map[string]http.HandlerFunc{
+		"GET /health": func(w http.ResponseWriter, r *http.Request) {
+			statusCode := statusCodes[requestCount]
+			requestCount++
+			w.WriteHeader(statusCode)
+			_, _ = fmt.Fprintln(w, http.StatusText(statusCode))
+		},
+		"POST /firmware": func(w http.ResponseWriter, r *http.Request) {
+			requestCount++
+			w.WriteHeader(http.StatusServiceUnavailable)
+		},
+	})
+	client := newTestClient(server, "")
+
+	// Server-side errors are retried.
+	statusCodes = []int{http.StatusInternalServerError, http.StatusBadGateway, http.StatusOK}
+	assert.Nil(t, client.Health(context.Background()))
+	assert.Equal(t, 3, requestCount)
+
+	// Giving up after the maximum number of retries.
+	requestCount = 0
+	statusCodes = []int{http.StatusBadGateway, http.StatusBadGateway, http.StatusBadGateway, http.StatusOK}
+	err := client.Health(context.Background())
+	if assert.NotNil(t, err) {
+		assert.Equal(t, "GET /health returned status 502: Bad Gateway", err.Error())
+	}
+	assert.Equal(t, 3, requestCount)
+
+	// Client-side errors are not retried.
+	requestCount = 0
+	statusCodes = []int{http.StatusBadRequest, http.StatusOK}
+	assert.NotNil(t, client.Health(context.Background()))
+	assert.Equal(t, 1, requestCount)
+
+	// Retries can be disabled.
+	requestCount = 0
+	client.MaxRetries = 0
+	statusCodes = []int{http.StatusInternalServerError, http.StatusOK}
+	assert.NotNil(t, client.Health(context.Background()))
+	assert.Equal(t, 1, requestCount)
+
+	// Firmware uploads are never retried.
+	requestCount = 0
+	client.MaxRetries = 2
+	_, err = client.UpdateFirmware(context.Background(), strings.NewReader("firmware"), "abc123")
+	assert.NotNil(t, err)
+	assert.Equal(t, 1, requestCount)
+}
+
+func TestClient_ContextCanceled(t *testing.T) {
+	server := newTestServer(t, "", map[string]http.HandlerFunc{
+		"GET /health": func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		},
+	})
+	client := newTestClient(server, "")
+	client.RetryInterval = time.Hour
+
+	// The wait between retries is cut short when the context is done.
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	startTime := time.Now()
+	err := client.Health(ctx)
+	if assert.NotNil(t, err) {
+		assert.Equal(t, "GET /health returned status 503: ", err.Error())
+	}
+	assert.Less(t, time.Since(startTime), time.Second)
+
+	err = client.Health(ctx)
+	assert.True(t, errors.Is(err, context.DeadlineExceeded))
+}
+
+func TestClient_DetectRadioType(t *testing.T) {
+	statusJson := ""
+	server := newTestServer(t, "", map[string]http.HandlerFunc{
+		"GET /status": func(w http.ResponseWriter, r *http.Request) {
+			_, _ = fmt.Fprint(w, statusJson)
+		},
+	})
+	client := newTestClient(server, "")
+
+	statusJson = `{"channel": 93, "status": "ACTIVE", "stationStatuses": {"red1": null}}`
+	radioType, err := client.DetectRadioType(context.Background())
+	assert.Nil(t, err)
+	assert.Equal(t, TypeAccessPoint, radioType)
+
+	statusJson = `{"mode": "TEAM_ROBOT_RADIO", "networkStatus24": {}, "networkStatus6": {}}`
+	radioType, err = client.DetectRadioType(context.Background())
+	assert.Nil(t, err)
+	assert.Equal(t, TypeRobotRadio, radioType)
+
+	statusJson = `{"status": "ACTIVE"}`
+	_, err = client.DetectRadioType(context.Background())
+	if assert.NotNil(t, err) {
+		assert.Equal(t, "unable to determine radio type from its status", err.Error())
+	}
+
+	statusJson = "not JSON"
+	_, err = client.DetectRadioType(context.Background())
+	if assert.NotNil(t, err) {
+		assert.Contains(t, err.Error(), "invalid response from /status")
+	}
+}
+
+func TestClient_GetLinkHistory(t *testing.T) {
+	var query string
+	server := newTestServer(t, "", map[string]http.HandlerFunc{
+		"GET /status/history": func(w http.ResponseWriter, r *http.Request) {
+			query = r.URL.RawQuery
+			if r.URL.Query().Get("station") == "red4" {
+				http.Error(w, "HTTP request error 400: invalid network \"red4\"", http.StatusBadRequest)
+				return
+			}
+			_, _ = fmt.Fprint(
+				w, `[{"time": "2024-04-20T12:00:00Z", "ssid": "254", "isLinked": true, "rxRateMbps": 550.6}]`,
+			)
+		},
+	})
+	client := newTestClient(server, "")
+
+	samples, err := client.GetLinkHistory(context.Background(), "red1", time.Time{})
+	assert.Nil(t, err)
+	assert.Equal(t, "station=red1", query)
+	if assert.Equal(t, 1, len(samples)) {
+		assert.Equal(t, time.Date(2024, 4, 20, 12, 0, 0, 0, time.UTC), samples[0].Time)
+		assert.Equal(t, "254", samples[0].Ssid)
+		assert.True(t, samples[0].IsLinked)
+		assert.Equal(t, 550.6, samples[0].RxRateMbps)
+	}
+
+	_, err = client.GetLinkHistory(
+		context.Background(), "2.4GHz", time.Date(2024, 4, 20, 12, 0, 0, 0, time.UTC),
+	)
+	assert.Nil(t, err)
+	assert.Equal(t, "since=2024-04-20T12%3A00%3A00Z&station=2.4GHz", query)
+
+	_, err = client.GetLinkHistory(context.Background(), "red4", time.Time{})
+	if assert.NotNil(t, err) {
+		assert.Contains(t, err.Error(), "returned status 400")
+	}
+}
+
+func TestClient_GetLinkEvents(t *testing.T) {
+	var query string
+	server := newTestServer(t, "", map[string]http.HandlerFunc{
+		"GET /events": func(w http.ResponseWriter, r *http.Request) {
+			query = r.URL.RawQuery
+			_, _ = fmt.Fprint(
+				w,
+				`[{"id": 3, "time": "2024-04-20T12:00:00Z", "station": "blue2", "ssid": "1678", "type": "DISASSOCIATED", `+
+					`"before": {"isLinked": true, "macAddress": "48:DA:35:B0:00:CF", "connectionQuality": "good"}, `+
+					`"after": {"isLinked": false, "macAddress": "", "connectionQuality": ""}}]`,
+			)
+		},
+	})
+	client := newTestClient(server, "")
+
+	events, err := client.GetLinkEvents(context.Background(), "", time.Time{})
+	assert.Nil(t, err)
+	assert.Equal(t, "", query)
+	assert.Equal(
+		t,
+		[]LinkEvent{
+			{
+				Id:      3,
+				Time:    time.Date(2024, 4, 20, 12, 0, 0, 0, time.UTC),
+				Station: "blue2",
+				Ssid:    "1678",
+				Type:    "DISASSOCIATED",
+				Before:  LinkState{IsLinked: true, MacAddress: "48:DA:35:B0:00:CF", ConnectionQuality: "good"},
+			},
+		},
+		events,
+	)
+
+	_, err = client.GetLinkEvents(context.Background(), "blue2", time.Date(2024, 4, 20, 12, 0, 0, 0, time.UTC))
+	assert.Nil(t, err)
+	assert.Equal(t, "since=2024-04-20T12%3A00%3A00Z&station=blue2", query)
+}
+
+func TestClient_waitForConfiguration(t *testing.T) {
+	var jobStatuses []string
+	var rollbackError string
+	server := newTestServer(t, "", map[string]http.HandlerFunc{
+		"GET /configuration/abc": func(w http.ResponseWriter, r *http.Request) {
+			status := jobStatuses[0]
+			if len(jobStatuses) > 1 {
+				jobStatuses = jobStatuses[1:]
+			}
+			_, _ = fmt.Fprintf(
+				w,
+				`{"id": "abc", "status": "%s", "error": "something broke", "rollbackError": "%s"}`,
+				status,
+				rollbackError,
+			)
+		},
+	})
+	client := newTestClient(server, "")
+	radioStatuses := []string{StatusConfiguring, StatusActive}
+	getStatus := func(ctx context.Context) (string, error) {
+		status := radioStatuses[0]
+		radioStatuses = radioStatuses[1:]
+		return status, nil
+	}
+
+	jobStatuses = []string{JobQueued, JobApplying, JobApplying, JobSucceeded}
+	assert.Nil(t, client.waitForConfiguration(context.Background(), "abc", getStatus))
+	assert.Equal(t, []string{JobSucceeded}, jobStatuses)
+	assert.Empty(t, radioStatuses)
+
+	jobStatuses = []string{JobApplying, JobFailed}
+	err := client.waitForConfiguration(context.Background(), "abc", getStatus)
+	if assert.NotNil(t, err) {
+		assert.Equal(t, "configuration failed: something broke", err.Error())
+	}
+
+	// The job reports whether the radio could be restored to its previous configuration.
+	jobStatuses, rollbackError = []string{JobFailed}, "reload failed"
+	err = client.waitForConfiguration(context.Background(), "abc", getStatus)
+	if assert.NotNil(t, err) {
+		assert.Equal(t, "configuration failed: something broke; rollback also failed: reload failed", err.Error())
+	}
+	rollbackError = ""
+
+	jobStatuses = []string{JobSuperseded}
+	err = client.waitForConfiguration(context.Background(), "abc", getStatus)
+	if assert.NotNil(t, err) {
+		assert.Equal(t, "configuration was superseded by a newer request", err.Error())
+	}
+
+	jobStatuses = []string{JobApplying}
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	err = client.waitForConfiguration(ctx, "abc", getStatus)
+	if assert.NotNil(t, err) {
+		assert.Equal(
+			t, "gave up waiting for configuration to be applied; job is APPLYING: context deadline exceeded", err.Error(),
+		)
+		assert.True(t, errors.Is(err, context.DeadlineExceeded))
+	}
+
+	err = client.waitForConfiguration(context.Background(), "nonexistent", getStatus)
+	if assert.NotNil(t, err) {
+		assert.True(t, strings.HasPrefix(err.Error(), "GET /configuration/nonexistent returned status 404"))
+	}
+}
+
+func TestClient_UpdateFirmware(t *testing.T) {
+	server := newTestServer(t, "mypassword", map[string]http.HandlerFunc{
+		"POST /firmware": func(w http.ResponseWriter, r *http.Request) {
+			file, _, err := r.FormFile("file")
+			if assert.Nil(t, err) {
+				contents, _ := io.ReadAll(file)
+				assert.Equal(t, "firmware contents", string(contents))
+			}
+			assert.Equal(t, "abc123", r.FormValue("checksum"))
+			w.WriteHeader(http.StatusAccepted)
+			_, _ = fmt.Fprintln(w, "New firmware received and will be applied now.")
+		},
+	})
+
+	message, err := newTestClient(server, "mypassword").UpdateFirmware(
+		context.Background(), strings.NewReader("firmware contents"), "abc123",
+	)
+	assert.Nil(t, err)
+	assert.Equal(t, "New firmware received and will be applied now.", message)
+}
@@ -0,0 +1,52 @@
+package client
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// ApiError is returned when the radio responds to a request with an error status.
+type ApiError struct {
+	// Method and path of the request that failed.
+	Method string
+	Path   string
+
+	// HTTP status code of the response.
+	StatusCode int
+
+	// Machine-readable code identifying the kind of error, e.g. "INVALID_CHANNEL" or "UNAUTHORIZED". Blank if the
+	// radio is running a version of the API that doesn't report one.
+	Code string `json:"code"`
+
+	// Path of the request field that caused the error, e.g. "stationConfigurations.red1.wpaKey", if applicable.
+	Field string `json:"field"`
+
+	// Human-readable description of the error, or the raw response body if it isn't a JSON error.
+	Message string `json:"message"`
+
+	// Every problem found with the fields of a configuration request, grouped by field path (e.g.
+	// "stationConfigurations.red2.wpaKey"), if the request failed validation.
+	Errors map[string][]FieldError `json:"errors"`
+}
+
+// FieldError describes a problem found with a single field of a configuration request.
+type FieldError struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+// newApiError builds the error describing the given error response, falling back to treating the body as plain text
+// if it isn't a JSON error.
+func newApiError(method, path string, statusCode int, body []byte) *ApiError {
+	apiErr := ApiError{}
+	if err := json.Unmarshal(body, &apiErr); err != nil || apiErr.Message == "" {
+		apiErr = ApiError{Message: strings.TrimSpace(string(body))}
+	}
+	apiErr.Method, apiErr.Path, apiErr.StatusCode = method, path, statusCode
+	return &apiErr
+}
+
+func (err *ApiError) Error() string {
+	return fmt.Sprintf("%s %s returned status %d: %s", err.Method, err.Path, err.StatusCode, err.Message)
+}
@@ -0,0 +1,62 @@
+package client
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestNewApiError(t *testing.T) {
+	apiErr := newApiError(
+		"POST",
+		"/configuration",
+		400,
+		[]byte(`{"code": "INVALID_WPA_KEY", "field": "stationConfigurations.red2.wpaKey", "message": "bad key"}`),
+	)
+	assert.Equal(
+		t,
+		ApiError{
+			Method:     "POST",
+			Path:       "/configuration",
+			StatusCode: 400,
+			Code:       "INVALID_WPA_KEY",
+			Field:      "stationConfigurations.red2.wpaKey",
+			Message:    "bad key",
+		},
+		*apiErr,
+	)
+	assert.EqualError(t, apiErr, "POST /configuration returned status 400: bad key")
+
+	// Every validation error is available by field.
+	apiErr = newApiError(
+		"POST",
+		"/configuration",
+		400,
+		[]byte(
+			`{"code": "VALIDATION_FAILED", "message": "bad key; bad SSID", "errors": {`+
+				`"stationConfigurations.red2.wpaKey": [{"code": "INVALID_WPA_KEY", "message": "bad key"}],`+
+				`"stationConfigurations.blue1.ssid": [{"code": "INVALID_SSID", "message": "bad SSID"}]}}`,
+		),
+	)
+	assert.Equal(t, "VALIDATION_FAILED", apiErr.Code)
+	assert.Empty(t, apiErr.Field)
+	assert.Equal(
+		t,
+		map[string][]FieldError{
+			"stationConfigurations.red2.wpaKey": {{Code: "INVALID_WPA_KEY", Message: "bad key"}},
+			"stationConfigurations.blue1.ssid":  {{Code: "INVALID_SSID", Message: "bad SSID"}},
+		},
+		apiErr.Errors,
+	)
+
+	// Plain-text bodies from older versions of the API, or from proxies, are passed through as the message.
+	apiErr = newApiError("GET", "/status", 401, []byte("HTTP request error 401: not authorized\n"))
+	assert.Equal(
+		t,
+		ApiError{Method: "GET", Path: "/status", StatusCode: 401, Message: "HTTP request error 401: not authorized"},
+		*apiErr,
+	)
+	apiErr = newApiError("GET", "/status", 502, []byte(`{"error": "bad gateway"}`))
+	assert.Equal(t, `{"error": "bad gateway"}`, apiErr.Message)
+	assert.Empty(t, apiErr.Code)
+	assert.EqualError(t, newApiError("GET", "/health", 503, nil), "GET /health returned status 503: ")
+}
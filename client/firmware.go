@@ -0,0 +1,36 @@
+package client
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"filippo.io/age"
+	"fmt"
+	"io"
+)
+
+// FirmwareChecksum returns the hexadecimal-encoded SHA-256 hash of the given unencrypted firmware, as expected by the
+// radio's /firmware endpoint.
+func FirmwareChecksum(firmware io.Reader) (string, error) {
+	hash := sha256.New()
+	if _, err := io.Copy(hash, firmware); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// EncryptFirmware encrypts the given firmware to the given age public key (e.g. "age1..."), writing the result to the
+// given destination. Only needed when the radio has been set up with the corresponding secret key.
+func EncryptFirmware(firmware io.Reader, destination io.Writer, publicKey string) error {
+	recipient, err := age.ParseX25519Recipient(publicKey)
+	if err != nil {
+		return fmt.Errorf("invalid age public key: %v", err)
+	}
+	writer, err := age.Encrypt(destination, recipient)
+	if err != nil {
+		return err
+	}
+	if _, err = io.Copy(writer, firmware); err != nil {
+		return err
+	}
+	return writer.Close()
+}
@@ -0,0 +1,36 @@
+package client
+
+import (
+	"bytes"
+	"filippo.io/age"
+	"github.com/stretchr/testify/assert"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestFirmwareChecksum(t *testing.T) {
+	checksum, err := FirmwareChecksum(strings.NewReader("firmware contents"))
+	assert.Nil(t, err)
+	assert.Equal(t, "32ef8b989e46b1e42b9a2cecc57df13052c8f791f26cf71aad269d405e43cff2", checksum)
+}
+
+func TestEncryptFirmware(t *testing.T) {
+	identity, err := age.GenerateX25519Identity()
+	assert.Nil(t, err)
+
+	var encrypted bytes.Buffer
+	assert.Nil(t, EncryptFirmware(strings.NewReader("firmware contents"), &encrypted, identity.Recipient().String()))
+	assert.NotContains(t, encrypted.String(), "firmware contents")
+
+	decrypted, err := age.Decrypt(&encrypted, identity)
+	if assert.Nil(t, err) {
+		contents, _ := io.ReadAll(decrypted)
+		assert.Equal(t, "firmware contents", string(contents))
+	}
+
+	err = EncryptFirmware(strings.NewReader("firmware contents"), &encrypted, "not-a-key")
+	if assert.NotNil(t, err) {
+		assert.Contains(t, err.Error(), "invalid age public key")
+	}
+}
@@ -0,0 +1,62 @@
+package client
+
+import (
+	"bytes"
+	"encoding/json"
+	"github.com/patfair/frc-radio-api/radio"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+// assertJsonCompatible checks that every field of the given source value can be decoded into the given destination.
+func assertJsonCompatible(t *testing.T, source any, destination any) {
+	jsonData, err := json.Marshal(source)
+	assert.Nil(t, err)
+	decoder := json.NewDecoder(bytes.NewReader(jsonData))
+	decoder.DisallowUnknownFields()
+	assert.Nil(t, decoder.Decode(destination), "%T -> %T", source, destination)
+}
+
+func TestAccessPointTypesMatchRadio(t *testing.T) {
+	radioStatus := radio.AccessPointRadio{
+		StationStatuses: map[string]*radio.NetworkStatus{
+			"red1": {Ssid: "254", AssociatedClients: []radio.AssociatedClient{{MacAddress: "48:DA:35:B0:00:CF"}}},
+		},
+	}
+	assertJsonCompatible(t, &radioStatus, &AccessPointStatus{})
+
+	request := AccessPointConfigurationRequest{
+		StationVlans:          map[string]int{"red1": 110},
+		StationConfigurations: map[string]StationConfiguration{"red1": {Ssid: "254", WpaKey: "12345678"}},
+	}
+	assertJsonCompatible(t, request, &radio.AccessPointConfigurationRequest{})
+	assertJsonCompatible(t, radio.AccessPointConfigurationRequest{}, &AccessPointConfigurationRequest{})
+	assertJsonCompatible(
+		t, AccessPointConfigurationRequest{Channel: AutoChannel}, &radio.AccessPointConfigurationRequest{},
+	)
+	assertJsonCompatible(
+		t, radio.AccessPointConfigurationRequest{Channel: radio.AutoChannel}, &AccessPointConfigurationRequest{},
+	)
+	assertJsonCompatible(
+		t, AccessPointConfigurationRequest{TxPower: TxPowerDefault}, &radio.AccessPointConfigurationRequest{},
+	)
+	assert.Equal(t, radio.TxPowerDefault, TxPowerDefault)
+	assertJsonCompatible(
+		t,
+		radio.ChannelSurvey{Networks: []radio.SurveyedNetwork{{}}, Channels: []radio.ChannelCongestion{{}}},
+		&ChannelSurvey{},
+	)
+
+	assertJsonCompatible(t, radio.ConfigurationJob{}, &ConfigurationJob{})
+	assertJsonCompatible(t, []radio.LinkSample{{}}, &[]LinkSample{})
+	assertJsonCompatible(t, []radio.LinkEvent{{}}, &[]LinkEvent{})
+}
+
+func TestRobotRadioTypesMatchRadio(t *testing.T) {
+	assertJsonCompatible(t, &radio.RobotRadio{}, &RobotRadioStatus{})
+
+	assertJsonCompatible(
+		t, RobotRadioConfigurationRequest{Mode: ModeTeamRobotRadio}, &radio.RobotRadioConfigurationRequest{},
+	)
+	assertJsonCompatible(t, radio.RobotRadioConfigurationRequest{}, &RobotRadioConfigurationRequest{})
+}
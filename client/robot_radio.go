@@ -0,0 +1,121 @@
+package client
+
+import "context"
+
+// Values of the mode field of the robot radio.
+const (
+	// The radio is configured as a Wi-Fi client and connects to an access point.
+	ModeTeamRobotRadio = "TEAM_ROBOT_RADIO"
+
+	// The radio is configured as an access point and provides Wi-Fi to robot radios and other devices such as computers
+	// used in programming robots.
+	ModeTeamAccessPoint = "TEAM_ACCESS_POINT"
+)
+
+// RobotRadioClient talks to the API on a robot radio.
+type RobotRadioClient struct {
+	*Client
+}
+
+// NewRobotRadioClient creates a client for the robot radio at the given address; see NewClient.
+func NewRobotRadioClient(address, password string) *RobotRadioClient {
+	return &RobotRadioClient{NewClient(address, password)}
+}
+
+// RobotRadioStatus represents the JSON status returned by a robot radio.
+type RobotRadioStatus struct {
+	// Operation mode the radio is configured for.
+	Mode string `json:"mode"`
+
+	// 6GHz channel the radio is using, or blank if it is in TEAM_ROBOT_RADIO mode and hasn't connected yet.
+	Channel string `json:"channel"`
+
+	// Team number the radio is configured for.
+	TeamNumber int `json:"teamNumber"`
+
+	// Suffix appended to all WPA SSIDs.
+	SsidSuffix string `json:"ssidSuffix"`
+
+	// Effective transmit power of the 6GHz network in dBm. Zero if it couldn't be determined.
+	TxPower int `json:"txPower"`
+
+	// Status of the 2.4GHz network broadcast by the radio for team use.
+	NetworkStatus24 NetworkStatus `json:"networkStatus24"`
+
+	// Status of the 6GHz network used by the FMS.
+	NetworkStatus6 NetworkStatus `json:"networkStatus6"`
+
+	// Enum representing the current configuration stage of the radio.
+	Status string `json:"status"`
+
+	// Error encountered while applying the most recent configuration request. Blank if it succeeded.
+	ConfigurationError string `json:"configurationError"`
+
+	// Whether the radio reverted to its previous configuration after the most recent request failed.
+	RolledBack bool `json:"rolledBack"`
+
+	// Version of the radio software.
+	Version string `json:"version"`
+}
+
+// RobotRadioConfigurationRequest represents a JSON request to configure a robot radio.
+type RobotRadioConfigurationRequest struct {
+	// Operation mode to configure the radio for.
+	Mode string `json:"mode"`
+
+	// 6GHz channel number for the radio to use. If not specified and the radio is configured for TEAM_ACCESS_POINT
+	// mode, the radio will automatically select a channel.
+	Channel int `json:"channel"`
+
+	// Transmit power in dBm for the radio to use on the 6GHz network and, in TEAM_ROBOT_RADIO mode, the 2.4GHz network.
+	// Set to 0 to leave unchanged, or to TxPowerDefault to restore the driver's default.
+	TxPower int `json:"txPower"`
+
+	// Team number to configure the radio for. Must be between 1 and 25499.
+	TeamNumber int `json:"teamNumber"`
+
+	// Suffix to be appended to all WPA SSIDs. Must be alphanumeric and at most eight characters long.
+	SsidSuffix string `json:"ssidSuffix"`
+
+	// Team-specific WPA key for the 6GHz network used by the FMS. Must be at least eight alphanumeric characters long.
+	WpaKey6 string `json:"wpaKey6"`
+
+	// WPA key for the 2.4GHz network broadcast by the radio for team use. Must be at least eight alphanumeric
+	// characters long.
+	WpaKey24 string `json:"wpaKey24"`
+}
+
+// GetStatus returns the current status of the robot radio.
+func (client *RobotRadioClient) GetStatus(ctx context.Context) (*RobotRadioStatus, error) {
+	var status RobotRadioStatus
+	if err := client.getJson(ctx, "/status", &status); err != nil {
+		return nil, err
+	}
+	return &status, nil
+}
+
+// Configure submits the given configuration request to the robot radio and returns the job tracking it. The request
+// is applied asynchronously; use WaitForConfiguration to wait for it to finish.
+func (client *RobotRadioClient) Configure(
+	ctx context.Context, request RobotRadioConfigurationRequest,
+) (*ConfigurationJob, error) {
+	return client.configure(ctx, request)
+}
+
+// WaitForConfiguration polls the configuration job with the given ID until it finishes and the robot radio reports an
+// ACTIVE status, returning the final status. Returns an error if the job fails or is superseded, or if the context is
+// done first.
+func (client *RobotRadioClient) WaitForConfiguration(ctx context.Context, jobId string) (*RobotRadioStatus, error) {
+	var status *RobotRadioStatus
+	err := client.waitForConfiguration(ctx, jobId, func(ctx context.Context) (string, error) {
+		var err error
+		if status, err = client.GetStatus(ctx); err != nil {
+			return "", err
+		}
+		return status.Status, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return status, nil
+}
@@ -0,0 +1,69 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"testing"
+)
+
+func TestRobotRadioClient_GetStatus(t *testing.T) {
+	server := newTestServer(t, "mypassword", map[string]http.HandlerFunc{
+		"GET /status": func(w http.ResponseWriter, r *http.Request) {
+			_, _ = fmt.Fprint(
+				w,
+				`{"mode": "TEAM_ROBOT_RADIO", "channel": "5", "teamNumber": 254, "ssidSuffix": "", "status": "ERROR",
+				"configurationError": "timed out", "rolledBack": true, "networkStatus24": {"ssid": "FRC-254"},
+				"networkStatus6": {"ssid": "254", "isLinked": true}, "version": "1.2.3"}`,
+			)
+		},
+	})
+	client := RobotRadioClient{newTestClient(server, "mypassword")}
+
+	status, err := client.GetStatus(context.Background())
+	if assert.Nil(t, err) {
+		assert.Equal(t, ModeTeamRobotRadio, status.Mode)
+		assert.Equal(t, "5", status.Channel)
+		assert.Equal(t, 254, status.TeamNumber)
+		assert.Equal(t, StatusError, status.Status)
+		assert.Equal(t, "timed out", status.ConfigurationError)
+		assert.True(t, status.RolledBack)
+		assert.Equal(t, "FRC-254", status.NetworkStatus24.Ssid)
+		assert.Equal(t, "254", status.NetworkStatus6.Ssid)
+		assert.True(t, status.NetworkStatus6.IsLinked)
+		assert.Equal(t, "1.2.3", status.Version)
+	}
+}
+
+func TestRobotRadioClient_Configure(t *testing.T) {
+	var receivedRequest RobotRadioConfigurationRequest
+	jobStatus := JobApplying
+	server := newTestServer(t, "", map[string]http.HandlerFunc{
+		"POST /configuration": func(w http.ResponseWriter, r *http.Request) {
+			assert.Nil(t, json.NewDecoder(r.Body).Decode(&receivedRequest))
+			w.WriteHeader(http.StatusAccepted)
+			_, _ = fmt.Fprint(w, `{"id": "abc", "status": "QUEUED"}`)
+		},
+		"GET /configuration/abc": func(w http.ResponseWriter, r *http.Request) {
+			_, _ = fmt.Fprintf(w, `{"id": "abc", "status": "%s", "error": "wireless configuration still incorrect"}`, jobStatus)
+			jobStatus = JobFailed
+		},
+	})
+	client := RobotRadioClient{newTestClient(server, "")}
+
+	request := RobotRadioConfigurationRequest{
+		Mode: ModeTeamRobotRadio, TeamNumber: 254, WpaKey6: "12345678", WpaKey24: "87654321",
+	}
+	job, err := client.Configure(context.Background(), request)
+	if assert.Nil(t, err) {
+		assert.Equal(t, "abc", job.Id)
+	}
+	assert.Equal(t, request, receivedRequest)
+
+	_, err = client.WaitForConfiguration(context.Background(), job.Id)
+	if assert.NotNil(t, err) {
+		assert.Equal(t, "configuration failed: wireless configuration still incorrect", err.Error())
+	}
+}
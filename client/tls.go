@@ -0,0 +1,74 @@
+package client
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/tls"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// Header carrying the fingerprint of the radio's TLS certificate in responses from the /health endpoint.
+const TlsFingerprintHeader = "X-FRC-Radio-TLS-Fingerprint"
+
+// GetTlsFingerprint returns the fingerprint of the certificate that the radio serves over HTTPS, as reported by its
+// /health endpoint, or a blank string if HTTPS is disabled on the radio. The fingerprint can be fetched once over a
+// trusted connection and then passed to PinTlsFingerprint.
+func (client *Client) GetTlsFingerprint(ctx context.Context) (string, error) {
+	ctx, cancel := context.WithTimeout(ctx, requestTimeout)
+	defer cancel()
+
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, client.baseUrl+"/health", nil)
+	if err != nil {
+		return "", err
+	}
+	if client.password != "" {
+		request.Header.Set("Authorization", "Bearer "+client.password)
+	}
+	response, err := client.HttpClient.Do(request)
+	if err != nil {
+		return "", err
+	}
+	defer response.Body.Close()
+	if response.StatusCode < 200 || response.StatusCode >= 300 {
+		return "", fmt.Errorf("GET /health returned status %d", response.StatusCode)
+	}
+	return response.Header.Get(TlsFingerprintHeader), nil
+}
+
+// PinTlsFingerprint makes the client trust only the HTTPS certificate having the given fingerprint, as returned by
+// GetTlsFingerprint, in place of validating it against the system's certificate authorities. This allows talking to a
+// radio that is using a self-signed certificate. Colons and letter case in the fingerprint are ignored.
+func (client *Client) PinTlsFingerprint(fingerprint string) {
+	expectedFingerprint := normalizeTlsFingerprint(fingerprint)
+	client.HttpClient = &http.Client{
+		Transport: &http.Transport{
+			TLSClientConfig: &tls.Config{
+				// The default verification is replaced by the fingerprint check below rather than being skipped.
+				InsecureSkipVerify: true,
+				VerifyConnection: func(state tls.ConnectionState) error {
+					if len(state.PeerCertificates) == 0 {
+						return errors.New("radio presented no TLS certificate")
+					}
+					hash := sha256.Sum256(state.PeerCertificates[0].Raw)
+					if actualFingerprint := hex.EncodeToString(hash[:]); actualFingerprint != expectedFingerprint {
+						return fmt.Errorf(
+							"radio's TLS certificate fingerprint %s doesn't match the pinned fingerprint %s",
+							actualFingerprint,
+							expectedFingerprint,
+						)
+					}
+					return nil
+				},
+			},
+		},
+	}
+}
+
+// normalizeTlsFingerprint returns the given fingerprint in lowercase hexadecimal without separators.
+func normalizeTlsFingerprint(fingerprint string) string {
+	return strings.ToLower(strings.ReplaceAll(strings.TrimSpace(fingerprint), ":", ""))
+}
@@ -0,0 +1,71 @@
+package client
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestClient_TlsFingerprint(t *testing.T) {
+	var fingerprint string
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/health" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set(TlsFingerprintHeader, fingerprint)
+		_, _ = fmt.Fprintln(w, "OK")
+	}))
+	defer server.Close()
+	hash := sha256.Sum256(server.Certificate().Raw)
+	fingerprint = hex.EncodeToString(hash[:])
+
+	// The test server's certificate isn't trusted by default.
+	client := NewClient(server.URL, "")
+	client.MaxRetries = 0
+	err := client.Health(context.Background())
+	if assert.NotNil(t, err) {
+		assert.Contains(t, err.Error(), "certificate")
+	}
+
+	// Pinning the right fingerprint makes the certificate trusted, in any common format.
+	client.PinTlsFingerprint(fingerprint)
+	assert.Nil(t, client.Health(context.Background()))
+	actualFingerprint, err := client.GetTlsFingerprint(context.Background())
+	assert.Nil(t, err)
+	assert.Equal(t, fingerprint, actualFingerprint)
+	var colonSeparated []string
+	for i := 0; i < len(fingerprint); i += 2 {
+		colonSeparated = append(colonSeparated, strings.ToUpper(fingerprint[i:i+2]))
+	}
+	client.PinTlsFingerprint(strings.Join(colonSeparated, ":"))
+	assert.Nil(t, client.Health(context.Background()))
+
+	// Pinning the wrong fingerprint makes the certificate untrusted.
+	client.PinTlsFingerprint(strings.Repeat("0", 64))
+	err = client.Health(context.Background())
+	if assert.NotNil(t, err) {
+		assert.Contains(t, err.Error(), "doesn't match the pinned fingerprint "+strings.Repeat("0", 64))
+	}
+}
+
+func TestClient_GetTlsFingerprintDisabled(t *testing.T) {
+	server := newTestServer(t, "", map[string]http.HandlerFunc{
+		"GET /health": func(w http.ResponseWriter, r *http.Request) {
+			_, _ = fmt.Fprintln(w, "OK")
+		},
+	})
+
+	fingerprint, err := newTestClient(server, "").GetTlsFingerprint(context.Background())
+	assert.Nil(t, err)
+	assert.Equal(t, "", fingerprint)
+
+	_, err = NewClient(server.URL+"/missing", "").GetTlsFingerprint(context.Background())
+	assert.EqualError(t, err, "GET /health returned status 404")
+}
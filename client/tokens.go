@@ -0,0 +1,79 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// Scopes that an API token can be granted.
+const (
+	ScopeReadStatus = "read-status"
+	ScopeConfigure  = "configure"
+	ScopeFirmware   = "firmware"
+	ScopeAdmin      = "admin"
+)
+
+// Token represents a named credential for accessing the API, as listed by the radio.
+type Token struct {
+	// Unique identifier for the token.
+	Id string `json:"id"`
+
+	// Human-readable name describing who or what uses the token.
+	Name string `json:"name"`
+
+	// Scopes that the token grants access to; one or more of the Scope* constants.
+	Scopes []string `json:"scopes"`
+
+	// Time at which the token was created.
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+// CreatedToken represents a newly created API token along with its secret.
+type CreatedToken struct {
+	Token
+
+	// Secret to pass as the password to NewClient or its variants. The radio only stores a hash of it, so it can't be
+	// retrieved again.
+	Secret string `json:"token"`
+}
+
+// CreateToken creates a new API token having the given name and scopes and returns it along with its secret. Requires
+// the password or a token having the admin scope.
+func (client *Client) CreateToken(ctx context.Context, name string, scopes ...string) (*CreatedToken, error) {
+	if scopes == nil {
+		scopes = []string{}
+	}
+	body, err := json.Marshal(map[string]any{"name": name, "scopes": scopes})
+	if err != nil {
+		return nil, err
+	}
+
+	// Creating a token isn't idempotent, so don't retry it in case the first attempt took effect.
+	response, err := client.do(ctx, http.MethodPost, "/tokens", "application/json", body, requestTimeout, false)
+	if err != nil {
+		return nil, err
+	}
+	var token CreatedToken
+	if err = json.Unmarshal(response, &token); err != nil {
+		return nil, fmt.Errorf("invalid token response: %v", err)
+	}
+	return &token, nil
+}
+
+// GetTokens returns the API tokens that exist on the radio, without their secrets.
+func (client *Client) GetTokens(ctx context.Context) ([]Token, error) {
+	var tokens []Token
+	if err := client.getJson(ctx, "/tokens", &tokens); err != nil {
+		return nil, err
+	}
+	return tokens, nil
+}
+
+// RevokeToken deletes the API token having the given ID, after which it can no longer be used.
+func (client *Client) RevokeToken(ctx context.Context, id string) error {
+	_, err := client.do(ctx, http.MethodDelete, "/tokens/"+id, "", nil, requestTimeout, true)
+	return err
+}
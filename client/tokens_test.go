@@ -0,0 +1,61 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestClient_Tokens(t *testing.T) {
+	var creation map[string]any
+	var revokedPath string
+	server := newTestServer(t, "mypassword", map[string]http.HandlerFunc{
+		"POST /tokens": func(w http.ResponseWriter, r *http.Request) {
+			_ = json.NewDecoder(r.Body).Decode(&creation)
+			w.WriteHeader(http.StatusCreated)
+			_, _ = fmt.Fprint(
+				w,
+				`{"id": "abc", "name": "FMS", "scopes": ["read-status", "configure"], `+
+					`"createdAt": "2024-04-20T12:00:00Z", "token": "0123456789abcdef"}`,
+			)
+		},
+		"GET /tokens": func(w http.ResponseWriter, r *http.Request) {
+			_, _ = fmt.Fprint(
+				w, `[{"id": "abc", "name": "FMS", "scopes": ["admin"], "createdAt": "2024-04-20T12:00:00Z"}]`,
+			)
+		},
+		"DELETE /tokens/abc": func(w http.ResponseWriter, r *http.Request) {
+			revokedPath = r.URL.Path
+			w.WriteHeader(http.StatusNoContent)
+		},
+	})
+	client := newTestClient(server, "mypassword")
+	createdAt := time.Date(2024, 4, 20, 12, 0, 0, 0, time.UTC)
+
+	token, err := client.CreateToken(context.Background(), "FMS", ScopeReadStatus, ScopeConfigure)
+	assert.Nil(t, err)
+	assert.Equal(t, map[string]any{"name": "FMS", "scopes": []any{"read-status", "configure"}}, creation)
+	assert.Equal(
+		t,
+		&CreatedToken{
+			Token:  Token{Id: "abc", Name: "FMS", Scopes: []string{"read-status", "configure"}, CreatedAt: createdAt},
+			Secret: "0123456789abcdef",
+		},
+		token,
+	)
+
+	tokens, err := client.GetTokens(context.Background())
+	assert.Nil(t, err)
+	assert.Equal(t, []Token{{Id: "abc", Name: "FMS", Scopes: []string{"admin"}, CreatedAt: createdAt}}, tokens)
+
+	assert.Nil(t, client.RevokeToken(context.Background(), "abc"))
+	assert.Equal(t, "/tokens/abc", revokedPath)
+	err = client.RevokeToken(context.Background(), "xyz")
+	if assert.NotNil(t, err) {
+		assert.Contains(t, err.Error(), "returned status 404")
+	}
+}
@@ -0,0 +1,204 @@
+package client
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"time"
+)
+
+// Values of the status field reported by the radio.
+const (
+	StatusBooting     = "BOOTING"
+	StatusConfiguring = "CONFIGURING"
+	StatusActive      = "ACTIVE"
+	StatusError       = "ERROR"
+)
+
+// Values of the status field of a configuration job.
+const (
+	JobQueued     = "QUEUED"
+	JobSuperseded = "SUPERSEDED"
+	JobApplying   = "APPLYING"
+	JobSucceeded  = "SUCCEEDED"
+	JobFailed     = "FAILED"
+)
+
+// NetworkStatus represents the status of a single Wi-Fi network on the radio (i.e. a team SSID network on the access
+// point or one of the two interfaces on the robot radio).
+type NetworkStatus struct {
+	// SSID for the network.
+	Ssid string `json:"ssid"`
+
+	// SHA-256 hash of the WPA key and salt for the network, encoded as a hexadecimal string. See VerifyWpaKey.
+	HashedWpaKey string `json:"hashedWpaKey"`
+
+	// Randomly generated salt used to hash the WPA key.
+	WpaKeySalt string `json:"wpaKeySalt"`
+
+	// Whether this network is currently associated with a remote device.
+	IsLinked bool `json:"isLinked"`
+
+	// MAC address of the remote device currently associated with this network. Blank if not associated.
+	MacAddress string `json:"macAddress"`
+
+	// Signal strength of the link to the remote device, in decibel-milliwatts. Zero if not associated.
+	SignalDbm int `json:"signalDbm"`
+
+	// Noise level of the link to the remote device, in decibel-milliwatts. Zero if not associated.
+	NoiseDbm int `json:"noiseDbm"`
+
+	// Current signal-to-noise ratio (SNR) in decibels. Zero if not associated.
+	SignalNoiseRatio int `json:"signalNoiseRatio"`
+
+	// Upper-bound link receive rate (from the remote device to the radio) in megabits per second.
+	RxRateMbps float64 `json:"rxRateMbps"`
+
+	// Number of packets received from the remote device.
+	RxPackets int `json:"rxPackets"`
+
+	// Number of bytes received from the remote device.
+	RxBytes int `json:"rxBytes"`
+
+	// Upper-bound link transmit rate (from the radio to the remote device) in megabits per second.
+	TxRateMbps float64 `json:"txRateMbps"`
+
+	// Number of packets transmitted to the remote device.
+	TxPackets int `json:"txPackets"`
+
+	// Number of bytes transmitted to the remote device.
+	TxBytes int `json:"txBytes"`
+
+	// Current five-second average total (rx + tx) bandwidth in megabits per second.
+	BandwidthUsedMbps float64 `json:"bandwidthUsedMbps"`
+
+	// Maximum throughput in megabits per second allowed in each direction on the network. Zero if unlimited.
+	BandwidthLimitMbps float64 `json:"bandwidthLimitMbps"`
+
+	// Human-readable string describing connection quality to the remote device. Blank if not associated.
+	ConnectionQuality string `json:"connectionQuality"`
+
+	// All remote devices currently associated with the network. The first one is the primary link described by the
+	// fields above.
+	AssociatedClients []AssociatedClient `json:"associatedClients"`
+}
+
+// AssociatedClient represents the link state of a single remote device associated with a network.
+type AssociatedClient struct {
+	// MAC address of the remote device.
+	MacAddress string `json:"macAddress"`
+
+	// Signal strength of the link to the remote device, in decibel-milliwatts.
+	SignalDbm int `json:"signalDbm"`
+
+	// Noise level of the link to the remote device, in decibel-milliwatts.
+	NoiseDbm int `json:"noiseDbm"`
+
+	// Current signal-to-noise ratio (SNR) in decibels.
+	SignalNoiseRatio int `json:"signalNoiseRatio"`
+
+	// Upper-bound link receive rate (from the remote device to the radio) in megabits per second.
+	RxRateMbps float64 `json:"rxRateMbps"`
+
+	// Number of packets received from the remote device.
+	RxPackets int `json:"rxPackets"`
+
+	// Upper-bound link transmit rate (from the radio to the remote device) in megabits per second.
+	TxRateMbps float64 `json:"txRateMbps"`
+
+	// Number of packets transmitted to the remote device.
+	TxPackets int `json:"txPackets"`
+
+	// Time elapsed since the radio last heard from the remote device, in milliseconds.
+	InactiveMs int `json:"inactiveMs"`
+}
+
+// LinkSample represents the status of a network as of the time at which the radio polled its link metrics.
+type LinkSample struct {
+	// Time at which the sample was taken.
+	Time time.Time `json:"time"`
+
+	NetworkStatus
+}
+
+// LinkState represents the parts of a network's status whose transitions are reported as link events.
+type LinkState struct {
+	// Whether the network was associated with a remote device.
+	IsLinked bool `json:"isLinked"`
+
+	// MAC address of the remote device associated with the network. Blank if not associated.
+	MacAddress string `json:"macAddress"`
+
+	// Human-readable string describing connection quality to the remote device. Blank if not associated.
+	ConnectionQuality string `json:"connectionQuality"`
+}
+
+// LinkEvent represents a transition in the link state of a network between two consecutive polls by the radio.
+type LinkEvent struct {
+	// Sequence number of the event, which increases by one with each event recorded.
+	Id int `json:"id"`
+
+	// Time at which the transition was detected.
+	Time time.Time `json:"time"`
+
+	// Name of the network whose link state changed (i.e. a team station on the access point, or "6GHz" or "2.4GHz" on
+	// the robot radio).
+	Station string `json:"station"`
+
+	// SSID of the network as of the time of the event.
+	Ssid string `json:"ssid"`
+
+	// Kind of transition; one of ASSOCIATED, DISASSOCIATED, DEVICE_CHANGED or QUALITY_CHANGED.
+	Type string `json:"type"`
+
+	// Link state of the network before the transition.
+	Before LinkState `json:"before"`
+
+	// Link state of the network after the transition.
+	After LinkState `json:"after"`
+}
+
+// VerifyWpaKey returns true if the given WPA key is the one the network is configured with, by hashing it with the
+// salt reported by the radio and comparing the result against the reported hash.
+func (status *NetworkStatus) VerifyWpaKey(wpaKey string) bool {
+	if status.HashedWpaKey == "" {
+		return false
+	}
+	hash := sha256.Sum256([]byte(wpaKey + status.WpaKeySalt))
+	return hex.EncodeToString(hash[:]) == status.HashedWpaKey
+}
+
+// ConfigurationJob represents the progress of a single configuration request through the radio's asynchronous queue.
+type ConfigurationJob struct {
+	// Unique identifier for the job.
+	Id string `json:"id"`
+
+	// Enum representing the current processing stage of the job.
+	Status string `json:"status"`
+
+	// Number of attempts made so far to apply the configuration to the radio.
+	Attempts int `json:"attempts"`
+
+	// Time at which the request was accepted into the queue.
+	QueuedAt time.Time `json:"queuedAt"`
+
+	// Time at which the radio started applying the request. Nil if it hasn't started yet.
+	StartedAt *time.Time `json:"startedAt"`
+
+	// Time at which the job succeeded, failed or was superseded. Nil if it hasn't finished yet.
+	FinishedAt *time.Time `json:"finishedAt"`
+
+	// Error encountered while applying the configuration. Blank unless the job failed.
+	Error string `json:"error"`
+
+	// Whether the radio was successfully rolled back to its previous configuration after the job failed.
+	RolledBack bool `json:"rolledBack"`
+
+	// Error encountered while rolling back to the previous configuration after the job failed. Blank unless the
+	// rollback failed.
+	RollbackError string `json:"rollbackError"`
+}
+
+// IsFinished returns true if the job has reached a terminal state.
+func (job *ConfigurationJob) IsFinished() bool {
+	return job.Status == JobSucceeded || job.Status == JobFailed || job.Status == JobSuperseded
+}
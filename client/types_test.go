@@ -0,0 +1,31 @@
+package client
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestNetworkStatus_VerifyWpaKey(t *testing.T) {
+	// Hash of "12345678" + "abcdefghijklmnop".
+	status := NetworkStatus{
+		HashedWpaKey: "f2ec9bc783fef6c1e6e70af1647d391f3098b59d8c1c63e35957400bc9c9c945",
+		WpaKeySalt:   "abcdefghijklmnop",
+	}
+	assert.True(t, status.VerifyWpaKey("12345678"))
+	assert.False(t, status.VerifyWpaKey("87654321"))
+
+	status.WpaKeySalt = "ponmlkjihgfedcba"
+	assert.False(t, status.VerifyWpaKey("12345678"))
+
+	// A network with no WPA key configured can't be verified against anything.
+	assert.False(t, (&NetworkStatus{}).VerifyWpaKey(""))
+}
+
+func TestConfigurationJob_IsFinished(t *testing.T) {
+	for status, isFinished := range map[string]bool{
+		JobQueued: false, JobApplying: false, JobSucceeded: true, JobFailed: true, JobSuperseded: true,
+	} {
+		job := ConfigurationJob{Status: status}
+		assert.Equal(t, isFinished, job.IsFinished(), status)
+	}
+}
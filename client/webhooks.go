@@ -0,0 +1,99 @@
+package client
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// Header carrying the signature of each webhook payload sent by the radio.
+const WebhookSignatureHeader = "X-FRC-Radio-Signature"
+
+// Types of event that the radio can send webhook payloads for.
+const (
+	WebhookStatusChanged          = "STATUS_CHANGED"
+	WebhookLinkUp                 = "LINK_UP"
+	WebhookLinkDown               = "LINK_DOWN"
+	WebhookConfigurationCompleted = "CONFIGURATION_COMPLETED"
+	WebhookFirmwareUpdateStarted  = "FIRMWARE_UPDATE_STARTED"
+)
+
+// Webhook represents a URL registered with the radio to receive payloads describing events on it.
+type Webhook struct {
+	// Unique identifier for the webhook.
+	Id string `json:"id"`
+
+	// URL that payloads are POSTed to.
+	Url string `json:"url"`
+
+	// Types of event that payloads are sent for. Empty means all types.
+	Events []string `json:"events"`
+}
+
+// WebhookPayload represents the JSON body that the radio POSTs to a webhook to describe a single event.
+type WebhookPayload struct {
+	// Unique identifier for the payload, which stays the same across retries.
+	Id string `json:"id"`
+
+	// Type of event; one of the Webhook* constants.
+	Type string `json:"type"`
+
+	// Time at which the event occurred.
+	Time time.Time `json:"time"`
+
+	// Details of the event, whose structure depends on its type: a LinkEvent for LINK_UP and LINK_DOWN, a
+	// ConfigurationJob for CONFIGURATION_COMPLETED, and an object containing the status or firmware checksum otherwise.
+	Data json.RawMessage `json:"data"`
+}
+
+// RegisterWebhook registers the given URL to receive payloads for the given types of event (or all types, if none are
+// given) and returns the new webhook.
+func (client *Client) RegisterWebhook(ctx context.Context, url string, events ...string) (*Webhook, error) {
+	if events == nil {
+		events = []string{}
+	}
+	body, err := json.Marshal(map[string]any{"url": url, "events": events})
+	if err != nil {
+		return nil, err
+	}
+
+	// Registering isn't idempotent, so don't retry it in case the first attempt took effect.
+	response, err := client.do(ctx, http.MethodPost, "/webhooks", "application/json", body, requestTimeout, false)
+	if err != nil {
+		return nil, err
+	}
+	var webhook Webhook
+	if err = json.Unmarshal(response, &webhook); err != nil {
+		return nil, fmt.Errorf("invalid webhook response: %v", err)
+	}
+	return &webhook, nil
+}
+
+// GetWebhooks returns the webhooks registered with the radio.
+func (client *Client) GetWebhooks(ctx context.Context) ([]Webhook, error) {
+	var webhooks []Webhook
+	if err := client.getJson(ctx, "/webhooks", &webhooks); err != nil {
+		return nil, err
+	}
+	return webhooks, nil
+}
+
+// DeleteWebhook unregisters the webhook having the given ID.
+func (client *Client) DeleteWebhook(ctx context.Context, id string) error {
+	_, err := client.do(ctx, http.MethodDelete, "/webhooks/"+id, "", nil, requestTimeout, true)
+	return err
+}
+
+// VerifyWebhookSignature returns true if the given signature, taken from the WebhookSignatureHeader of a webhook
+// request, matches the given request body as signed using the given API password.
+func VerifyWebhookSignature(body []byte, signature, password string) bool {
+	mac := hmac.New(sha256.New, []byte(password))
+	mac.Write(body)
+	expectedSignature := "sha256=" + hex.EncodeToString(mac.Sum(nil))
+	return hmac.Equal([]byte(signature), []byte(expectedSignature))
+}
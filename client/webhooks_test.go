@@ -0,0 +1,61 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"testing"
+)
+
+func TestClient_Webhooks(t *testing.T) {
+	var registration map[string]any
+	var deletedPath string
+	server := newTestServer(t, "mypassword", map[string]http.HandlerFunc{
+		"POST /webhooks": func(w http.ResponseWriter, r *http.Request) {
+			_ = json.NewDecoder(r.Body).Decode(&registration)
+			w.WriteHeader(http.StatusCreated)
+			_, _ = fmt.Fprint(w, `{"id": "abc", "url": "http://10.0.100.5/hook", "events": ["LINK_UP"]}`)
+		},
+		"GET /webhooks": func(w http.ResponseWriter, r *http.Request) {
+			_, _ = fmt.Fprint(w, `[{"id": "abc", "url": "http://10.0.100.5/hook", "events": []}]`)
+		},
+		"DELETE /webhooks/abc": func(w http.ResponseWriter, r *http.Request) {
+			deletedPath = r.URL.Path
+			w.WriteHeader(http.StatusNoContent)
+		},
+	})
+	client := newTestClient(server, "mypassword")
+
+	webhook, err := client.RegisterWebhook(context.Background(), "http://10.0.100.5/hook", WebhookLinkUp)
+	assert.Nil(t, err)
+	assert.Equal(t, map[string]any{"url": "http://10.0.100.5/hook", "events": []any{"LINK_UP"}}, registration)
+	assert.Equal(t, &Webhook{Id: "abc", Url: "http://10.0.100.5/hook", Events: []string{"LINK_UP"}}, webhook)
+
+	_, err = client.RegisterWebhook(context.Background(), "http://10.0.100.5/hook")
+	assert.Nil(t, err)
+	assert.Equal(t, []any{}, registration["events"])
+
+	webhooks, err := client.GetWebhooks(context.Background())
+	assert.Nil(t, err)
+	assert.Equal(t, []Webhook{{Id: "abc", Url: "http://10.0.100.5/hook", Events: []string{}}}, webhooks)
+
+	assert.Nil(t, client.DeleteWebhook(context.Background(), "abc"))
+	assert.Equal(t, "/webhooks/abc", deletedPath)
+	err = client.DeleteWebhook(context.Background(), "xyz")
+	if assert.NotNil(t, err) {
+		assert.Contains(t, err.Error(), "returned status 404")
+	}
+}
+
+func TestVerifyWebhookSignature(t *testing.T) {
+	body := []byte(`{"id":"abc","type":"LINK_UP"}`)
+	signature := "sha256=6eba332adf34f6f8dd11344c94f68c1de017ca710a0cfe7a313ec27a67f8d6ba"
+
+	assert.True(t, VerifyWebhookSignature(body, signature, "mypassword"))
+	assert.False(t, VerifyWebhookSignature(body, signature, "wrongpassword"))
+	assert.False(t, VerifyWebhookSignature([]byte(`{"id":"abc","type":"LINK_DOWN"}`), signature, "mypassword"))
+	assert.False(t, VerifyWebhookSignature(body, signature[len("sha256="):], "mypassword"))
+	assert.False(t, VerifyWebhookSignature(body, "", "mypassword"))
+}
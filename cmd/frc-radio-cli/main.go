@@ -0,0 +1,449 @@
+// Command frc-radio-cli is a command-line client for the API that runs on the FRC access point and robot radios.
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"github.com/patfair/frc-radio-api/client"
+	"io"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Environment variable that the password is read from if it isn't given on the command line.
+const passwordEnvVar = "FRC_RADIO_API_PASSWORD"
+
+const usage = `Usage: frc-radio-cli [global flags] <command> [command flags]
+
+Commands:
+  health          check that the API on the radio is up and show its TLS certificate fingerprint, if any
+  status          show the radio status and a table of its networks
+  configure       send a configuration request and wait for it to be applied
+  survey          scan for neighboring networks from the access point and show channel congestion
+  firmware        upload new firmware to the radio
+  verify-wpa-key  check that a network is configured with a known WPA key
+
+Run 'frc-radio-cli <command> -h' for the flags accepted by each command.
+
+Global flags:
+`
+
+// Flags of the configure command that only apply to one type of radio.
+var (
+	accessPointOnlyFlags = []string{
+		"channel-bandwidth", "red-vlans", "blue-vlans", "station-vlan", "syslog-ip", "station", "bandwidth-limit",
+	}
+	robotRadioOnlyFlags = []string{"mode", "team-number", "ssid-suffix", "wpa-key-6", "wpa-key-24"}
+)
+
+func main() {
+	// Cancel any in-flight request or wait when the user hits Ctrl-C.
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	err := run(ctx, os.Args[1:], os.Stdout)
+	stop()
+	if err != nil && !errors.Is(err, flag.ErrHelp) {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+// run parses the given command-line arguments and executes the requested command, writing its output to the given
+// writer.
+func run(ctx context.Context, args []string, out io.Writer) error {
+	flags := flag.NewFlagSet("frc-radio-cli", flag.ContinueOnError)
+	address := flags.String("address", "10.0.100.2:8081", "address and port or URL of the radio API")
+	password := flags.String(
+		"password",
+		os.Getenv(passwordEnvVar),
+		"password or API token for the radio API (default from $"+passwordEnvVar+")",
+	)
+	tlsFingerprint := flags.String(
+		"tls-fingerprint",
+		"",
+		"SHA-256 fingerprint of the radio's TLS certificate to trust when using an https:// address, as shown by the "+
+			"health command",
+	)
+	flags.Usage = func() {
+		fmt.Fprint(flags.Output(), usage)
+		flags.PrintDefaults()
+	}
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() == 0 {
+		flags.Usage()
+		return errors.New("no command given")
+	}
+
+	radioClient := client.NewClient(*address, *password)
+	if *tlsFingerprint != "" {
+		radioClient.PinTlsFingerprint(*tlsFingerprint)
+	}
+	command, commandArgs := flags.Arg(0), flags.Args()[1:]
+	switch command {
+	case "health":
+		return runHealth(ctx, radioClient, commandArgs, out)
+	case "status":
+		return runStatus(ctx, radioClient, commandArgs, out)
+	case "configure":
+		return runConfigure(ctx, radioClient, commandArgs, out)
+	case "survey":
+		return runSurvey(ctx, radioClient, commandArgs, out)
+	case "firmware":
+		return runFirmware(ctx, radioClient, commandArgs, out)
+	case "verify-wpa-key":
+		return runVerifyWpaKey(ctx, radioClient, commandArgs, out)
+	default:
+		flags.Usage()
+		return fmt.Errorf("unknown command %q", command)
+	}
+}
+
+func runHealth(ctx context.Context, radioClient *client.Client, args []string, out io.Writer) error {
+	flags := flag.NewFlagSet("health", flag.ContinueOnError)
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if err := radioClient.Health(ctx); err != nil {
+		return err
+	}
+	fmt.Fprintln(out, "OK")
+	fingerprint, err := radioClient.GetTlsFingerprint(ctx)
+	if err != nil {
+		return err
+	}
+	if fingerprint != "" {
+		fmt.Fprintf(out, "TLS certificate fingerprint: %s\n", fingerprint)
+	}
+	return nil
+}
+
+func runStatus(ctx context.Context, radioClient *client.Client, args []string, out io.Writer) error {
+	flags := flag.NewFlagSet("status", flag.ContinueOnError)
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	radioType, err := radioClient.DetectRadioType(ctx)
+	if err != nil {
+		return err
+	}
+
+	if radioType == client.TypeRobotRadio {
+		status, err := (&client.RobotRadioClient{Client: radioClient}).GetStatus(ctx)
+		if err != nil {
+			return err
+		}
+		printRobotRadioStatus(out, status)
+	} else {
+		status, err := (&client.AccessPointClient{Client: radioClient}).GetStatus(ctx)
+		if err != nil {
+			return err
+		}
+		printAccessPointStatus(out, status)
+	}
+	return nil
+}
+
+func runConfigure(ctx context.Context, radioClient *client.Client, args []string, out io.Writer) error {
+	var accessPointRequest client.AccessPointConfigurationRequest
+	var robotRadioRequest client.RobotRadioConfigurationRequest
+	stations := stationFlag{}
+	stationVlans := stationVlanFlag{}
+	flags := flag.NewFlagSet("configure", flag.ContinueOnError)
+	var channel channelFlag
+	flags.Var(
+		&channel,
+		"channel",
+		"channel number for the radio to use, or (access point only) auto for the least congested valid channel",
+	)
+	txPower := flags.Int("tx-power", 0, "transmit power in dBm for the radio to use, or -1 to restore the default")
+	flags.StringVar(
+		&accessPointRequest.ChannelBandwidth,
+		"channel-bandwidth",
+		"",
+		"access point only: channel bandwidth (e.g. 40MHz)",
+	)
+	flags.StringVar(
+		&accessPointRequest.RedVlans, "red-vlans", "", "access point only: red alliance VLANs (e.g. 10_20_30)",
+	)
+	flags.StringVar(
+		&accessPointRequest.BlueVlans, "blue-vlans", "", "access point only: blue alliance VLANs (e.g. 40_50_60)",
+	)
+	flags.Var(
+		stationVlans,
+		"station-vlan",
+		"access point only: VLAN ID overriding the alliance VLANs as station=vlan (e.g. red1=110); repeat for each "+
+			"station",
+	)
+	flags.StringVar(
+		&accessPointRequest.SyslogIpAddress, "syslog-ip", "", "access point only: IP address of the syslog server",
+	)
+	flags.Var(
+		stations, "station", "access point only: team network as station=ssid:wpaKey (e.g. red1=254:12345678); repeat "+
+			"for each station",
+	)
+	flags.Float64Var(
+		&accessPointRequest.BandwidthLimitMbps,
+		"bandwidth-limit",
+		0,
+		"access point only: maximum throughput in Mbps to allow in each direction for each station (0 for no limit)",
+	)
+	flags.StringVar(&robotRadioRequest.Mode, "mode", "", "robot radio only: TEAM_ROBOT_RADIO or TEAM_ACCESS_POINT")
+	flags.IntVar(&robotRadioRequest.TeamNumber, "team-number", 0, "robot radio only: team number")
+	flags.StringVar(&robotRadioRequest.SsidSuffix, "ssid-suffix", "", "robot radio only: suffix to append to the SSIDs")
+	flags.StringVar(&robotRadioRequest.WpaKey6, "wpa-key-6", "", "robot radio only: WPA key for the 6GHz network")
+	flags.StringVar(&robotRadioRequest.WpaKey24, "wpa-key-24", "", "robot radio only: WPA key for the 2.4GHz network")
+	wait := flags.Bool("wait", true, "wait for the configuration to be applied and the radio to become ACTIVE")
+	timeout := flags.Duration("timeout", 3*time.Minute, "how long to wait for the configuration to be applied")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	radioType, err := radioClient.DetectRadioType(ctx)
+	if err != nil {
+		return err
+	}
+	inapplicableFlags := robotRadioOnlyFlags
+	if radioType == client.TypeRobotRadio {
+		inapplicableFlags = accessPointOnlyFlags
+	}
+	var flagErr error
+	flags.Visit(func(f *flag.Flag) {
+		if contains(inapplicableFlags, f.Name) {
+			flagErr = fmt.Errorf("flag -%s doesn't apply to this type of radio", f.Name)
+		}
+	})
+	if flagErr != nil {
+		return flagErr
+	}
+
+	var job *client.ConfigurationJob
+	accessPointClient := &client.AccessPointClient{Client: radioClient}
+	robotRadioClient := &client.RobotRadioClient{Client: radioClient}
+	if radioType == client.TypeRobotRadio {
+		if channel == channelFlag(client.AutoChannel) {
+			return errors.New("-channel auto is only supported by the access point")
+		}
+		robotRadioRequest.Channel = int(channel)
+		robotRadioRequest.TxPower = *txPower
+		job, err = robotRadioClient.Configure(ctx, robotRadioRequest)
+	} else {
+		accessPointRequest.Channel = client.AccessPointChannel(channel)
+		accessPointRequest.TxPower = *txPower
+		accessPointRequest.StationConfigurations = stations
+		if len(stationVlans) > 0 {
+			accessPointRequest.StationVlans = stationVlans
+		}
+		job, err = accessPointClient.Configure(ctx, accessPointRequest)
+	}
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(out, "Configuration request accepted as job %s.\n", job.Id)
+	if !*wait {
+		return nil
+	}
+
+	fmt.Fprintln(out, "Waiting for the configuration to be applied...")
+	ctx, cancel := context.WithTimeout(ctx, *timeout)
+	defer cancel()
+	if radioType == client.TypeRobotRadio {
+		status, err := robotRadioClient.WaitForConfiguration(ctx, job.Id)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintln(out, "Configuration applied successfully.")
+		fmt.Fprintln(out)
+		printRobotRadioStatus(out, status)
+	} else {
+		status, err := accessPointClient.WaitForConfiguration(ctx, job.Id)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintln(out, "Configuration applied successfully.")
+		fmt.Fprintln(out)
+		printAccessPointStatus(out, status)
+	}
+	return nil
+}
+
+func runSurvey(ctx context.Context, radioClient *client.Client, args []string, out io.Writer) error {
+	flags := flag.NewFlagSet("survey", flag.ContinueOnError)
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	survey, err := (&client.AccessPointClient{Client: radioClient}).GetSurvey(ctx)
+	if err != nil {
+		return err
+	}
+	printSurvey(out, survey)
+	return nil
+}
+
+func runFirmware(ctx context.Context, radioClient *client.Client, args []string, out io.Writer) error {
+	flags := flag.NewFlagSet("firmware", flag.ContinueOnError)
+	path := flags.String("file", "", "path to the firmware file (required)")
+	checksum := flags.String(
+		"checksum",
+		"",
+		"SHA-256 checksum of the unencrypted firmware; only needed if the file is already encrypted, since it is "+
+			"otherwise computed from the file",
+	)
+	publicKey := flags.String("encrypt-to", "", "age public key to encrypt the firmware to before uploading")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if *path == "" {
+		return errors.New("-file is required")
+	}
+
+	firmware, err := os.ReadFile(*path)
+	if err != nil {
+		return err
+	}
+	if *checksum == "" {
+		if *checksum, err = client.FirmwareChecksum(bytes.NewReader(firmware)); err != nil {
+			return err
+		}
+	}
+	if *publicKey != "" {
+		var encrypted bytes.Buffer
+		if err = client.EncryptFirmware(bytes.NewReader(firmware), &encrypted, *publicKey); err != nil {
+			return err
+		}
+		firmware = encrypted.Bytes()
+	}
+
+	fmt.Fprintf(out, "Uploading %d bytes of firmware with checksum %s...\n", len(firmware), *checksum)
+	message, err := radioClient.UpdateFirmware(ctx, bytes.NewReader(firmware), *checksum)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintln(out, message)
+	return nil
+}
+
+func runVerifyWpaKey(ctx context.Context, radioClient *client.Client, args []string, out io.Writer) error {
+	flags := flag.NewFlagSet("verify-wpa-key", flag.ContinueOnError)
+	network := flags.String(
+		"network",
+		"",
+		"network to check: a station (e.g. red1) on the access point, or 6GHz or 2.4GHz on the robot radio",
+	)
+	wpaKey := flags.String("key", "", "WPA key that the network is expected to have (required)")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if *network == "" || *wpaKey == "" {
+		return errors.New("-network and -key are required")
+	}
+
+	radioType, err := radioClient.DetectRadioType(ctx)
+	if err != nil {
+		return err
+	}
+	var networks []namedNetwork
+	if radioType == client.TypeRobotRadio {
+		status, err := (&client.RobotRadioClient{Client: radioClient}).GetStatus(ctx)
+		if err != nil {
+			return err
+		}
+		networks = robotRadioNetworks(status)
+	} else {
+		status, err := (&client.AccessPointClient{Client: radioClient}).GetStatus(ctx)
+		if err != nil {
+			return err
+		}
+		networks = accessPointNetworks(status)
+	}
+
+	networkStatus := findNetwork(networks, *network)
+	if networkStatus == nil {
+		return fmt.Errorf("radio has no network named %q", *network)
+	}
+	if !networkStatus.VerifyWpaKey(*wpaKey) {
+		return fmt.Errorf("WPA key for network %s (SSID %q) does not match", *network, networkStatus.Ssid)
+	}
+	fmt.Fprintf(out, "WPA key for network %s (SSID %q) matches.\n", *network, networkStatus.Ssid)
+	return nil
+}
+
+// channelFlag parses the -channel flag, which is either a channel number or "auto".
+type channelFlag client.AccessPointChannel
+
+func (channel *channelFlag) String() string {
+	if channel == nil {
+		return "0"
+	}
+	if *channel == channelFlag(client.AutoChannel) {
+		return "auto"
+	}
+	return strconv.Itoa(int(*channel))
+}
+
+func (channel *channelFlag) Set(value string) error {
+	if value == "auto" {
+		*channel = channelFlag(client.AutoChannel)
+		return nil
+	}
+	number, err := strconv.Atoi(value)
+	if err != nil || number < 0 {
+		return errors.New("expected a channel number or auto")
+	}
+	*channel = channelFlag(number)
+	return nil
+}
+
+// stationFlag accumulates repeated -station flags into a map of station configurations.
+type stationFlag map[string]client.StationConfiguration
+
+func (stations stationFlag) String() string {
+	var values []string
+	for station, configuration := range stations {
+		values = append(values, fmt.Sprintf("%s=%s:%s", station, configuration.Ssid, configuration.WpaKey))
+	}
+	return strings.Join(values, ",")
+}
+
+func (stations stationFlag) Set(value string) error {
+	station, network, ok := strings.Cut(value, "=")
+	if !ok {
+		return errors.New("expected station=ssid:wpaKey")
+	}
+	ssid, wpaKey, ok := strings.Cut(network, ":")
+	if !ok {
+		return errors.New("expected station=ssid:wpaKey")
+	}
+	stations[station] = client.StationConfiguration{Ssid: ssid, WpaKey: wpaKey}
+	return nil
+}
+
+// stationVlanFlag accumulates repeated -station-vlan flags into a map of station names to VLAN IDs.
+type stationVlanFlag map[string]int
+
+func (stationVlans stationVlanFlag) String() string {
+	var values []string
+	for station, vlan := range stationVlans {
+		values = append(values, fmt.Sprintf("%s=%d", station, vlan))
+	}
+	return strings.Join(values, ",")
+}
+
+func (stationVlans stationVlanFlag) Set(value string) error {
+	station, vlanValue, ok := strings.Cut(value, "=")
+	if !ok {
+		return errors.New("expected station=vlan")
+	}
+	vlan, err := strconv.Atoi(vlanValue)
+	if err != nil {
+		return errors.New("expected station=vlan")
+	}
+	stationVlans[station] = vlan
+	return nil
+}
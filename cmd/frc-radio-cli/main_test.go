@@ -0,0 +1,296 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// Hash of the WPA key "12345678" with the salt "abcdefghijklmnop".
+const testHashedWpaKey = "f2ec9bc783fef6c1e6e70af1647d391f3098b59d8c1c63e35957400bc9c9c945"
+
+// Status of a robot radio with no networks linked.
+const robotRadioStatusJson = `{"status": "ACTIVE", "mode": "TEAM_ROBOT_RADIO", "networkStatus24": {"ssid": "FRC-254"},
+	"networkStatus6": {"ssid": "254"}}`
+
+// fakeRadio is a minimal stand-in for the radio API that records the requests it receives.
+type fakeRadio struct {
+	statusJson     string
+	tlsFingerprint string
+	configuration  map[string]any
+	firmware       string
+	checksum       string
+}
+
+func (radio *fakeRadio) start(t *testing.T) string {
+	server := httptest.NewServer(radio.handler())
+	t.Cleanup(server.Close)
+	return server.URL
+}
+
+// startTls is like start, except that the fake radio serves HTTPS and reports the fingerprint of its certificate.
+func (radio *fakeRadio) startTls(t *testing.T) string {
+	server := httptest.NewTLSServer(radio.handler())
+	t.Cleanup(server.Close)
+	hash := sha256.Sum256(server.Certificate().Raw)
+	radio.tlsFingerprint = hex.EncodeToString(hash[:])
+	return server.URL
+}
+
+func (radio *fakeRadio) handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer mypassword" {
+			http.Error(w, "HTTP request error 401: not authorized", http.StatusUnauthorized)
+			return
+		}
+		switch r.Method + " " + r.URL.Path {
+		case "GET /health":
+			if radio.tlsFingerprint != "" {
+				w.Header().Set("X-FRC-Radio-TLS-Fingerprint", radio.tlsFingerprint)
+			}
+			_, _ = fmt.Fprintln(w, "OK")
+		case "GET /status":
+			_, _ = fmt.Fprint(w, radio.statusJson)
+		case "POST /configuration":
+			_ = json.NewDecoder(r.Body).Decode(&radio.configuration)
+			w.WriteHeader(http.StatusAccepted)
+			_, _ = fmt.Fprint(w, `{"id": "abc", "status": "QUEUED"}`)
+		case "GET /survey":
+			_, _ = fmt.Fprint(
+				w,
+				`{"networks": [{"macAddress": "48:DA:35:B0:10:01", "channel": 37, "signalDbm": -58}], `+
+					`"channels": [{"channel": 5}], "leastCongestedChannel": 5}`,
+			)
+		case "GET /configuration/abc":
+			_, _ = fmt.Fprint(w, `{"id": "abc", "status": "SUCCEEDED"}`)
+		case "POST /firmware":
+			file, _, _ := r.FormFile("file")
+			contents, _ := io.ReadAll(file)
+			radio.firmware = string(contents)
+			radio.checksum = r.FormValue("checksum")
+			w.WriteHeader(http.StatusAccepted)
+			_, _ = fmt.Fprintln(w, "New firmware received and will be applied now.")
+		default:
+			http.NotFound(w, r)
+		}
+	})
+}
+
+func runWithFakeRadio(t *testing.T, radio *fakeRadio, args ...string) (string, error) {
+	var out bytes.Buffer
+	args = append([]string{"-address", radio.start(t), "-password", "mypassword"}, args...)
+	err := run(context.Background(), args, &out)
+	return out.String(), err
+}
+
+func TestRun_Health(t *testing.T) {
+	out, err := runWithFakeRadio(t, &fakeRadio{}, "health")
+	assert.Nil(t, err)
+	assert.Equal(t, "OK\n", out)
+
+	var buffer bytes.Buffer
+	err = run(context.Background(), []string{"-address", (&fakeRadio{}).start(t), "health"}, &buffer)
+	if assert.NotNil(t, err) {
+		assert.Contains(t, err.Error(), "returned status 401")
+	}
+}
+
+func TestRun_HealthTls(t *testing.T) {
+	radio := &fakeRadio{}
+	address := radio.startTls(t)
+	var out bytes.Buffer
+	err := run(
+		context.Background(),
+		[]string{"-address", address, "-password", "mypassword", "-tls-fingerprint", radio.tlsFingerprint, "health"},
+		&out,
+	)
+	assert.Nil(t, err)
+	assert.Equal(t, fmt.Sprintf("OK\nTLS certificate fingerprint: %s\n", radio.tlsFingerprint), out.String())
+
+	// The radio's self-signed certificate isn't trusted without the fingerprint.
+	err = run(context.Background(), []string{"-address", address, "-password", "mypassword", "health"}, &out)
+	if assert.NotNil(t, err) {
+		assert.Contains(t, err.Error(), "certificate")
+	}
+}
+
+func TestRun_InvalidCommand(t *testing.T) {
+	var out bytes.Buffer
+	err := run(context.Background(), []string{}, &out)
+	if assert.NotNil(t, err) {
+		assert.Equal(t, "no command given", err.Error())
+	}
+	err = run(context.Background(), []string{"reboot"}, &out)
+	if assert.NotNil(t, err) {
+		assert.Equal(t, "unknown command \"reboot\"", err.Error())
+	}
+}
+
+func TestRun_Configure(t *testing.T) {
+	radio := fakeRadio{statusJson: `{"status": "ACTIVE", "channel": 93, "stationStatuses": {}}`}
+	out, err := runWithFakeRadio(
+		t,
+		&radio,
+		"configure",
+		"-channel",
+		"93",
+		"-station",
+		"red1=254:12345678",
+		"-station",
+		"blue3=1678:87654321",
+		"-bandwidth-limit",
+		"4",
+		"-tx-power",
+		"20",
+		"-station-vlan",
+		"red1=110",
+	)
+	assert.Nil(t, err)
+	assert.Contains(t, out, "Configuration request accepted as job abc.")
+	assert.Contains(t, out, "Configuration applied successfully.")
+	assert.Equal(
+		t,
+		map[string]any{
+			"channel":          93.0,
+			"channelBandwidth": "",
+			"txPower":          20.0,
+			"redVlans":         "",
+			"blueVlans":        "",
+			"stationVlans":     map[string]any{"red1": 110.0},
+			"stationConfigurations": map[string]any{
+				"red1":  map[string]any{"ssid": "254", "wpaKey": "12345678", "bandwidthLimitMbps": 0.0},
+				"blue3": map[string]any{"ssid": "1678", "wpaKey": "87654321", "bandwidthLimitMbps": 0.0},
+			},
+			"syslogIpAddress":    "",
+			"bandwidthLimitMbps": 4.0,
+		},
+		radio.configuration,
+	)
+
+	_, err = runWithFakeRadio(t, &radio, "configure", "-team-number", "254")
+	if assert.NotNil(t, err) {
+		assert.Equal(t, "flag -team-number doesn't apply to this type of radio", err.Error())
+	}
+
+	_, err = runWithFakeRadio(t, &radio, "configure", "-station", "red1")
+	if assert.NotNil(t, err) {
+		assert.Contains(t, err.Error(), "expected station=ssid:wpaKey")
+	}
+
+	_, err = runWithFakeRadio(t, &radio, "configure", "-channel", "auto")
+	assert.Nil(t, err)
+	assert.Equal(t, "auto", radio.configuration["channel"])
+	assert.Nil(t, radio.configuration["stationVlans"])
+
+	_, err = runWithFakeRadio(t, &radio, "configure", "-station-vlan", "red1")
+	if assert.NotNil(t, err) {
+		assert.Contains(t, err.Error(), "expected station=vlan")
+	}
+
+	_, err = runWithFakeRadio(t, &radio, "configure", "-channel", "best")
+	if assert.NotNil(t, err) {
+		assert.Contains(t, err.Error(), "expected a channel number or auto")
+	}
+}
+
+func TestRun_ConfigureNoWait(t *testing.T) {
+	radio := fakeRadio{statusJson: robotRadioStatusJson}
+	out, err := runWithFakeRadio(
+		t, &radio, "configure", "-mode", "TEAM_ROBOT_RADIO", "-team-number", "254", "-wpa-key-6", "12345678", "-wait=false",
+	)
+	assert.Nil(t, err)
+	assert.Equal(t, "Configuration request accepted as job abc.\n", out)
+	assert.Equal(
+		t,
+		map[string]any{
+			"mode":       "TEAM_ROBOT_RADIO",
+			"channel":    0.0,
+			"txPower":    0.0,
+			"teamNumber": 254.0,
+			"ssidSuffix": "",
+			"wpaKey6":    "12345678",
+			"wpaKey24":   "",
+		},
+		radio.configuration,
+	)
+
+	// Flags for the other type of radio are rejected.
+	_, err = runWithFakeRadio(t, &radio, "configure", "-station", "red1=254:12345678")
+	if assert.NotNil(t, err) {
+		assert.Equal(t, "flag -station doesn't apply to this type of radio", err.Error())
+	}
+	_, err = runWithFakeRadio(t, &radio, "configure", "-channel", "auto")
+	if assert.NotNil(t, err) {
+		assert.Equal(t, "-channel auto is only supported by the access point", err.Error())
+	}
+}
+
+func TestRun_Survey(t *testing.T) {
+	out, err := runWithFakeRadio(t, &fakeRadio{}, "survey")
+	assert.Nil(t, err)
+	assert.Contains(t, out, "Least congested channel: 5\n")
+	assert.Contains(t, out, "48:DA:35:B0:10:01")
+}
+
+func TestRun_Firmware(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "firmware.tar")
+	assert.Nil(t, os.WriteFile(path, []byte("firmware contents"), 0644))
+
+	radio := fakeRadio{}
+	out, err := runWithFakeRadio(t, &radio, "firmware", "-file", path)
+	assert.Nil(t, err)
+	assert.Contains(t, out, "New firmware received and will be applied now.")
+	assert.Equal(t, "firmware contents", radio.firmware)
+	assert.Equal(t, "32ef8b989e46b1e42b9a2cecc57df13052c8f791f26cf71aad269d405e43cff2", radio.checksum)
+
+	// The checksum can be overridden for a file that was encrypted ahead of time.
+	_, err = runWithFakeRadio(t, &radio, "firmware", "-file", path, "-checksum", "0123")
+	assert.Nil(t, err)
+	assert.Equal(t, "0123", radio.checksum)
+
+	_, err = runWithFakeRadio(t, &radio, "firmware")
+	if assert.NotNil(t, err) {
+		assert.Equal(t, "-file is required", err.Error())
+	}
+}
+
+func TestRun_VerifyWpaKey(t *testing.T) {
+	radio := fakeRadio{
+		statusJson: fmt.Sprintf(
+			`{"status": "ACTIVE", "stationStatuses": {"red1": {"ssid": "254", "hashedWpaKey": "%s", `+
+				`"wpaKeySalt": "abcdefghijklmnop"}, "red2": null}}`,
+			testHashedWpaKey,
+		),
+	}
+	out, err := runWithFakeRadio(t, &radio, "verify-wpa-key", "-network", "red1", "-key", "12345678")
+	assert.Nil(t, err)
+	assert.Equal(t, "WPA key for network red1 (SSID \"254\") matches.\n", out)
+
+	_, err = runWithFakeRadio(t, &radio, "verify-wpa-key", "-network", "red1", "-key", "87654321")
+	if assert.NotNil(t, err) {
+		assert.Equal(t, "WPA key for network red1 (SSID \"254\") does not match", err.Error())
+	}
+
+	_, err = runWithFakeRadio(t, &radio, "verify-wpa-key", "-network", "red2", "-key", "12345678")
+	if assert.NotNil(t, err) {
+		assert.Equal(t, "radio has no network named \"red2\"", err.Error())
+	}
+
+	radio.statusJson = fmt.Sprintf(
+		`{"status": "ACTIVE", "networkStatus6": {"ssid": "254", "hashedWpaKey": "%s", `+
+			`"wpaKeySalt": "abcdefghijklmnop"}, "networkStatus24": {"ssid": "FRC-254"}}`,
+		testHashedWpaKey,
+	)
+	_, err = runWithFakeRadio(t, &radio, "verify-wpa-key", "-network", "6GHz", "-key", "12345678")
+	assert.Nil(t, err)
+}
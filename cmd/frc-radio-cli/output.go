@@ -0,0 +1,184 @@
+package main
+
+import (
+	"fmt"
+	"github.com/patfair/frc-radio-api/client"
+	"io"
+	"sort"
+	"strings"
+	"text/tabwriter"
+)
+
+// Order in which the access point's team stations are listed.
+var stationOrder = []string{"red1", "red2", "red3", "blue1", "blue2", "blue3"}
+
+// namedNetwork pairs the status of a network with the name it is displayed and looked up by.
+type namedNetwork struct {
+	name   string
+	status *client.NetworkStatus
+}
+
+// printAccessPointStatus writes a human-readable summary of the given access point status, including a table of its
+// team networks.
+func printAccessPointStatus(out io.Writer, status *client.AccessPointStatus) {
+	printCommonStatus(out, status.Status, status.ConfigurationError, status.RolledBack, status.Version)
+	fmt.Fprintf(out, "Channel:  %d (%s)\n", status.Channel, status.ChannelBandwidth)
+	fmt.Fprintf(out, "Tx power: %d dBm\n", status.TxPower)
+	fmt.Fprintf(out, "VLANs:    red %s, blue %s%s\n", status.RedVlans, status.BlueVlans, stationVlanOverrides(status))
+	fmt.Fprintln(out)
+	printNetworkTable(out, accessPointNetworks(status))
+}
+
+// printRobotRadioStatus writes a human-readable summary of the given robot radio status, including a table of its
+// networks.
+func printRobotRadioStatus(out io.Writer, status *client.RobotRadioStatus) {
+	printCommonStatus(out, status.Status, status.ConfigurationError, status.RolledBack, status.Version)
+	fmt.Fprintf(out, "Mode:     %s\n", status.Mode)
+	fmt.Fprintf(out, "Team:     %d\n", status.TeamNumber)
+	fmt.Fprintf(out, "Channel:  %s\n", status.Channel)
+	fmt.Fprintf(out, "Tx power: %d dBm\n", status.TxPower)
+	fmt.Fprintln(out)
+	printNetworkTable(out, robotRadioNetworks(status))
+}
+
+func printCommonStatus(out io.Writer, status, configurationError string, rolledBack bool, version string) {
+	fmt.Fprintf(out, "Status:   %s\n", status)
+	if configurationError != "" {
+		fmt.Fprintf(out, "Error:    %s (rolled back: %t)\n", configurationError, rolledBack)
+	}
+	fmt.Fprintf(out, "Version:  %s\n", version)
+}
+
+// printNetworkTable writes a table with one row per network. Networks that are disabled are shown with a dash.
+func printNetworkTable(out io.Writer, networks []namedNetwork) {
+	table := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(table, "NETWORK\tSSID\tLINKED\tMAC ADDRESS\tSIGNAL\tSNR\tRX MBPS\tTX MBPS\tBANDWIDTH\tQUALITY")
+	for _, network := range networks {
+		networkStatus := network.status
+		if networkStatus == nil {
+			fmt.Fprintf(table, "%s\t-\t\t\t\t\t\t\t\t\n", network.name)
+			continue
+		}
+		fmt.Fprintf(
+			table,
+			"%s\t%s\t%s\t%s\t%d dBm\t%d dB\t%.1f\t%.1f\t%.2f Mbps\t%s\n",
+			network.name,
+			networkStatus.Ssid,
+			yesNo(networkStatus.IsLinked),
+			networkStatus.MacAddress,
+			networkStatus.SignalDbm,
+			networkStatus.SignalNoiseRatio,
+			networkStatus.RxRateMbps,
+			networkStatus.TxRateMbps,
+			networkStatus.BandwidthUsedMbps,
+			networkStatus.ConnectionQuality,
+		)
+	}
+	_ = table.Flush()
+}
+
+// printSurvey writes a table of the channels covered by the given survey, followed by a table of the neighboring
+// networks found.
+func printSurvey(out io.Writer, survey *client.ChannelSurvey) {
+	fmt.Fprintf(out, "Least congested channel: %d\n", survey.LeastCongestedChannel)
+	fmt.Fprintln(out)
+
+	table := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(table, "CHANNEL\tNETWORKS\tSTRONGEST\tTOTAL")
+	for _, channel := range survey.Channels {
+		if channel.NetworkCount == 0 {
+			fmt.Fprintf(table, "%d\t0\t-\t-\n", channel.Channel)
+			continue
+		}
+		fmt.Fprintf(
+			table,
+			"%d\t%d\t%d dBm\t%d dBm\n",
+			channel.Channel,
+			channel.NetworkCount,
+			channel.StrongestSignalDbm,
+			channel.TotalSignalDbm,
+		)
+	}
+	_ = table.Flush()
+	fmt.Fprintln(out)
+
+	table = tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(table, "MAC ADDRESS\tSSID\tCHANNEL\tWIDTH\tSIGNAL\tENCRYPTION")
+	for _, network := range survey.Networks {
+		fmt.Fprintf(
+			table,
+			"%s\t%s\t%d\t%d MHz\t%d dBm\t%s\n",
+			network.MacAddress,
+			network.Ssid,
+			network.Channel,
+			network.ChannelWidthMhz,
+			network.SignalDbm,
+			network.Encryption,
+		)
+	}
+	_ = table.Flush()
+}
+
+// stationVlanOverrides returns a description of the given access point's per-station VLANs in display order, or an
+// empty string if there are none.
+func stationVlanOverrides(status *client.AccessPointStatus) string {
+	var overrides []string
+	for _, name := range stationOrder {
+		if vlan, ok := status.StationVlans[name]; ok {
+			overrides = append(overrides, fmt.Sprintf("%s %d", name, vlan))
+		}
+	}
+	if len(overrides) == 0 {
+		return ""
+	}
+	return "; " + strings.Join(overrides, ", ")
+}
+
+// accessPointNetworks returns the team networks of the given access point, in display order.
+func accessPointNetworks(status *client.AccessPointStatus) []namedNetwork {
+	var extraNames []string
+	for name := range status.StationStatuses {
+		if !contains(stationOrder, name) {
+			extraNames = append(extraNames, name)
+		}
+	}
+	sort.Strings(extraNames)
+
+	var networks []namedNetwork
+	for _, name := range append(append([]string{}, stationOrder...), extraNames...) {
+		networks = append(networks, namedNetwork{name, status.StationStatuses[name]})
+	}
+	return networks
+}
+
+// robotRadioNetworks returns the networks of the given robot radio, in display order.
+func robotRadioNetworks(status *client.RobotRadioStatus) []namedNetwork {
+	return []namedNetwork{{"6GHz", &status.NetworkStatus6}, {"2.4GHz", &status.NetworkStatus24}}
+}
+
+// findNetwork returns the status of the network with the given name, or nil if there is no such network or it is
+// disabled.
+func findNetwork(networks []namedNetwork, name string) *client.NetworkStatus {
+	for _, network := range networks {
+		if network.name == name {
+			return network.status
+		}
+	}
+	return nil
+}
+
+func yesNo(value bool) string {
+	if value {
+		return "yes"
+	}
+	return "no"
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,143 @@
+package main
+
+import (
+	"bytes"
+	"github.com/patfair/frc-radio-api/client"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestPrintStatus_AccessPoint(t *testing.T) {
+	status := client.AccessPointStatus{
+		Status:           client.StatusActive,
+		Version:          "1.2.3",
+		Channel:          93,
+		ChannelBandwidth: "HT40",
+		TxPower:          23,
+		RedVlans:         "10_20_30",
+		BlueVlans:        "40_50_60",
+		StationVlans:     map[string]int{"blue3": 120, "red1": 110},
+		StationStatuses: map[string]*client.NetworkStatus{
+			"red1": {
+				Ssid:              "254",
+				IsLinked:          true,
+				MacAddress:        "48:DA:35:B0:00:CF",
+				SignalDbm:         -53,
+				SignalNoiseRatio:  42,
+				RxRateMbps:        860.3,
+				TxRateMbps:        1441.3,
+				BandwidthUsedMbps: 4.25,
+				ConnectionQuality: "excellent",
+			},
+			"blue2": {Ssid: "1678"},
+		},
+	}
+
+	var out bytes.Buffer
+	printAccessPointStatus(&out, &status)
+	assert.Equal(
+		t,
+		"Status:   ACTIVE\n"+
+			"Version:  1.2.3\n"+
+			"Channel:  93 (HT40)\n"+
+			"Tx power: 23 dBm\n"+
+			"VLANs:    red 10_20_30, blue 40_50_60; red1 110, blue3 120\n"+
+			"\n"+
+			"NETWORK  SSID  LINKED  MAC ADDRESS        SIGNAL   SNR    RX MBPS  TX MBPS  BANDWIDTH  QUALITY\n"+
+			"red1     254   yes     48:DA:35:B0:00:CF  -53 dBm  42 dB  860.3    1441.3   4.25 Mbps  excellent\n"+
+			"red2     -                                                                             \n"+
+			"red3     -                                                                             \n"+
+			"blue1    -                                                                             \n"+
+			"blue2    1678  no                         0 dBm    0 dB   0.0      0.0      0.00 Mbps  \n"+
+			"blue3    -                                                                             \n",
+		out.String(),
+	)
+}
+
+func TestPrintSurvey(t *testing.T) {
+	survey := client.ChannelSurvey{
+		Networks: []client.SurveyedNetwork{
+			{
+				MacAddress:      "48:DA:35:B0:10:01",
+				Ssid:            "practice-field",
+				Channel:         37,
+				ChannelWidthMhz: 40,
+				SignalDbm:       -58,
+				Encryption:      "WPA3 SAE (CCMP)",
+			},
+			{
+				MacAddress: "48:DA:35:B0:10:02", Channel: 37, ChannelWidthMhz: 20, SignalDbm: -61, Encryption: "none",
+			},
+		},
+		Channels: []client.ChannelCongestion{
+			{Channel: 5},
+			{Channel: 37, NetworkCount: 2, StrongestSignalDbm: -58, TotalSignalDbm: -56},
+		},
+		LeastCongestedChannel: 5,
+	}
+
+	var out bytes.Buffer
+	printSurvey(&out, &survey)
+	assert.Equal(
+		t,
+		"Least congested channel: 5\n"+
+			"\n"+
+			"CHANNEL  NETWORKS  STRONGEST  TOTAL\n"+
+			"5        0         -          -\n"+
+			"37       2         -58 dBm    -56 dBm\n"+
+			"\n"+
+			"MAC ADDRESS        SSID            CHANNEL  WIDTH   SIGNAL   ENCRYPTION\n"+
+			"48:DA:35:B0:10:01  practice-field  37       40 MHz  -58 dBm  WPA3 SAE (CCMP)\n"+
+			"48:DA:35:B0:10:02                  37       20 MHz  -61 dBm  none\n",
+		out.String(),
+	)
+}
+
+func TestPrintStatus_RobotRadio(t *testing.T) {
+	status := client.RobotRadioStatus{
+		Status:             client.StatusError,
+		ConfigurationError: "wireless configuration still incorrect",
+		RolledBack:         true,
+		Version:            "1.2.3",
+		Channel:            "5",
+		Mode:               "TEAM_ROBOT_RADIO",
+		TeamNumber:         254,
+		TxPower:            20,
+		NetworkStatus6:     client.NetworkStatus{Ssid: "254", IsLinked: true, ConnectionQuality: "good"},
+		NetworkStatus24:    client.NetworkStatus{Ssid: "FRC-254"},
+	}
+
+	var out bytes.Buffer
+	printRobotRadioStatus(&out, &status)
+	assert.Equal(
+		t,
+		"Status:   ERROR\n"+
+			"Error:    wireless configuration still incorrect (rolled back: true)\n"+
+			"Version:  1.2.3\n"+
+			"Mode:     TEAM_ROBOT_RADIO\n"+
+			"Team:     254\n"+
+			"Channel:  5\n"+
+			"Tx power: 20 dBm\n"+
+			"\n"+
+			"NETWORK  SSID     LINKED  MAC ADDRESS  SIGNAL  SNR   RX MBPS  TX MBPS  BANDWIDTH  QUALITY\n"+
+			"6GHz     254      yes                  0 dBm   0 dB  0.0      0.0      0.00 Mbps  good\n"+
+			"2.4GHz   FRC-254  no                   0 dBm   0 dB  0.0      0.0      0.00 Mbps  \n",
+		out.String(),
+	)
+}
+
+func TestAccessPointNetworks(t *testing.T) {
+	red1 := client.NetworkStatus{Ssid: "254"}
+	status := client.AccessPointStatus{
+		StationStatuses: map[string]*client.NetworkStatus{"red1": &red1, "red2": nil, "spare": nil},
+	}
+	networks := accessPointNetworks(&status)
+	var names []string
+	for _, network := range networks {
+		names = append(names, network.name)
+	}
+	assert.Equal(t, []string{"red1", "red2", "red3", "blue1", "blue2", "blue3", "spare"}, names)
+	assert.Same(t, &red1, findNetwork(networks, "red1"))
+	assert.Nil(t, findNetwork(networks, "red2"))
+	assert.Nil(t, findNetwork(networks, "6GHz"))
+}
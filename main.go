@@ -1,36 +1,176 @@
 package main
 
 import (
+	"flag"
 	"fmt"
 	"github.com/patfair/frc-radio-api/radio"
 	"github.com/patfair/frc-radio-api/web"
 	"log"
 	"os"
+	"path/filepath"
+	"time"
 )
 
 const (
-	// Path of the current log file.
-	logFilePath = "/root/frc-radio-api.log"
+	// Name of the current log file within the data directory.
+	logFileName = "frc-radio-api.log"
 
-	// Path of the old log file, which is rotated when the current log file gets too big.
-	oldLogFilePath = "/root/frc-radio-api.log.old"
+	// Name of the old log file within the data directory, which is rotated when the current log file gets too big.
+	oldLogFileName = "frc-radio-api.log.old"
+
+	// Name of the directory within the system's temporary directory that is used as the data directory when simulating.
+	simulatedDataDirName = "frc-radio-api"
 
 	// Maximum size of the current log file in bytes.
 	logFileMaxSizeBytes = 3 * 1 << 19 // 1.5 MB
+
+	// Environment variable that the MQTT broker password is read from if not given on the command line, to keep it out
+	// of the process list.
+	mqttPasswordEnvVar = "FRC_RADIO_API_MQTT_PASSWORD"
 )
 
 func main() {
-	logFile := setupLogging()
-	log.Println("Starting FRC Radio API...")
-	if logFile != nil {
-		defer logFile.Close()
+	maxConfigurationAttempts := flag.Int(
+		"max-configuration-attempts",
+		radio.DefaultMaxConfigurationAttempts,
+		"maximum number of attempts to make at applying a configuration request before giving up (0 for no limit)",
+	)
+	configurationTimeout := flag.Duration(
+		"configuration-timeout",
+		radio.DefaultConfigurationTimeout,
+		"maximum amount of time to spend applying a configuration request before giving up (0 for no limit)",
+	)
+	linkHistoryRetention := flag.Duration(
+		"link-history-retention",
+		radio.DefaultLinkHistoryRetention,
+		"amount of time for which to keep samples of each network's link metrics (0 to disable)",
+	)
+	simulate := flag.Bool(
+		"simulate", false, "simulate the radio hardware in memory instead of configuring the real device",
+	)
+	simulatedAssociationDelay := flag.Duration(
+		"simulated-association-delay",
+		3*time.Second,
+		"how long after a network is configured that a simulated remote device associates with it",
+	)
+	role := flag.String(
+		"role",
+		"",
+		"role of the radio, either access-point or robot-radio (default determined from the radio's model, or "+
+			"access-point when simulating)",
+	)
+	listenAddress := flag.String(
+		"listen-address",
+		"",
+		"address and port for the API server to listen on (default determined from the radio's configuration, or "+
+			"localhost:8081 when simulating)",
+	)
+	mqttBrokerUrl := flag.String(
+		"mqtt-broker",
+		"",
+		"URL of the MQTT broker to publish the radio status to, e.g. tcp://10.0.100.5:1883 (default disabled)",
+	)
+	mqttClientId := flag.String("mqtt-client-id", "", "client ID to connect to the MQTT broker with (default random)")
+	mqttUsername := flag.String("mqtt-username", "", "username to connect to the MQTT broker with")
+	mqttPassword := flag.String(
+		"mqtt-password",
+		os.Getenv(mqttPasswordEnvVar),
+		"password to connect to the MQTT broker with (default from $"+mqttPasswordEnvVar+")",
+	)
+	mqttTopicPrefix := flag.String(
+		"mqtt-topic-prefix",
+		"",
+		"prefix of the MQTT topics to publish and subscribe to (default frc/ap or frc/robot depending on the role)",
+	)
+	tlsEnabled := flag.Bool("tls", false, "serve the API over HTTPS in addition to plain HTTP")
+	tlsListenAddress := flag.String(
+		"tls-listen-address",
+		"",
+		"address and port for the HTTPS listener (default the host of the HTTP listener with port 8443)",
+	)
+	tlsCertFile := flag.String(
+		"tls-cert",
+		"",
+		"path to a PEM certificate to serve over HTTPS (default a self-signed one generated on first boot)",
+	)
+	tlsKeyFile := flag.String("tls-key", "", "path to the PEM private key of the certificate given by -tls-cert")
+	httpEnabled := flag.Bool("http", true, "serve the API over plain HTTP; only takes effect if -tls is also given")
+	dataDir := flag.String(
+		"data-dir",
+		"",
+		"directory holding the API's password, keys, tokens, webhooks, certificate and logs (default "+
+			web.DefaultDataDir+", or "+filepath.Join(os.TempDir(), simulatedDataDirName)+" when simulating)",
+	)
+	flag.Parse()
+
+	var radioRole radio.Role
+	if *role != "" {
+		var err error
+		if radioRole, err = radio.ParseRole(*role); err != nil {
+			log.Fatal(err)
+		}
 	}
 
-	radio := radio.NewRadio()
+	if *simulate {
+		// Log to stdout since the log file location only exists on the real radio.
+		log.Println("Starting FRC Radio API in simulation mode...")
+		if radioRole == "" {
+			radioRole = radio.RoleAccessPoint
+		}
+		if err := radio.EnableSimulation(radioRole, *simulatedAssociationDelay); err != nil {
+			log.Fatal(err)
+		}
+		if *listenAddress == "" {
+			*listenAddress = "localhost:8081"
+		}
+
+		// Keep the files that the API persists out of the real radio's data directory, which is unlikely to be writable
+		// on a development machine.
+		if *dataDir == "" {
+			*dataDir = filepath.Join(os.TempDir(), simulatedDataDirName)
+		}
+		if err := os.MkdirAll(*dataDir, 0700); err != nil {
+			log.Fatal(err)
+		}
+		log.Printf("Using data directory %s", *dataDir)
+	} else {
+		if *dataDir == "" {
+			*dataDir = web.DefaultDataDir
+		}
+		logFile := setupLogging(*dataDir)
+		log.Println("Starting FRC Radio API...")
+		if logFile != nil {
+			defer logFile.Close()
+		}
+	}
+
+	if radioRole == "" {
+		radioRole = radio.DetectRole()
+	}
+	log.Printf("Running with radio role: %s", radioRole)
+	radio := radio.NewRadio(radioRole)
+	radio.SetConfigurationLimits(*maxConfigurationAttempts, *configurationTimeout)
+	radio.SetLinkHistoryRetention(*linkHistoryRetention)
 	fmt.Println("created radio")
 
 	// Launch the web server in a separate thread.
 	webServer := web.NewWebServer(radio)
+	webServer.ListenAddress = *listenAddress
+	webServer.DataDir = *dataDir
+	webServer.Mqtt = web.MqttOptions{
+		BrokerUrl:   *mqttBrokerUrl,
+		ClientId:    *mqttClientId,
+		Username:    *mqttUsername,
+		Password:    *mqttPassword,
+		TopicPrefix: *mqttTopicPrefix,
+	}
+	webServer.Tls = web.TlsOptions{
+		Enabled:       *tlsEnabled,
+		ListenAddress: *tlsListenAddress,
+		CertFile:      *tlsCertFile,
+		KeyFile:       *tlsKeyFile,
+		DisableHttp:   !*httpEnabled,
+	}
 	fmt.Println("created webserver")
 	go webServer.Run()
 
@@ -38,8 +178,11 @@ func main() {
 	radio.Run()
 }
 
-// setupLogging sets up logging to a file, or to stdout if the file can't be opened.
-func setupLogging() *os.File {
+// setupLogging sets up logging to a file in the given directory, or to stdout if the file can't be opened.
+func setupLogging(dataDir string) *os.File {
+	logFilePath := filepath.Join(dataDir, logFileName)
+	oldLogFilePath := filepath.Join(dataDir, oldLogFileName)
+
 	// Rotate the log file if the current one is too big.
 	if fileInfo, err := os.Stat(logFilePath); err == nil {
 		if fileInfo.Size() >= logFileMaxSizeBytes {
@@ -0,0 +1,104 @@
+// This file is specific to the access point role of the API.
+
+package radio
+
+import (
+	"fmt"
+	"log"
+	"strconv"
+)
+
+const (
+	// Amount of traffic that the bandwidth limit lets through in a single burst, expressed as the number of seconds'
+	// worth of traffic at the limited rate.
+	bandwidthLimitBurstSec = 0.1
+
+	// Minimum size of a burst in bytes, so that low limits still let full-sized packets through.
+	minBandwidthLimitBurstBytes = 15000
+)
+
+// applyBandwidthLimits installs traffic-control rules on the Wi-Fi interface of each team station that has a limit in
+// the given map of stations to megabits per second, and removes those of any station that no longer has one. The rules
+// are reinstalled even if the limit hasn't changed, since reloading the Wi-Fi may have removed them.
+func (radio *AccessPointRadio) applyBandwidthLimits(limits map[station]float64) error {
+	defer radio.updateStationBandwidthLimits()
+	for station := red1; station <= blue3; station++ {
+		limitMbps, hasLimit := limits[station]
+		_, hadLimit := radio.bandwidthLimits[station]
+		if !hasLimit && !hadLimit {
+			continue
+		}
+
+		wifiInterface := radio.stationInterfaces[station]
+		clearTrafficControl(wifiInterface)
+		delete(radio.bandwidthLimits, station)
+		if hasLimit {
+			if err := installTrafficControl(wifiInterface, limitMbps); err != nil {
+				return fmt.Errorf("failed to apply bandwidth limit to station %s: %v", station.String(), err)
+			}
+			radio.bandwidthLimits[station] = limitMbps
+			log.Printf("Limited bandwidth of station %s to %v Mbps.", station.String(), limitMbps)
+		} else {
+			log.Printf("Removed bandwidth limit of station %s.", station.String())
+		}
+	}
+	return nil
+}
+
+// clearBandwidthLimits removes any traffic-control rules from the Wi-Fi interface of every team station, regardless of
+// whether this process installed them, so that limits left behind by a previous run of the API don't outlive it.
+func (radio *AccessPointRadio) clearBandwidthLimits() {
+	for station := red1; station <= blue3; station++ {
+		clearTrafficControl(radio.stationInterfaces[station])
+		delete(radio.bandwidthLimits, station)
+	}
+	radio.updateStationBandwidthLimits()
+}
+
+// updateStationBandwidthLimits reflects the bandwidth limits currently applied in the status of each station.
+func (radio *AccessPointRadio) updateStationBandwidthLimits() {
+	radio.mutex.Lock()
+	defer radio.mutex.Unlock()
+	for station := red1; station <= blue3; station++ {
+		if status := radio.StationStatuses[station.String()]; status != nil {
+			status.BandwidthLimitMbps = radio.bandwidthLimits[station]
+		}
+	}
+}
+
+// installTrafficControl limits the traffic leaving the given interface (i.e. to the robot) using a token bucket filter,
+// and the traffic arriving on it (i.e. from the robot) using a policer, to the given rate in megabits per second.
+func installTrafficControl(wifiInterface string, limitMbps float64) error {
+	rate := strconv.FormatFloat(limitMbps, 'f', -1, 64) + "mbit"
+	burst := strconv.Itoa(bandwidthLimitBurstBytes(limitMbps))
+	commands := [][]string{
+		{"qdisc", "add", "dev", wifiInterface, "root", "tbf", "rate", rate, "burst", burst, "latency", "50ms"},
+		{"qdisc", "add", "dev", wifiInterface, "handle", "ffff:", "ingress"},
+		{
+			"filter", "add", "dev", wifiInterface, "parent", "ffff:", "protocol", "all", "u32", "match", "u32", "0", "0",
+			"police", "rate", rate, "burst", burst, "drop",
+		},
+	}
+	for _, args := range commands {
+		if output, err := shell.runCommand("tc", args...); err != nil {
+			return fmt.Errorf("%v: %s", err, output)
+		}
+	}
+	return nil
+}
+
+// clearTrafficControl removes any traffic-control rules from the given interface.
+func clearTrafficControl(wifiInterface string) {
+	// These commands fail if there are no rules to remove, which is fine.
+	_, _ = shell.runCommand("tc", "qdisc", "del", "dev", wifiInterface, "root")
+	_, _ = shell.runCommand("tc", "qdisc", "del", "dev", wifiInterface, "ingress")
+}
+
+// bandwidthLimitBurstBytes returns the burst size in bytes to use for the given limit in megabits per second.
+func bandwidthLimitBurstBytes(limitMbps float64) int {
+	burstBytes := int(limitMbps * 1000000 / 8 * bandwidthLimitBurstSec)
+	if burstBytes < minBandwidthLimitBurstBytes {
+		return minBandwidthLimitBurstBytes
+	}
+	return burstBytes
+}
@@ -0,0 +1,121 @@
+// This file is specific to the access point role of the API.
+
+package radio
+
+import (
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestAccessPointRadio_applyBandwidthLimits(t *testing.T) {
+	originalUciTree, originalShell := uciTree, shell
+	defer func() {
+		uciTree, shell = originalUciTree, originalShell
+	}()
+
+	fakeTree := newFakeUciTree()
+	uciTree = fakeTree
+	fakeTree.valuesForGet["system.@system[0].model"] = "VH-109(AP)"
+	fakeShell := newFakeShell(t)
+	shell = fakeShell
+	fakeShell.commandOutput["cat /etc/vh_firmware"] = ""
+	radio := NewAccessPointRadio()
+	radio.StationStatuses["red1"] = &NetworkStatus{Ssid: "254"}
+	radio.StationStatuses["blue2"] = &NetworkStatus{Ssid: "1678"}
+
+	// No limits to apply or remove.
+	fakeShell.reset()
+	assert.Nil(t, radio.applyBandwidthLimits(map[station]float64{}))
+	assert.Empty(t, fakeShell.commandsRun)
+
+	// Apply limits to two stations.
+	for _, wifiInterface := range []string{"ath1", "ath14"} {
+		fakeShell.commandErrors["tc qdisc del dev "+wifiInterface+" root"] = errors.New("no such file or directory")
+		fakeShell.commandErrors["tc qdisc del dev "+wifiInterface+" ingress"] = errors.New("no such file or directory")
+		fakeShell.commandOutput["tc qdisc add dev "+wifiInterface+" handle ffff: ingress"] = ""
+	}
+	fakeShell.commandOutput["tc qdisc add dev ath1 root tbf rate 4mbit burst 50000 latency 50ms"] = ""
+	fakeShell.commandOutput["tc filter add dev ath1 parent ffff: protocol all u32 match u32 0 0 police rate 4mbit "+
+		"burst 50000 drop"] = ""
+	fakeShell.commandOutput["tc qdisc add dev ath14 root tbf rate 0.5mbit burst 15000 latency 50ms"] = ""
+	fakeShell.commandOutput["tc filter add dev ath14 parent ffff: protocol all u32 match u32 0 0 police rate 0.5mbit "+
+		"burst 15000 drop"] = ""
+	assert.Nil(t, radio.applyBandwidthLimits(map[station]float64{red1: 4, blue2: 0.5}))
+	assert.Equal(t, 10, len(fakeShell.commandsRun))
+	assert.Equal(t, map[station]float64{red1: 4, blue2: 0.5}, radio.bandwidthLimits)
+	assert.Equal(t, 4.0, radio.StationStatuses["red1"].BandwidthLimitMbps)
+	assert.Equal(t, 0.5, radio.StationStatuses["blue2"].BandwidthLimitMbps)
+
+	// Remove the limit from one station and change that of the other.
+	fakeShell.reset()
+	fakeShell.commandOutput["tc qdisc del dev ath1 root"] = ""
+	fakeShell.commandOutput["tc qdisc del dev ath1 ingress"] = ""
+	fakeShell.commandOutput["tc qdisc del dev ath14 root"] = ""
+	fakeShell.commandOutput["tc qdisc del dev ath14 ingress"] = ""
+	fakeShell.commandOutput["tc qdisc add dev ath14 handle ffff: ingress"] = ""
+	fakeShell.commandOutput["tc qdisc add dev ath14 root tbf rate 12mbit burst 150000 latency 50ms"] = ""
+	fakeShell.commandOutput["tc filter add dev ath14 parent ffff: protocol all u32 match u32 0 0 police rate 12mbit "+
+		"burst 150000 drop"] = ""
+	assert.Nil(t, radio.applyBandwidthLimits(map[station]float64{blue2: 12}))
+	assert.Equal(t, 7, len(fakeShell.commandsRun))
+	assert.Equal(t, map[station]float64{blue2: 12}, radio.bandwidthLimits)
+	assert.Equal(t, 0.0, radio.StationStatuses["red1"].BandwidthLimitMbps)
+	assert.Equal(t, 12.0, radio.StationStatuses["blue2"].BandwidthLimitMbps)
+
+	// Failure to install the rules.
+	fakeShell.reset()
+	fakeShell.commandOutput["tc qdisc del dev ath14 root"] = ""
+	fakeShell.commandOutput["tc qdisc del dev ath14 ingress"] = ""
+	fakeShell.commandErrors["tc qdisc add dev ath14 root tbf rate 12mbit burst 150000 latency 50ms"] =
+		errors.New("exit status 2")
+	err := radio.applyBandwidthLimits(map[station]float64{blue2: 12})
+	assert.EqualError(t, err, "failed to apply bandwidth limit to station blue2: exit status 2: ")
+	assert.Equal(t, map[station]float64{}, radio.bandwidthLimits)
+	assert.Equal(t, 0.0, radio.StationStatuses["blue2"].BandwidthLimitMbps)
+}
+
+func TestAccessPointRadio_clearBandwidthLimits(t *testing.T) {
+	originalUciTree, originalShell := uciTree, shell
+	defer func() {
+		uciTree, shell = originalUciTree, originalShell
+	}()
+
+	fakeTree := newFakeUciTree()
+	uciTree = fakeTree
+	fakeTree.valuesForGet["system.@system[0].model"] = "VH-109(AP)"
+	fakeShell := newFakeShell(t)
+	shell = fakeShell
+	fakeShell.commandOutput["cat /etc/vh_firmware"] = ""
+	radio := NewAccessPointRadio()
+	radio.StationStatuses["red1"] = &NetworkStatus{Ssid: "254", BandwidthLimitMbps: 4}
+	radio.bandwidthLimits[red1] = 4
+
+	// Every station is cleared, including those that this process never limited.
+	fakeShell.reset()
+	for station := red1; station <= blue3; station++ {
+		wifiInterface := radio.stationInterfaces[station]
+		fakeShell.commandErrors["tc qdisc del dev "+wifiInterface+" root"] = errors.New("no such file or directory")
+		fakeShell.commandErrors["tc qdisc del dev "+wifiInterface+" ingress"] = errors.New("no such file or directory")
+	}
+	radio.clearBandwidthLimits()
+	assert.Equal(t, 12, len(fakeShell.commandsRun))
+	assert.Empty(t, radio.bandwidthLimits)
+	assert.Equal(t, 0.0, radio.StationStatuses["red1"].BandwidthLimitMbps)
+}
+
+func TestBandwidthLimitBurstBytes(t *testing.T) {
+	assert.Equal(t, 15000, bandwidthLimitBurstBytes(0.5))
+	assert.Equal(t, 15000, bandwidthLimitBurstBytes(1.2))
+	assert.Equal(t, 50000, bandwidthLimitBurstBytes(4))
+	assert.Equal(t, 12500000, bandwidthLimitBurstBytes(1000))
+}
+
+// allowClearingBandwidthLimits sets up the given fake shell to accept the removal of the traffic-control rules from
+// every team station of the given radio, which happens whenever its state is initialized.
+func allowClearingBandwidthLimits(fakeShell *fakeShell, radio *AccessPointRadio) {
+	for station := red1; station <= blue3; station++ {
+		fakeShell.commandOutput["tc qdisc del dev "+radio.stationInterfaces[station]+" root"] = ""
+		fakeShell.commandOutput["tc qdisc del dev "+radio.stationInterfaces[station]+" ingress"] = ""
+	}
+}
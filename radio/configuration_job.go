@@ -0,0 +1,169 @@
+package radio
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"sync"
+	"time"
+)
+
+// Maximum number of configuration jobs to keep track of; the oldest are forgotten first.
+const maxConfigurationJobs = 100
+
+// configurationJobStatus represents the processing stage of a configuration request.
+type configurationJobStatus string
+
+const (
+	jobQueued     configurationJobStatus = "QUEUED"
+	jobSuperseded configurationJobStatus = "SUPERSEDED"
+	jobApplying   configurationJobStatus = "APPLYING"
+	jobSucceeded  configurationJobStatus = "SUCCEEDED"
+	jobFailed     configurationJobStatus = "FAILED"
+)
+
+// ConfigurationJob tracks the progress of a single configuration request through the asynchronous queue.
+type ConfigurationJob struct {
+	// Unique identifier for the job.
+	Id string `json:"id"`
+
+	// Enum representing the current processing stage of the job.
+	Status configurationJobStatus `json:"status"`
+
+	// Number of attempts made so far to apply the configuration to the radio.
+	Attempts int `json:"attempts"`
+
+	// Time at which the request was accepted into the queue.
+	QueuedAt time.Time `json:"queuedAt"`
+
+	// Time at which the radio started applying the request. Null if it hasn't started yet.
+	StartedAt *time.Time `json:"startedAt"`
+
+	// Time at which the job succeeded, failed or was superseded. Null if it hasn't finished yet.
+	FinishedAt *time.Time `json:"finishedAt"`
+
+	// Error encountered while applying the configuration. Blank unless the job failed.
+	Error string `json:"error"`
+
+	// Whether the radio was successfully rolled back to its previous configuration after the job failed.
+	RolledBack bool `json:"rolledBack"`
+
+	// Error encountered while rolling back to the previous configuration after the job failed, leaving the radio in an
+	// indeterminate state. Blank unless the rollback failed.
+	RollbackError string `json:"rollbackError"`
+}
+
+// configurationJobStore keeps track of the most recent configuration jobs in a manner that is safe to access from
+// multiple goroutines.
+type configurationJobStore struct {
+	mutex sync.Mutex
+
+	// Map of job IDs to jobs.
+	jobs map[string]*ConfigurationJob
+
+	// IDs of the tracked jobs in the order they were created, for evicting the oldest.
+	jobIds []string
+
+	// ID of the job currently being applied, or blank if there is none.
+	activeJobId string
+}
+
+// create starts tracking a new queued job and returns a copy of it.
+func (store *configurationJobStore) create() ConfigurationJob {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+
+	if store.jobs == nil {
+		store.jobs = make(map[string]*ConfigurationJob)
+	}
+	if len(store.jobIds) >= maxConfigurationJobs {
+		delete(store.jobs, store.jobIds[0])
+		store.jobIds = store.jobIds[1:]
+	}
+
+	job := ConfigurationJob{Id: newJobId(), Status: jobQueued, QueuedAt: time.Now()}
+	store.jobs[job.Id] = &job
+	store.jobIds = append(store.jobIds, job.Id)
+	return job
+}
+
+// get returns a copy of the job with the given ID, and whether it exists.
+func (store *configurationJobStore) get(id string) (ConfigurationJob, bool) {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+
+	job, ok := store.jobs[id]
+	if !ok {
+		return ConfigurationJob{}, false
+	}
+	return *job, true
+}
+
+// markSuperseded records that the given job was dropped in favor of a newer one before it could be completed.
+func (store *configurationJobStore) markSuperseded(id string) {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+
+	if store.activeJobId == id {
+		store.activeJobId = ""
+	}
+	if job, ok := store.jobs[id]; ok {
+		now := time.Now()
+		job.Status = jobSuperseded
+		job.FinishedAt = &now
+	}
+}
+
+// markApplying records that the radio has started applying the given job.
+func (store *configurationJobStore) markApplying(id string) {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+
+	store.activeJobId = id
+	if job, ok := store.jobs[id]; ok {
+		now := time.Now()
+		job.Status = jobApplying
+		job.StartedAt = &now
+	}
+}
+
+// recordAttempt increments the number of attempts made to apply the job currently being applied, if any.
+func (store *configurationJobStore) recordAttempt() {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+
+	if job, ok := store.jobs[store.activeJobId]; ok {
+		job.Attempts++
+	}
+}
+
+// markFinished records the outcome of applying the given job and, if it failed, of rolling back afterwards.
+func (store *configurationJobStore) markFinished(id string, err, rollbackErr error) {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+
+	if store.activeJobId == id {
+		store.activeJobId = ""
+	}
+	if job, ok := store.jobs[id]; ok {
+		now := time.Now()
+		job.FinishedAt = &now
+		if err == nil {
+			job.Status = jobSucceeded
+		} else {
+			job.Status = jobFailed
+			job.Error = err.Error()
+			if rollbackErr == nil {
+				job.RolledBack = true
+			} else {
+				job.RollbackError = rollbackErr.Error()
+			}
+		}
+	}
+}
+
+// newJobId returns a random hexadecimal string to uniquely identify a job.
+func newJobId() string {
+	idBytes := make([]byte, 8)
+	_, _ = rand.Read(idBytes)
+	return hex.EncodeToString(idBytes)
+}
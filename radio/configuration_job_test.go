@@ -0,0 +1,79 @@
+package radio
+
+import (
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestConfigurationJobStore(t *testing.T) {
+	var store configurationJobStore
+	_, ok := store.get("nonexistent")
+	assert.False(t, ok)
+
+	job1 := store.create()
+	job2 := store.create()
+	job3 := store.create()
+	assert.Len(t, job1.Id, 16)
+	assert.NotEqual(t, job1.Id, job2.Id)
+	assert.Equal(t, jobQueued, job1.Status)
+	assert.Nil(t, job1.StartedAt)
+	assert.Nil(t, job1.FinishedAt)
+
+	store.markSuperseded(job1.Id)
+	store.markApplying(job2.Id)
+	store.recordAttempt()
+	store.recordAttempt()
+	job, ok := store.get(job2.Id)
+	assert.True(t, ok)
+	assert.Equal(t, jobApplying, job.Status)
+	assert.Equal(t, 2, job.Attempts)
+	assert.NotNil(t, job.StartedAt)
+	assert.Nil(t, job.FinishedAt)
+	store.markFinished(job2.Id, errors.New("oops"), nil)
+
+	store.markApplying(job3.Id)
+	store.recordAttempt()
+	store.markFinished(job3.Id, nil, nil)
+
+	// Attempts made while no job is active shouldn't be attributed to any job.
+	store.recordAttempt()
+
+	job, _ = store.get(job1.Id)
+	assert.Equal(t, jobSuperseded, job.Status)
+	assert.Equal(t, 0, job.Attempts)
+	assert.NotNil(t, job.FinishedAt)
+	job, _ = store.get(job2.Id)
+	assert.Equal(t, jobFailed, job.Status)
+	assert.Equal(t, 2, job.Attempts)
+	assert.Equal(t, "oops", job.Error)
+	assert.True(t, job.RolledBack)
+	assert.Equal(t, "", job.RollbackError)
+	assert.NotNil(t, job.FinishedAt)
+	job, _ = store.get(job3.Id)
+	assert.Equal(t, jobSucceeded, job.Status)
+	assert.Equal(t, 1, job.Attempts)
+	assert.Equal(t, "", job.Error)
+	assert.False(t, job.RolledBack)
+
+	// Untracked jobs should be ignored.
+	store.markApplying("")
+	store.recordAttempt()
+	store.markFinished("", nil, nil)
+	assert.Len(t, store.jobs, 3)
+}
+
+func TestConfigurationJobStoreEviction(t *testing.T) {
+	var store configurationJobStore
+	firstJob := store.create()
+	var lastJob ConfigurationJob
+	for i := 1; i < maxConfigurationJobs+5; i++ {
+		lastJob = store.create()
+	}
+	assert.Len(t, store.jobs, maxConfigurationJobs)
+	assert.Len(t, store.jobIds, maxConfigurationJobs)
+	_, ok := store.get(firstJob.Id)
+	assert.False(t, ok)
+	_, ok = store.get(lastJob.Id)
+	assert.True(t, ok)
+}
@@ -1,40 +1,59 @@
-// This file is specific to the access point version of the API.
-//go:build !robot
+// This file is specific to the access point role of the API.
 
 package radio
 
 import (
-	"errors"
+	"encoding/json"
 	"fmt"
 	"regexp"
+	"sort"
 )
 
 const (
-	maxStationSsidLength = 14
-	stationSsidRegex     = "^[a-zA-Z0-9-]*$"
+	maxStationSsidLength  = 14
+	stationSsidRegex      = "^[a-zA-Z0-9-]*$"
+	maxBandwidthLimitMbps = 1000
+	max6GhzChannel        = 233
 )
 
-// ConfigurationRequest represents a JSON request to configure the radio.
-type ConfigurationRequest struct {
-	// 5GHz or 6GHz channel number for the radio to use. Set to 0 to leave unchanged.
-	Channel int `json:"channel"`
+// AccessPointConfigurationRequest represents a JSON request to configure the access point.
+type AccessPointConfigurationRequest struct {
+	// 5GHz or 6GHz channel number for the radio to use, or "auto" to use the least congested valid channel. Set to 0
+	// to leave unchanged.
+	Channel AccessPointChannel `json:"channel"`
 
-	// Channel bandwidth mode for the radio to use. Valid values are "20MHz" and "40MHz". Set to an empty string to
-	// leave unchanged.
+	// Channel bandwidth mode for the radio to use. Valid values are "20MHz", "40MHz", "80MHz" and "160MHz". Set to an
+	// empty string to leave unchanged.
 	ChannelBandwidth string `json:"channelBandwidth"`
 
+	// Transmit power in dBm for the radio to use. The valid range depends on the hardware type. Set to 0 to leave
+	// unchanged, or to TxPowerDefault to restore the driver's default.
+	TxPower int `json:"txPower"`
+
 	// VLANs to use for the teams of the red alliance. Valid values are "10_20_30", "40_50_60", and "70_80_90".
 	RedVlans AllianceVlans `json:"redVlans"`
 
 	// VLANs to use for the teams of the blue alliance. Valid values are "10_20_30", "40_50_60", and "70_80_90".
 	BlueVlans AllianceVlans `json:"blueVlans"`
 
+	// VLAN IDs to use for individual team stations, keyed by station name (e.g. "red1"), overriding the ones given by
+	// RedVlans and BlueVlans. Each must have a matching "vlanN" interface in the radio's network configuration.
+	// Replaces any previous overrides if present (an empty map removes them all), or leaves them unchanged if omitted.
+	StationVlans map[string]int `json:"stationVlans"`
+
 	// SSID and WPA key for each team station, keyed by alliance and number (e.g. "red1", "blue3). If a station is not
 	// included, its network will be disabled by setting its SSID to a placeholder.
 	StationConfigurations map[string]StationConfiguration `json:"stationConfigurations"`
 
 	// IP address of the syslog server to send logs to (via UDP on port 514).
 	SyslogIpAddress string `json:"syslogIpAddress"`
+
+	// Maximum throughput in megabits per second to allow in each direction for every configured station that doesn't
+	// specify its own limit. Set to 0 for no limit.
+	BandwidthLimitMbps float64 `json:"bandwidthLimitMbps"`
+
+	// ID of the job tracking this request, assigned when it is queued.
+	jobId string
 }
 
 // StationConfiguration represents the configuration for a single team station.
@@ -44,99 +63,296 @@ type StationConfiguration struct {
 
 	// Team-specific WPA key for the station. Must be at least eight characters long.
 	WpaKey string `json:"wpaKey"`
+
+	// Maximum throughput in megabits per second to allow in each direction for the station, overriding the limit in
+	// the request as a whole. Set to 0 to fall back to that limit.
+	BandwidthLimitMbps float64 `json:"bandwidthLimitMbps"`
 }
 
 var validLinksysChannels = []int{36, 40, 44, 48, 149, 153, 157, 161, 165}
 
-// Validate checks that all parameters within the configuration request have valid values.
-func (request ConfigurationRequest) Validate(radio *Radio) error {
-	if request.Channel == 0 && request.ChannelBandwidth == "" && len(request.StationConfigurations) == 0 &&
-		request.RedVlans == "" && request.BlueVlans == "" && request.SyslogIpAddress == "" {
-		return errors.New("empty configuration request")
+// Map of each channel bandwidth that can be requested to the UCI htmode that configures it.
+var channelBandwidthHtmodes = map[string]string{"20MHz": "HE20", "40MHz": "HE40", "80MHz": "HE80", "160MHz": "HE160"}
+
+// Map of the htmodes that older configurations may use to the channel bandwidth that they configure.
+var legacyHtmodeChannelBandwidths = map[string]string{"HT20": "20MHz", "HT40": "40MHz"}
+
+// AccessPointChannel represents the channel requested for the access point, which is given in JSON either as a number
+// or as the string "auto".
+type AccessPointChannel int
+
+// AutoChannel is the channel requested as "auto", for which the radio picks the least congested valid channel.
+const AutoChannel AccessPointChannel = -1
+
+// MarshalJSON encodes the channel as a number, or as "auto" for AutoChannel.
+func (channel AccessPointChannel) MarshalJSON() ([]byte, error) {
+	if channel == AutoChannel {
+		return json.Marshal("auto")
 	}
+	return json.Marshal(int(channel))
+}
 
-	if request.Channel != 0 {
+// UnmarshalJSON decodes the channel from either a number or the string "auto".
+func (channel *AccessPointChannel) UnmarshalJSON(data []byte) error {
+	var number int
+	if err := json.Unmarshal(data, &number); err == nil {
+		*channel = AccessPointChannel(number)
+		return nil
+	}
+	var value string
+	if err := json.Unmarshal(data, &value); err != nil || value != "auto" {
+		return newValidationError(
+			ValidationInvalidChannel, "channel", "invalid channel %s (expecting a number or \"auto\")", string(data),
+		)
+	}
+	*channel = AutoChannel
+	return nil
+}
+
+// Validate checks that all parameters within the configuration request have valid values, returning every problem
+// found rather than just the first.
+func (request AccessPointConfigurationRequest) Validate(radio Radio) error {
+	radioType := radio.HardwareType()
+	if request.Channel == 0 && request.ChannelBandwidth == "" && request.TxPower == 0 &&
+		len(request.StationConfigurations) == 0 && request.RedVlans == "" && request.BlueVlans == "" &&
+		request.StationVlans == nil && request.SyslogIpAddress == "" {
+		return newValidationError(ValidationEmptyRequest, "", "empty configuration request")
+	}
+
+	var errs ValidationErrors
+	if request.Channel != 0 && request.Channel != AutoChannel {
 		// Validate channel number.
 		valid := false
-		switch radio.Type {
+		switch radioType {
 		case TypeLinksys:
 			for _, channel := range validLinksysChannels {
-				if request.Channel == channel {
+				if int(request.Channel) == channel {
 					valid = true
 					break
 				}
 			}
 		case TypeVividHosting:
-			valid = isValid6GhzChannel(request.Channel)
+			valid = isValid6GhzChannel(int(request.Channel))
 		}
 		if !valid {
-			return fmt.Errorf("invalid channel for %s: %d", radio.Type.String(), request.Channel)
+			errs.add(
+				ValidationInvalidChannel, "channel", "invalid channel for %s: %d", radioType.String(), request.Channel,
+			)
 		}
 	}
 
 	if request.ChannelBandwidth != "" {
 		// Validate channel bandwidth.
-		if radio.Type == TypeLinksys {
-			return fmt.Errorf("channel bandwidth cannot be changed on %s", radio.Type.String())
+		if radioType == TypeLinksys {
+			errs.add(
+				ValidationInvalidBandwidth,
+				"channelBandwidth",
+				"channel bandwidth cannot be changed on %s",
+				radioType.String(),
+			)
+		} else if _, ok := channelBandwidthHtmodes[request.ChannelBandwidth]; !ok {
+			errs.add(
+				ValidationInvalidBandwidth,
+				"channelBandwidth",
+				"invalid channel bandwidth: %s",
+				request.ChannelBandwidth,
+			)
 		}
-		if request.ChannelBandwidth != "20MHz" && request.ChannelBandwidth != "40MHz" {
-			return fmt.Errorf("invalid channel bandwidth: %s", request.ChannelBandwidth)
+	}
+
+	if radioType == TypeVividHosting && (request.Channel > 0 || request.ChannelBandwidth != "") {
+		// Validate that the channel fits within the band at the channel bandwidth, taking whichever of the two the
+		// request leaves unchanged from the radio's current configuration.
+		channel, channelBandwidth := int(request.Channel), request.ChannelBandwidth
+		if accessPoint, ok := radio.(*AccessPointRadio); ok {
+			accessPoint.mutex.RLock()
+			if channel == 0 {
+				channel = accessPoint.Channel
+			}
+			if channelBandwidth == "" {
+				channelBandwidth = accessPoint.ChannelBandwidth
+			}
+			accessPoint.mutex.RUnlock()
+		}
+		_, isKnownBandwidth := channelBandwidthHtmodes[channelBandwidth]
+		if isValid6GhzChannel(channel) && isKnownBandwidth &&
+			!isValid6GhzChannelForBandwidth(channel, channelBandwidth) {
+			errs.add(
+				ValidationInvalidChannel,
+				"channel",
+				"channel %d cannot be used with a channel bandwidth of %s",
+				channel,
+				channelBandwidth,
+			)
 		}
 	}
 
-	if request.RedVlans != "" || request.BlueVlans != "" {
-		if request.RedVlans == "" || request.BlueVlans == "" {
-			return errors.New("both red and blue VLANs must be specified")
+	if request.TxPower != 0 && request.TxPower != TxPowerDefault {
+		// Validate transmit power.
+		maxTxPower := maxTxPowerDbm6Ghz
+		if radioType == TypeLinksys {
+			maxTxPower = maxTxPowerDbmLinksys
+		}
+		if request.TxPower < minTxPowerDbm || request.TxPower > maxTxPower {
+			errs.add(
+				ValidationInvalidTxPower,
+				"txPower",
+				"invalid transmit power for %s: %d dBm (expecting %d-%d)",
+				radioType.String(),
+				request.TxPower,
+				minTxPowerDbm,
+				maxTxPower,
+			)
 		}
+	}
+
+	// Keep track of whether the VLAN settings are individually valid, since it only makes sense to check them against
+	// each other if they are.
+	vlanErrorCount := len(errs)
+	if request.RedVlans != "" || request.BlueVlans != "" {
 		validVlans := map[AllianceVlans]struct{}{Vlans102030: {}, Vlans405060: {}, Vlans708090: {}}
-		if _, ok := validVlans[request.RedVlans]; !ok {
-			return fmt.Errorf("invalid value for red VLANs: %s", request.RedVlans)
+		if request.RedVlans == "" {
+			errs.add(ValidationInvalidVlan, "redVlans", "both red and blue VLANs must be specified")
+		} else if _, ok := validVlans[request.RedVlans]; !ok {
+			errs.add(ValidationInvalidVlan, "redVlans", "invalid value for red VLANs: %s", request.RedVlans)
 		}
-		if _, ok := validVlans[request.BlueVlans]; !ok {
-			return fmt.Errorf("invalid value for blue VLANs: %s", request.BlueVlans)
+		if request.BlueVlans == "" {
+			errs.add(ValidationInvalidVlan, "blueVlans", "both red and blue VLANs must be specified")
+		} else if _, ok := validVlans[request.BlueVlans]; !ok {
+			errs.add(ValidationInvalidVlan, "blueVlans", "invalid value for blue VLANs: %s", request.BlueVlans)
+		} else if request.RedVlans == request.BlueVlans {
+			errs.add(ValidationInvalidVlan, "blueVlans", "red and blue VLANs cannot be the same")
 		}
-		if request.RedVlans == request.BlueVlans {
-			return fmt.Errorf("red and blue VLANs cannot be the same")
+	}
+
+	if request.StationVlans != nil {
+		// Validate per-station VLANs against the networks that exist on the radio.
+		networks, _ := uciTree.GetSections("network", "interface")
+		networkExists := make(map[string]bool)
+		for _, network := range networks {
+			networkExists[network] = true
+		}
+		for _, stationName := range sortedKeys(request.StationVlans) {
+			vlan := request.StationVlans[stationName]
+			if !isValidStationName(stationName) {
+				errs.add(
+					ValidationInvalidStation,
+					"stationVlans."+stationName,
+					"invalid station for VLAN: %s",
+					stationName,
+				)
+			} else if !networkExists[fmt.Sprintf("vlan%d", vlan)] {
+				errs.add(
+					ValidationInvalidVlan,
+					"stationVlans."+stationName,
+					"invalid VLAN for station %s: %d (no such network on the radio)",
+					stationName,
+					vlan,
+				)
+			}
 		}
 	}
 
-	// Validate station configurations.
-	for stationName, stationConfiguration := range request.StationConfigurations {
-		stationNameValid := false
-		for name := red1; name <= blue3; name++ {
-			if stationName == name.String() {
-				stationNameValid = true
-				break
+	if (request.RedVlans != "" || request.StationVlans != nil) && len(errs) == vlanErrorCount {
+		// Validate that no two stations would end up sharing a VLAN, taking whichever VLAN settings the request leaves
+		// unchanged from the radio's current configuration.
+		redVlans, blueVlans, stationVlans := request.RedVlans, request.BlueVlans, request.StationVlans
+		if accessPoint, ok := radio.(*AccessPointRadio); ok {
+			accessPoint.mutex.RLock()
+			if redVlans == "" {
+				redVlans, blueVlans = accessPoint.RedVlans, accessPoint.BlueVlans
+			}
+			if stationVlans == nil {
+				stationVlans = accessPoint.StationVlans
 			}
+			accessPoint.mutex.RUnlock()
 		}
-		if !stationNameValid {
-			return fmt.Errorf("invalid station: %s", stationName)
+		stationsByVlan := make(map[int]station)
+		for station := red1; station <= blue3; station++ {
+			vlan := stationVlan(station, redVlans, blueVlans, stationVlans)
+			if vlan < 0 {
+				continue
+			}
+			if otherStation, ok := stationsByVlan[vlan]; ok {
+				// Report the conflict against the later station so that each one has its own field path.
+				errs.add(
+					ValidationInvalidVlan,
+					"stationVlans."+station.String(),
+					"stations %s and %s cannot use the same VLAN: %d",
+					otherStation,
+					station,
+					vlan,
+				)
+			}
+			stationsByVlan[vlan] = station
 		}
-		if stationConfiguration.Ssid == "" {
-			return fmt.Errorf("SSID for station %s cannot be blank", stationName)
+	}
+
+	if request.BandwidthLimitMbps < 0 || request.BandwidthLimitMbps > maxBandwidthLimitMbps {
+		errs.add(
+			ValidationInvalidBandwidthLimit,
+			"bandwidthLimitMbps",
+			"invalid bandwidth limit: %v Mbps (expecting 0-%d)",
+			request.BandwidthLimitMbps,
+			maxBandwidthLimitMbps,
+		)
+	}
+
+	// Validate station configurations.
+	for _, stationName := range sortedKeys(request.StationConfigurations) {
+		stationConfiguration := request.StationConfigurations[stationName]
+		field := "stationConfigurations." + stationName
+		if !isValidStationName(stationName) {
+			errs.add(ValidationInvalidStation, field, "invalid station: %s", stationName)
+			continue
 		}
-		if len(stationConfiguration.Ssid) > maxStationSsidLength {
-			return fmt.Errorf(
+		if stationConfiguration.Ssid == "" {
+			errs.add(ValidationInvalidSsid, field+".ssid", "SSID for station %s cannot be blank", stationName)
+		} else if len(stationConfiguration.Ssid) > maxStationSsidLength {
+			errs.add(
+				ValidationInvalidSsid,
+				field+".ssid",
 				"invalid SSID length for station %s: %d (expecting 1-%d)",
 				stationName,
 				len(stationConfiguration.Ssid),
 				maxStationSsidLength,
 			)
-		}
-		if !regexp.MustCompile(stationSsidRegex).MatchString(stationConfiguration.Ssid) {
-			return fmt.Errorf("invalid SSID for station %s (expecting alphanumeric with hyphens)", stationName)
+		} else if !regexp.MustCompile(stationSsidRegex).MatchString(stationConfiguration.Ssid) {
+			errs.add(
+				ValidationInvalidSsid,
+				field+".ssid",
+				"invalid SSID for station %s (expecting alphanumeric with hyphens)",
+				stationName,
+			)
 		}
 		if len(stationConfiguration.WpaKey) < minWpaKeyLength || len(stationConfiguration.WpaKey) > maxWpaKeyLength {
-			return fmt.Errorf(
+			errs.add(
+				ValidationInvalidWpaKey,
+				field+".wpaKey",
 				"invalid WPA key length for station %s: %d (expecting %d-%d)",
 				stationName,
 				len(stationConfiguration.WpaKey),
 				minWpaKeyLength,
 				maxWpaKeyLength,
 			)
+		} else if !regexp.MustCompile(alphanumericRegex).MatchString(stationConfiguration.WpaKey) {
+			errs.add(
+				ValidationInvalidWpaKey,
+				field+".wpaKey",
+				"invalid WPA key for station %s (expecting alphanumeric)",
+				stationName,
+			)
 		}
-		if !regexp.MustCompile(alphanumericRegex).MatchString(stationConfiguration.WpaKey) {
-			return fmt.Errorf("invalid WPA key for station %s (expecting alphanumeric)", stationName)
+		limitMbps := stationConfiguration.BandwidthLimitMbps
+		if limitMbps < 0 || limitMbps > maxBandwidthLimitMbps {
+			errs.add(
+				ValidationInvalidBandwidthLimit,
+				field+".bandwidthLimitMbps",
+				"invalid bandwidth limit for station %s: %v Mbps (expecting 0-%d)",
+				stationName,
+				limitMbps,
+				maxBandwidthLimitMbps,
+			)
 		}
 	}
 
@@ -144,9 +360,96 @@ func (request ConfigurationRequest) Validate(radio *Radio) error {
 	if request.SyslogIpAddress != "" {
 		match, _ := regexp.MatchString("^((25[0-5]|(2[0-4]|1\\d|[1-9]|)\\d)\\.?\\b){4}$", request.SyslogIpAddress)
 		if !match {
-			return fmt.Errorf("invalid syslog IP address: %s", request.SyslogIpAddress)
+			errs.add(
+				ValidationInvalidSyslogIp,
+				"syslogIpAddress",
+				"invalid syslog IP address: %s",
+				request.SyslogIpAddress,
+			)
 		}
 	}
 
-	return nil
+	return errs.orNil()
+}
+
+// stationBandwidthLimits returns the bandwidth limit in megabits per second to apply to each configured station that
+// has one, taking into account the limit in the request as a whole.
+func (request AccessPointConfigurationRequest) stationBandwidthLimits() map[station]float64 {
+	limits := make(map[station]float64)
+	for station := red1; station <= blue3; station++ {
+		stationConfiguration, ok := request.StationConfigurations[station.String()]
+		if !ok {
+			continue
+		}
+		if stationConfiguration.BandwidthLimitMbps > 0 {
+			limits[station] = stationConfiguration.BandwidthLimitMbps
+		} else if request.BandwidthLimitMbps > 0 {
+			limits[station] = request.BandwidthLimitMbps
+		}
+	}
+	return limits
+}
+
+// getJobId returns the ID of the job tracking the request, assigned when it is queued.
+func (request AccessPointConfigurationRequest) getJobId() string {
+	return request.jobId
+}
+
+// setJobId records the ID of the job tracking the request.
+func (request *AccessPointConfigurationRequest) setJobId(id string) {
+	request.jobId = id
+}
+
+// isValidStationName returns true if the given name is that of a team station (e.g. "red1").
+func isValidStationName(stationName string) bool {
+	for station := red1; station <= blue3; station++ {
+		if stationName == station.String() {
+			return true
+		}
+	}
+	return false
+}
+
+// sortedKeys returns the keys of the given map in ascending order, so that the stations in a request are validated in a
+// consistent order.
+func sortedKeys[V any](values map[string]V) []string {
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// channelBandwidthForHtmode returns the channel bandwidth configured by the given UCI htmode, or "INVALID" if it isn't
+// one that the API can configure.
+func channelBandwidthForHtmode(htmode string) string {
+	for channelBandwidth, channelBandwidthHtmode := range channelBandwidthHtmodes {
+		if htmode == channelBandwidthHtmode {
+			return channelBandwidth
+		}
+	}
+	if channelBandwidth, ok := legacyHtmodeChannelBandwidths[htmode]; ok {
+		return channelBandwidth
+	}
+	return "INVALID"
+}
+
+// isValid6GhzChannelForBandwidth returns true if the given 6GHz channel can be the primary channel at the given channel
+// bandwidth (e.g. "80MHz"). A wide channel is made up of a fixed, aligned block of adjacent 20MHz channels, and the
+// whole block containing the primary channel has to fall within the band.
+func isValid6GhzChannelForBandwidth(channel int, channelBandwidth string) bool {
+	var bandwidthMhz int
+	if _, err := fmt.Sscanf(channelBandwidth, "%dMHz", &bandwidthMhz); err != nil || bandwidthMhz < 20 {
+		return false
+	}
+	if !isValid6GhzChannel(channel) {
+		return false
+	}
+
+	// 20MHz channels are numbered every fourth channel number, starting from 1.
+	blockSize := bandwidthMhz / 20
+	blockStart := (channel - 1) / 4 / blockSize * blockSize
+	lastChannelInBlock := 1 + 4*(blockStart+blockSize-1)
+	return lastChannelInBlock <= max6GhzChannel
 }
@@ -1,19 +1,20 @@
-// This file is specific to the access point version of the API.
-//go:build !robot
+// This file is specific to the access point role of the API.
 
 package radio
 
 import (
+	"encoding/json"
+	"errors"
 	"github.com/stretchr/testify/assert"
 	"testing"
 )
 
-func TestConfigurationRequest_Validate(t *testing.T) {
-	linksysRadio := &Radio{Type: TypeLinksys}
-	vividHostingRadio := &Radio{Type: TypeVividHosting}
+func TestAccessPointConfigurationRequest_Validate(t *testing.T) {
+	linksysRadio := &AccessPointRadio{Type: TypeLinksys}
+	vividHostingRadio := &AccessPointRadio{Type: TypeVividHosting}
 
 	// Empty request.
-	request := ConfigurationRequest{}
+	request := AccessPointConfigurationRequest{}
 	err := request.Validate(linksysRadio)
 	assert.EqualError(t, err, "empty configuration request")
 
@@ -27,84 +28,306 @@ func TestConfigurationRequest_Validate(t *testing.T) {
 	err = request.Validate(vividHostingRadio)
 	assert.EqualError(t, err, "invalid channel for TypeVividHosting: 36")
 
+	// Automatic channel selection is valid for either hardware type.
+	request.Channel = AutoChannel
+	assert.Nil(t, request.Validate(linksysRadio))
+	assert.Nil(t, request.Validate(vividHostingRadio))
+
 	// Invalid channel bandwidth.
-	request = ConfigurationRequest{ChannelBandwidth: "30MHz"}
+	request = AccessPointConfigurationRequest{ChannelBandwidth: "30MHz"}
 	err = request.Validate(vividHostingRadio)
 	assert.EqualError(t, err, "invalid channel bandwidth: 30MHz")
 
+	// Wide channels that don't fit within the band.
+	request = AccessPointConfigurationRequest{Channel: 229, ChannelBandwidth: "80MHz"}
+	err = request.Validate(vividHostingRadio)
+	assert.EqualError(t, err, "channel 229 cannot be used with a channel bandwidth of 80MHz")
+	request = AccessPointConfigurationRequest{Channel: 229, ChannelBandwidth: "160MHz"}
+	err = request.Validate(vividHostingRadio)
+	assert.EqualError(t, err, "channel 229 cannot be used with a channel bandwidth of 160MHz")
+	request = AccessPointConfigurationRequest{Channel: 221, ChannelBandwidth: "160MHz"}
+	assert.Nil(t, request.Validate(vividHostingRadio))
+	request = AccessPointConfigurationRequest{Channel: 229, ChannelBandwidth: "40MHz"}
+	assert.Nil(t, request.Validate(vividHostingRadio))
+
+	// Whichever of the channel and bandwidth is left unchanged is checked against the current configuration.
+	configuredRadio := &AccessPointRadio{Type: TypeVividHosting, Channel: 229, ChannelBandwidth: "160MHz"}
+	request = AccessPointConfigurationRequest{ChannelBandwidth: "80MHz"}
+	err = request.Validate(configuredRadio)
+	assert.EqualError(t, err, "channel 229 cannot be used with a channel bandwidth of 80MHz")
+	request = AccessPointConfigurationRequest{Channel: 5}
+	assert.Nil(t, request.Validate(configuredRadio))
+	configuredRadio.Channel = 5
+	request = AccessPointConfigurationRequest{Channel: 229}
+	err = request.Validate(configuredRadio)
+	assert.EqualError(t, err, "channel 229 cannot be used with a channel bandwidth of 160MHz")
+	request = AccessPointConfigurationRequest{Channel: AutoChannel}
+	assert.Nil(t, request.Validate(configuredRadio))
+
 	// Channel bandwidth not supported on Linksys.
-	request = ConfigurationRequest{ChannelBandwidth: "20MHz"}
+	request = AccessPointConfigurationRequest{ChannelBandwidth: "20MHz"}
 	err = request.Validate(linksysRadio)
 	assert.EqualError(t, err, "channel bandwidth cannot be changed on TypeLinksys")
 
+	// Invalid transmit power.
+	request = AccessPointConfigurationRequest{TxPower: 24}
+	err = request.Validate(linksysRadio)
+	assert.EqualError(t, err, "invalid transmit power for TypeLinksys: 24 dBm (expecting 1-23)")
+	assert.Nil(t, request.Validate(vividHostingRadio))
+	request = AccessPointConfigurationRequest{TxPower: 31}
+	err = request.Validate(vividHostingRadio)
+	assert.EqualError(t, err, "invalid transmit power for TypeVividHosting: 31 dBm (expecting 1-30)")
+	request = AccessPointConfigurationRequest{TxPower: -2}
+	err = request.Validate(vividHostingRadio)
+	assert.EqualError(t, err, "invalid transmit power for TypeVividHosting: -2 dBm (expecting 1-30)")
+	request = AccessPointConfigurationRequest{TxPower: TxPowerDefault}
+	assert.Nil(t, request.Validate(linksysRadio))
+	assert.Nil(t, request.Validate(vividHostingRadio))
+
 	// Invalid VLANs.
-	request = ConfigurationRequest{RedVlans: "10_20_30"}
+	request = AccessPointConfigurationRequest{RedVlans: "10_20_30"}
 	err = request.Validate(linksysRadio)
 	assert.EqualError(t, err, "both red and blue VLANs must be specified")
-	request = ConfigurationRequest{BlueVlans: "10_20_30"}
+	request = AccessPointConfigurationRequest{BlueVlans: "10_20_30"}
 	err = request.Validate(linksysRadio)
 	assert.EqualError(t, err, "both red and blue VLANs must be specified")
-	request = ConfigurationRequest{RedVlans: "20_30_40", BlueVlans: "30_40_50"}
+	request = AccessPointConfigurationRequest{RedVlans: "20_30_40", BlueVlans: "30_40_50"}
 	err = request.Validate(linksysRadio)
-	assert.EqualError(t, err, "invalid value for red VLANs: 20_30_40")
-	request = ConfigurationRequest{RedVlans: "70_80_90", BlueVlans: "30_40_50"}
+	assert.EqualError(t, err, "invalid value for red VLANs: 20_30_40; invalid value for blue VLANs: 30_40_50")
+	request = AccessPointConfigurationRequest{RedVlans: "70_80_90", BlueVlans: "30_40_50"}
 	err = request.Validate(linksysRadio)
 	assert.EqualError(t, err, "invalid value for blue VLANs: 30_40_50")
-	request = ConfigurationRequest{RedVlans: "70_80_90", BlueVlans: "70_80_90"}
+	request = AccessPointConfigurationRequest{RedVlans: "70_80_90", BlueVlans: "70_80_90"}
 	err = request.Validate(linksysRadio)
 	assert.EqualError(t, err, "red and blue VLANs cannot be the same")
 
 	// Invalid station.
-	request = ConfigurationRequest{
+	request = AccessPointConfigurationRequest{
 		StationConfigurations: map[string]StationConfiguration{"red4": {Ssid: "254", WpaKey: "12345678"}},
 	}
 	err = request.Validate(linksysRadio)
 	assert.EqualError(t, err, "invalid station: red4")
 
 	// Blank SSID.
-	request = ConfigurationRequest{
+	request = AccessPointConfigurationRequest{
 		StationConfigurations: map[string]StationConfiguration{"blue1": {Ssid: "", WpaKey: "12345678"}},
 	}
 	err = request.Validate(linksysRadio)
 	assert.EqualError(t, err, "SSID for station blue1 cannot be blank")
 
 	// Too-long SSID.
-	request = ConfigurationRequest{
+	request = AccessPointConfigurationRequest{
 		StationConfigurations: map[string]StationConfiguration{"blue1": {Ssid: "12345-longsuffix", WpaKey: "12345678"}},
 	}
 	err = request.Validate(linksysRadio)
 	assert.EqualError(t, err, "invalid SSID length for station blue1: 16 (expecting 1-14)")
 
 	// Invalid characters in SSID.
-	request = ConfigurationRequest{
+	request = AccessPointConfigurationRequest{
 		StationConfigurations: map[string]StationConfiguration{"blue1": {Ssid: "abc_XYZ", WpaKey: "12345678"}},
 	}
 	err = request.Validate(linksysRadio)
 	assert.EqualError(t, err, "invalid SSID for station blue1 (expecting alphanumeric with hyphens)")
 
 	// Too-short WPA key.
-	request = ConfigurationRequest{
+	request = AccessPointConfigurationRequest{
 		StationConfigurations: map[string]StationConfiguration{"blue1": {Ssid: "12345-suffix", WpaKey: "1234567"}},
 	}
 	err = request.Validate(linksysRadio)
 	assert.EqualError(t, err, "invalid WPA key length for station blue1: 7 (expecting 8-16)")
 
 	// Too-long WPA key.
-	request = ConfigurationRequest{
+	request = AccessPointConfigurationRequest{
 		StationConfigurations: map[string]StationConfiguration{"blue1": {Ssid: "254", WpaKey: "12345678123456789"}},
 	}
 	err = request.Validate(linksysRadio)
 	assert.EqualError(t, err, "invalid WPA key length for station blue1: 17 (expecting 8-16)")
 
 	// Invalid characters in WPA key.
-	request = ConfigurationRequest{
+	request = AccessPointConfigurationRequest{
 		StationConfigurations: map[string]StationConfiguration{"blue1": {Ssid: "254", WpaKey: "aAbC2__+#"}},
 	}
 	err = request.Validate(linksysRadio)
 	assert.EqualError(t, err, "invalid WPA key for station blue1 (expecting alphanumeric)")
 
+	// Invalid bandwidth limits.
+	request = AccessPointConfigurationRequest{
+		StationConfigurations: map[string]StationConfiguration{"blue1": {Ssid: "254", WpaKey: "12345678"}},
+		BandwidthLimitMbps:    -1,
+	}
+	err = request.Validate(linksysRadio)
+	assert.EqualError(t, err, "invalid bandwidth limit: -1 Mbps (expecting 0-1000)")
+	request = AccessPointConfigurationRequest{
+		StationConfigurations: map[string]StationConfiguration{
+			"blue1": {Ssid: "254", WpaKey: "12345678", BandwidthLimitMbps: 1000.5},
+		},
+	}
+	err = request.Validate(linksysRadio)
+	assert.EqualError(t, err, "invalid bandwidth limit for station blue1: 1000.5 Mbps (expecting 0-1000)")
+
 	// Invalid syslog IP address.
-	request = ConfigurationRequest{SyslogIpAddress: "10.0.100.256"}
+	request = AccessPointConfigurationRequest{SyslogIpAddress: "10.0.100.256"}
 	err = request.Validate(linksysRadio)
 	assert.EqualError(t, err, "invalid syslog IP address: 10.0.100.256")
+
+	// Valid bandwidth limits.
+	request = AccessPointConfigurationRequest{
+		StationConfigurations: map[string]StationConfiguration{
+			"red1": {Ssid: "254", WpaKey: "12345678", BandwidthLimitMbps: 7.5},
+		},
+		BandwidthLimitMbps: 4,
+	}
+	assert.Nil(t, request.Validate(linksysRadio))
+}
+
+func TestAccessPointConfigurationRequest_ValidateAllErrors(t *testing.T) {
+	request := AccessPointConfigurationRequest{
+		Channel:          3,
+		ChannelBandwidth: "40MHz",
+		RedVlans:         "10_20_30",
+		StationConfigurations: map[string]StationConfiguration{
+			"red1":  {Ssid: "254", WpaKey: "12345678"},
+			"red2":  {Ssid: "1114", WpaKey: "1234"},
+			"red3":  {Ssid: "1678", WpaKey: "aAbC2__+#"},
+			"blue4": {Ssid: "5940", WpaKey: "12345678"},
+			"blue1": {Ssid: "", WpaKey: "", BandwidthLimitMbps: -1},
+		},
+		SyslogIpAddress: "10.0.100.256",
+	}
+	var errs ValidationErrors
+	if assert.True(t, errors.As(request.Validate(&AccessPointRadio{Type: TypeLinksys}), &errs)) {
+		var fields []string
+		for _, err := range errs {
+			fields = append(fields, err.Field)
+		}
+		assert.Equal(
+			t,
+			[]string{
+				"channel",
+				"channelBandwidth",
+				"blueVlans",
+				"stationConfigurations.blue1.ssid",
+				"stationConfigurations.blue1.wpaKey",
+				"stationConfigurations.blue1.bandwidthLimitMbps",
+				"stationConfigurations.blue4",
+				"stationConfigurations.red2.wpaKey",
+				"stationConfigurations.red3.wpaKey",
+				"syslogIpAddress",
+			},
+			fields,
+		)
+		assert.Equal(t, "invalid WPA key length for station red2: 4 (expecting 8-16)", errs[7].Message)
+	}
+}
+
+func TestAccessPointConfigurationRequest_stationBandwidthLimits(t *testing.T) {
+	request := AccessPointConfigurationRequest{
+		StationConfigurations: map[string]StationConfiguration{
+			"red1":  {Ssid: "254", WpaKey: "12345678"},
+			"blue2": {Ssid: "1678", WpaKey: "12345678", BandwidthLimitMbps: 7.5},
+		},
+	}
+	assert.Equal(t, map[station]float64{blue2: 7.5}, request.stationBandwidthLimits())
+
+	// The limit for the request as a whole applies to the configured stations that don't have their own.
+	request.BandwidthLimitMbps = 4
+	assert.Equal(t, map[station]float64{red1: 4, blue2: 7.5}, request.stationBandwidthLimits())
+
+	request.StationConfigurations = nil
+	assert.Equal(t, map[station]float64{}, request.stationBandwidthLimits())
+}
+
+func TestAccessPointConfigurationRequest_ValidateStationVlans(t *testing.T) {
+	fakeTree := newFakeUciTree()
+	uciTree = fakeTree
+	fakeTree.sectionsForGet["network.interface"] = []string{"lan", "vlan10", "vlan20", "vlan30", "vlan110", "vlan120"}
+	radio := &AccessPointRadio{Type: TypeVividHosting, RedVlans: Vlans102030, BlueVlans: Vlans405060}
+
+	request := AccessPointConfigurationRequest{StationVlans: map[string]int{"red1": 110, "blue3": 120}}
+	assert.Nil(t, request.Validate(radio))
+
+	// An empty map is a valid request on its own, since it removes any existing overrides.
+	request = AccessPointConfigurationRequest{StationVlans: map[string]int{}}
+	assert.Nil(t, request.Validate(radio))
+
+	// Invalid station.
+	request = AccessPointConfigurationRequest{StationVlans: map[string]int{"red4": 110}}
+	err := request.Validate(radio)
+	assert.EqualError(t, err, "invalid station for VLAN: red4")
+
+	// VLAN that doesn't have a network on the radio.
+	request = AccessPointConfigurationRequest{StationVlans: map[string]int{"red1": 130}}
+	err = request.Validate(radio)
+	assert.EqualError(t, err, "invalid VLAN for station red1: 130 (no such network on the radio)")
+
+	// Stations sharing a VLAN, either with each other or with one from the alliance VLANs.
+	request = AccessPointConfigurationRequest{StationVlans: map[string]int{"red1": 110, "blue1": 110}}
+	err = request.Validate(radio)
+	assert.EqualError(t, err, "stations red1 and blue1 cannot use the same VLAN: 110")
+	request = AccessPointConfigurationRequest{StationVlans: map[string]int{"blue2": 20}}
+	err = request.Validate(radio)
+	assert.EqualError(t, err, "stations red2 and blue2 cannot use the same VLAN: 20")
+
+	// Several conflicts are each reported against the later station of the pair.
+	request = AccessPointConfigurationRequest{StationVlans: map[string]int{"blue1": 10, "blue2": 20}}
+	err = request.Validate(radio)
+	var errs ValidationErrors
+	if assert.True(t, errors.As(err, &errs)) && assert.Equal(t, 2, len(errs)) {
+		assert.Equal(t, "stationVlans.blue1", errs[0].Field)
+		assert.Equal(t, "stations red1 and blue1 cannot use the same VLAN: 10", errs[0].Message)
+		assert.Equal(t, "stationVlans.blue2", errs[1].Field)
+		assert.Equal(t, "stations red2 and blue2 cannot use the same VLAN: 20", errs[1].Message)
+	}
+
+	// Alliance VLANs that clash with the radio's existing overrides.
+	radio.StationVlans = map[string]int{"red1": 70}
+	request = AccessPointConfigurationRequest{RedVlans: Vlans405060, BlueVlans: Vlans708090}
+	err = request.Validate(radio)
+	assert.EqualError(t, err, "stations red1 and blue1 cannot use the same VLAN: 70")
+	request = AccessPointConfigurationRequest{
+		RedVlans: Vlans405060, BlueVlans: Vlans708090, StationVlans: map[string]int{},
+	}
+	assert.Nil(t, request.Validate(radio))
+}
+
+func TestAccessPointChannel_Json(t *testing.T) {
+	var request AccessPointConfigurationRequest
+	if assert.Nil(t, json.Unmarshal([]byte(`{"channel": 93}`), &request)) {
+		assert.Equal(t, AccessPointChannel(93), request.Channel)
+	}
+	if assert.Nil(t, json.Unmarshal([]byte(`{"channel": "auto"}`), &request)) {
+		assert.Equal(t, AutoChannel, request.Channel)
+	}
+	err := json.Unmarshal([]byte(`{"channel": "best"}`), &request)
+	assert.EqualError(t, err, "invalid channel \"best\" (expecting a number or \"auto\")")
+
+	data, err := json.Marshal(AccessPointConfigurationRequest{Channel: 93})
+	assert.Nil(t, err)
+	assert.Contains(t, string(data), `"channel":93,`)
+	data, err = json.Marshal(AccessPointConfigurationRequest{Channel: AutoChannel})
+	assert.Nil(t, err)
+	assert.Contains(t, string(data), `"channel":"auto",`)
+}
+
+func TestChannelBandwidthForHtmode(t *testing.T) {
+	assert.Equal(t, "20MHz", channelBandwidthForHtmode("HE20"))
+	assert.Equal(t, "40MHz", channelBandwidthForHtmode("HE40"))
+	assert.Equal(t, "80MHz", channelBandwidthForHtmode("HE80"))
+	assert.Equal(t, "160MHz", channelBandwidthForHtmode("HE160"))
+	assert.Equal(t, "20MHz", channelBandwidthForHtmode("HT20"))
+	assert.Equal(t, "40MHz", channelBandwidthForHtmode("HT40"))
+	assert.Equal(t, "INVALID", channelBandwidthForHtmode("VHT80"))
+	assert.Equal(t, "INVALID", channelBandwidthForHtmode(""))
+}
+
+func TestIsValid6GhzChannelForBandwidth(t *testing.T) {
+	for _, channel := range validChannels(TypeVividHosting) {
+		assert.True(t, isValid6GhzChannelForBandwidth(channel, "20MHz"), channel)
+		assert.True(t, isValid6GhzChannelForBandwidth(channel, "40MHz"), channel)
+		assert.Equal(t, channel <= 221, isValid6GhzChannelForBandwidth(channel, "80MHz"), channel)
+		assert.Equal(t, channel <= 221, isValid6GhzChannelForBandwidth(channel, "160MHz"), channel)
+	}
+	assert.False(t, isValid6GhzChannelForBandwidth(36, "20MHz"))
+	assert.False(t, isValid6GhzChannelForBandwidth(5, "10MHz"))
+	assert.False(t, isValid6GhzChannelForBandwidth(5, "INVALID"))
 }
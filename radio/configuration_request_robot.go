@@ -1,13 +1,8 @@
-// This file is specific to the robot radio version of the API.
-//go:build robot
+// This file is specific to the robot radio role of the API.
 
 package radio
 
-import (
-	"errors"
-	"fmt"
-	"regexp"
-)
+import "regexp"
 
 const (
 	// Maximum length for the SSID suffix.
@@ -17,8 +12,8 @@ const (
 	ssidSuffixRegex = "^[a-zA-Z0-9]*$"
 )
 
-// ConfigurationRequest represents a JSON request to configure the radio.
-type ConfigurationRequest struct {
+// RobotRadioConfigurationRequest represents a JSON request to configure the robot radio.
+type RobotRadioConfigurationRequest struct {
 	// Operation mode to configure the radio for.
 	Mode radioMode `json:"mode"`
 
@@ -26,6 +21,10 @@ type ConfigurationRequest struct {
 	// mode, the radio will automatically select a channel.
 	Channel int `json:"channel"`
 
+	// Transmit power in dBm for the radio to use on the 6GHz network and, in TEAM_ROBOT_RADIO mode, the 2.4GHz network.
+	// Set to 0 to leave unchanged, or to TxPowerDefault to restore the driver's default.
+	TxPower int `json:"txPower"`
+
 	// Team number to configure the radio for. Must be between 1 and 25499.
 	TeamNumber int `json:"teamNumber"`
 
@@ -38,51 +37,94 @@ type ConfigurationRequest struct {
 	// WPA key for the 2.4GHz network broadcast by the radio for team use. Must be at least eight alphanumeric
 	// characters long.
 	WpaKey24 string `json:"wpaKey24"`
+
+	// ID of the job tracking this request, assigned when it is queued.
+	jobId string
 }
 
-// Validate checks that all parameters within the configuration request have valid values.
-func (request ConfigurationRequest) Validate(radio *Radio) error {
+// Validate checks that all parameters within the configuration request have valid values, returning every problem
+// found rather than just the first.
+func (request RobotRadioConfigurationRequest) Validate(radio Radio) error {
+	var errs ValidationErrors
 	if request.Mode != modeTeamRobotRadio && request.Mode != modeTeamAccessPoint {
-		return fmt.Errorf("invalid operation mode: %s", request.Mode)
+		errs.add(ValidationInvalidMode, "mode", "invalid operation mode: %s", request.Mode)
 	}
 
 	if request.Mode == modeTeamRobotRadio && request.Channel != 0 {
-		return fmt.Errorf("channel cannot be set in %s mode", modeTeamRobotRadio)
+		errs.add(ValidationInvalidChannel, "channel", "channel cannot be set in %s mode", modeTeamRobotRadio)
+	} else if request.Mode == modeTeamAccessPoint && request.Channel != 0 && !isValid6GhzChannel(request.Channel) {
+		errs.add(ValidationInvalidChannel, "channel", "invalid 6GHz channel: %d", request.Channel)
 	}
-	if request.Mode == modeTeamAccessPoint && request.Channel != 0 && !isValid6GhzChannel(request.Channel) {
-		return fmt.Errorf("invalid 6GHz channel: %d", request.Channel)
+
+	if request.TxPower != 0 && request.TxPower != TxPowerDefault {
+		maxTxPower := maxTxPowerDbm6Ghz
+		if request.Mode == modeTeamRobotRadio {
+			// The 2.4GHz network is only enabled in this mode.
+			maxTxPower = maxTxPowerDbmTeamRobotRadio
+		}
+		if request.TxPower < minTxPowerDbm || request.TxPower > maxTxPower {
+			errs.add(
+				ValidationInvalidTxPower,
+				"txPower",
+				"invalid transmit power: %d dBm (expecting %d-%d)",
+				request.TxPower,
+				minTxPowerDbm,
+				maxTxPower,
+			)
+		}
 	}
 
 	if request.TeamNumber < 1 || request.TeamNumber > 25499 {
-		return fmt.Errorf("invalid team number: %d", request.TeamNumber)
+		errs.add(ValidationInvalidTeamNumber, "teamNumber", "invalid team number: %d", request.TeamNumber)
 	}
 
 	if len(request.SsidSuffix) > maxSsidSuffixLength {
-		return fmt.Errorf(
-			"invalid ssidSuffix length: %d (expecting 0-%d)", len(request.SsidSuffix), maxSsidSuffixLength,
+		errs.add(
+			ValidationInvalidSsid,
+			"ssidSuffix",
+			"invalid ssidSuffix length: %d (expecting 0-%d)",
+			len(request.SsidSuffix),
+			maxSsidSuffixLength,
 		)
-	}
-	if !regexp.MustCompile(ssidSuffixRegex).MatchString(request.SsidSuffix) {
-		return errors.New("invalid ssidSuffix (expecting alphanumeric)")
+	} else if !regexp.MustCompile(ssidSuffixRegex).MatchString(request.SsidSuffix) {
+		errs.add(ValidationInvalidSsid, "ssidSuffix", "invalid ssidSuffix (expecting alphanumeric)")
 	}
 
 	if len(request.WpaKey6) < minWpaKeyLength || len(request.WpaKey6) > maxWpaKeyLength {
-		return fmt.Errorf(
-			"invalid wpaKey6 length: %d (expecting %d-%d)", len(request.WpaKey6), minWpaKeyLength, maxWpaKeyLength,
+		errs.add(
+			ValidationInvalidWpaKey,
+			"wpaKey6",
+			"invalid wpaKey6 length: %d (expecting %d-%d)",
+			len(request.WpaKey6),
+			minWpaKeyLength,
+			maxWpaKeyLength,
 		)
-	}
-	if !regexp.MustCompile(alphanumericRegex).MatchString(request.WpaKey6) {
-		return errors.New("invalid wpaKey6 (expecting alphanumeric)")
+	} else if !regexp.MustCompile(alphanumericRegex).MatchString(request.WpaKey6) {
+		errs.add(ValidationInvalidWpaKey, "wpaKey6", "invalid wpaKey6 (expecting alphanumeric)")
 	}
 
 	if len(request.WpaKey24) < minWpaKeyLength || len(request.WpaKey24) > maxWpaKeyLength {
-		return fmt.Errorf(
-			"invalid wpaKey24 length: %d (expecting %d-%d)", len(request.WpaKey24), minWpaKeyLength, maxWpaKeyLength,
+		errs.add(
+			ValidationInvalidWpaKey,
+			"wpaKey24",
+			"invalid wpaKey24 length: %d (expecting %d-%d)",
+			len(request.WpaKey24),
+			minWpaKeyLength,
+			maxWpaKeyLength,
 		)
+	} else if !regexp.MustCompile(alphanumericRegex).MatchString(request.WpaKey24) {
+		errs.add(ValidationInvalidWpaKey, "wpaKey24", "invalid wpaKey24 (expecting alphanumeric)")
 	}
-	if !regexp.MustCompile(alphanumericRegex).MatchString(request.WpaKey24) {
-		return errors.New("invalid wpaKey24 (expecting alphanumeric)")
-	}
 
-	return nil
+	return errs.orNil()
+}
+
+// getJobId returns the ID of the job tracking the request, assigned when it is queued.
+func (request RobotRadioConfigurationRequest) getJobId() string {
+	return request.jobId
+}
+
+// setJobId records the ID of the job tracking the request.
+func (request *RobotRadioConfigurationRequest) setJobId(id string) {
+	request.jobId = id
 }
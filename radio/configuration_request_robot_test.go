@@ -1,18 +1,18 @@
-// This file is specific to the robot radio version of the API.
-//go:build robot
+// This file is specific to the robot radio role of the API.
 
 package radio
 
 import (
+	"errors"
 	"github.com/stretchr/testify/assert"
 	"testing"
 )
 
-func TestConfigurationRequest_Validate(t *testing.T) {
-	radio := &Radio{}
+func TestRobotRadioConfigurationRequest_Validate(t *testing.T) {
+	radio := &RobotRadio{}
 
 	// Invalid operation mode.
-	request := ConfigurationRequest{TeamNumber: 254, WpaKey6: "12345678", WpaKey24: "87654321"}
+	request := RobotRadioConfigurationRequest{TeamNumber: 254, WpaKey6: "12345678", WpaKey24: "87654321"}
 	request.Mode = "NONEXISTENT_MODE"
 	err := request.Validate(radio)
 	assert.EqualError(t, err, "invalid operation mode: NONEXISTENT_MODE")
@@ -34,6 +34,25 @@ func TestConfigurationRequest_Validate(t *testing.T) {
 	request.Mode = modeTeamRobotRadio
 	request.Channel = 0
 
+	// Invalid transmit power, which is more limited when the 2.4GHz network is enabled.
+	request.TxPower = 23
+	assert.Nil(t, request.Validate(radio))
+	request.TxPower = 24
+	err = request.Validate(radio)
+	assert.EqualError(t, err, "invalid transmit power: 24 dBm (expecting 1-23)")
+	request.Mode = modeTeamAccessPoint
+	assert.Nil(t, request.Validate(radio))
+	request.TxPower = 31
+	err = request.Validate(radio)
+	assert.EqualError(t, err, "invalid transmit power: 31 dBm (expecting 1-30)")
+	request.TxPower = -5
+	err = request.Validate(radio)
+	assert.EqualError(t, err, "invalid transmit power: -5 dBm (expecting 1-30)")
+	request.TxPower = TxPowerDefault
+	assert.Nil(t, request.Validate(radio))
+	request.Mode = modeTeamRobotRadio
+	request.TxPower = 0
+
 	// Invalid team number.
 	request.TeamNumber = 0
 	err = request.Validate(radio)
@@ -90,3 +109,18 @@ func TestConfigurationRequest_Validate(t *testing.T) {
 	err = request.Validate(radio)
 	assert.EqualError(t, err, "invalid wpaKey24 (expecting alphanumeric)")
 }
+
+func TestRobotRadioConfigurationRequest_ValidateAllErrors(t *testing.T) {
+	request := RobotRadioConfigurationRequest{
+		Mode: modeTeamRobotRadio, Channel: 5, TeamNumber: 0, SsidSuffix: "a_b", WpaKey6: "1234", WpaKey24: "87654321",
+	}
+	var errs ValidationErrors
+	if assert.True(t, errors.As(request.Validate(&RobotRadio{}), &errs)) {
+		assert.EqualError(
+			t,
+			errs,
+			"channel cannot be set in TEAM_ROBOT_RADIO mode; invalid team number: 0; invalid ssidSuffix (expecting "+
+				"alphanumeric); invalid wpaKey6 length: 4 (expecting 8-16)",
+		)
+	}
+}
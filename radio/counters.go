@@ -0,0 +1,57 @@
+package radio
+
+import "sync"
+
+// Counters holds cumulative totals of configuration and monitoring events since the API was started.
+type Counters struct {
+	// Number of configuration requests that the radio has attempted to apply.
+	ConfigurationAttempts int
+
+	// Number of times that the Wi-Fi configuration had to be reapplied because the radio didn't yet reflect it.
+	ConfigurationRetries int
+
+	// Number of monitoring commands that have failed, keyed by command name (e.g. "luci-bwc").
+	MonitoringCommandFailures map[string]int
+}
+
+// counterSet accumulates Counters in a manner that is safe to access from multiple goroutines.
+type counterSet struct {
+	mutex    sync.Mutex
+	counters Counters
+}
+
+// incrementConfigurationAttempts records that a configuration request is being applied.
+func (set *counterSet) incrementConfigurationAttempts() {
+	set.mutex.Lock()
+	defer set.mutex.Unlock()
+	set.counters.ConfigurationAttempts++
+}
+
+// incrementConfigurationRetries records that the Wi-Fi configuration is being reapplied.
+func (set *counterSet) incrementConfigurationRetries() {
+	set.mutex.Lock()
+	defer set.mutex.Unlock()
+	set.counters.ConfigurationRetries++
+}
+
+// incrementMonitoringCommandFailures records that the given monitoring command has failed.
+func (set *counterSet) incrementMonitoringCommandFailures(command string) {
+	set.mutex.Lock()
+	defer set.mutex.Unlock()
+	if set.counters.MonitoringCommandFailures == nil {
+		set.counters.MonitoringCommandFailures = make(map[string]int)
+	}
+	set.counters.MonitoringCommandFailures[command]++
+}
+
+// snapshot returns a copy of the current counter values.
+func (set *counterSet) snapshot() Counters {
+	set.mutex.Lock()
+	defer set.mutex.Unlock()
+	counters := set.counters
+	counters.MonitoringCommandFailures = make(map[string]int)
+	for command, count := range set.counters.MonitoringCommandFailures {
+		counters.MonitoringCommandFailures[command] = count
+	}
+	return counters
+}
@@ -0,0 +1,32 @@
+package radio
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestCounterSet(t *testing.T) {
+	var set counterSet
+	assert.Equal(t, Counters{MonitoringCommandFailures: map[string]int{}}, set.snapshot())
+
+	set.incrementConfigurationAttempts()
+	set.incrementConfigurationAttempts()
+	set.incrementConfigurationRetries()
+	set.incrementMonitoringCommandFailures("luci-bwc")
+	set.incrementMonitoringCommandFailures("iwinfo")
+	set.incrementMonitoringCommandFailures("luci-bwc")
+	counters := set.snapshot()
+	assert.Equal(
+		t,
+		Counters{
+			ConfigurationAttempts:     2,
+			ConfigurationRetries:      1,
+			MonitoringCommandFailures: map[string]int{"luci-bwc": 2, "iwinfo": 1},
+		},
+		counters,
+	)
+
+	// Modifying the snapshot shouldn't affect the underlying counters.
+	counters.MonitoringCommandFailures["ifconfig"] = 5
+	assert.NotContains(t, set.snapshot().MonitoringCommandFailures, "ifconfig")
+}
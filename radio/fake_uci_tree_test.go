@@ -7,22 +7,30 @@ import (
 
 // fakeUciTree stubs the uci.Tree interface for testing purposes.
 type fakeUciTree struct {
-	valuesForGet  map[string]string
-	valuesFromSet map[string]string
-	setCount      int
-	commitCount   int
+	valuesForGet   map[string]string
+	valuesFromSet  map[string]string
+	sectionsForGet map[string][]string
+	setCount       int
+	commitCount    int
+	commitError    error
 }
 
 func newFakeUciTree() *fakeUciTree {
-	return &fakeUciTree{valuesForGet: make(map[string]string), valuesFromSet: make(map[string]string)}
+	return &fakeUciTree{
+		valuesForGet:   make(map[string]string),
+		valuesFromSet:  make(map[string]string),
+		sectionsForGet: make(map[string][]string),
+	}
 }
 
 // reset clears the state of the fake UCI tree.
 func (tree *fakeUciTree) reset() {
 	tree.valuesForGet = make(map[string]string)
 	tree.valuesFromSet = make(map[string]string)
+	tree.sectionsForGet = make(map[string][]string)
 	tree.setCount = 0
 	tree.commitCount = 0
+	tree.commitError = nil
 }
 
 func (tree *fakeUciTree) SetType(config, section, option string, typ uci.OptionType, values ...string) bool {
@@ -53,7 +61,7 @@ func (tree *fakeUciTree) DelSection(config, section string) {
 
 func (tree *fakeUciTree) Commit() error {
 	tree.commitCount++
-	return nil
+	return tree.commitError
 }
 
 func (tree *fakeUciTree) LoadConfig(name string, forceReload bool) error {
@@ -65,11 +73,16 @@ func (tree *fakeUciTree) Revert(configs ...string) {
 }
 
 func (tree *fakeUciTree) GetSections(config, secType string) ([]string, bool) {
-	panic("not implemented")
+	sections, ok := tree.sectionsForGet[fmt.Sprintf("%s.%s", config, secType)]
+	return sections, ok
 }
 
 func (tree *fakeUciTree) Get(config, section, option string) ([]string, bool) {
-	panic("not implemented")
+	value, ok := tree.valuesForGet[fmt.Sprintf("%s.%s.%s", config, section, option)]
+	if !ok {
+		return nil, false
+	}
+	return []string{value}, true
 }
 
 func (tree *fakeUciTree) GetBool(config, section, option string) (bool, bool) {
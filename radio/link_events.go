@@ -0,0 +1,149 @@
+package radio
+
+import (
+	"log"
+	"sync"
+	"time"
+)
+
+// Maximum number of link events to keep track of; the oldest are forgotten first.
+const maxLinkEvents = 1000
+
+// linkEventType represents the kind of transition in a network's link state that a link event describes.
+type linkEventType string
+
+const (
+	linkEventAssociated     linkEventType = "ASSOCIATED"
+	linkEventDisassociated  linkEventType = "DISASSOCIATED"
+	linkEventDeviceChanged  linkEventType = "DEVICE_CHANGED"
+	linkEventQualityChanged linkEventType = "QUALITY_CHANGED"
+)
+
+// LinkState captures the parts of a network's status whose transitions are recorded as link events.
+type LinkState struct {
+	// Whether the network was associated with a remote device.
+	IsLinked bool `json:"isLinked"`
+
+	// MAC address of the remote device associated with the network. Blank if not associated.
+	MacAddress string `json:"macAddress"`
+
+	// Human-readable string describing connection quality to the remote device. Blank if not associated.
+	ConnectionQuality string `json:"connectionQuality"`
+}
+
+// LinkEvent records a transition in the link state of a network between two consecutive monitoring polls.
+type LinkEvent struct {
+	// Sequence number of the event, which increases by one with each event recorded.
+	Id int `json:"id"`
+
+	// Time at which the transition was detected.
+	Time time.Time `json:"time"`
+
+	// Name of the network whose link state changed (i.e. a team station on the access point, or the band of the
+	// network on the robot radio).
+	Station string `json:"station"`
+
+	// SSID of the network as of the time of the event.
+	Ssid string `json:"ssid"`
+
+	// Enum representing the kind of transition.
+	Type linkEventType `json:"type"`
+
+	// Link state of the network before the transition.
+	Before LinkState `json:"before"`
+
+	// Link state of the network after the transition.
+	After LinkState `json:"after"`
+}
+
+// linkEventLog keeps track of the most recent link events in a manner that is safe to access from multiple goroutines.
+type linkEventLog struct {
+	mutex sync.Mutex
+
+	// Recorded events, oldest first.
+	events []LinkEvent
+
+	// ID of the most recently recorded event.
+	lastId int
+
+	// Notifier for listeners interested in new events.
+	notifier statusNotifier
+}
+
+// record compares the given statuses of a network from before and after a monitoring poll and, if its link state
+// changed, records the transition as an event and logs it.
+func (eventLog *linkEventLog) record(station string, eventTime time.Time, before, after *NetworkStatus) {
+	eventType, changed := detectLinkEvent(before, after)
+	if !changed {
+		return
+	}
+
+	eventLog.mutex.Lock()
+	eventLog.lastId++
+	event := LinkEvent{
+		Id:      eventLog.lastId,
+		Time:    eventTime,
+		Station: station,
+		Ssid:    after.Ssid,
+		Type:    eventType,
+		Before:  linkStateOf(before),
+		After:   linkStateOf(after),
+	}
+	if len(eventLog.events) >= maxLinkEvents {
+		eventLog.events = eventLog.events[1:]
+	}
+	eventLog.events = append(eventLog.events, event)
+	eventLog.mutex.Unlock()
+
+	log.Printf(
+		"Link event on %s (SSID %q): %s; linked %t -> %t, MAC address %q -> %q, connection quality %q -> %q",
+		event.Station,
+		event.Ssid,
+		event.Type,
+		event.Before.IsLinked,
+		event.After.IsLinked,
+		event.Before.MacAddress,
+		event.After.MacAddress,
+		event.Before.ConnectionQuality,
+		event.After.ConnectionQuality,
+	)
+	eventLog.notifier.notify()
+}
+
+// since returns copies of the events having an ID greater than the given one, oldest first.
+func (eventLog *linkEventLog) since(afterId int) []LinkEvent {
+	eventLog.mutex.Lock()
+	defer eventLog.mutex.Unlock()
+
+	events := make([]LinkEvent, 0)
+	for _, event := range eventLog.events {
+		if event.Id > afterId {
+			events = append(events, event)
+		}
+	}
+	return events
+}
+
+// detectLinkEvent returns the kind of transition between the given link states of a network, and whether there was one
+// at all.
+func detectLinkEvent(before, after *NetworkStatus) (linkEventType, bool) {
+	switch {
+	case !before.IsLinked && after.IsLinked:
+		return linkEventAssociated, true
+	case before.IsLinked && !after.IsLinked:
+		return linkEventDisassociated, true
+	case before.IsLinked && before.MacAddress != after.MacAddress:
+		return linkEventDeviceChanged, true
+	case before.IsLinked && before.ConnectionQuality != after.ConnectionQuality:
+		return linkEventQualityChanged, true
+	default:
+		return "", false
+	}
+}
+
+// linkStateOf returns the link state of the given network status.
+func linkStateOf(status *NetworkStatus) LinkState {
+	return LinkState{
+		IsLinked: status.IsLinked, MacAddress: status.MacAddress, ConnectionQuality: status.ConnectionQuality,
+	}
+}
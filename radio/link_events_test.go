@@ -0,0 +1,97 @@
+package radio
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestDetectLinkEvent(t *testing.T) {
+	unlinked := NetworkStatus{Ssid: "254"}
+	linked := NetworkStatus{Ssid: "254", IsLinked: true, MacAddress: "48:DA:35:B0:00:CF", ConnectionQuality: "good"}
+
+	_, changed := detectLinkEvent(&unlinked, &unlinked)
+	assert.False(t, changed)
+	other := linked
+	other.SignalDbm = -70
+	_, changed = detectLinkEvent(&linked, &other)
+	assert.False(t, changed)
+
+	eventType, changed := detectLinkEvent(&unlinked, &linked)
+	assert.True(t, changed)
+	assert.Equal(t, linkEventAssociated, eventType)
+
+	eventType, changed = detectLinkEvent(&linked, &unlinked)
+	assert.True(t, changed)
+	assert.Equal(t, linkEventDisassociated, eventType)
+
+	other = linked
+	other.MacAddress = "5C:DA:35:B0:00:12"
+	other.ConnectionQuality = "warning"
+	eventType, changed = detectLinkEvent(&linked, &other)
+	assert.True(t, changed)
+	assert.Equal(t, linkEventDeviceChanged, eventType)
+
+	other = linked
+	other.ConnectionQuality = "caution"
+	eventType, changed = detectLinkEvent(&linked, &other)
+	assert.True(t, changed)
+	assert.Equal(t, linkEventQualityChanged, eventType)
+}
+
+func TestLinkEventLog(t *testing.T) {
+	var eventLog linkEventLog
+	listener := eventLog.notifier.subscribe()
+	eventTime := time.Date(2024, 4, 20, 12, 0, 0, 0, time.UTC)
+	unlinked := NetworkStatus{Ssid: "254"}
+	linked := NetworkStatus{Ssid: "254", IsLinked: true, MacAddress: "48:DA:35:B0:00:CF", ConnectionQuality: "good"}
+
+	assert.Equal(t, []LinkEvent{}, eventLog.since(0))
+
+	// No event is recorded if the link state is unchanged.
+	eventLog.record("blue2", eventTime, &unlinked, &unlinked)
+	assert.Equal(t, []LinkEvent{}, eventLog.since(0))
+	assert.Equal(t, 0, len(listener))
+
+	eventLog.record("blue2", eventTime, &unlinked, &linked)
+	eventLog.record("blue2", eventTime.Add(5*time.Second), &linked, &unlinked)
+	assert.Equal(
+		t,
+		[]LinkEvent{
+			{
+				Id:      1,
+				Time:    eventTime,
+				Station: "blue2",
+				Ssid:    "254",
+				Type:    linkEventAssociated,
+				Before:  LinkState{},
+				After:   LinkState{IsLinked: true, MacAddress: "48:DA:35:B0:00:CF", ConnectionQuality: "good"},
+			},
+			{
+				Id:      2,
+				Time:    eventTime.Add(5 * time.Second),
+				Station: "blue2",
+				Ssid:    "254",
+				Type:    linkEventDisassociated,
+				Before:  LinkState{IsLinked: true, MacAddress: "48:DA:35:B0:00:CF", ConnectionQuality: "good"},
+				After:   LinkState{},
+			},
+		},
+		eventLog.since(0),
+	)
+	assert.Equal(t, 1, len(listener))
+	events := eventLog.since(1)
+	if assert.Equal(t, 1, len(events)) {
+		assert.Equal(t, 2, events[0].Id)
+	}
+	assert.Equal(t, []LinkEvent{}, eventLog.since(2))
+
+	// The oldest events are forgotten once the limit is reached.
+	for i := 0; i < maxLinkEvents; i++ {
+		eventLog.record("red1", eventTime, &unlinked, &linked)
+	}
+	events = eventLog.since(0)
+	assert.Equal(t, maxLinkEvents, len(events))
+	assert.Equal(t, 3, events[0].Id)
+	assert.Equal(t, maxLinkEvents+2, events[len(events)-1].Id)
+}
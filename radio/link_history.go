@@ -0,0 +1,111 @@
+package radio
+
+import (
+	"sync"
+	"time"
+)
+
+const (
+	// Default amount of time for which to keep samples of each network's link metrics, which covers a full match with
+	// time to spare.
+	DefaultLinkHistoryRetention = 5 * time.Minute
+
+	// Maximum number of samples to keep per network regardless of the retention period, to bound memory usage. At the
+	// monitoring poll interval this amounts to one hour.
+	maxLinkHistorySamples = 720
+)
+
+// LinkSample is a copy of the status of a network as of the time at which its link metrics were polled.
+type LinkSample struct {
+	// Time at which the sample was taken.
+	Time time.Time `json:"time"`
+
+	NetworkStatus
+}
+
+// linkHistory keeps a rolling window of link metric samples for each network of the radio in a manner that is safe to
+// access from multiple goroutines.
+type linkHistory struct {
+	mutex sync.Mutex
+
+	// Amount of time for which to keep samples. Zero means that no samples are kept.
+	retention time.Duration
+
+	// Map of network names to their samples.
+	rings map[string]*linkSampleRing
+}
+
+// linkSampleRing is a fixed-capacity ring buffer of samples, which overwrites the oldest sample once it is full.
+type linkSampleRing struct {
+	samples []LinkSample
+
+	// Index at which the next sample will be written.
+	next int
+
+	// Number of samples currently held.
+	count int
+}
+
+// setRetention changes the amount of time for which samples are kept, discarding any samples already recorded.
+func (history *linkHistory) setRetention(retention time.Duration) {
+	history.mutex.Lock()
+	defer history.mutex.Unlock()
+
+	history.retention = retention
+	history.rings = nil
+}
+
+// capacity returns the number of samples needed per network to cover the retention period at the monitoring poll
+// interval, capped at the maximum.
+func (history *linkHistory) capacity() int {
+	capacity := int(history.retention/(monitoringPollIntervalSec*time.Second)) + 1
+	if capacity > maxLinkHistorySamples {
+		return maxLinkHistorySamples
+	}
+	return capacity
+}
+
+// record adds a sample of the given network's status taken at the given time.
+func (history *linkHistory) record(network string, sampleTime time.Time, status NetworkStatus) {
+	history.mutex.Lock()
+	defer history.mutex.Unlock()
+
+	if history.retention <= 0 {
+		return
+	}
+	if history.rings == nil {
+		history.rings = make(map[string]*linkSampleRing)
+	}
+	ring, ok := history.rings[network]
+	if !ok {
+		ring = &linkSampleRing{samples: make([]LinkSample, history.capacity())}
+		history.rings[network] = ring
+	}
+
+	ring.samples[ring.next] = LinkSample{Time: sampleTime, NetworkStatus: status}
+	ring.next = (ring.next + 1) % len(ring.samples)
+	if ring.count < len(ring.samples) {
+		ring.count++
+	}
+}
+
+// get returns the samples of the given network taken after the given time and still within the retention period as of
+// the given current time, oldest first.
+func (history *linkHistory) get(network string, since time.Time, now time.Time) []LinkSample {
+	history.mutex.Lock()
+	defer history.mutex.Unlock()
+
+	samples := make([]LinkSample, 0)
+	ring, ok := history.rings[network]
+	if !ok {
+		return samples
+	}
+	cutoff := now.Add(-history.retention)
+	for i := 0; i < ring.count; i++ {
+		sample := ring.samples[(ring.next-ring.count+i+len(ring.samples))%len(ring.samples)]
+		if sample.Time.After(since) && !sample.Time.Before(cutoff) {
+			samples = append(samples, sample)
+		}
+	}
+	return samples
+}
@@ -0,0 +1,68 @@
+package radio
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestLinkHistory(t *testing.T) {
+	history := linkHistory{retention: time.Minute}
+	start := time.Date(2024, 4, 20, 12, 0, 0, 0, time.UTC)
+
+	assert.Equal(t, []LinkSample{}, history.get("red1", time.Time{}, start))
+
+	history.record("red1", start, NetworkStatus{Ssid: "254", RxPackets: 1})
+	history.record("red1", start.Add(5*time.Second), NetworkStatus{Ssid: "254", RxPackets: 2})
+	history.record("blue1", start.Add(5*time.Second), NetworkStatus{Ssid: "1678"})
+	assert.Equal(
+		t,
+		[]LinkSample{
+			{Time: start, NetworkStatus: NetworkStatus{Ssid: "254", RxPackets: 1}},
+			{Time: start.Add(5 * time.Second), NetworkStatus: NetworkStatus{Ssid: "254", RxPackets: 2}},
+		},
+		history.get("red1", time.Time{}, start.Add(5*time.Second)),
+	)
+	assert.Equal(t, 1, len(history.get("blue1", time.Time{}, start.Add(5*time.Second))))
+	assert.Equal(t, []LinkSample{}, history.get("red2", time.Time{}, start.Add(5*time.Second)))
+
+	// Only samples taken after the given time are returned.
+	samples := history.get("red1", start, start.Add(5*time.Second))
+	if assert.Equal(t, 1, len(samples)) {
+		assert.Equal(t, 2, samples[0].RxPackets)
+	}
+
+	// Samples older than the retention period are no longer returned.
+	samples = history.get("red1", time.Time{}, start.Add(time.Minute+time.Second))
+	if assert.Equal(t, 1, len(samples)) {
+		assert.Equal(t, 2, samples[0].RxPackets)
+	}
+}
+
+func TestLinkHistory_Wraparound(t *testing.T) {
+	history := linkHistory{retention: time.Minute}
+	assert.Equal(t, 13, history.capacity())
+	start := time.Date(2024, 4, 20, 12, 0, 0, 0, time.UTC)
+
+	// Recording more samples than fit overwrites the oldest ones, even if they are within the retention period.
+	for i := 0; i < 20; i++ {
+		history.record("6GHz", start.Add(time.Duration(i)*time.Second), NetworkStatus{RxPackets: i})
+	}
+	samples := history.get("6GHz", time.Time{}, start.Add(20*time.Second))
+	if assert.Equal(t, 13, len(samples)) {
+		for i, sample := range samples {
+			assert.Equal(t, i+7, sample.RxPackets)
+		}
+	}
+
+	// The capacity is bounded regardless of the retention period.
+	history.setRetention(24 * time.Hour)
+	assert.Equal(t, maxLinkHistorySamples, history.capacity())
+	assert.Equal(t, []LinkSample{}, history.get("6GHz", time.Time{}, start.Add(20*time.Second)))
+}
+
+func TestLinkHistory_Disabled(t *testing.T) {
+	var history linkHistory
+	history.record("red1", time.Now(), NetworkStatus{Ssid: "254"})
+	assert.Equal(t, []LinkSample{}, history.get("red1", time.Time{}, time.Now()))
+}
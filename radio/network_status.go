@@ -9,7 +9,7 @@ import (
 
 const (
 	// Sentinel value used to populate status fields when a monitoring command failed.
-	monitoringErrorCode = -999
+	MonitoringErrorCode = -999
 
 	// Cutoff values used to determine the connection quality of the interface based on RX rate.
 	connectionQualityExcellentMinimum = 412.9
@@ -70,21 +70,60 @@ type NetworkStatus struct {
 	// Current five-second average total (rx + tx) bandwidth in megabits per second.
 	BandwidthUsedMbps float64 `json:"bandwidthUsedMbps"`
 
+	// Maximum throughput in megabits per second allowed in each direction by the traffic-control rules applied to the
+	// network. Zero if unlimited.
+	BandwidthLimitMbps float64 `json:"bandwidthLimitMbps"`
+
 	// Human-readable string describing connection quality to the remote device. Based on RX rate. Blank if not associated.
 	ConnectionQuality string `json:"connectionQuality"`
 
+	// All remote devices currently associated with this network, in the order reported by the radio. The first one is
+	// the primary link described by the fields above. Nil if not associated.
+	AssociatedClients []AssociatedClient `json:"associatedClients"`
+
 	// Flag representing whether the interface is for a robot.
 	IsRobot bool `json:"-"`
 }
 
+// AssociatedClient encapsulates the link state of a single remote device associated with a network.
+type AssociatedClient struct {
+	// MAC address of the remote device.
+	MacAddress string `json:"macAddress"`
+
+	// Signal strength of the link to the remote device, in decibel-milliwatts.
+	SignalDbm int `json:"signalDbm"`
+
+	// Noise level of the link to the remote device, in decibel-milliwatts.
+	NoiseDbm int `json:"noiseDbm"`
+
+	// Current signal-to-noise ratio (SNR) in decibels.
+	SignalNoiseRatio int `json:"signalNoiseRatio"`
+
+	// Upper-bound link receive rate (from the remote device to this one) in megabits per second.
+	RxRateMbps float64 `json:"rxRateMbps"`
+
+	// Number of packets received from the remote device.
+	RxPackets int `json:"rxPackets"`
+
+	// Upper-bound link transmit rate (from this device to the remote one) in megabits per second.
+	TxRateMbps float64 `json:"txRateMbps"`
+
+	// Number of packets transmitted to the remote device.
+	TxPackets int `json:"txPackets"`
+
+	// Time elapsed since the radio last heard from the remote device, in milliseconds.
+	InactiveMs int `json:"inactiveMs"`
+}
+
 // updateMonitoring polls the access point for the current bandwidth usage and link state of the given network interface
-// and updates the in-memory state.
-func (status *NetworkStatus) updateMonitoring(networkInterface string) {
+// and updates the in-memory state. Any failed commands are tallied in the given counters.
+func (status *NetworkStatus) updateMonitoring(networkInterface string, counters *counterSet) {
 	// Update the bandwidth usage.
 	output, err := shell.runCommand("luci-bwc", "-i", networkInterface)
 	if err != nil {
 		log.Printf("Error running 'luci-bwc -i %s': %v", networkInterface, err)
-		status.BandwidthUsedMbps = monitoringErrorCode
+		counters.incrementMonitoringCommandFailures("luci-bwc")
+		status.BandwidthUsedMbps = MonitoringErrorCode
 	} else {
 		status.parseBandwidthUsed(output)
 	}
@@ -93,9 +132,10 @@ func (status *NetworkStatus) updateMonitoring(networkInterface string) {
 	output, err = shell.runCommand("iwinfo", networkInterface, "assoclist")
 	if err != nil {
 		log.Printf("Error running 'iwinfo %s assoclist': %v", networkInterface, err)
-		status.RxRateMbps = monitoringErrorCode
-		status.TxRateMbps = monitoringErrorCode
-		status.SignalNoiseRatio = monitoringErrorCode
+		counters.incrementMonitoringCommandFailures("iwinfo")
+		status.RxRateMbps = MonitoringErrorCode
+		status.TxRateMbps = MonitoringErrorCode
+		status.SignalNoiseRatio = MonitoringErrorCode
 	} else {
 		status.parseAssocList(output)
 	}
@@ -104,8 +144,9 @@ func (status *NetworkStatus) updateMonitoring(networkInterface string) {
 	output, err = shell.runCommand("ifconfig", networkInterface)
 	if err != nil {
 		log.Printf("Error running 'ifconfig %s': %v", networkInterface, err)
-		status.RxBytes = monitoringErrorCode
-		status.TxBytes = monitoringErrorCode
+		counters.incrementMonitoringCommandFailures("ifconfig")
+		status.RxBytes = MonitoringErrorCode
+		status.TxBytes = MonitoringErrorCode
 	} else {
 		status.parseIfconfig(output)
 	}
@@ -129,7 +170,8 @@ func (status *NetworkStatus) parseBandwidthUsed(response string) {
 }
 
 // parseAssocList parses the given data from the radio's association list and updates the status structure with the
-// result.
+// result. Every device heard from in the last four seconds is listed as an associated client, and the first of them is
+// treated as the primary link.
 func (status *NetworkStatus) parseAssocList(response string) {
 	line1Re := regexp.MustCompile(
 		"((?:[0-9A-F]{2}:){5}(?:[0-9A-F]{2}))\\s+(-\\d+) dBm / (-\\d+) dBm \\(SNR (\\d+)\\)\\s+(\\d+) ms ago",
@@ -147,33 +189,59 @@ func (status *NetworkStatus) parseAssocList(response string) {
 	status.TxRateMbps = 0
 	status.TxPackets = 0
 	status.ConnectionQuality = ""
-	for _, line1Match := range line1Re.FindAllStringSubmatch(response, -1) {
-		macAddress := line1Match[1]
-		dataAgeMs, _ := strconv.Atoi(line1Match[5])
-		if macAddress != "00:00:00:00:00:00" && dataAgeMs <= 4000 {
+	status.AssociatedClients = nil
+	line1Indices := line1Re.FindAllStringSubmatchIndex(response, -1)
+	for i, line1Index := range line1Indices {
+		// Each entry extends until the start of the next one, so that its RX and TX lines aren't confused with those of
+		// another device.
+		entryEnd := len(response)
+		if i+1 < len(line1Indices) {
+			entryEnd = line1Indices[i+1][0]
+		}
+		entry := response[line1Index[1]:entryEnd]
+
+		macAddress := response[line1Index[2]:line1Index[3]]
+		dataAgeMs, _ := strconv.Atoi(response[line1Index[10]:line1Index[11]])
+		if macAddress == "00:00:00:00:00:00" || dataAgeMs > 4000 {
+			continue
+		}
+		client := AssociatedClient{MacAddress: macAddress, InactiveMs: dataAgeMs}
+		client.SignalDbm, _ = strconv.Atoi(response[line1Index[4]:line1Index[5]])
+		client.NoiseDbm, _ = strconv.Atoi(response[line1Index[6]:line1Index[7]])
+		client.SignalNoiseRatio, _ = strconv.Atoi(response[line1Index[8]:line1Index[9]])
+		line2Match := line2Re.FindStringSubmatch(entry)
+		if len(line2Match) > 0 {
+			client.RxRateMbps, _ = strconv.ParseFloat(line2Match[1], 64)
+			client.RxPackets, _ = strconv.Atoi(line2Match[2])
+		}
+		line3Match := line3R3.FindStringSubmatch(entry)
+		if len(line3Match) > 0 {
+			client.TxRateMbps, _ = strconv.ParseFloat(line3Match[1], 64)
+			client.TxPackets, _ = strconv.Atoi(line3Match[2])
+		}
+
+		if !status.IsLinked {
 			status.IsLinked = true
-			status.MacAddress = macAddress
-			status.SignalDbm, _ = strconv.Atoi(line1Match[2])
-			status.NoiseDbm, _ = strconv.Atoi(line1Match[3])
-			status.SignalNoiseRatio, _ = strconv.Atoi(line1Match[4])
-			line2Match := line2Re.FindStringSubmatch(response)
+			status.MacAddress = client.MacAddress
+			status.SignalDbm = client.SignalDbm
+			status.NoiseDbm = client.NoiseDbm
+			status.SignalNoiseRatio = client.SignalNoiseRatio
 			if len(line2Match) > 0 {
-				status.RxRateMbps, _ = strconv.ParseFloat(line2Match[1], 64)
-				status.RxPackets, _ = strconv.Atoi(line2Match[2])
+				status.RxRateMbps = client.RxRateMbps
+				status.RxPackets = client.RxPackets
 				if !status.IsRobot {
 					status.determineConnectionQuality(status.RxRateMbps)
 				}
 			}
-			line3Match := line3R3.FindStringSubmatch(response)
 			if len(line3Match) > 0 {
-				status.TxRateMbps, _ = strconv.ParseFloat(line3Match[1], 64)
-				status.TxPackets, _ = strconv.Atoi(line3Match[2])
+				status.TxRateMbps = client.TxRateMbps
+				status.TxPackets = client.TxPackets
 				if status.IsRobot {
 					status.determineConnectionQuality(status.TxRateMbps)
 				}
 			}
-			break
 		}
+		status.AssociatedClients = append(status.AssociatedClients, client)
 	}
 }
 
@@ -191,6 +259,29 @@ func (status *NetworkStatus) parseIfconfig(response string) {
 	}
 }
 
+// copy returns a copy of the status that shares none of its associated clients with it.
+func (status *NetworkStatus) copy() NetworkStatus {
+	statusCopy := *status
+	statusCopy.AssociatedClients = append([]AssociatedClient(nil), status.AssociatedClients...)
+	return statusCopy
+}
+
+// hasSameLinkState returns true if the given status has the same associated devices and connection quality as this
+// one.
+func (status *NetworkStatus) hasSameLinkState(other *NetworkStatus) bool {
+	if status.IsLinked != other.IsLinked || status.MacAddress != other.MacAddress ||
+		status.ConnectionQuality != other.ConnectionQuality ||
+		len(status.AssociatedClients) != len(other.AssociatedClients) {
+		return false
+	}
+	for i := range status.AssociatedClients {
+		if status.AssociatedClients[i].MacAddress != other.AssociatedClients[i].MacAddress {
+			return false
+		}
+	}
+	return true
+}
+
 // determineConnectionQuality uses the stored RxRateMbps value to determine a connection quality string and updates the
 // status structure with the result.
 func (status *NetworkStatus) determineConnectionQuality(rate float64) {
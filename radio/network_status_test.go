@@ -65,6 +65,18 @@ func TestNetworkStatus_ParseAssocList(t *testing.T) {
 			TxRateMbps:        254.0,
 			TxPackets:         123,
 			ConnectionQuality: "excellent",
+			AssociatedClients: []AssociatedClient{
+				{
+					MacAddress:       "48:DA:35:B0:00:CF",
+					SignalDbm:        -53,
+					NoiseDbm:         -95,
+					SignalNoiseRatio: 42,
+					RxRateMbps:       550.6,
+					RxPackets:        4095,
+					TxRateMbps:       254.0,
+					TxPackets:        123,
+				},
+			},
 		},
 		status,
 	)
@@ -90,6 +102,19 @@ func TestNetworkStatus_ParseAssocList(t *testing.T) {
 			TxRateMbps:        550.6,
 			TxPackets:         789,
 			ConnectionQuality: "warning",
+			AssociatedClients: []AssociatedClient{
+				{
+					MacAddress:       "37:DA:35:B0:00:BE",
+					SignalDbm:        -64,
+					NoiseDbm:         -84,
+					SignalNoiseRatio: 7,
+					RxRateMbps:       123.4,
+					RxPackets:        5091,
+					TxRateMbps:       550.6,
+					TxPackets:        789,
+					InactiveMs:       4000,
+				},
+			},
 		},
 		status,
 	)
@@ -101,6 +126,76 @@ func TestNetworkStatus_ParseAssocList(t *testing.T) {
 		"\texpected throughput: unknown"
 	status.parseAssocList(response)
 	assert.Equal(t, NetworkStatus{}, status)
+
+	// Multiple devices are associated, some of them stale or invalid.
+	response = "00:00:00:00:00:00  -53 dBm / -95 dBm (SNR 42)  0 ms ago\n" +
+		"\tRX: 6.0 MBit/s                                  1 Pkts.\n" +
+		"\tTX: 6.0 MBit/s                                  1 Pkts.\n" +
+		"\texpected throughput: unknown\n" +
+		"48:DA:35:B0:00:CF  -53 dBm / -95 dBm (SNR 42)  10 ms ago\n" +
+		"\tRX: 550.6 MBit/s                                4095 Pkts.\n" +
+		"\tTX: 254.0 MBit/s                                 123 Pkts.\n" +
+		"\texpected throughput: unknown\n" +
+		"37:DA:35:B0:00:BE  -64 dBm / -84 dBm (SNR 20)  9000 ms ago\n" +
+		"\tRX: 123.4 MBit/s                                5091 Pkts.\n" +
+		"\tTX: 550.6 MBit/s                                 789 Pkts.\n" +
+		"\texpected throughput: unknown\n" +
+		"5C:DA:35:B0:00:12  -70 dBm / -90 dBm (SNR 20)  1500 ms ago\n" +
+		"\tRX: 86.7 MBit/s                                  321 Pkts.\n" +
+		"\tTX: 144.4 MBit/s                                  45 Pkts.\n" +
+		"\texpected throughput: unknown"
+	status.parseAssocList(response)
+	assert.Equal(
+		t,
+		NetworkStatus{
+			IsLinked:          true,
+			MacAddress:        "48:DA:35:B0:00:CF",
+			SignalDbm:         -53,
+			NoiseDbm:          -95,
+			SignalNoiseRatio:  42,
+			RxRateMbps:        550.6,
+			RxPackets:         4095,
+			TxRateMbps:        254.0,
+			TxPackets:         123,
+			ConnectionQuality: "excellent",
+			AssociatedClients: []AssociatedClient{
+				{
+					MacAddress:       "48:DA:35:B0:00:CF",
+					SignalDbm:        -53,
+					NoiseDbm:         -95,
+					SignalNoiseRatio: 42,
+					RxRateMbps:       550.6,
+					RxPackets:        4095,
+					TxRateMbps:       254.0,
+					TxPackets:        123,
+					InactiveMs:       10,
+				},
+				{
+					MacAddress:       "5C:DA:35:B0:00:12",
+					SignalDbm:        -70,
+					NoiseDbm:         -90,
+					SignalNoiseRatio: 20,
+					RxRateMbps:       86.7,
+					RxPackets:        321,
+					TxRateMbps:       144.4,
+					TxPackets:        45,
+					InactiveMs:       1500,
+				},
+			},
+		},
+		status,
+	)
+
+	// The primary link is taken over by the next device when the first one leaves.
+	response = "5C:DA:35:B0:00:12  -70 dBm / -90 dBm (SNR 20)  30 ms ago\n" +
+		"\tRX: 86.7 MBit/s                                  321 Pkts.\n" +
+		"\tTX: 144.4 MBit/s                                  45 Pkts.\n" +
+		"\texpected throughput: unknown"
+	status.parseAssocList(response)
+	assert.Equal(t, "5C:DA:35:B0:00:12", status.MacAddress)
+	assert.Equal(t, 86.7, status.RxRateMbps)
+	assert.Equal(t, "warning", status.ConnectionQuality)
+	assert.Equal(t, 1, len(status.AssociatedClients))
 }
 
 func TestNetworkStatus_ParseIfconfig(t *testing.T) {
@@ -141,3 +236,48 @@ func TestNetworkStatus_DetermineConnectionQuality(t *testing.T) {
 	status.parseAssocList("")
 	assert.Equal(t, NetworkStatus{}, status)
 }
+
+func TestNetworkStatus_Copy(t *testing.T) {
+	status := NetworkStatus{Ssid: "254", AssociatedClients: []AssociatedClient{{MacAddress: "48:DA:35:B0:00:CF"}}}
+	statusCopy := status.copy()
+	assert.Equal(t, status, statusCopy)
+
+	// Updating the associated clients in place shouldn't affect the copy.
+	status.AssociatedClients[0].SignalDbm = -60
+	assert.Equal(t, 0, statusCopy.AssociatedClients[0].SignalDbm)
+
+	assert.Nil(t, (&NetworkStatus{}).copy().AssociatedClients)
+}
+
+func TestNetworkStatus_HasSameLinkState(t *testing.T) {
+	status := NetworkStatus{IsLinked: true, MacAddress: "48:DA:35:B0:00:CF", ConnectionQuality: "good", RxBytes: 1}
+	other := status
+	other.RxBytes = 2
+	other.SignalDbm = -50
+	assert.True(t, status.hasSameLinkState(&other))
+
+	other.ConnectionQuality = "caution"
+	assert.False(t, status.hasSameLinkState(&other))
+
+	other = status
+	other.MacAddress = "48:DA:35:B0:00:D0"
+	assert.False(t, status.hasSameLinkState(&other))
+
+	other = status
+	other.IsLinked = false
+	assert.False(t, status.hasSameLinkState(&other))
+
+	// Changes to the secondary devices count as well, but not changes to their statistics.
+	status.AssociatedClients = []AssociatedClient{{MacAddress: "48:DA:35:B0:00:CF"}, {MacAddress: "5C:DA:35:B0:00:12"}}
+	other = status
+	other.AssociatedClients = []AssociatedClient{
+		{MacAddress: "48:DA:35:B0:00:CF", SignalDbm: -60}, {MacAddress: "5C:DA:35:B0:00:12", TxPackets: 5},
+	}
+	assert.True(t, status.hasSameLinkState(&other))
+
+	other.AssociatedClients = other.AssociatedClients[:1]
+	assert.False(t, status.hasSameLinkState(&other))
+
+	other.AssociatedClients = []AssociatedClient{{MacAddress: "48:DA:35:B0:00:CF"}, {MacAddress: "5C:DA:35:B0:00:13"}}
+	assert.False(t, status.hasSameLinkState(&other))
+}
@@ -0,0 +1,116 @@
+package radio
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Role represents which of its two jobs the radio is doing: serving as the field access point, or being a robot radio.
+type Role string
+
+const (
+	RoleAccessPoint Role = "access-point"
+	RoleRobotRadio  Role = "robot-radio"
+)
+
+// Radio is the interface through which the API drives the radio, regardless of its role.
+type Radio interface {
+	// Role returns the job that the radio is doing.
+	Role() Role
+
+	// HardwareType returns the hardware type of the radio.
+	HardwareType() RadioType
+
+	// Run loops indefinitely, handling configuration requests and polling the Wi-Fi status.
+	Run()
+
+	// SetConfigurationLimits sets the maximum number of attempts and the maximum amount of time to spend applying each
+	// configuration request before giving up. Zero means no limit. Must be called before Run.
+	SetConfigurationLimits(maxAttempts int, timeout time.Duration)
+
+	// SetLinkHistoryRetention sets the amount of time for which to keep samples of each network's link metrics. Zero
+	// disables the history. Must be called before Run.
+	SetLinkHistoryRetention(retention time.Duration)
+
+	// LinkHistory returns the samples of the given network's link metrics taken after the given time, oldest first.
+	// Returns an error if the radio has no network by that name.
+	LinkHistory(network string, since time.Time) ([]LinkSample, error)
+
+	// LinkEvents returns the recorded link events of the given network having an ID greater than the given one, oldest
+	// first. A blank network returns the events of all networks. Returns an error if the radio has no network by that
+	// name.
+	LinkEvents(network string, afterId int) ([]LinkEvent, error)
+
+	// SubscribeLinkEvents returns a channel that receives a notification whenever a new link event is recorded.
+	SubscribeLinkEvents() chan struct{}
+
+	// UnsubscribeLinkEvents stops notifications to a channel previously returned by SubscribeLinkEvents.
+	UnsubscribeLinkEvents(listener chan struct{})
+
+	// StatusSnapshot returns a deep copy of the radio's current state, which is safe to read and serialize while the
+	// radio continues to be updated.
+	StatusSnapshot() any
+
+	// NewConfigurationRequest returns an empty configuration request of the type that the radio accepts, for decoding
+	// a JSON request into.
+	NewConfigurationRequest() ConfigurationRequest
+
+	// QueueConfigurationRequest assigns the given request a job for tracking its progress and adds it to the
+	// asynchronous queue. Returns a copy of the job in its initial state.
+	QueueConfigurationRequest(request ConfigurationRequest) ConfigurationJob
+
+	// GetConfigurationJob returns a copy of the job having the given ID, and whether such a job is being tracked.
+	GetConfigurationJob(id string) (ConfigurationJob, bool)
+
+	// Counters returns a snapshot of the cumulative totals of configuration and monitoring events since the radio
+	// started.
+	Counters() Counters
+
+	// SubscribeStatusChanges returns a channel that receives a notification whenever the radio's status changes.
+	SubscribeStatusChanges() chan struct{}
+
+	// UnsubscribeStatusChanges stops notifications to a channel previously returned by SubscribeStatusChanges.
+	UnsubscribeStatusChanges(listener chan struct{})
+}
+
+// ConfigurationRequest represents a JSON request to configure the radio, whose parameters depend on the radio's role.
+type ConfigurationRequest interface {
+	// Validate checks that all parameters within the configuration request have valid values for the given radio,
+	// returning a *ValidationError describing the problem if not.
+	Validate(radio Radio) error
+
+	// getJobId returns the ID of the job tracking the request, assigned when it is queued.
+	getJobId() string
+
+	// setJobId records the ID of the job tracking the request.
+	setJobId(id string)
+}
+
+// ParseRole converts the given string into a role, returning an error if it isn't one of the known roles.
+func ParseRole(role string) (Role, error) {
+	switch Role(role) {
+	case RoleAccessPoint, RoleRobotRadio:
+		return Role(role), nil
+	default:
+		return "", fmt.Errorf("invalid radio role %q (expecting %s or %s)", role, RoleAccessPoint, RoleRobotRadio)
+	}
+}
+
+// DetectRole determines the role of the radio from its model; the Vivid-Hosting VH-113 is a robot radio and anything
+// else is an access point.
+func DetectRole() Role {
+	model, _ := uciTree.GetLast("system", "@system[0]", "model")
+	if strings.Contains(model, "VH-113") {
+		return RoleRobotRadio
+	}
+	return RoleAccessPoint
+}
+
+// NewRadio creates a new radio having the given role and initializes its fields to default values.
+func NewRadio(role Role) Radio {
+	if role == RoleRobotRadio {
+		return NewRobotRadio()
+	}
+	return NewAccessPointRadio()
+}
@@ -1,9 +1,9 @@
-// This file is specific to the access point version of the API.
-//go:build !robot
+// This file is specific to the access point role of the API.
 
 package radio
 
 import (
+	"context"
 	"fmt"
 	"github.com/digineo/go-uci"
 	"log"
@@ -12,22 +12,28 @@ import (
 	"time"
 )
 
-// Radio holds the current state of the access point's configuration and any robot radios connected to it.
-type Radio struct {
+// AccessPointRadio holds the current state of the access point's configuration and any robot radios connected to it.
+type AccessPointRadio struct {
+	radioBase
+
 	// 5GHz or 6GHz channel number the radio is broadcasting on.
 	Channel int `json:"channel"`
 
-	// Channel bandwidth mode for the radio to use. Valid values are "20MHz" and "40MHz".
+	// Channel bandwidth mode for the radio to use. Valid values are "20MHz", "40MHz", "80MHz" and "160MHz".
 	ChannelBandwidth string `json:"channelBandwidth"`
 
+	// Effective transmit power of the radio in dBm, as reported by the Wi-Fi driver. Zero if it couldn't be determined.
+	TxPower int `json:"txPower"`
+
 	// VLANs to use for the teams of the red alliance. Valid values are "10_20_30", "40_50_60", and "70_80_90".
 	RedVlans AllianceVlans `json:"redVlans"`
 
 	// VLANs to use for the teams of the blue alliance. Valid values are "10_20_30", "40_50_60", and "70_80_90".
 	BlueVlans AllianceVlans `json:"blueVlans"`
 
-	// Enum representing the current configuration stage of the radio.
-	Status radioStatus `json:"status"`
+	// VLAN IDs to use for individual team stations, keyed by station name, overriding the ones given by RedVlans and
+	// BlueVlans.
+	StationVlans map[string]int `json:"stationVlans"`
 
 	// Map of team station names to their current status.
 	StationStatuses map[string]*NetworkStatus `json:"stationStatuses"`
@@ -35,12 +41,6 @@ type Radio struct {
 	// IP address of the syslog server to send logs to (via UDP on port 514).
 	SyslogIpAddress string `json:"syslogIpAddress"`
 
-	// Version of the radio software.
-	Version string `json:"version"`
-
-	// Queue for receiving and buffering configuration requests.
-	ConfigurationRequestChannel chan ConfigurationRequest `json:"-"`
-
 	// Hardware type of the radio.
 	Type RadioType `json:"-"`
 
@@ -49,6 +49,10 @@ type Radio struct {
 
 	// Map of team station names to their Wi-Fi interface names, dependent on the hardware type.
 	stationInterfaces map[station]string
+
+	// Map of team stations to the bandwidth limit in megabits per second currently applied to their Wi-Fi interfaces.
+	// Stations without a limit are absent. Only accessed from the goroutine that configures the radio.
+	bandwidthLimits map[station]float64
 }
 
 // AllianceVlans represents which three VLANs are used for the teams of an alliance.
@@ -60,14 +64,10 @@ const (
 	Vlans708090 AllianceVlans = "70_80_90"
 )
 
-// NewRadio creates a new Radio instance and initializes its fields to default values.
-func NewRadio() *Radio {
-	radio := Radio{
-		RedVlans:                    Vlans102030,
-		BlueVlans:                   Vlans405060,
-		Status:                      statusBooting,
-		ConfigurationRequestChannel: make(chan ConfigurationRequest, configurationRequestBufferSize),
-	}
+// NewAccessPointRadio creates a new AccessPointRadio instance and initializes its fields to default values.
+func NewAccessPointRadio() *AccessPointRadio {
+	radio := &AccessPointRadio{RedVlans: Vlans102030, BlueVlans: Vlans405060, StationVlans: make(map[string]int)}
+	radio.radioBase = newRadioBase(radio)
 	radio.determineAndSetType()
 	if radio.Type == TypeUnknown {
 		log.Fatal("Unable to determine radio hardware type; exiting.")
@@ -99,23 +99,86 @@ func NewRadio() *Radio {
 		}
 	}
 
+	radio.bandwidthLimits = make(map[station]float64)
 	radio.StationStatuses = make(map[string]*NetworkStatus)
 	for station := red1; station <= blue3; station++ {
 		radio.StationStatuses[station.String()] = nil
 	}
 
-	return &radio
+	return radio
+}
+
+// Role returns the job that the radio is doing, which is always that of the access point.
+func (radio *AccessPointRadio) Role() Role {
+	return RoleAccessPoint
+}
+
+// HardwareType returns the hardware type of the access point.
+func (radio *AccessPointRadio) HardwareType() RadioType {
+	return radio.Type
+}
+
+// NewConfigurationRequest returns an empty access point configuration request.
+func (radio *AccessPointRadio) NewConfigurationRequest() ConfigurationRequest {
+	return &AccessPointConfigurationRequest{}
+}
+
+// Snapshot returns a deep copy of the radio's current state, which is safe to read and serialize while the radio
+// continues to be updated.
+func (radio *AccessPointRadio) Snapshot() *AccessPointRadio {
+	radio.mutex.RLock()
+	defer radio.mutex.RUnlock()
+
+	snapshot := AccessPointRadio{
+		radioBase:        radio.snapshotBase(),
+		Channel:          radio.Channel,
+		ChannelBandwidth: radio.ChannelBandwidth,
+		TxPower:          radio.TxPower,
+		RedVlans:         radio.RedVlans,
+		BlueVlans:        radio.BlueVlans,
+		StationVlans:     make(map[string]int),
+		StationStatuses:  make(map[string]*NetworkStatus),
+		SyslogIpAddress:  radio.SyslogIpAddress,
+		Type:             radio.Type,
+	}
+	for stationName, vlan := range radio.StationVlans {
+		snapshot.StationVlans[stationName] = vlan
+	}
+	for stationName, stationStatus := range radio.StationStatuses {
+		if stationStatus == nil {
+			snapshot.StationStatuses[stationName] = nil
+		} else {
+			stationStatusCopy := stationStatus.copy()
+			snapshot.StationStatuses[stationName] = &stationStatusCopy
+		}
+	}
+	return &snapshot
+}
+
+// StatusSnapshot returns the same deep copy as Snapshot, for use through the Radio interface.
+func (radio *AccessPointRadio) StatusSnapshot() any {
+	return radio.Snapshot()
 }
 
 // getStationVlan returns the VLAN number for the given team station.
-func (radio *Radio) getStationVlan(station station) int {
+func (radio *AccessPointRadio) getStationVlan(station station) int {
+	return stationVlan(station, radio.RedVlans, radio.BlueVlans, radio.StationVlans)
+}
+
+// stationVlan returns the VLAN number for the given team station under the given alliance VLANs and per-station
+// overrides, or -1 if it can't be determined.
+func stationVlan(station station, redVlans, blueVlans AllianceVlans, stationVlans map[string]int) int {
+	if vlan, ok := stationVlans[station.String()]; ok {
+		return vlan
+	}
+
 	var vlans AllianceVlans
 	var position int
 	if station == red1 || station == red2 || station == red3 {
-		vlans = radio.RedVlans
+		vlans = redVlans
 		position = int(station) - int(red1) + 1
 	} else if station == blue1 || station == blue2 || station == blue3 {
-		vlans = radio.BlueVlans
+		vlans = blueVlans
 		position = int(station) - int(blue1) + 1
 	}
 
@@ -133,7 +196,7 @@ func (radio *Radio) getStationVlan(station station) int {
 }
 
 // determineAndSetType determines the model of the radio.
-func (radio *Radio) determineAndSetType() {
+func (radio *AccessPointRadio) determineAndSetType() {
 	model, _ := uciTree.GetLast("system", "@system[0]", "model")
 	if strings.Contains(model, "VH") {
 		radio.Type = TypeVividHosting
@@ -143,58 +206,98 @@ func (radio *Radio) determineAndSetType() {
 }
 
 // isStarted returns true if the Wi-Fi interface is up and running.
-func (radio *Radio) isStarted() bool {
+func (radio *AccessPointRadio) isStarted() bool {
 	_, err := shell.runCommand("iwinfo", radio.stationInterfaces[blue3], "info")
 	return err == nil
 }
 
 // setInitialState initializes the in-memory state to match the radio's current configuration.
-func (radio *Radio) setInitialState() {
+func (radio *AccessPointRadio) setInitialState() {
 	channel, _ := uciTree.GetLast("wireless", radio.device, "channel")
-	radio.Channel, _ = strconv.Atoi(channel)
+	channelNumber, _ := strconv.Atoi(channel)
 	htmode, _ := uciTree.GetLast("wireless", radio.device, "htmode")
-	switch htmode {
-	case "HT20":
-		radio.ChannelBandwidth = "20MHz"
-	case "HT40":
-		radio.ChannelBandwidth = "40MHz"
-	default:
-		radio.ChannelBandwidth = "INVALID"
-	}
+	channelBandwidth := channelBandwidthForHtmode(htmode)
 	_ = radio.updateStationStatuses()
-
-	radio.SyslogIpAddress, _ = uciTree.GetLast("system", "@system[0]", "log_ip")
+	radio.updateTxPower()
+	syslogIpAddress, _ := uciTree.GetLast("system", "@system[0]", "log_ip")
+
+	// Bandwidth limits are only tracked in memory, so any rules still in place from before a restart are unknown and
+	// would otherwise never be removed.
+	radio.clearBandwidthLimits()
+
+	radio.mutex.Lock()
+	defer radio.mutex.Unlock()
+	radio.Channel = channelNumber
+	radio.ChannelBandwidth = channelBandwidth
+	radio.SyslogIpAddress = syslogIpAddress
 }
 
 // configure configures the radio with the given configuration.
-func (radio *Radio) configure(request ConfigurationRequest) error {
-	if request.Channel > 0 {
-		uciTree.SetType("wireless", radio.device, "channel", uci.TypeOption, strconv.Itoa(request.Channel))
-		radio.Channel = request.Channel
+func (radio *AccessPointRadio) configure(ctx context.Context, configurationRequest ConfigurationRequest) error {
+	request, ok := configurationRequest.(*AccessPointConfigurationRequest)
+	if !ok {
+		return fmt.Errorf("invalid configuration request type for access point: %T", configurationRequest)
+	}
+	channel := int(request.Channel)
+	if request.Channel == AutoChannel {
+		// Only consider channels that can be used at the bandwidth the radio is about to have.
+		channelBandwidth := request.ChannelBandwidth
+		if channelBandwidth == "" {
+			radio.mutex.RLock()
+			channelBandwidth = radio.ChannelBandwidth
+			radio.mutex.RUnlock()
+		}
+		survey, err := radio.survey(channelBandwidth)
+		if err != nil {
+			return fmt.Errorf("failed to select channel automatically: %v", err)
+		}
+		channel = survey.LeastCongestedChannel
+		log.Printf("Selected channel %d as the least congested of %d surveyed.", channel, len(survey.Channels))
+	}
+	if channel > 0 {
+		uciTree.SetType("wireless", radio.device, "channel", uci.TypeOption, strconv.Itoa(channel))
+		radio.mutex.Lock()
+		radio.Channel = channel
+		radio.mutex.Unlock()
 	}
 	if request.ChannelBandwidth != "" {
-		var htmode string
-		switch request.ChannelBandwidth {
-		case "20MHz":
-			htmode = "HT20"
-		case "40MHz":
-			htmode = "HT40"
-		default:
+		htmode, ok := channelBandwidthHtmodes[request.ChannelBandwidth]
+		if !ok {
 			return fmt.Errorf("invalid channel bandwidth: %s", request.ChannelBandwidth)
 		}
 		uciTree.SetType("wireless", radio.device, "htmode", uci.TypeOption, htmode)
+		radio.mutex.Lock()
 		radio.ChannelBandwidth = request.ChannelBandwidth
+		radio.mutex.Unlock()
+	}
+	if request.TxPower == TxPowerDefault {
+		uciTree.Del("wireless", radio.device, "txpower")
+	} else if request.TxPower > 0 {
+		uciTree.SetType("wireless", radio.device, "txpower", uci.TypeOption, strconv.Itoa(request.TxPower))
 	}
 	if request.RedVlans != "" && request.BlueVlans != "" {
+		radio.mutex.Lock()
 		radio.RedVlans = request.RedVlans
 		radio.BlueVlans = request.BlueVlans
+		radio.mutex.Unlock()
+	}
+	if request.StationVlans != nil {
+		stationVlans := make(map[string]int)
+		for stationName, vlan := range request.StationVlans {
+			stationVlans[stationName] = vlan
+		}
+		radio.mutex.Lock()
+		radio.StationVlans = stationVlans
+		radio.mutex.Unlock()
 	}
 	if request.SyslogIpAddress != "" {
 		uciTree.SetType("system", "@system[0]", "log_ip", uci.TypeOption, request.SyslogIpAddress)
 		if err := uciTree.Commit(); err != nil {
 			return fmt.Errorf("failed to commit system configuration: %v", err)
 		}
+		radio.mutex.Lock()
 		radio.SyslogIpAddress = request.SyslogIpAddress
+		radio.mutex.Unlock()
 		if _, err := shell.runCommand("/etc/init.d/log", "restart"); err != nil {
 			return fmt.Errorf("failed to restart syslog service: %v", err)
 		}
@@ -202,19 +305,29 @@ func (radio *Radio) configure(request ConfigurationRequest) error {
 
 	if radio.Type == TypeLinksys {
 		// Clear the state of the radio before loading teams; the Linksys AP is crash-prone otherwise.
-		if err := radio.configureStations(map[string]StationConfiguration{}); err != nil {
+		if err := radio.configureStations(ctx, map[string]StationConfiguration{}); err != nil {
 			return err
 		}
-		time.Sleep(wifiReloadBackoffDuration)
+		if err := sleepWithContext(ctx, wifiReloadBackoffDuration); err != nil {
+			return fmt.Errorf("interrupted while clearing Wi-Fi configuration: %w", err)
+		}
+	}
+	if err := radio.configureStations(ctx, request.StationConfigurations); err != nil {
+		return err
 	}
-	return radio.configureStations(request.StationConfigurations)
+	radio.updateTxPower()
+	return radio.applyBandwidthLimits(request.stationBandwidthLimits())
 }
 
-// configureStations configures the access point with the given team station configurations.
-func (radio *Radio) configureStations(stationConfigurations map[string]StationConfiguration) error {
+// configureStations configures the access point with the given team station configurations, retrying until the radio
+// reflects them or the maximum number of attempts or the context's deadline is reached.
+func (radio *AccessPointRadio) configureStations(
+	ctx context.Context, stationConfigurations map[string]StationConfiguration,
+) error {
 	retryCount := 1
 
 	for {
+		radio.jobs.recordAttempt()
 		for station := red1; station <= blue3; station++ {
 			position := int(station) + 1
 			var ssid, wpaKey string
@@ -240,10 +353,12 @@ func (radio *Radio) configureStations(stationConfigurations map[string]StationCo
 			}
 		}
 
-		if _, err := shell.runCommand("wifi", "reload", radio.device); err != nil {
-			return fmt.Errorf("failed to reload configuration for device %s: %v", radio.device, err)
+		if err := radio.reloadWifi(); err != nil {
+			return err
+		}
+		if err := sleepWithContext(ctx, wifiReloadBackoffDuration); err != nil {
+			return fmt.Errorf("interrupted after %d attempts at configuring Wi-Fi: %w", retryCount, err)
 		}
-		time.Sleep(wifiReloadBackoffDuration)
 
 		err := radio.updateStationStatuses()
 		if err != nil {
@@ -253,38 +368,115 @@ func (radio *Radio) configureStations(stationConfigurations map[string]StationCo
 			break
 		}
 
+		if radio.MaxConfigurationAttempts > 0 && retryCount >= radio.MaxConfigurationAttempts {
+			return fmt.Errorf("wireless configuration still incorrect after %d attempts; giving up", retryCount)
+		}
 		log.Printf("Wi-Fi configuration still incorrect after %d attempts; trying again.", retryCount)
-		time.Sleep(retryBackoffDuration)
+		if err := sleepWithContext(ctx, retryBackoffDuration); err != nil {
+			return fmt.Errorf("interrupted after %d attempts at configuring Wi-Fi: %w", retryCount, err)
+		}
 		retryCount++
+		radio.counters.incrementConfigurationRetries()
 	}
 
 	return nil
 }
 
+// reloadWifi applies the committed wireless configuration to the radio's Wi-Fi device.
+func (radio *AccessPointRadio) reloadWifi() error {
+	if _, err := shell.runCommand("wifi", "reload", radio.device); err != nil {
+		return fmt.Errorf("failed to reload configuration for device %s: %v", radio.device, err)
+	}
+	return nil
+}
+
+// configurationUciOptions returns the UCI options that applying a configuration request may change.
+func (radio *AccessPointRadio) configurationUciOptions() []uciOption {
+	options := []uciOption{
+		{"wireless", radio.device, "channel", uci.TypeOption},
+		{"wireless", radio.device, "htmode", uci.TypeOption},
+		{"wireless", radio.device, "txpower", uci.TypeOption},
+		{"system", "@system[0]", "log_ip", uci.TypeOption},
+	}
+	for station := red1; station <= blue3; station++ {
+		wifiInterface := fmt.Sprintf("@wifi-iface[%d]", int(station)+1)
+		for _, option := range []string{"ssid", "key", "sae_password", "network"} {
+			options = append(options, uciOption{"wireless", wifiInterface, option, uci.TypeOption})
+		}
+	}
+	return options
+}
+
+// restoreState reverts the in-memory state to that of the given snapshot, once the UCI configuration it was taken
+// alongside has been restored.
+func (radio *AccessPointRadio) restoreState(previous any) {
+	// The VLANs only exist in memory, whereas everything else can be reloaded from the radio itself.
+	if previousRadio, ok := previous.(*AccessPointRadio); ok {
+		radio.mutex.Lock()
+		radio.RedVlans = previousRadio.RedVlans
+		radio.BlueVlans = previousRadio.BlueVlans
+		radio.StationVlans = previousRadio.StationVlans
+		radio.mutex.Unlock()
+	}
+	radio.setInitialState()
+
+	// The bandwidth limits are likewise only tracked in memory, and reloading the Wi-Fi may have removed them. They are
+	// reinstalled after reinitializing the state, since that clears them.
+	if previousRadio, ok := previous.(*AccessPointRadio); ok {
+		previousLimits := make(map[station]float64)
+		for station := red1; station <= blue3; station++ {
+			if status := previousRadio.StationStatuses[station.String()]; status != nil && status.BandwidthLimitMbps > 0 {
+				previousLimits[station] = status.BandwidthLimitMbps
+			}
+		}
+		if err := radio.applyBandwidthLimits(previousLimits); err != nil {
+			log.Printf("Error restoring bandwidth limits: %v", err)
+		}
+	}
+}
+
 // updateStationStatuses fetches the current Wi-Fi status (SSID, WPA key, etc.) for each team station and updates the
 // in-memory state.
-func (radio *Radio) updateStationStatuses() error {
+func (radio *AccessPointRadio) updateStationStatuses() error {
+	stationStatuses := make(map[string]*NetworkStatus)
 	for station := red1; station <= blue3; station++ {
 		ssid, err := getSsid(radio.stationInterfaces[station])
 		if err != nil {
 			return err
 		}
 		if strings.HasPrefix(ssid, "no-team-") {
-			radio.StationStatuses[station.String()] = nil
+			stationStatuses[station.String()] = nil
 		} else {
 			var status NetworkStatus
 			status.Ssid = ssid
 			status.HashedWpaKey, status.WpaKeySalt = radio.getHashedWpaKeyAndSalt(int(station) + 1)
-			radio.StationStatuses[station.String()] = &status
+			status.BandwidthLimitMbps = radio.bandwidthLimits[station]
+			stationStatuses[station.String()] = &status
 		}
 	}
 
+	radio.mutex.Lock()
+	defer radio.mutex.Unlock()
+	radio.StationStatuses = stationStatuses
 	return nil
 }
 
+// updateTxPower fetches the effective transmit power of the radio and updates the in-memory state. All the team
+// stations share the same Wi-Fi device, so any of their interfaces will do.
+func (radio *AccessPointRadio) updateTxPower() {
+	txPower, err := getTxPower(radio.stationInterfaces[red1])
+	if err != nil {
+		log.Printf("Error determining transmit power: %v", err)
+	}
+
+	radio.mutex.Lock()
+	defer radio.mutex.Unlock()
+	radio.TxPower = txPower
+}
+
 // stationSsidsAreCorrect returns true if the configured networks as read from the access point match the requested
 // configuration.
-func (radio *Radio) stationSsidsAreCorrect(stationConfigurations map[string]StationConfiguration) bool {
+func (radio *AccessPointRadio) stationSsidsAreCorrect(stationConfigurations map[string]StationConfiguration) bool {
 	for stationName, stationStatus := range radio.StationStatuses {
 		if config, ok := stationConfigurations[stationName]; ok {
 			if radio.StationStatuses[stationName] == nil || radio.StationStatuses[stationName].Ssid != config.Ssid {
@@ -303,7 +495,10 @@ func (radio *Radio) stationSsidsAreCorrect(stationConfigurations map[string]Stat
 
 // updateMonitoring polls the access point for the current bandwidth usage and link state of each team station and
 // updates the in-memory state.
-func (radio *Radio) updateMonitoring() {
+func (radio *AccessPointRadio) updateMonitoring() {
+	oldStationStatuses := make(map[string]*NetworkStatus)
+	newStationStatuses := make(map[string]*NetworkStatus)
+	linkStateChanged := false
 	for station := red1; station <= blue3; station++ {
 		stationStatus := radio.StationStatuses[station.String()]
 		if stationStatus == nil {
@@ -311,6 +506,40 @@ func (radio *Radio) updateMonitoring() {
 			continue
 		}
 
-		stationStatus.updateMonitoring(radio.stationInterfaces[station])
+		newStationStatus := *stationStatus
+		newStationStatus.updateMonitoring(radio.stationInterfaces[station], &radio.counters)
+		oldStationStatuses[station.String()] = stationStatus
+		newStationStatuses[station.String()] = &newStationStatus
+		if !newStationStatus.hasSameLinkState(stationStatus) {
+			linkStateChanged = true
+		}
+	}
+
+	// Swap in the new statuses all at once so that readers never observe a partially updated state.
+	radio.mutex.Lock()
+	for stationName, stationStatus := range newStationStatuses {
+		radio.StationStatuses[stationName] = stationStatus
+	}
+	radio.mutex.Unlock()
+
+	now := time.Now()
+	for station := red1; station <= blue3; station++ {
+		if stationStatus, ok := newStationStatuses[station.String()]; ok {
+			radio.linkHistory.record(station.String(), now, *stationStatus)
+			radio.linkEvents.record(station.String(), now, oldStationStatuses[station.String()], stationStatus)
+		}
+	}
+
+	if linkStateChanged {
+		radio.statusNotifier.notify()
+	}
+}
+
+// networkNames returns the names of the team stations, by which their networks are identified in the link history.
+func (radio *AccessPointRadio) networkNames() []string {
+	var names []string
+	for station := red1; station <= blue3; station++ {
+		names = append(names, station.String())
 	}
+	return names
 }
@@ -1,22 +1,25 @@
-// This file is specific to the access point version of the API.
-//go:build !robot
+// This file is specific to the access point role of the API.
 
 package radio
 
 import (
+	"context"
+	"encoding/json"
 	"errors"
+	"fmt"
 	"github.com/stretchr/testify/assert"
+	"strings"
 	"testing"
 	"time"
 )
 
-func TestNewRadio(t *testing.T) {
+func TestNewAccessPointRadio(t *testing.T) {
 	fakeTree := newFakeUciTree()
 	uciTree = fakeTree
 
 	// Using Vivid-Hosting radio.
 	fakeTree.valuesForGet["system.@system[0].model"] = "VH-109(AP)"
-	radio := NewRadio()
+	radio := NewAccessPointRadio()
 	assert.Equal(t, 0, radio.Channel)
 	assert.Equal(t, statusBooting, radio.Status)
 	if assert.Equal(t, 6, len(radio.StationStatuses)) {
@@ -44,7 +47,7 @@ func TestNewRadio(t *testing.T) {
 
 	// Using Linksys radio.
 	fakeTree.valuesForGet["system.@system[0].model"] = ""
-	radio = NewRadio()
+	radio = NewAccessPointRadio()
 	assert.Equal(t, 0, radio.Channel)
 	assert.Equal(t, statusBooting, radio.Status)
 	if assert.Equal(t, 6, len(radio.StationStatuses)) {
@@ -71,8 +74,8 @@ func TestNewRadio(t *testing.T) {
 	)
 }
 
-func TestRadio_getStationVlan(t *testing.T) {
-	radio := NewRadio()
+func TestAccessPointRadio_getStationVlan(t *testing.T) {
+	radio := NewAccessPointRadio()
 	assert.Equal(t, 10, radio.getStationVlan(red1))
 	assert.Equal(t, 20, radio.getStationVlan(red2))
 	assert.Equal(t, 30, radio.getStationVlan(red3))
@@ -90,13 +93,22 @@ func TestRadio_getStationVlan(t *testing.T) {
 	assert.Equal(t, 20, radio.getStationVlan(blue2))
 	assert.Equal(t, 30, radio.getStationVlan(blue3))
 	assert.Equal(t, -1, radio.getStationVlan(6))
+
+	// Per-station VLANs override the alliance VLANs.
+	radio.StationVlans = map[string]int{"red2": 110, "blue3": 120}
+	assert.Equal(t, 70, radio.getStationVlan(red1))
+	assert.Equal(t, 110, radio.getStationVlan(red2))
+	assert.Equal(t, 90, radio.getStationVlan(red3))
+	assert.Equal(t, 10, radio.getStationVlan(blue1))
+	assert.Equal(t, 20, radio.getStationVlan(blue2))
+	assert.Equal(t, 120, radio.getStationVlan(blue3))
 }
 
-func TestRadio_isStarted(t *testing.T) {
+func TestAccessPointRadio_isStarted(t *testing.T) {
 	fakeShell := newFakeShell(t)
 	shell = fakeShell
 	fakeShell.commandOutput["sh -c source /etc/openwrt_release && echo $DISTRIB_DESCRIPTION"] = ""
-	radio := NewRadio()
+	radio := NewAccessPointRadio()
 
 	// Radio is not started.
 	fakeShell.commandErrors["iwinfo wlan0-5 info"] = errors.New("failed")
@@ -112,27 +124,30 @@ func TestRadio_isStarted(t *testing.T) {
 	assert.True(t, ok)
 }
 
-func TestRadio_setInitialState(t *testing.T) {
+func TestAccessPointRadio_setInitialState(t *testing.T) {
 	fakeTree := newFakeUciTree()
 	uciTree = fakeTree
 	fakeTree.valuesForGet["system.@system[0].model"] = "VH-109(AP)"
 	fakeShell := newFakeShell(t)
 	shell = fakeShell
 	fakeShell.commandOutput["cat /etc/vh_firmware"] = ""
-	radio := NewRadio()
+	radio := NewAccessPointRadio()
 
 	fakeTree.valuesForGet["wireless.wifi1.channel"] = "23"
 	fakeTree.valuesForGet["wireless.wifi1.htmode"] = "HT20"
 	fakeTree.valuesForGet["system.@system[0].log_ip"] = "10.20.30.40"
-	fakeShell.commandOutput["iwinfo ath1 info"] = "ath1\nESSID: \"1111\"\n"
+	fakeShell.commandOutput["iwinfo ath1 info"] = "ath1\nESSID: \"1111\"\nTx-Power: 21 dBm\n"
 	fakeShell.commandOutput["iwinfo ath11 info"] = "ath11\nESSID: \"no-team-2\"\n"
 	fakeShell.commandOutput["iwinfo ath12 info"] = "ath12\nESSID: \"no-team-3\"\n"
 	fakeShell.commandOutput["iwinfo ath13 info"] = "ath13\nESSID: \"no-team-4\"\n"
 	fakeShell.commandOutput["iwinfo ath14 info"] = "ath14\nESSID: \"no-team-5\"\n"
 	fakeShell.commandOutput["iwinfo ath15 info"] = "ath15\nESSID: \"6666\"\n"
+	allowClearingBandwidthLimits(fakeShell, radio)
+	radio.bandwidthLimits[red1] = 4
 	radio.setInitialState()
 	assert.Equal(t, 23, radio.Channel)
 	assert.Equal(t, "20MHz", radio.ChannelBandwidth)
+	assert.Equal(t, 21, radio.TxPower)
 	assert.Equal(t, "1111", radio.StationStatuses["red1"].Ssid)
 	assert.Nil(t, radio.StationStatuses["red2"])
 	assert.Nil(t, radio.StationStatuses["red3"])
@@ -140,9 +155,23 @@ func TestRadio_setInitialState(t *testing.T) {
 	assert.Nil(t, radio.StationStatuses["blue2"])
 	assert.Equal(t, "6666", radio.StationStatuses["blue3"].Ssid)
 	assert.Equal(t, "10.20.30.40", radio.SyslogIpAddress)
+
+	// Bandwidth limits left behind by a previous run are removed, since they can't be known.
+	assert.Contains(t, fakeShell.commandsRun, "tc qdisc del dev ath1 root")
+	assert.Contains(t, fakeShell.commandsRun, "tc qdisc del dev ath15 ingress")
+	assert.Empty(t, radio.bandwidthLimits)
+	assert.Equal(t, 0.0, radio.StationStatuses["red1"].BandwidthLimitMbps)
+
+	// Wide HE channels are reported with their bandwidth, and unsupported modes as invalid.
+	fakeTree.valuesForGet["wireless.wifi1.htmode"] = "HE160"
+	radio.setInitialState()
+	assert.Equal(t, "160MHz", radio.ChannelBandwidth)
+	fakeTree.valuesForGet["wireless.wifi1.htmode"] = "EHT320"
+	radio.setInitialState()
+	assert.Equal(t, "INVALID", radio.ChannelBandwidth)
 }
 
-func TestRadio_handleConfigurationRequestVividHosting(t *testing.T) {
+func TestAccessPointRadio_handleConfigurationRequestVividHosting(t *testing.T) {
 	fakeTree := newFakeUciTree()
 	uciTree = fakeTree
 	fakeTree.valuesForGet["system.@system[0].model"] = "VH-109(AP)"
@@ -150,7 +179,7 @@ func TestRadio_handleConfigurationRequestVividHosting(t *testing.T) {
 	shell = fakeShell
 	wifiReloadBackoffDuration = 10 * time.Millisecond
 	fakeShell.commandOutput["cat /etc/vh_firmware"] = ""
-	radio := NewRadio()
+	radio := NewAccessPointRadio()
 
 	fakeShell.commandOutput["/etc/init.d/log restart"] = ""
 	fakeShell.commandOutput["wifi reload wifi1"] = ""
@@ -160,15 +189,15 @@ func TestRadio_handleConfigurationRequestVividHosting(t *testing.T) {
 	fakeShell.commandOutput["iwinfo ath13 info"] = "ath13\nESSID: \"no-team-4\"\n"
 	fakeShell.commandOutput["iwinfo ath14 info"] = "ath14\nESSID: \"5555\"\n"
 	fakeShell.commandOutput["iwinfo ath15 info"] = "ath15\nESSID: \"6666\"\n"
-	dummyRequest1 := ConfigurationRequest{
+	dummyRequest1 := &AccessPointConfigurationRequest{
 		Channel:               1,
 		StationConfigurations: map[string]StationConfiguration{"red1": {Ssid: "1", WpaKey: "foo"}},
 	}
-	dummyRequest2 := ConfigurationRequest{
+	dummyRequest2 := &AccessPointConfigurationRequest{
 		Channel:               2,
 		StationConfigurations: map[string]StationConfiguration{"blue2": {Ssid: "2", WpaKey: "bar"}},
 	}
-	request := ConfigurationRequest{
+	request := &AccessPointConfigurationRequest{
 		Channel: 5,
 		StationConfigurations: map[string]StationConfiguration{
 			"red1":  {Ssid: "1111", WpaKey: "11111111"},
@@ -178,9 +207,10 @@ func TestRadio_handleConfigurationRequestVividHosting(t *testing.T) {
 		},
 		SyslogIpAddress: "12.34.56.78",
 	}
-	radio.ConfigurationRequestChannel <- dummyRequest2
-	radio.ConfigurationRequestChannel <- request
-	assert.Nil(t, radio.handleConfigurationRequest(dummyRequest1))
+	dummyJob1 := radio.QueueConfigurationRequest(dummyRequest1)
+	dummyJob2 := radio.QueueConfigurationRequest(dummyRequest2)
+	job := radio.QueueConfigurationRequest(request)
+	assert.Nil(t, radio.handleConfigurationRequest(<-radio.ConfigurationRequestChannel))
 	assert.Equal(t, 26, fakeTree.setCount)
 	assert.Equal(t, fakeTree.valuesFromSet["wireless.wifi1.channel"], "5")
 	assert.Equal(t, fakeTree.valuesFromSet["system.@system[0].log_ip"], "12.34.56.78")
@@ -226,9 +256,24 @@ func TestRadio_handleConfigurationRequestVividHosting(t *testing.T) {
 	assert.Nil(t, radio.StationStatuses["blue1"])
 	assert.Equal(t, "5555", radio.StationStatuses["blue2"].Ssid)
 	assert.Equal(t, "6666", radio.StationStatuses["blue3"].Ssid)
+
+	// The older requests should have been superseded by the latest one.
+	for _, dummyJob := range []ConfigurationJob{dummyJob1, dummyJob2} {
+		dummyJob, _ = radio.GetConfigurationJob(dummyJob.Id)
+		assert.Equal(t, jobSuperseded, dummyJob.Status)
+		assert.Equal(t, 0, dummyJob.Attempts)
+		assert.Nil(t, dummyJob.StartedAt)
+		assert.NotNil(t, dummyJob.FinishedAt)
+	}
+	job, _ = radio.GetConfigurationJob(job.Id)
+	assert.Equal(t, jobSucceeded, job.Status)
+	assert.Equal(t, 1, job.Attempts)
+	assert.NotNil(t, job.StartedAt)
+	assert.NotNil(t, job.FinishedAt)
+	assert.Equal(t, "", job.Error)
 }
 
-func TestRadio_handleConfigurationRequestLinksys(t *testing.T) {
+func TestAccessPointRadio_handleConfigurationRequestLinksys(t *testing.T) {
 	fakeTree := newFakeUciTree()
 	uciTree = fakeTree
 	fakeTree.valuesForGet["system.@system[0].model"] = ""
@@ -236,7 +281,7 @@ func TestRadio_handleConfigurationRequestLinksys(t *testing.T) {
 	shell = fakeShell
 	wifiReloadBackoffDuration = 100 * time.Millisecond
 	fakeShell.commandOutput["sh -c source /etc/openwrt_release && echo $DISTRIB_DESCRIPTION"] = ""
-	radio := NewRadio()
+	radio := NewAccessPointRadio()
 
 	fakeShell.commandOutput["wifi reload radio0"] = ""
 	fakeShell.commandOutput["iwinfo wlan0 info"] = "wlan0\nESSID: \"no-team-1\"\n"
@@ -245,15 +290,15 @@ func TestRadio_handleConfigurationRequestLinksys(t *testing.T) {
 	fakeShell.commandOutput["iwinfo wlan0-3 info"] = "wlan0-3\nESSID: \"no-team-4\"\n"
 	fakeShell.commandOutput["iwinfo wlan0-4 info"] = "wlan0-4\nESSID: \"no-team-5\"\n"
 	fakeShell.commandOutput["iwinfo wlan0-5 info"] = "wlan0-5\nESSID: \"no-team-6\"\n"
-	dummyRequest1 := ConfigurationRequest{
+	dummyRequest1 := &AccessPointConfigurationRequest{
 		Channel:               1,
 		StationConfigurations: map[string]StationConfiguration{"red1": {Ssid: "1", WpaKey: "foo"}},
 	}
-	dummyRequest2 := ConfigurationRequest{
+	dummyRequest2 := &AccessPointConfigurationRequest{
 		Channel:               2,
 		StationConfigurations: map[string]StationConfiguration{"blue2": {Ssid: "2", WpaKey: "bar"}},
 	}
-	request := ConfigurationRequest{
+	request := &AccessPointConfigurationRequest{
 		Channel: 5,
 		StationConfigurations: map[string]StationConfiguration{
 			"red2":  {Ssid: "2222", WpaKey: "22222222"},
@@ -340,7 +385,7 @@ func TestRadio_handleConfigurationRequestLinksys(t *testing.T) {
 	assert.Contains(t, fakeShell.commandsRun, "iwinfo wlan0-5 info")
 }
 
-func TestRadio_handleConfigurationRequestSpareVlans(t *testing.T) {
+func TestAccessPointRadio_handleConfigurationRequestSpareVlans(t *testing.T) {
 	fakeTree := newFakeUciTree()
 	uciTree = fakeTree
 	fakeTree.valuesForGet["system.@system[0].model"] = "VH-109(AP)"
@@ -348,7 +393,7 @@ func TestRadio_handleConfigurationRequestSpareVlans(t *testing.T) {
 	shell = fakeShell
 	wifiReloadBackoffDuration = 10 * time.Millisecond
 	fakeShell.commandOutput["cat /etc/vh_firmware"] = ""
-	radio := NewRadio()
+	radio := NewAccessPointRadio()
 
 	fakeShell.commandOutput["wifi reload wifi1"] = ""
 	fakeShell.commandOutput["iwinfo ath1 info"] = "ath1\nESSID: \"1111\"\n"
@@ -357,7 +402,7 @@ func TestRadio_handleConfigurationRequestSpareVlans(t *testing.T) {
 	fakeShell.commandOutput["iwinfo ath13 info"] = "ath13\nESSID: \"no-team-4\"\n"
 	fakeShell.commandOutput["iwinfo ath14 info"] = "ath14\nESSID: \"5555\"\n"
 	fakeShell.commandOutput["iwinfo ath15 info"] = "ath15\nESSID: \"6666\"\n"
-	request := ConfigurationRequest{
+	request := &AccessPointConfigurationRequest{
 		RedVlans:  Vlans708090,
 		BlueVlans: Vlans102030,
 		StationConfigurations: map[string]StationConfiguration{
@@ -411,7 +456,7 @@ func TestRadio_handleConfigurationRequestSpareVlans(t *testing.T) {
 	assert.Equal(t, "6666", radio.StationStatuses["blue3"].Ssid)
 }
 
-func TestRadio_handleConfigurationRequestErrors(t *testing.T) {
+func TestAccessPointRadio_handleConfigurationRequestErrors(t *testing.T) {
 	fakeTree := newFakeUciTree()
 	uciTree = fakeTree
 	fakeTree.valuesForGet["system.@system[0].model"] = "VH-109(AP)"
@@ -420,19 +465,27 @@ func TestRadio_handleConfigurationRequestErrors(t *testing.T) {
 	retryBackoffDuration = 10 * time.Millisecond
 	wifiReloadBackoffDuration = 10 * time.Millisecond
 	fakeShell.commandOutput["cat /etc/vh_firmware"] = ""
-	radio := NewRadio()
+	radio := NewAccessPointRadio()
 
 	// wifi reload fails.
 	fakeShell.commandErrors["wifi reload wifi1"] = errors.New("oops")
-	request := ConfigurationRequest{Channel: 5}
+	request := &AccessPointConfigurationRequest{Channel: 5}
+	job := radio.QueueConfigurationRequest(request)
 	assert.Equal(
-		t, "failed to reload configuration for device wifi1: oops", radio.handleConfigurationRequest(request).Error(),
+		t,
+		"failed to reload configuration for device wifi1: oops",
+		radio.handleConfigurationRequest(<-radio.ConfigurationRequestChannel).Error(),
 	)
+	job, _ = radio.GetConfigurationJob(job.Id)
+	assert.Equal(t, jobFailed, job.Status)
+	assert.Equal(t, 1, job.Attempts)
+	assert.Equal(t, "failed to reload configuration for device wifi1: oops", job.Error)
 
 	// iwinfo fails.
 	fakeTree.reset()
 	fakeShell.reset()
 	fakeShell.commandOutput["wifi reload wifi1"] = ""
+	allowClearingBandwidthLimits(fakeShell, radio)
 	fakeShell.commandErrors["iwinfo ath1 info"] = errors.New("oops")
 	fakeShell.commandOutput["iwinfo ath11 info"] = "ath11\nESSID: \"no-team-2\"\n"
 	fakeShell.commandOutput["iwinfo ath12 info"] = "ath12\nESSID: \"no-team-3\"\n"
@@ -449,6 +502,7 @@ func TestRadio_handleConfigurationRequestErrors(t *testing.T) {
 	fakeTree.reset()
 	fakeShell.reset()
 	fakeShell.commandOutput["wifi reload wifi1"] = ""
+	allowClearingBandwidthLimits(fakeShell, radio)
 	fakeShell.commandOutput["iwinfo ath1 info"] = "ath1\nESSID: \"1111\"\n"
 	fakeShell.commandOutput["iwinfo ath11 info"] = "ath11\nESSID: \"no-team-2\"\n"
 	fakeShell.commandOutput["iwinfo ath12 info"] = "ath12\nESSID: \"3333\"\n"
@@ -465,6 +519,7 @@ func TestRadio_handleConfigurationRequestErrors(t *testing.T) {
 	fakeTree.reset()
 	fakeShell.reset()
 	fakeShell.commandOutput["wifi reload wifi1"] = ""
+	allowClearingBandwidthLimits(fakeShell, radio)
 	fakeShell.commandOutput["iwinfo ath1 info"] = "ath1\nESSID: \"1111\"\n"
 	fakeShell.commandOutput["iwinfo ath11 info"] = "ath11\nESSID: \"no-team-2\"\n"
 	fakeShell.commandOutput["iwinfo ath12 info"] = "ath12\nESSID: \"no-team-3\"\n"
@@ -475,19 +530,481 @@ func TestRadio_handleConfigurationRequestErrors(t *testing.T) {
 		time.Sleep(100 * time.Millisecond)
 		fakeShell.commandOutput["iwinfo ath1 info"] = "ath1\nESSID: \"no-team-1\"\n"
 	}()
-	assert.Nil(t, radio.handleConfigurationRequest(request))
+	job = radio.QueueConfigurationRequest(request)
+	assert.Nil(t, radio.handleConfigurationRequest(<-radio.ConfigurationRequestChannel))
 	assert.Greater(t, fakeTree.commitCount, 20)
+	assert.Equal(t, 4, radio.Counters().ConfigurationAttempts)
+	assert.Greater(t, radio.Counters().ConfigurationRetries, 2)
+	job, _ = radio.GetConfigurationJob(job.Id)
+	assert.Equal(t, jobSucceeded, job.Status)
+	assert.Equal(t, radio.Counters().ConfigurationRetries+1, job.Attempts)
+
+	// Loop gives up after the maximum number of attempts.
+	fakeShell.commandOutput["iwinfo ath1 info"] = "ath1\nESSID: \"1111\"\n"
+	radio.MaxConfigurationAttempts = 3
+	job = radio.QueueConfigurationRequest(request)
+	err := radio.handleConfigurationRequest(<-radio.ConfigurationRequestChannel)
+	assert.Equal(t, "wireless configuration still incorrect after 3 attempts; giving up", err.Error())
+	assert.Equal(t, statusError, radio.Status)
+	job, _ = radio.GetConfigurationJob(job.Id)
+	assert.Equal(t, jobFailed, job.Status)
+	assert.Equal(t, 3, job.Attempts)
+
+	// Loop gives up once the timeout elapses.
+	radio.MaxConfigurationAttempts = 0
+	radio.ConfigurationTimeout = 50 * time.Millisecond
+	err = radio.handleConfigurationRequest(request)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.Contains(t, err.Error(), "configuration timed out after 50ms")
+	assert.Equal(t, statusError, radio.Status)
+
+	// Loop is preempted by a newer request.
+	radio.ConfigurationTimeout = DefaultConfigurationTimeout
+	job = radio.QueueConfigurationRequest(request)
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		radio.QueueConfigurationRequest(&AccessPointConfigurationRequest{Channel: 149})
+	}()
+	err = radio.handleConfigurationRequest(<-radio.ConfigurationRequestChannel)
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.Equal(t, statusConfiguring, radio.Status)
+	job, _ = radio.GetConfigurationJob(job.Id)
+	assert.Equal(t, jobSuperseded, job.Status)
+	if assert.Equal(t, 1, len(radio.ConfigurationRequestChannel)) {
+		request := (<-radio.ConfigurationRequestChannel).(*AccessPointConfigurationRequest)
+		assert.Equal(t, AccessPointChannel(149), request.Channel)
+	}
+}
+
+func TestAccessPointRadio_handleConfigurationRequestRollback(t *testing.T) {
+	fakeTree := newFakeUciTree()
+	uciTree = fakeTree
+	fakeTree.valuesForGet["system.@system[0].model"] = "VH-109(AP)"
+	fakeShell := newFakeShell(t)
+	shell = fakeShell
+	wifiReloadBackoffDuration = 10 * time.Millisecond
+	fakeShell.commandOutput["cat /etc/vh_firmware"] = ""
+	radio := NewAccessPointRadio()
+
+	// Set up the existing configuration.
+	fakeTree.valuesForGet["wireless.wifi1.channel"] = "93"
+	fakeTree.valuesForGet["wireless.wifi1.htmode"] = "HT20"
+	fakeTree.valuesForGet["wireless.@wifi-iface[1].ssid"] = "1111"
+	fakeTree.valuesForGet["wireless.@wifi-iface[1].key"] = "11111111"
+	fakeTree.valuesForGet["wireless.@wifi-iface[1].network"] = "vlan10"
+	fakeTree.valuesForGet["system.@system[0].log_ip"] = "10.0.100.40"
+	fakeShell.commandErrors["iwinfo ath1 info"] = errors.New("oops")
+	allowClearingBandwidthLimits(fakeShell, radio)
+	radio.setInitialState()
+
+	// Configuration fails partway through and is rolled back.
+	fakeShell.commandOutput["/etc/init.d/log restart"] = ""
+	fakeShell.commandOutput["wifi reload wifi1"] = ""
+	request := &AccessPointConfigurationRequest{
+		Channel:               5,
+		ChannelBandwidth:      "40MHz",
+		RedVlans:              Vlans708090,
+		BlueVlans:             Vlans102030,
+		StationConfigurations: map[string]StationConfiguration{"red1": {Ssid: "9999", WpaKey: "99999999"}},
+		SyslogIpAddress:       "12.34.56.78",
+	}
+	job := radio.jobs.create()
+	request.setJobId(job.Id)
+	err := radio.handleConfigurationRequest(request)
+	assert.Equal(t, "error updating station statuses: error getting iwinfo for interface ath1: oops", err.Error())
+	job, _ = radio.GetConfigurationJob(job.Id)
+	assert.Equal(t, jobFailed, job.Status)
+	assert.Equal(t, err.Error(), job.Error)
+	assert.True(t, job.RolledBack)
+	assert.Equal(t, "", job.RollbackError)
+	assert.Equal(t, "93", fakeTree.valuesFromSet["wireless.wifi1.channel"])
+	assert.Equal(t, "HT20", fakeTree.valuesFromSet["wireless.wifi1.htmode"])
+	assert.Equal(t, "1111", fakeTree.valuesFromSet["wireless.@wifi-iface[1].ssid"])
+	assert.Equal(t, "11111111", fakeTree.valuesFromSet["wireless.@wifi-iface[1].key"])
+	assert.Equal(t, "***DELETED***", fakeTree.valuesFromSet["wireless.@wifi-iface[1].sae_password"])
+	assert.Equal(t, "vlan10", fakeTree.valuesFromSet["wireless.@wifi-iface[1].network"])
+	assert.Equal(t, "***DELETED***", fakeTree.valuesFromSet["wireless.@wifi-iface[2].ssid"])
+	assert.Equal(t, "10.0.100.40", fakeTree.valuesFromSet["system.@system[0].log_ip"])
+	assert.Equal(t, statusError, radio.Status)
+	assert.Equal(t, err.Error(), radio.ConfigurationError)
+	assert.True(t, radio.RolledBack)
+	assert.Equal(t, 93, radio.Channel)
+	assert.Equal(t, "20MHz", radio.ChannelBandwidth)
+	assert.Equal(t, Vlans102030, radio.RedVlans)
+	assert.Equal(t, Vlans405060, radio.BlueVlans)
+	assert.Equal(t, "10.0.100.40", radio.SyslogIpAddress)
+
+	// Rollback also fails.
+	fakeShell.reset()
+	fakeShell.commandOutput["/etc/init.d/log restart"] = ""
+	fakeShell.commandErrors["wifi reload wifi1"] = errors.New("oops")
+	job = radio.jobs.create()
+	request.setJobId(job.Id)
+	err = radio.handleConfigurationRequest(request)
+	assert.Equal(t, "failed to reload configuration for device wifi1: oops", err.Error())
+	job, _ = radio.GetConfigurationJob(job.Id)
+	assert.Equal(t, jobFailed, job.Status)
+	assert.False(t, job.RolledBack)
+	assert.Equal(t, "failed to reload configuration for device wifi1: oops", job.RollbackError)
+	assert.Equal(t, statusError, radio.Status)
+	assert.Equal(
+		t,
+		"failed to reload configuration for device wifi1: oops; rollback also failed: failed to reload "+
+			"configuration for device wifi1: oops",
+		radio.ConfigurationError,
+	)
+	assert.False(t, radio.RolledBack)
+
+	// A subsequent successful configuration clears the error.
+	fakeShell.reset()
+	fakeShell.commandOutput["wifi reload wifi1"] = ""
+	fakeShell.commandOutput["iwinfo ath1 info"] = "ath1\nESSID: \"no-team-1\"\n"
+	fakeShell.commandOutput["iwinfo ath11 info"] = "ath11\nESSID: \"no-team-2\"\n"
+	fakeShell.commandOutput["iwinfo ath12 info"] = "ath12\nESSID: \"no-team-3\"\n"
+	fakeShell.commandOutput["iwinfo ath13 info"] = "ath13\nESSID: \"no-team-4\"\n"
+	fakeShell.commandOutput["iwinfo ath14 info"] = "ath14\nESSID: \"no-team-5\"\n"
+	fakeShell.commandOutput["iwinfo ath15 info"] = "ath15\nESSID: \"no-team-6\"\n"
+	assert.Nil(t, radio.handleConfigurationRequest(&AccessPointConfigurationRequest{Channel: 5}))
+	assert.Equal(t, statusActive, radio.Status)
+	assert.Equal(t, "", radio.ConfigurationError)
+	assert.False(t, radio.RolledBack)
+}
+
+func TestAccessPointRadio_handleConfigurationRequestRollbackAfterPreemption(t *testing.T) {
+	fakeTree := newFakeUciTree()
+	uciTree = fakeTree
+	fakeTree.valuesForGet["system.@system[0].model"] = "VH-109(AP)"
+	fakeShell := newFakeShell(t)
+	shell = fakeShell
+	wifiReloadBackoffDuration = 10 * time.Millisecond
+	fakeShell.commandOutput["cat /etc/vh_firmware"] = ""
+	radio := NewAccessPointRadio()
+
+	// Set up the existing configuration.
+	fakeTree.valuesForGet["wireless.wifi1.channel"] = "93"
+	fakeTree.valuesForGet["wireless.wifi1.htmode"] = "HT20"
+	fakeShell.commandErrors["iwinfo ath1 info"] = errors.New("oops")
+	allowClearingBandwidthLimits(fakeShell, radio)
+	radio.setInitialState()
+
+	// The first request is preempted by a newer one while it is still waiting for the configuration to take effect.
+	fakeShell.commandOutput["wifi reload wifi1"] = ""
+	delete(fakeShell.commandErrors, "iwinfo ath1 info")
+	fakeShell.commandOutput["iwinfo ath1 info"] = "ath1\nESSID: \"1111\"\n"
+	for i, wifiInterface := range []string{"ath11", "ath12", "ath13", "ath14", "ath15"} {
+		fakeShell.commandOutput["iwinfo "+wifiInterface+" info"] = fmt.Sprintf(
+			"%s\nESSID: \"no-team-%d\"\n", wifiInterface, i+2,
+		)
+	}
+	job := radio.QueueConfigurationRequest(
+		&AccessPointConfigurationRequest{
+			Channel: 5, ChannelBandwidth: "40MHz", RedVlans: Vlans708090, BlueVlans: Vlans102030,
+		},
+	)
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		radio.QueueConfigurationRequest(&AccessPointConfigurationRequest{Channel: 37})
+	}()
+	err := radio.handleConfigurationRequest(<-radio.ConfigurationRequestChannel)
+	assert.ErrorIs(t, err, context.Canceled)
+	job, _ = radio.GetConfigurationJob(job.Id)
+	assert.Equal(t, jobSuperseded, job.Status)
+	assert.Equal(t, "40MHz", radio.ChannelBandwidth)
+
+	// The newer request fails and is rolled back to the configuration from before the preempted request, rather than
+	// to the half-applied one it left behind, which is simulated as having been committed.
+	fakeTree.valuesForGet["wireless.wifi1.channel"] = "5"
+	fakeTree.valuesForGet["wireless.wifi1.htmode"] = "HT40"
+	delete(fakeShell.commandOutput, "iwinfo ath1 info")
+	fakeShell.commandErrors["iwinfo ath1 info"] = errors.New("oops")
+	err = radio.handleConfigurationRequest(<-radio.ConfigurationRequestChannel)
+	assert.Equal(t, "error updating station statuses: error getting iwinfo for interface ath1: oops", err.Error())
+	assert.True(t, radio.RolledBack)
+	assert.Equal(t, "93", fakeTree.valuesFromSet["wireless.wifi1.channel"])
+	assert.Equal(t, "HT20", fakeTree.valuesFromSet["wireless.wifi1.htmode"])
+	assert.Equal(t, Vlans102030, radio.RedVlans)
+	assert.Equal(t, Vlans405060, radio.BlueVlans)
+
+	// The preempted request's snapshot is only used once.
+	assert.Nil(t, radio.preemptedSnapshot)
+}
+
+func TestAccessPointRadio_handleConfigurationRequestTxPower(t *testing.T) {
+	fakeTree := newFakeUciTree()
+	uciTree = fakeTree
+	fakeTree.valuesForGet["system.@system[0].model"] = "VH-109(AP)"
+	fakeShell := newFakeShell(t)
+	shell = fakeShell
+	wifiReloadBackoffDuration = 10 * time.Millisecond
+	fakeShell.commandOutput["cat /etc/vh_firmware"] = ""
+	radio := NewAccessPointRadio()
+
+	fakeShell.commandOutput["wifi reload wifi1"] = ""
+	for i, wifiInterface := range []string{"ath1", "ath11", "ath12", "ath13", "ath14", "ath15"} {
+		fakeShell.commandOutput["iwinfo "+wifiInterface+" info"] = fmt.Sprintf(
+			"%s\nESSID: \"no-team-%d\"\nTx-Power: 12 dBm\n", wifiInterface, i+1,
+		)
+	}
+	assert.Nil(t, radio.handleConfigurationRequest(&AccessPointConfigurationRequest{TxPower: 12}))
+	assert.Equal(t, statusActive, radio.Status)
+	assert.Equal(t, "12", fakeTree.valuesFromSet["wireless.wifi1.txpower"])
+	assert.Equal(t, 12, radio.TxPower)
+
+	// The transmit power is left alone if not specified, but still reported as the driver sees it.
+	fakeTree.reset()
+	fakeShell.commandOutput["iwinfo ath1 info"] = "ath1\nESSID: \"no-team-1\"\nTx-Power: unknown\n"
+	assert.Nil(t, radio.handleConfigurationRequest(&AccessPointConfigurationRequest{Channel: 5}))
+	assert.NotContains(t, fakeTree.valuesFromSet, "wireless.wifi1.txpower")
+	assert.Equal(t, 0, radio.TxPower)
+
+	// The transmit power is restored to the driver's default if requested.
+	fakeTree.reset()
+	fakeShell.commandOutput["iwinfo ath1 info"] = "ath1\nESSID: \"no-team-1\"\nTx-Power: 23 dBm\n"
+	assert.Nil(t, radio.handleConfigurationRequest(&AccessPointConfigurationRequest{TxPower: TxPowerDefault}))
+	assert.Equal(t, "***DELETED***", fakeTree.valuesFromSet["wireless.wifi1.txpower"])
+	assert.Equal(t, 23, radio.TxPower)
+}
+
+func TestAccessPointRadio_handleConfigurationRequestChannelBandwidth(t *testing.T) {
+	fakeTree := newFakeUciTree()
+	uciTree = fakeTree
+	fakeTree.valuesForGet["system.@system[0].model"] = "VH-109(AP)"
+	fakeShell := newFakeShell(t)
+	shell = fakeShell
+	wifiReloadBackoffDuration = 10 * time.Millisecond
+	fakeShell.commandOutput["cat /etc/vh_firmware"] = ""
+	radio := NewAccessPointRadio()
+
+	fakeShell.commandOutput["wifi reload wifi1"] = ""
+	for i, wifiInterface := range []string{"ath1", "ath11", "ath12", "ath13", "ath14", "ath15"} {
+		fakeShell.commandOutput["iwinfo "+wifiInterface+" info"] = fmt.Sprintf(
+			"%s\nESSID: \"no-team-%d\"\n", wifiInterface, i+1,
+		)
+	}
+	for channelBandwidth, htmode := range map[string]string{
+		"20MHz": "HE20", "40MHz": "HE40", "80MHz": "HE80", "160MHz": "HE160",
+	} {
+		fakeTree.reset()
+		assert.Nil(
+			t, radio.handleConfigurationRequest(&AccessPointConfigurationRequest{ChannelBandwidth: channelBandwidth}),
+		)
+		assert.Equal(t, htmode, fakeTree.valuesFromSet["wireless.wifi1.htmode"])
+		assert.Equal(t, channelBandwidth, radio.ChannelBandwidth)
+	}
+
+	// Automatic channel selection skips channels that can't be used at the requested bandwidth.
+	fakeTree.reset()
+	var scanOutput strings.Builder
+	for i, channel := range validChannels(TypeVividHosting) {
+		if channel != 229 {
+			scanOutput.WriteString(fmt.Sprintf("Cell %02d - Address: 48:DA:35:B0:20:%02X\n", i, i))
+			scanOutput.WriteString(fmt.Sprintf("Channel: %d\nSignal: -50 dBm\n", channel))
+		}
+	}
+	fakeShell.commandOutput["iwinfo wifi1 scan"] = scanOutput.String()
+	assert.Nil(
+		t,
+		radio.handleConfigurationRequest(
+			&AccessPointConfigurationRequest{Channel: AutoChannel, ChannelBandwidth: "80MHz"},
+		),
+	)
+	assert.Equal(t, "5", fakeTree.valuesFromSet["wireless.wifi1.channel"])
+	fakeTree.reset()
+	assert.Nil(
+		t,
+		radio.handleConfigurationRequest(
+			&AccessPointConfigurationRequest{Channel: AutoChannel, ChannelBandwidth: "40MHz"},
+		),
+	)
+	assert.Equal(t, "229", fakeTree.valuesFromSet["wireless.wifi1.channel"])
+}
+
+func TestAccessPointRadio_handleConfigurationRequestStationVlans(t *testing.T) {
+	fakeTree := newFakeUciTree()
+	uciTree = fakeTree
+	fakeTree.valuesForGet["system.@system[0].model"] = "VH-109(AP)"
+	fakeShell := newFakeShell(t)
+	shell = fakeShell
+	wifiReloadBackoffDuration = 10 * time.Millisecond
+	fakeShell.commandOutput["cat /etc/vh_firmware"] = ""
+	radio := NewAccessPointRadio()
+
+	fakeShell.commandOutput["wifi reload wifi1"] = ""
+	fakeShell.commandOutput["iwinfo ath1 info"] = "ath1\nESSID: \"1111\"\n"
+	fakeShell.commandOutput["iwinfo ath11 info"] = "ath11\nESSID: \"no-team-2\"\n"
+	fakeShell.commandOutput["iwinfo ath12 info"] = "ath12\nESSID: \"no-team-3\"\n"
+	fakeShell.commandOutput["iwinfo ath13 info"] = "ath13\nESSID: \"no-team-4\"\n"
+	fakeShell.commandOutput["iwinfo ath14 info"] = "ath14\nESSID: \"no-team-5\"\n"
+	fakeShell.commandOutput["iwinfo ath15 info"] = "ath15\nESSID: \"no-team-6\"\n"
+	stationConfigurations := map[string]StationConfiguration{"red1": {Ssid: "1111", WpaKey: "11111111"}}
+	request := &AccessPointConfigurationRequest{
+		RedVlans:              Vlans708090,
+		BlueVlans:             Vlans102030,
+		StationVlans:          map[string]int{"red1": 110, "blue3": 120},
+		StationConfigurations: stationConfigurations,
+	}
+	assert.Nil(t, radio.handleConfigurationRequest(request))
+	assert.Equal(t, map[string]int{"red1": 110, "blue3": 120}, radio.StationVlans)
+	assert.Equal(t, "vlan110", fakeTree.valuesFromSet["wireless.@wifi-iface[1].network"])
+	assert.Equal(t, "vlan80", fakeTree.valuesFromSet["wireless.@wifi-iface[2].network"])
+	assert.Equal(t, "vlan90", fakeTree.valuesFromSet["wireless.@wifi-iface[3].network"])
+	assert.Equal(t, "vlan10", fakeTree.valuesFromSet["wireless.@wifi-iface[4].network"])
+	assert.Equal(t, "vlan20", fakeTree.valuesFromSet["wireless.@wifi-iface[5].network"])
+	assert.Equal(t, "vlan120", fakeTree.valuesFromSet["wireless.@wifi-iface[6].network"])
+
+	// A request that omits the per-station VLANs leaves them unchanged.
+	fakeTree.reset()
+	assert.Nil(
+		t,
+		radio.handleConfigurationRequest(
+			&AccessPointConfigurationRequest{StationConfigurations: stationConfigurations},
+		),
+	)
+	assert.Equal(t, map[string]int{"red1": 110, "blue3": 120}, radio.StationVlans)
+	assert.Equal(t, "vlan110", fakeTree.valuesFromSet["wireless.@wifi-iface[1].network"])
+
+	// An empty map removes them.
+	fakeTree.reset()
+	request = &AccessPointConfigurationRequest{
+		StationVlans: map[string]int{}, StationConfigurations: stationConfigurations,
+	}
+	assert.Nil(t, radio.handleConfigurationRequest(request))
+	assert.Equal(t, map[string]int{}, radio.StationVlans)
+	assert.Equal(t, "vlan70", fakeTree.valuesFromSet["wireless.@wifi-iface[1].network"])
+	assert.Equal(t, "vlan30", fakeTree.valuesFromSet["wireless.@wifi-iface[6].network"])
+}
+
+func TestAccessPointRadio_handleConfigurationRequestAutoChannel(t *testing.T) {
+	fakeTree := newFakeUciTree()
+	uciTree = fakeTree
+	fakeTree.valuesForGet["system.@system[0].model"] = "VH-109(AP)"
+	fakeShell := newFakeShell(t)
+	shell = fakeShell
+	wifiReloadBackoffDuration = 10 * time.Millisecond
+	fakeShell.commandOutput["cat /etc/vh_firmware"] = ""
+	radio := NewAccessPointRadio()
+
+	fakeShell.commandOutput["wifi reload wifi1"] = ""
+	for i, wifiInterface := range []string{"ath1", "ath11", "ath12", "ath13", "ath14", "ath15"} {
+		fakeShell.commandOutput["iwinfo "+wifiInterface+" info"] = fmt.Sprintf(
+			"%s\nESSID: \"no-team-%d\"\n", wifiInterface, i+1,
+		)
+	}
+	allowClearingBandwidthLimits(fakeShell, radio)
+	fakeShell.commandOutput["iwinfo wifi1 scan"] = "Cell 01 - Address: 48:DA:35:B0:10:01\nChannel: 5\n" +
+		"Signal: -60 dBm\nCell 02 - Address: 48:DA:35:B0:10:02\nChannel: 13\nSignal: -75 dBm\n"
+	assert.Nil(t, radio.handleConfigurationRequest(&AccessPointConfigurationRequest{Channel: AutoChannel}))
+	assert.Equal(t, statusActive, radio.Status)
+	assert.Equal(t, "21", fakeTree.valuesFromSet["wireless.wifi1.channel"])
+	assert.Equal(t, 21, radio.Channel)
+
+	// The scan fails, leaving the previous channel in place.
+	fakeTree.reset()
+	fakeTree.valuesForGet["wireless.wifi1.channel"] = "21"
+	delete(fakeShell.commandOutput, "iwinfo wifi1 scan")
+	fakeShell.commandErrors["iwinfo wifi1 scan"] = errors.New("oops")
+	err := radio.handleConfigurationRequest(&AccessPointConfigurationRequest{Channel: AutoChannel})
+	assert.EqualError(
+		t, err, "failed to select channel automatically: error scanning for networks on device wifi1: oops",
+	)
+	assert.Equal(t, "21", fakeTree.valuesFromSet["wireless.wifi1.channel"])
+	assert.True(t, radio.RolledBack)
 }
 
-func TestRadio_updateMonitoring(t *testing.T) {
+func TestAccessPointRadio_handleConfigurationRequestBandwidthLimits(t *testing.T) {
+	fakeTree := newFakeUciTree()
+	uciTree = fakeTree
+	fakeTree.valuesForGet["system.@system[0].model"] = "VH-109(AP)"
+	fakeShell := newFakeShell(t)
+	shell = fakeShell
+	wifiReloadBackoffDuration = 10 * time.Millisecond
+	fakeShell.commandOutput["cat /etc/vh_firmware"] = ""
+	radio := NewAccessPointRadio()
+
+	// Configure two stations, one of which overrides the limit for the request as a whole.
+	fakeShell.commandOutput["wifi reload wifi1"] = ""
+	fakeShell.commandOutput["iwinfo ath1 info"] = "ath1\nESSID: \"254\"\n"
+	fakeShell.commandOutput["iwinfo ath11 info"] = "ath11\nESSID: \"no-team-2\"\n"
+	fakeShell.commandOutput["iwinfo ath12 info"] = "ath12\nESSID: \"no-team-3\"\n"
+	fakeShell.commandOutput["iwinfo ath13 info"] = "ath13\nESSID: \"no-team-4\"\n"
+	fakeShell.commandOutput["iwinfo ath14 info"] = "ath14\nESSID: \"1678\"\n"
+	fakeShell.commandOutput["iwinfo ath15 info"] = "ath15\nESSID: \"no-team-6\"\n"
+	for _, wifiInterface := range []string{"ath1", "ath14"} {
+		fakeShell.commandOutput["tc qdisc del dev "+wifiInterface+" root"] = ""
+		fakeShell.commandOutput["tc qdisc del dev "+wifiInterface+" ingress"] = ""
+		fakeShell.commandOutput["tc qdisc add dev "+wifiInterface+" handle ffff: ingress"] = ""
+	}
+	fakeShell.commandOutput["tc qdisc add dev ath1 root tbf rate 4mbit burst 50000 latency 50ms"] = ""
+	fakeShell.commandOutput["tc filter add dev ath1 parent ffff: protocol all u32 match u32 0 0 police rate 4mbit "+
+		"burst 50000 drop"] = ""
+	fakeShell.commandOutput["tc qdisc add dev ath14 root tbf rate 8mbit burst 100000 latency 50ms"] = ""
+	fakeShell.commandOutput["tc filter add dev ath14 parent ffff: protocol all u32 match u32 0 0 police rate 8mbit "+
+		"burst 100000 drop"] = ""
+	request := &AccessPointConfigurationRequest{
+		StationConfigurations: map[string]StationConfiguration{
+			"red1":  {Ssid: "254", WpaKey: "11111111"},
+			"blue2": {Ssid: "1678", WpaKey: "55555555", BandwidthLimitMbps: 8},
+		},
+		BandwidthLimitMbps: 4,
+	}
+	assert.Nil(t, radio.handleConfigurationRequest(request))
+	assert.Equal(t, statusActive, radio.Status)
+	assert.Contains(t, fakeShell.commandsRun, "tc qdisc add dev ath1 root tbf rate 4mbit burst 50000 latency 50ms")
+	assert.Contains(t, fakeShell.commandsRun, "tc qdisc add dev ath14 root tbf rate 8mbit burst 100000 latency 50ms")
+	assert.Equal(t, 4.0, radio.StationStatuses["red1"].BandwidthLimitMbps)
+	assert.Equal(t, 8.0, radio.StationStatuses["blue2"].BandwidthLimitMbps)
+
+	// The limits are kept in the status when it is refreshed.
+	assert.Nil(t, radio.updateStationStatuses())
+	assert.Equal(t, 4.0, radio.StationStatuses["red1"].BandwidthLimitMbps)
+	assert.Equal(t, 8.0, radio.StationStatuses["blue2"].BandwidthLimitMbps)
+
+	// A failed configuration restores the limits after reinitializing the state, which clears every station.
+	allowClearingBandwidthLimits(fakeShell, radio)
+	fakeShell.commandErrors["iwinfo wifi1 scan"] = errors.New("oops")
+	fakeShell.commandsRun = make(map[string]struct{})
+	assert.NotNil(t, radio.handleConfigurationRequest(&AccessPointConfigurationRequest{Channel: AutoChannel}))
+	assert.True(t, radio.RolledBack)
+	assert.Contains(t, fakeShell.commandsRun, "tc qdisc del dev ath12 root")
+	assert.Contains(t, fakeShell.commandsRun, "tc qdisc add dev ath1 root tbf rate 4mbit burst 50000 latency 50ms")
+	assert.Contains(t, fakeShell.commandsRun, "tc qdisc add dev ath14 root tbf rate 8mbit burst 100000 latency 50ms")
+	assert.Equal(t, map[station]float64{red1: 4, blue2: 8}, radio.bandwidthLimits)
+	assert.Equal(t, 4.0, radio.StationStatuses["red1"].BandwidthLimitMbps)
+	assert.Equal(t, 8.0, radio.StationStatuses["blue2"].BandwidthLimitMbps)
+
+	// Resetting the stations clears their rules.
+	fakeShell.reset()
+	fakeShell.commandOutput["wifi reload wifi1"] = ""
+	for i, wifiInterface := range []string{"ath1", "ath11", "ath12", "ath13", "ath14", "ath15"} {
+		fakeShell.commandOutput["iwinfo "+wifiInterface+" info"] = fmt.Sprintf(
+			"%s\nESSID: \"no-team-%d\"\n", wifiInterface, i+1,
+		)
+	}
+	fakeShell.commandOutput["tc qdisc del dev ath1 root"] = ""
+	fakeShell.commandOutput["tc qdisc del dev ath1 ingress"] = ""
+	fakeShell.commandOutput["tc qdisc del dev ath14 root"] = ""
+	fakeShell.commandOutput["tc qdisc del dev ath14 ingress"] = ""
+	assert.Nil(t, radio.handleConfigurationRequest(&AccessPointConfigurationRequest{Channel: 5}))
+	assert.Equal(t, statusActive, radio.Status)
+	assert.Contains(t, fakeShell.commandsRun, "tc qdisc del dev ath1 root")
+	assert.Contains(t, fakeShell.commandsRun, "tc qdisc del dev ath14 ingress")
+	assert.Empty(t, radio.bandwidthLimits)
+	assert.Nil(t, radio.StationStatuses["red1"])
+	assert.Nil(t, radio.StationStatuses["blue2"])
+}
+
+func TestAccessPointRadio_updateMonitoring(t *testing.T) {
+	uciTree = newFakeUciTree()
 	fakeShell := newFakeShell(t)
 	shell = fakeShell
 	fakeShell.commandOutput["sh -c source /etc/openwrt_release && echo $DISTRIB_DESCRIPTION"] = ""
-	radio := NewRadio()
+	radio := NewAccessPointRadio()
+	listener := radio.SubscribeStatusChanges()
 
 	// No teams assigned.
 	radio.updateMonitoring()
 	assert.Equal(t, 1, len(fakeShell.commandsRun))
+	assert.Equal(t, 0, len(listener))
 
 	// Some teams assigned.
 	fakeShell.reset()
@@ -551,4 +1068,102 @@ func TestRadio_updateMonitoring(t *testing.T) {
 	assert.Contains(t, fakeShell.commandsRun, "luci-bwc -i wlan0-4")
 	assert.Contains(t, fakeShell.commandsRun, "iwinfo wlan0-4 assoclist")
 	assert.Contains(t, fakeShell.commandsRun, "ifconfig wlan0-4")
+	assert.Equal(
+		t, map[string]int{"luci-bwc": 1, "iwinfo": 1, "ifconfig": 1}, radio.Counters().MonitoringCommandFailures,
+	)
+	assert.Equal(t, 1, len(listener))
+
+	// Link state unchanged.
+	<-listener
+	radio.updateMonitoring()
+	assert.Equal(t, 0, len(listener))
+
+	// Each poll of an assigned station is recorded in its link history.
+	history, err := radio.LinkHistory("red1", time.Time{})
+	assert.Nil(t, err)
+	if assert.Equal(t, 2, len(history)) {
+		assert.Equal(t, "48:DA:35:B0:00:CF", history[0].MacAddress)
+		assert.Equal(t, 550.6, history[1].RxRateMbps)
+		assert.False(t, history[1].Time.Before(history[0].Time))
+	}
+	history, err = radio.LinkHistory("red2", time.Time{})
+	assert.Nil(t, err)
+	assert.Empty(t, history)
+	_, err = radio.LinkHistory("6GHz", time.Time{})
+	assert.EqualError(t, err, "invalid network \"6GHz\" (expecting one of red1, red2, red3, blue1, blue2, blue3)")
+
+	// The association of red1 is recorded as a link event.
+	events, err := radio.LinkEvents("", 0)
+	assert.Nil(t, err)
+	if assert.Equal(t, 1, len(events)) {
+		assert.Equal(t, "red1", events[0].Station)
+		assert.Equal(t, linkEventAssociated, events[0].Type)
+		assert.Equal(t, "48:DA:35:B0:00:CF", events[0].After.MacAddress)
+		assert.Equal(t, "excellent", events[0].After.ConnectionQuality)
+	}
+	events, err = radio.LinkEvents("blue2", 0)
+	assert.Nil(t, err)
+	assert.Empty(t, events)
+	_, err = radio.LinkEvents("2.4GHz", 0)
+	assert.EqualError(t, err, "invalid network \"2.4GHz\" (expecting one of red1, red2, red3, blue1, blue2, blue3)")
+}
+
+func TestAccessPointRadio_Snapshot(t *testing.T) {
+	fakeShell := newFakeShell(t)
+	shell = fakeShell
+	fakeShell.commandOutput["sh -c source /etc/openwrt_release && echo $DISTRIB_DESCRIPTION"] = ""
+	radio := NewAccessPointRadio()
+	radio.Channel = 149
+	radio.Status = statusActive
+	radio.StationStatuses["blue2"] = &NetworkStatus{
+		Ssid: "254", IsLinked: true, AssociatedClients: []AssociatedClient{{MacAddress: "48:DA:35:B0:00:CF"}},
+	}
+	radio.StationVlans["red1"] = 110
+
+	snapshot := radio.Snapshot()
+	assert.Equal(t, 149, snapshot.Channel)
+	assert.Equal(t, map[string]int{"red1": 110}, snapshot.StationVlans)
+	assert.Equal(t, statusActive, snapshot.Status)
+	assert.Equal(t, TypeLinksys, snapshot.Type)
+	assert.Equal(t, radio.StationStatuses, snapshot.StationStatuses)
+	assert.Nil(t, snapshot.ConfigurationRequestChannel)
+
+	// Modifying the radio shouldn't affect the snapshot.
+	radio.StationStatuses["blue2"].IsLinked = false
+	radio.StationStatuses["blue2"].AssociatedClients[0].MacAddress = "5C:DA:35:B0:00:12"
+	radio.StationStatuses["red1"] = &NetworkStatus{Ssid: "1114"}
+	radio.StationVlans["red1"] = 120
+	assert.True(t, snapshot.StationStatuses["blue2"].IsLinked)
+	assert.Equal(t, "48:DA:35:B0:00:CF", snapshot.StationStatuses["blue2"].AssociatedClients[0].MacAddress)
+	assert.Nil(t, snapshot.StationStatuses["red1"])
+	assert.Equal(t, 110, snapshot.StationVlans["red1"])
+}
+
+func TestAccessPointRadio_SnapshotConcurrentWithMonitoring(t *testing.T) {
+	fakeShell := newFakeShell(t)
+	shell = fakeShell
+	fakeShell.commandOutput["sh -c source /etc/openwrt_release && echo $DISTRIB_DESCRIPTION"] = ""
+	radio := NewAccessPointRadio()
+	radio.StationStatuses["red1"] = &NetworkStatus{Ssid: "254"}
+	fakeShell.commandOutput["luci-bwc -i wlan0"] = ""
+	fakeShell.commandOutput["iwinfo wlan0 assoclist"] = "48:DA:35:B0:00:CF  -53 dBm / -95 dBm (SNR 42)  0 ms ago\n"
+	fakeShell.commandOutput["ifconfig wlan0"] = ""
+
+	done := make(chan struct{})
+	go func() {
+		for i := 0; i < 100; i++ {
+			radio.updateMonitoring()
+		}
+		close(done)
+	}()
+	for {
+		select {
+		case <-done:
+			assert.True(t, radio.Snapshot().StationStatuses["red1"].IsLinked)
+			return
+		default:
+			_, err := json.Marshal(radio.Snapshot())
+			assert.Nil(t, err)
+		}
+	}
 }
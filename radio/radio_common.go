@@ -1,14 +1,18 @@
 package radio
 
 import (
+	"context"
 	"crypto/sha256"
 	"encoding/hex"
+	"errors"
 	"fmt"
 	"github.com/digineo/go-uci"
 	"log"
 	"math/rand"
 	"regexp"
+	"strconv"
 	"strings"
+	"sync"
 	"time"
 )
 
@@ -28,6 +32,12 @@ const (
 	// How long to wait between retries when configuring the radio.
 	retryBackoffSec = 3
 
+	// Default maximum number of attempts to make at getting the radio to reflect a configuration request.
+	DefaultMaxConfigurationAttempts = 10
+
+	// Default maximum amount of time to spend applying a configuration request.
+	DefaultConfigurationTimeout = 2 * time.Minute
+
 	// Minimum length for WPA keys.
 	minWpaKeyLength = 8
 
@@ -42,8 +52,28 @@ const (
 
 	// Length of the randomly generated salt used to obscure the WPA key.
 	saltLength = 16
+
+	// Minimum transmit power in dBm that can be requested for any radio.
+	minTxPowerDbm = 1
+
+	// Maximum transmit power in dBm supported by the 5GHz radio of the Linksys access point.
+	maxTxPowerDbmLinksys = 23
+
+	// Maximum transmit power in dBm supported by the 6GHz radio of the Vivid-Hosting access point and robot radio.
+	maxTxPowerDbm6Ghz = 30
+
+	// Maximum transmit power in dBm supported by the 2.4GHz radio of the Vivid-Hosting robot radio.
+	maxTxPowerDbm24Ghz = 23
+
+	// Maximum transmit power in dBm of the robot radio in TEAM_ROBOT_RADIO mode, which applies the requested power to
+	// both its 6GHz and 2.4GHz radios and is therefore limited by the lower of their maximums.
+	maxTxPowerDbmTeamRobotRadio = maxTxPowerDbm24Ghz
 )
 
+// TxPowerDefault is the transmit power to request in order to remove any explicitly configured power and restore the
+// Wi-Fi driver's default.
+const TxPowerDefault = -1
+
 // RadioType represents the hardware type of the radio.
 //
 //go:generate stringer -type=RadioType
@@ -65,22 +95,136 @@ const (
 	statusError       radioStatus = "ERROR"
 )
 
+// radioBase holds the state and event loop that are common to both roles of the radio. Each role embeds it and
+// supplies the behavior that differs between them through the personality interface.
+type radioBase struct {
+	// Enum representing the current configuration stage of the radio.
+	Status radioStatus `json:"status"`
+
+	// Error encountered while applying the most recent configuration request. Blank if it succeeded.
+	ConfigurationError string `json:"configurationError"`
+
+	// Whether the radio was restored to its previous configuration after the most recent configuration request failed.
+	RolledBack bool `json:"rolledBack"`
+
+	// Version of the radio software.
+	Version string `json:"version"`
+
+	// Queue for receiving and buffering configuration requests.
+	ConfigurationRequestChannel chan ConfigurationRequest `json:"-"`
+
+	// Maximum number of attempts to make at getting the radio to reflect a configuration request before giving up.
+	// Zero means no limit.
+	MaxConfigurationAttempts int `json:"-"`
+
+	// Maximum amount of time to spend applying a configuration request before giving up. Zero means no limit.
+	ConfigurationTimeout time.Duration `json:"-"`
+
+	// Guards the exported state fields. They are only ever written by the goroutine running the radio event loop, which
+	// must hold the write lock while doing so; any other goroutine must hold the read lock or use Snapshot().
+	mutex sync.RWMutex
+
+	// Notifier for listeners interested in changes to the radio's status.
+	statusNotifier statusNotifier
+
+	// Cumulative totals of configuration and monitoring events, for exporting as metrics.
+	counters counterSet
+
+	// Record of the most recent configuration requests and their outcomes.
+	jobs configurationJobStore
+
+	// Rolling window of link metric samples for each network, recorded each time the radio is polled.
+	linkHistory linkHistory
+
+	// Record of the most recent transitions in the link state of the radio's networks.
+	linkEvents linkEventLog
+
+	// Cancels the configuration currently being applied so that a newer request can preempt it; nil if no
+	// configuration is in progress. Guarded by mutex.
+	cancelConfiguration context.CancelFunc
+
+	// Configuration that preceded a request which was preempted partway through being applied, to roll back to instead
+	// of the half-applied one if the next request fails; nil if the previous request wasn't preempted. Only accessed by
+	// the goroutine running the radio event loop.
+	preemptedSnapshot *configurationSnapshot
+
+	// Role-specific behavior of the radio, which is the struct embedding this one.
+	personality personality
+}
+
+// personality is implemented by each role of the radio to provide the parts of the event loop that differ between them.
+type personality interface {
+	// isStarted returns true if the Wi-Fi interface is up and running.
+	isStarted() bool
+
+	// setInitialState initializes the in-memory state to match the radio's current configuration.
+	setInitialState()
+
+	// configure configures the radio with the given configuration.
+	configure(ctx context.Context, request ConfigurationRequest) error
+
+	// reloadWifi applies the committed wireless configuration to the radio.
+	reloadWifi() error
+
+	// configurationUciOptions returns the UCI options that applying a configuration request may change.
+	configurationUciOptions() []uciOption
+
+	// StatusSnapshot returns a deep copy of the radio's current state.
+	StatusSnapshot() any
+
+	// restoreState reverts the in-memory state to that of the given status snapshot, once the UCI configuration it was
+	// taken alongside has been restored.
+	restoreState(previous any)
+
+	// updateMonitoring polls the radio for the current bandwidth usage and link state of its networks and updates the
+	// in-memory state.
+	updateMonitoring()
+
+	// networkNames returns the names by which the radio's networks are identified in its link history.
+	networkNames() []string
+}
+
 var uciTree = uci.NewTree(uci.DefaultTreePath)
 var shell shellWrapper = execShell{}
 var ssidRe = regexp.MustCompile("ESSID: \"([-\\w ]*)\"")
+var txPowerRe = regexp.MustCompile("Tx-Power: (\\d+) dBm")
 var retryBackoffDuration = retryBackoffSec * time.Second
 var wifiReloadBackoffDuration = wifiReloadBackoffSec * time.Second
 
+// newRadioBase creates the common state for a radio having the given role-specific behavior.
+func newRadioBase(personality personality) radioBase {
+	return radioBase{
+		Status:                      statusBooting,
+		ConfigurationRequestChannel: make(chan ConfigurationRequest, configurationRequestBufferSize),
+		MaxConfigurationAttempts:    DefaultMaxConfigurationAttempts,
+		ConfigurationTimeout:        DefaultConfigurationTimeout,
+		linkHistory:                 linkHistory{retention: DefaultLinkHistoryRetention},
+		personality:                 personality,
+	}
+}
+
+// snapshotBase returns a copy of the common exported state of the radio, for inclusion in a snapshot. The caller must
+// hold the read lock.
+func (radio *radioBase) snapshotBase() radioBase {
+	return radioBase{
+		Status:             radio.Status,
+		ConfigurationError: radio.ConfigurationError,
+		RolledBack:         radio.RolledBack,
+		Version:            radio.Version,
+	}
+}
+
 // Run loops indefinitely, handling configuration requests and polling the Wi-Fi status.
-func (radio *Radio) Run() {
-	for !radio.isStarted() {
+func (radio *radioBase) Run() {
+	for !radio.personality.isStarted() {
 		log.Println("Waiting for radio to finish starting up...")
 		time.Sleep(bootPollIntervalSec * time.Second)
 	}
 	log.Println("Radio ready.")
 
-	radio.setInitialState()
-	radio.Status = statusActive
+	radio.personality.setInitialState()
+	radio.setStatus(statusActive)
+	radio.statusNotifier.notify()
 
 	for {
 		// Check if there are any pending configuration requests; if not, periodically poll Wi-Fi status.
@@ -88,7 +232,7 @@ func (radio *Radio) Run() {
 		case request := <-radio.ConfigurationRequestChannel:
 			_ = radio.handleConfigurationRequest(request)
 		case <-time.After(monitoringPollIntervalSec * time.Second):
-			radio.updateMonitoring()
+			radio.personality.updateMonitoring()
 		}
 	}
 }
@@ -115,7 +259,7 @@ func TriggerFirmwareUpdate(firmwarePath string) {
 }
 
 // determineAndSetVersion determines the firmware version of the radio.
-func (radio *Radio) determineAndSetVersion() {
+func (radio *radioBase) determineAndSetVersion() {
 	model, _ := uciTree.GetLast("system", "@system[0]", "model")
 	var version string
 	var err error
@@ -132,28 +276,229 @@ func (radio *Radio) determineAndSetVersion() {
 	}
 }
 
-func (radio *Radio) handleConfigurationRequest(request ConfigurationRequest) error {
+// QueueConfigurationRequest assigns the given request a job for tracking its progress and adds it to the asynchronous
+// queue. Returns a copy of the job in its initial state.
+func (radio *radioBase) QueueConfigurationRequest(request ConfigurationRequest) ConfigurationJob {
+	job := radio.jobs.create()
+	request.setJobId(job.Id)
+	radio.ConfigurationRequestChannel <- request
+
+	// Preempt any configuration still in progress, since it will be superseded by this request anyway.
+	radio.mutex.RLock()
+	if radio.cancelConfiguration != nil {
+		radio.cancelConfiguration()
+	}
+	radio.mutex.RUnlock()
+
+	return job
+}
+
+// SetConfigurationLimits sets the maximum number of attempts and the maximum amount of time to spend applying each
+// configuration request before giving up. Zero means no limit. Must be called before Run.
+func (radio *radioBase) SetConfigurationLimits(maxAttempts int, timeout time.Duration) {
+	radio.MaxConfigurationAttempts = maxAttempts
+	radio.ConfigurationTimeout = timeout
+}
+
+// SetLinkHistoryRetention sets the amount of time for which to keep samples of each network's link metrics. Zero
+// disables the history. Must be called before Run.
+func (radio *radioBase) SetLinkHistoryRetention(retention time.Duration) {
+	radio.linkHistory.setRetention(retention)
+}
+
+// LinkHistory returns the samples of the given network's link metrics taken after the given time, oldest first.
+// Returns an error if the radio has no network by that name.
+func (radio *radioBase) LinkHistory(network string, since time.Time) ([]LinkSample, error) {
+	if err := radio.validateNetworkName(network); err != nil {
+		return nil, err
+	}
+	return radio.linkHistory.get(network, since, time.Now()), nil
+}
+
+// LinkEvents returns the recorded link events of the given network having an ID greater than the given one, oldest
+// first. A blank network returns the events of all networks. Returns an error if the radio has no network by that name.
+func (radio *radioBase) LinkEvents(network string, afterId int) ([]LinkEvent, error) {
+	events := radio.linkEvents.since(afterId)
+	if network == "" {
+		return events, nil
+	}
+	if err := radio.validateNetworkName(network); err != nil {
+		return nil, err
+	}
+	filteredEvents := make([]LinkEvent, 0)
+	for _, event := range events {
+		if event.Station == network {
+			filteredEvents = append(filteredEvents, event)
+		}
+	}
+	return filteredEvents, nil
+}
+
+// SubscribeLinkEvents returns a channel that receives a notification whenever a new link event is recorded. The caller
+// is responsible for calling UnsubscribeLinkEvents once it is no longer interested.
+func (radio *radioBase) SubscribeLinkEvents() chan struct{} {
+	return radio.linkEvents.notifier.subscribe()
+}
+
+// UnsubscribeLinkEvents stops notifications to a channel previously returned by SubscribeLinkEvents.
+func (radio *radioBase) UnsubscribeLinkEvents(listener chan struct{}) {
+	radio.linkEvents.notifier.unsubscribe(listener)
+}
+
+// validateNetworkName returns an error if the radio has no network by the given name.
+func (radio *radioBase) validateNetworkName(network string) error {
+	networkNames := radio.personality.networkNames()
+	for _, name := range networkNames {
+		if name == network {
+			return nil
+		}
+	}
+	return fmt.Errorf("invalid network %q (expecting one of %s)", network, strings.Join(networkNames, ", "))
+}
+
+// GetConfigurationJob returns a copy of the job having the given ID, and whether such a job is being tracked.
+func (radio *radioBase) GetConfigurationJob(id string) (ConfigurationJob, bool) {
+	return radio.jobs.get(id)
+}
+
+func (radio *radioBase) handleConfigurationRequest(request ConfigurationRequest) error {
 	// If there are multiple requests queued up, only consider the latest one.
 	numExtraRequests := len(radio.ConfigurationRequestChannel)
 	for i := 0; i < numExtraRequests; i++ {
+		radio.jobs.markSuperseded(request.getJobId())
 		request = <-radio.ConfigurationRequestChannel
 	}
+	radio.jobs.markApplying(request.getJobId())
+	var snapshot configurationSnapshot
+	if radio.preemptedSnapshot != nil {
+		// The preempted request may have left the configuration half-applied, so keep rolling back to the one before it.
+		snapshot = *radio.preemptedSnapshot
+		radio.preemptedSnapshot = nil
+	} else {
+		snapshot = radio.takeConfigurationSnapshot()
+	}
 
-	radio.Status = statusConfiguring
+	radio.setStatus(statusConfiguring)
+	radio.setConfigurationError("", false)
+	radio.counters.incrementConfigurationAttempts()
+	radio.statusNotifier.notify()
+	defer radio.statusNotifier.notify()
 	log.Printf("Processing configuration request: %+v", request)
-	if err := radio.configure(request); err != nil {
+	ctx, cancel := radio.startConfiguration()
+	err := radio.personality.configure(ctx, request)
+	radio.finishConfiguration(cancel)
+	if errors.Is(err, context.Canceled) {
+		// Leave the status as-is since the newer request that preempted this one will be handled next.
+		log.Printf("Configuration preempted by a newer request: %v", err)
+		radio.jobs.markSuperseded(request.getJobId())
+		radio.preemptedSnapshot = &snapshot
+		return err
+	} else if errors.Is(err, context.DeadlineExceeded) {
+		err = fmt.Errorf("configuration timed out after %v: %w", radio.ConfigurationTimeout, err)
+	}
+	if err != nil {
 		log.Printf("Error configuring radio: %v", err)
-		radio.Status = statusError
+		// Only mark the job as finished once the rollback is complete, so that anyone polling it doesn't see it
+		// finish while the radio is still being reconfigured.
+		rollbackErr := radio.rollBack(snapshot)
+		if rollbackErr != nil {
+			log.Printf("Error rolling back to previous configuration: %v", rollbackErr)
+			radio.setConfigurationError(fmt.Sprintf("%v; rollback also failed: %v", err, rollbackErr), false)
+		} else {
+			log.Println("Rolled back to previous configuration.")
+			radio.setConfigurationError(err.Error(), true)
+		}
+		radio.setStatus(statusError)
+		radio.jobs.markFinished(request.getJobId(), err, rollbackErr)
 		return err
 	} else if len(radio.ConfigurationRequestChannel) == 0 {
-		radio.Status = statusActive
+		radio.setStatus(statusActive)
+	}
+	radio.jobs.markFinished(request.getJobId(), nil, nil)
+	return nil
+}
+
+// takeConfigurationSnapshot captures the current configuration of the radio so that it can be rolled back to if a
+// configuration request fails.
+func (radio *radioBase) takeConfigurationSnapshot() configurationSnapshot {
+	return configurationSnapshot{
+		uci: takeUciSnapshot(radio.personality.configurationUciOptions()), radio: radio.personality.StatusSnapshot(),
+	}
+}
+
+// rollBack restores the configuration captured in the given snapshot and reloads the Wi-Fi to apply it.
+func (radio *radioBase) rollBack(snapshot configurationSnapshot) error {
+	if err := snapshot.uci.restore(); err != nil {
+		return err
+	}
+	if err := radio.personality.reloadWifi(); err != nil {
+		return err
 	}
+	time.Sleep(wifiReloadBackoffDuration)
+	radio.personality.restoreState(snapshot.radio)
 	return nil
 }
 
+// startConfiguration returns a context for applying a configuration request that expires once the configuration
+// timeout elapses or a newer request is queued, along with the function that releases it.
+func (radio *radioBase) startConfiguration() (context.Context, context.CancelFunc) {
+	var ctx context.Context
+	var cancel context.CancelFunc
+	if radio.ConfigurationTimeout > 0 {
+		ctx, cancel = context.WithTimeout(context.Background(), radio.ConfigurationTimeout)
+	} else {
+		ctx, cancel = context.WithCancel(context.Background())
+	}
+
+	radio.mutex.Lock()
+	defer radio.mutex.Unlock()
+	radio.cancelConfiguration = cancel
+	return ctx, cancel
+}
+
+// finishConfiguration releases the context returned by startConfiguration once the configuration is done with it.
+func (radio *radioBase) finishConfiguration(cancel context.CancelFunc) {
+	radio.mutex.Lock()
+	radio.cancelConfiguration = nil
+	radio.mutex.Unlock()
+	cancel()
+}
+
+// setStatus updates the configuration stage of the radio.
+func (radio *radioBase) setStatus(status radioStatus) {
+	radio.mutex.Lock()
+	defer radio.mutex.Unlock()
+	radio.Status = status
+}
+
+// setConfigurationError updates the outcome of the most recent configuration request.
+func (radio *radioBase) setConfigurationError(configurationError string, rolledBack bool) {
+	radio.mutex.Lock()
+	defer radio.mutex.Unlock()
+	radio.ConfigurationError = configurationError
+	radio.RolledBack = rolledBack
+}
+
+// Counters returns a snapshot of the cumulative totals of configuration and monitoring events since the radio started.
+func (radio *radioBase) Counters() Counters {
+	return radio.counters.snapshot()
+}
+
+// SubscribeStatusChanges returns a channel that receives a notification whenever the radio's status, its team network
+// configuration, or the link state of any of its networks changes. The caller is responsible for calling
+// UnsubscribeStatusChanges once it is no longer interested.
+func (radio *radioBase) SubscribeStatusChanges() chan struct{} {
+	return radio.statusNotifier.subscribe()
+}
+
+// UnsubscribeStatusChanges stops notifications to a channel previously returned by SubscribeStatusChanges.
+func (radio *radioBase) UnsubscribeStatusChanges(listener chan struct{}) {
+	radio.statusNotifier.unsubscribe(listener)
+}
+
 // getHashedWpaKeyAndSalt fetches the WPA key for the given station and returns its hashed value and the salt used for
 // hashing.
-func (radio *Radio) getHashedWpaKeyAndSalt(position int) (string, string) {
+func (radio *radioBase) getHashedWpaKeyAndSalt(position int) (string, string) {
 	wpaKey, ok := uciTree.GetLast("wireless", fmt.Sprintf("@wifi-iface[%d]", position), "key")
 	if !ok {
 		return "", ""
@@ -186,6 +531,31 @@ func getSsid(wifiInterface string) (string, error) {
 	}
 }
 
+// getTxPower fetches the effective transmit power in dBm of the given Wi-Fi interface using 'iwinfo info'.
+func getTxPower(wifiInterface string) (int, error) {
+	output, err := shell.runCommand("iwinfo", wifiInterface, "info")
+	if err != nil {
+		return 0, fmt.Errorf("error getting iwinfo for interface %s: %v", wifiInterface, err)
+	}
+	matches := txPowerRe.FindStringSubmatch(output)
+	if len(matches) == 0 {
+		return 0, fmt.Errorf(
+			"error parsing transmit power from iwinfo output for interface %s: %s", wifiInterface, output,
+		)
+	}
+	return strconv.Atoi(matches[1])
+}
+
+// sleepWithContext pauses for the given duration, returning the context's error early if it is done first.
+func sleepWithContext(ctx context.Context, duration time.Duration) error {
+	select {
+	case <-time.After(duration):
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
 // isValid6GhzChannel returns true if the given channel is a valid 6GHz channel.
 func isValid6GhzChannel(channel int) bool {
 	x := (channel - 5) / 8
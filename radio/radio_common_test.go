@@ -1,9 +1,11 @@
 package radio
 
 import (
+	"context"
 	"errors"
 	"github.com/stretchr/testify/assert"
 	"testing"
+	"time"
 )
 
 func TestTriggerFirmwareUpdate(t *testing.T) {
@@ -45,7 +47,7 @@ func TestTriggerFirmwareUpdate(t *testing.T) {
 	)
 }
 
-func TestRadio_determineAndSetVersion(t *testing.T) {
+func TestRadioBase_determineAndSetVersion(t *testing.T) {
 	fakeTree := newFakeUciTree()
 	uciTree = fakeTree
 	fakeShell := newFakeShell(t)
@@ -54,7 +56,7 @@ func TestRadio_determineAndSetVersion(t *testing.T) {
 	// Vivid-Hosting success case.
 	fakeTree.valuesForGet["system.@system[0].model"] = "VH-109(AP)"
 	fakeShell.commandOutput["cat /etc/vh_firmware"] = "\tVH version 1.2.3 \n"
-	radio := Radio{}
+	radio := &radioBase{}
 	radio.determineAndSetVersion()
 	assert.Equal(t, "VH version 1.2.3", radio.Version)
 
@@ -63,7 +65,7 @@ func TestRadio_determineAndSetVersion(t *testing.T) {
 	fakeTree.valuesForGet["system.@system[0].model"] = "VH-109(AP)"
 	fakeShell.reset()
 	fakeShell.commandErrors["cat /etc/vh_firmware"] = errors.New("oops")
-	radio = Radio{}
+	radio = &radioBase{}
 	radio.determineAndSetVersion()
 	assert.Equal(t, "unknown", radio.Version)
 
@@ -71,7 +73,7 @@ func TestRadio_determineAndSetVersion(t *testing.T) {
 	fakeTree.reset()
 	fakeTree.valuesForGet["system.@system[0].model"] = ""
 	fakeShell.commandOutput["sh -c source /etc/openwrt_release && echo $DISTRIB_DESCRIPTION"] = "\tLinksys v2.3.4 \n"
-	radio = Radio{}
+	radio = &radioBase{}
 	radio.determineAndSetVersion()
 	assert.Equal(t, "Linksys v2.3.4", radio.Version)
 
@@ -80,7 +82,17 @@ func TestRadio_determineAndSetVersion(t *testing.T) {
 	fakeTree.valuesForGet["system.@system[0].model"] = ""
 	fakeShell.reset()
 	fakeShell.commandErrors["sh -c source /etc/openwrt_release && echo $DISTRIB_DESCRIPTION"] = errors.New("oops")
-	radio = Radio{}
+	radio = &radioBase{}
 	radio.determineAndSetVersion()
 	assert.Equal(t, "unknown", radio.Version)
 }
+
+func TestSleepWithContext(t *testing.T) {
+	assert.Nil(t, sleepWithContext(context.Background(), time.Millisecond))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	startTime := time.Now()
+	assert.Equal(t, context.Canceled, sleepWithContext(ctx, time.Hour))
+	assert.Less(t, time.Since(startTime), time.Second)
+}
@@ -1,9 +1,9 @@
-// This file is specific to the robot radio version of the API.
-//go:build robot
+// This file is specific to the robot radio role of the API.
 
 package radio
 
 import (
+	"context"
 	"fmt"
 	"github.com/digineo/go-uci"
 	"log"
@@ -31,12 +31,20 @@ const (
 	// Index of the radio's 6GHz Wi-Fi interface section in the UCI configuration.
 	radioInterfaceIndex6 = 1
 
+	// Name by which the radio's 2.4GHz network is identified in the link history.
+	networkName24 = "2.4GHz"
+
+	// Name by which the radio's 6GHz network is identified in the link history.
+	networkName6 = "6GHz"
+
 	// Seperator between the team number and SSID suffix in the Wi-Fi SSIDs.
 	ssidSuffixSeperator = "-"
 )
 
-// Radio holds the current state of the access point's configuration and any robot radios connected to it.
-type Radio struct {
+// RobotRadio holds the current state of the robot radio's configuration.
+type RobotRadio struct {
+	radioBase
+
 	// Operation mode that the radio is currently configured for.
 	Mode radioMode `json:"mode"`
 
@@ -49,20 +57,15 @@ type Radio struct {
 	// Suffix currently appended to the 6GHz network SSID.
 	SsidSuffix string `json:"ssidSuffix"`
 
+	// Effective transmit power of the 6GHz network in dBm, as reported by the Wi-Fi driver. Zero if it couldn't be
+	// determined.
+	TxPower int `json:"txPower"`
+
 	// Status of the radio's 2.4GHz network.
 	NetworkStatus24 NetworkStatus `json:"networkStatus24"`
 
 	// Status of the radio's 6GHz network.
 	NetworkStatus6 NetworkStatus `json:"networkStatus6"`
-
-	// Enum representing the current configuration stage of the radio.
-	Status radioStatus `json:"status"`
-
-	// Version of the radio software.
-	Version string `json:"version"`
-
-	// Queue for receiving and buffering configuration requests.
-	ConfigurationRequestChannel chan ConfigurationRequest `json:"-"`
 }
 
 // radioMode represents the configuration mode of the radio.
@@ -77,25 +80,70 @@ const (
 	modeTeamAccessPoint radioMode = "TEAM_ACCESS_POINT"
 )
 
-// NewRadio creates a new Radio instance and initializes its fields to default values.
-func NewRadio() *Radio {
-	radio := Radio{
-		Status:                      statusBooting,
-		ConfigurationRequestChannel: make(chan ConfigurationRequest, configurationRequestBufferSize),
-	}
+// NewRobotRadio creates a new RobotRadio instance and initializes its fields to default values.
+func NewRobotRadio() *RobotRadio {
+	radio := &RobotRadio{}
+	radio.radioBase = newRadioBase(radio)
 	radio.determineAndSetVersion()
 
-	return &radio
+	return radio
+}
+
+// Role returns the job that the radio is doing, which is always that of a robot radio.
+func (radio *RobotRadio) Role() Role {
+	return RoleRobotRadio
+}
+
+// HardwareType returns the hardware type of the robot radio, which is only ever made by Vivid-Hosting.
+func (radio *RobotRadio) HardwareType() RadioType {
+	return TypeVividHosting
+}
+
+// NewConfigurationRequest returns an empty robot radio configuration request.
+func (radio *RobotRadio) NewConfigurationRequest() ConfigurationRequest {
+	return &RobotRadioConfigurationRequest{}
+}
+
+// Snapshot returns a deep copy of the radio's current state, which is safe to read and serialize while the radio
+// continues to be updated.
+func (radio *RobotRadio) Snapshot() *RobotRadio {
+	radio.mutex.RLock()
+	defer radio.mutex.RUnlock()
+
+	return &RobotRadio{
+		radioBase:       radio.snapshotBase(),
+		Mode:            radio.Mode,
+		Channel:         radio.Channel,
+		TeamNumber:      radio.TeamNumber,
+		SsidSuffix:      radio.SsidSuffix,
+		TxPower:         radio.TxPower,
+		NetworkStatus24: radio.NetworkStatus24.copy(),
+		NetworkStatus6:  radio.NetworkStatus6.copy(),
+	}
+}
+
+// StatusSnapshot returns the same deep copy as Snapshot, for use through the Radio interface.
+func (radio *RobotRadio) StatusSnapshot() any {
+	return radio.Snapshot()
 }
 
 // isStarted returns true if the Wi-Fi interface is up and running.
-func (radio *Radio) isStarted() bool {
+func (radio *RobotRadio) isStarted() bool {
 	_, err := shell.runCommand("iwinfo", radioInterface6, "info")
 	return err == nil
 }
 
 // setInitialState initializes the in-memory state to match the radio's current configuration.
-func (radio *Radio) setInitialState() {
+func (radio *RobotRadio) setInitialState() {
+	txPower, err := getTxPower(radioInterface6)
+	if err != nil {
+		log.Printf("Error determining transmit power: %v", err)
+	}
+
+	radio.mutex.Lock()
+	defer radio.mutex.Unlock()
+	radio.TxPower = txPower
+
 	wifiInterface24 := fmt.Sprintf("@wifi-iface[%d]", radioInterfaceIndex24)
 	wifiInterface6 := fmt.Sprintf("@wifi-iface[%d]", radioInterfaceIndex6)
 	mode, _ := uciTree.GetLast("wireless", wifiInterface6, "mode")
@@ -107,6 +155,8 @@ func (radio *Radio) setInitialState() {
 	} else {
 		radio.Mode = modeTeamAccessPoint
 		radio.Channel, _ = uciTree.GetLast("wireless", radioDevice6, "channel")
+		radio.NetworkStatus24.IsRobot = false
+		radio.NetworkStatus6.IsRobot = false
 	}
 
 	radio.NetworkStatus24.Ssid, _ = uciTree.GetLast("wireless", wifiInterface24, "ssid")
@@ -121,11 +171,18 @@ func (radio *Radio) setInitialState() {
 }
 
 // configure configures the radio with the given configuration.
-func (radio *Radio) configure(request ConfigurationRequest) error {
+func (radio *RobotRadio) configure(ctx context.Context, configurationRequest ConfigurationRequest) error {
+	request, ok := configurationRequest.(*RobotRadioConfigurationRequest)
+	if !ok {
+		return fmt.Errorf("invalid configuration request type for robot radio: %T", configurationRequest)
+	}
 	retryCount := 1
 
 	for {
+		radio.jobs.recordAttempt()
+		radio.mutex.Lock()
 		radio.Mode = request.Mode
+		radio.mutex.Unlock()
 
 		// Handle Wi-Fi.
 		var ssid string
@@ -138,6 +195,17 @@ func (radio *Radio) configure(request ConfigurationRequest) error {
 		wifiInterface24 := fmt.Sprintf("@wifi-iface[%d]", radioInterfaceIndex24)
 		uciTree.SetType("wireless", wifiInterface6, "ssid", uci.TypeOption, ssid)
 		uciTree.SetType("wireless", wifiInterface6, "key", uci.TypeOption, request.WpaKey6)
+		if request.TxPower == TxPowerDefault {
+			uciTree.Del("wireless", radioDevice6, "txpower")
+			if request.Mode == modeTeamRobotRadio {
+				uciTree.Del("wireless", radioDevice24, "txpower")
+			}
+		} else if request.TxPower > 0 {
+			uciTree.SetType("wireless", radioDevice6, "txpower", uci.TypeOption, strconv.Itoa(request.TxPower))
+			if request.Mode == modeTeamRobotRadio {
+				uciTree.SetType("wireless", radioDevice24, "txpower", uci.TypeOption, strconv.Itoa(request.TxPower))
+			}
+		}
 
 		teamPartialIp := fmt.Sprintf("%d.%d", request.TeamNumber/100, request.TeamNumber%100)
 		if request.Mode == modeTeamRobotRadio {
@@ -148,7 +216,9 @@ func (radio *Radio) configure(request ConfigurationRequest) error {
 			uciTree.SetType("wireless", wifiInterface24, "key", uci.TypeOption, request.WpaKey24)
 			uciTree.SetType("wireless", wifiInterface24, "mode", uci.TypeOption, "ap")
 
+			radio.mutex.Lock()
 			radio.Channel = ""
+			radio.mutex.Unlock()
 			uciTree.Del("wireless", radioDevice6, "channel")
 			uciTree.SetType("wireless", radioDevice24, "channel", uci.TypeOption, "auto")
 			uciTree.SetType("wireless", radioDevice24, "disabled", uci.TypeOption, "0")
@@ -160,19 +230,22 @@ func (radio *Radio) configure(request ConfigurationRequest) error {
 			uciTree.SetType("dhcp", "lan", "limit", uci.TypeOption, "20")
 
 			// Handle NetworkStatus as robot.
+			radio.mutex.Lock()
 			radio.NetworkStatus24.IsRobot = true
 			radio.NetworkStatus6.IsRobot = true
+			radio.mutex.Unlock()
 		} else {
 			uciTree.SetType("wireless", wifiInterface6, "mode", uci.TypeOption, "ap")
 
 			uciTree.SetType("wireless", radioDevice24, "disabled", uci.TypeOption, "1")
-			if request.Channel == 0 {
-				radio.Channel = "auto"
-				uciTree.SetType("wireless", radioDevice6, "channel", uci.TypeOption, "auto")
-			} else {
-				radio.Channel = strconv.Itoa(request.Channel)
-				uciTree.SetType("wireless", radioDevice6, "channel", uci.TypeOption, strconv.Itoa(request.Channel))
+			channel := "auto"
+			if request.Channel != 0 {
+				channel = strconv.Itoa(request.Channel)
 			}
+			uciTree.SetType("wireless", radioDevice6, "channel", uci.TypeOption, channel)
+			radio.mutex.Lock()
+			radio.Channel = channel
+			radio.mutex.Unlock()
 
 			// Handle IP address when in AP mode.
 			uciTree.SetType("network", "lan", "ipaddr", uci.TypeOption, fmt.Sprintf("10.%s.4", teamPartialIp))
@@ -181,8 +254,10 @@ func (radio *Radio) configure(request ConfigurationRequest) error {
 			uciTree.SetType("dhcp", "lan", "limit", uci.TypeOption, "180")
 
 			// Handle NetworkStatus as AP
+			radio.mutex.Lock()
 			radio.NetworkStatus24.IsRobot = false
-			radio.NetworkStatus24.IsRobot = false
+			radio.NetworkStatus6.IsRobot = false
+			radio.mutex.Unlock()
 		}
 
 		// Handle DHCP.
@@ -195,37 +270,120 @@ func (radio *Radio) configure(request ConfigurationRequest) error {
 		if err := uciTree.Commit(); err != nil {
 			return fmt.Errorf("failed to commit configuration: %v", err)
 		}
-		if _, err := shell.runCommand("wifi", "reload"); err != nil {
-			return fmt.Errorf("failed to reload Wi-Fi configuration: %v", err)
+		if err := radio.reloadWifi(); err != nil {
+			return err
+		}
+		if err := sleepWithContext(ctx, wifiReloadBackoffDuration); err != nil {
+			return fmt.Errorf("interrupted after %d attempts at configuring robot radio: %w", retryCount, err)
 		}
-		time.Sleep(wifiReloadBackoffDuration)
 
-		var err error
-		radio.NetworkStatus6.Ssid, err = getSsid(radioInterface6)
+		ssid, err := getSsid(radioInterface6)
 		if err != nil {
 			return err
 		}
-		teamNumber, suffix, _ := strings.Cut(radio.NetworkStatus6.Ssid, ssidSuffixSeperator)
+		teamNumber, suffix, _ := strings.Cut(ssid, ssidSuffixSeperator)
+		hashedWpaKey, wpaKeySalt := radio.getHashedWpaKeyAndSalt(radioInterfaceIndex6)
+		txPower, err := getTxPower(radioInterface6)
+		if err != nil {
+			log.Printf("Error determining transmit power: %v", err)
+		}
+		radio.mutex.Lock()
+		radio.TxPower = txPower
+		radio.NetworkStatus6.Ssid = ssid
 		radio.TeamNumber, _ = strconv.Atoi(teamNumber)
 		radio.SsidSuffix = suffix
-		radio.NetworkStatus6.HashedWpaKey, radio.NetworkStatus6.WpaKeySalt =
-			radio.getHashedWpaKeyAndSalt(radioInterfaceIndex6)
+		radio.NetworkStatus6.HashedWpaKey, radio.NetworkStatus6.WpaKeySalt = hashedWpaKey, wpaKeySalt
+		radio.mutex.Unlock()
 		if radio.TeamNumber == request.TeamNumber && radio.SsidSuffix == request.SsidSuffix {
 			log.Printf("Successfully configured robot radio after %d attempts.", retryCount)
 			break
 		}
 
+		if radio.MaxConfigurationAttempts > 0 && retryCount >= radio.MaxConfigurationAttempts {
+			return fmt.Errorf("wireless configuration still incorrect after %d attempts; giving up", retryCount)
+		}
 		log.Printf("Wi-Fi configuration still incorrect after %d attempts; trying again.", retryCount)
-		time.Sleep(retryBackoffDuration)
+		if err := sleepWithContext(ctx, retryBackoffDuration); err != nil {
+			return fmt.Errorf("interrupted after %d attempts at configuring robot radio: %w", retryCount, err)
+		}
 		retryCount++
+		radio.counters.incrementConfigurationRetries()
 	}
 
 	return nil
 }
 
+// reloadWifi applies the committed wireless configuration to the radio's Wi-Fi devices.
+func (radio *RobotRadio) reloadWifi() error {
+	if _, err := shell.runCommand("wifi", "reload"); err != nil {
+		return fmt.Errorf("failed to reload Wi-Fi configuration: %v", err)
+	}
+	return nil
+}
+
+// configurationUciOptions returns the UCI options that applying a configuration request may change.
+func (radio *RobotRadio) configurationUciOptions() []uciOption {
+	var options []uciOption
+	for _, index := range []int{radioInterfaceIndex24, radioInterfaceIndex6} {
+		wifiInterface := fmt.Sprintf("@wifi-iface[%d]", index)
+		for _, option := range []string{"ssid", "key", "mode"} {
+			options = append(options, uciOption{"wireless", wifiInterface, option, uci.TypeOption})
+		}
+	}
+	return append(
+		options,
+		uciOption{"wireless", radioDevice24, "channel", uci.TypeOption},
+		uciOption{"wireless", radioDevice24, "disabled", uci.TypeOption},
+		uciOption{"wireless", radioDevice24, "txpower", uci.TypeOption},
+		uciOption{"wireless", radioDevice6, "channel", uci.TypeOption},
+		uciOption{"wireless", radioDevice6, "txpower", uci.TypeOption},
+		uciOption{"network", "lan", "ipaddr", uci.TypeOption},
+		uciOption{"network", "lan", "gateway", uci.TypeOption},
+		uciOption{"dhcp", "lan", "start", uci.TypeOption},
+		uciOption{"dhcp", "lan", "limit", uci.TypeOption},
+		uciOption{"dhcp", "lan", "dhcp_option", uci.TypeList},
+		uciOption{"dhcp", "@host[0]", "name", uci.TypeOption},
+		uciOption{"dhcp", "@host[0]", "ip", uci.TypeOption},
+	)
+}
+
+// restoreState reverts the in-memory state to that of the given snapshot, once the UCI configuration it was taken
+// alongside has been restored.
+func (radio *RobotRadio) restoreState(_ any) {
+	// All the state of the robot radio can be reloaded from the radio itself.
+	radio.setInitialState()
+}
+
 // updateMonitoring polls the access point for the current bandwidth usage and link state of each network and updates
 // the in-memory state.
-func (radio *Radio) updateMonitoring() {
-	radio.NetworkStatus6.updateMonitoring(radioInterface6)
-	radio.NetworkStatus24.updateMonitoring(radioInterface24)
+func (radio *RobotRadio) updateMonitoring() {
+	oldStatus6 := radio.NetworkStatus6
+	oldStatus24 := radio.NetworkStatus24
+	newStatus6 := radio.NetworkStatus6
+	newStatus24 := radio.NetworkStatus24
+	newStatus6.updateMonitoring(radioInterface6, &radio.counters)
+	newStatus24.updateMonitoring(radioInterface24, &radio.counters)
+	linkStateChanged := !newStatus6.hasSameLinkState(&radio.NetworkStatus6) ||
+		!newStatus24.hasSameLinkState(&radio.NetworkStatus24)
+
+	// Swap in the new statuses all at once so that readers never observe a partially updated state.
+	radio.mutex.Lock()
+	radio.NetworkStatus6 = newStatus6
+	radio.NetworkStatus24 = newStatus24
+	radio.mutex.Unlock()
+
+	now := time.Now()
+	radio.linkHistory.record(networkName6, now, newStatus6)
+	radio.linkHistory.record(networkName24, now, newStatus24)
+	radio.linkEvents.record(networkName6, now, &oldStatus6, &newStatus6)
+	radio.linkEvents.record(networkName24, now, &oldStatus24, &newStatus24)
+
+	if linkStateChanged {
+		radio.statusNotifier.notify()
+	}
+}
+
+// networkNames returns the names by which the radio's networks are identified in the link history.
+func (radio *RobotRadio) networkNames() []string {
+	return []string{networkName6, networkName24}
 }
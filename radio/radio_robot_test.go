@@ -1,9 +1,9 @@
-// This file is specific to the robot radio version of the API.
-//go:build robot
+// This file is specific to the robot radio role of the API.
 
 package radio
 
 import (
+	"context"
 	"errors"
 	"github.com/stretchr/testify/assert"
 	"math/rand"
@@ -11,17 +11,17 @@ import (
 	"time"
 )
 
-func TestNewRadio(t *testing.T) {
-	radio := NewRadio()
+func TestNewRobotRadio(t *testing.T) {
+	radio := NewRobotRadio()
 	assert.Equal(t, statusBooting, radio.Status)
 	assert.NotNil(t, radio.ConfigurationRequestChannel)
 }
 
-func TestRadio_isStarted(t *testing.T) {
+func TestRobotRadio_isStarted(t *testing.T) {
 	fakeShell := newFakeShell(t)
 	shell = fakeShell
 	fakeShell.commandOutput["sh -c source /etc/openwrt_release && echo $DISTRIB_DESCRIPTION"] = ""
-	radio := NewRadio()
+	radio := NewRobotRadio()
 
 	// Radio is not started.
 	fakeShell.commandErrors["iwinfo ath1 info"] = errors.New("failed")
@@ -37,14 +37,15 @@ func TestRadio_isStarted(t *testing.T) {
 	assert.True(t, ok)
 }
 
-func TestRadio_setInitialState(t *testing.T) {
+func TestRobotRadio_setInitialState(t *testing.T) {
 	rand.Seed(0)
 	fakeTree := newFakeUciTree()
 	uciTree = fakeTree
 	fakeShell := newFakeShell(t)
 	shell = fakeShell
 	fakeShell.commandOutput["sh -c source /etc/openwrt_release && echo $DISTRIB_DESCRIPTION"] = ""
-	radio := NewRadio()
+	fakeShell.commandOutput["iwinfo ath1 info"] = "ath1\nESSID: \"12345\"\nTx-Power: 23 dBm\n"
+	radio := NewRobotRadio()
 
 	fakeTree.valuesForGet["wireless.@wifi-iface[0].ssid"] = "FRC-12345"
 	fakeTree.valuesForGet["wireless.@wifi-iface[0].key"] = "22222222"
@@ -63,6 +64,7 @@ func TestRadio_setInitialState(t *testing.T) {
 	assert.Equal(t, "HomcjcEQvymkzADm", radio.NetworkStatus6.WpaKeySalt)
 	assert.Equal(t, 12345, radio.TeamNumber)
 	assert.Equal(t, "", radio.SsidSuffix)
+	assert.Equal(t, 23, radio.TxPower)
 
 	// Test with team radio mode.
 	fakeTree.valuesForGet["wireless.@wifi-iface[1].mode"] = "sta"
@@ -92,7 +94,7 @@ func TestRadio_setInitialState(t *testing.T) {
 	assert.Equal(t, "suffix", radio.SsidSuffix)
 }
 
-func TestRadio_handleConfigurationRequest(t *testing.T) {
+func TestRobotRadio_handleConfigurationRequest(t *testing.T) {
 	rand.Seed(0)
 	fakeTree := newFakeUciTree()
 	uciTree = fakeTree
@@ -100,15 +102,15 @@ func TestRadio_handleConfigurationRequest(t *testing.T) {
 	shell = fakeShell
 	wifiReloadBackoffDuration = 10 * time.Millisecond
 	fakeShell.commandOutput["sh -c source /etc/openwrt_release && echo $DISTRIB_DESCRIPTION"] = ""
-	radio := NewRadio()
+	radio := NewRobotRadio()
 
 	// Configure to team radio mode.
 	fakeShell.commandOutput["wifi reload"] = ""
 	fakeShell.commandOutput["iwinfo ath1 info"] = "ath0\nESSID: \"12345\"\n"
 	fakeTree.valuesForGet["wireless.@wifi-iface[1].key"] = "11111111"
-	dummyRequest1 := ConfigurationRequest{TeamNumber: 1, WpaKey6: "foo"}
-	dummyRequest2 := ConfigurationRequest{TeamNumber: 2, WpaKey6: "bar"}
-	request := ConfigurationRequest{
+	dummyRequest1 := &RobotRadioConfigurationRequest{TeamNumber: 1, WpaKey6: "foo"}
+	dummyRequest2 := &RobotRadioConfigurationRequest{TeamNumber: 2, WpaKey6: "bar"}
+	request := &RobotRadioConfigurationRequest{
 		Mode: modeTeamRobotRadio, TeamNumber: 12345, WpaKey6: "11111111", WpaKey24: "22222222",
 	}
 	radio.ConfigurationRequestChannel <- dummyRequest2
@@ -151,7 +153,7 @@ func TestRadio_handleConfigurationRequest(t *testing.T) {
 	fakeShell.commandOutput["wifi reload"] = ""
 	fakeShell.commandOutput["iwinfo ath1 info"] = "ath0\nESSID: \"12345\"\n"
 	fakeTree.valuesForGet["wireless.@wifi-iface[1].key"] = "11111111"
-	request = ConfigurationRequest{Mode: modeTeamAccessPoint, TeamNumber: 12345, WpaKey6: "11111111", Channel: 229}
+	request = &RobotRadioConfigurationRequest{Mode: modeTeamAccessPoint, TeamNumber: 12345, WpaKey6: "11111111", Channel: 229}
 	assert.Nil(t, radio.handleConfigurationRequest(request))
 	assert.Equal(t, 14, fakeTree.setCount)
 	assert.Equal(t, fakeTree.valuesFromSet["wireless.@wifi-iface[1].ssid"], "12345")
@@ -184,7 +186,7 @@ func TestRadio_handleConfigurationRequest(t *testing.T) {
 	// Configure to team access point mode with automatic channel.
 	fakeTree.reset()
 	fakeTree.valuesForGet["wireless.@wifi-iface[0].key"] = "11111111"
-	request = ConfigurationRequest{Mode: modeTeamAccessPoint, TeamNumber: 12345, WpaKey6: "11111111"}
+	request = &RobotRadioConfigurationRequest{Mode: modeTeamAccessPoint, TeamNumber: 12345, WpaKey6: "11111111"}
 	assert.Nil(t, radio.handleConfigurationRequest(request))
 	assert.Equal(t, fakeTree.valuesFromSet["wireless.wifi1.channel"], "auto")
 	assert.Equal(t, modeTeamAccessPoint, radio.Mode)
@@ -193,7 +195,7 @@ func TestRadio_handleConfigurationRequest(t *testing.T) {
 	// Configure back to radio mode to ensure status is updated.
 	fakeTree.reset()
 	fakeTree.valuesForGet["wireless.@wifi-iface[0].key"] = "11111111"
-	request = ConfigurationRequest{Mode: modeTeamRobotRadio, TeamNumber: 12345, WpaKey6: "11111111"}
+	request = &RobotRadioConfigurationRequest{Mode: modeTeamRobotRadio, TeamNumber: 12345, WpaKey6: "11111111"}
 	assert.Nil(t, radio.handleConfigurationRequest(request))
 	assert.Equal(t, fakeTree.valuesFromSet["wireless.wifi1.channel"], "***DELETED***")
 	assert.Equal(t, modeTeamRobotRadio, radio.Mode)
@@ -201,7 +203,7 @@ func TestRadio_handleConfigurationRequest(t *testing.T) {
 
 	// Configure to team radio mode with SSID suffix.
 	fakeShell.commandOutput["iwinfo ath1 info"] = "ath0\nESSID: \"12345-suffix\"\n"
-	request = ConfigurationRequest{Mode: modeTeamRobotRadio, TeamNumber: 12345, SsidSuffix: "suffix", WpaKey6: "11111111", WpaKey24: "22222222"}
+	request = &RobotRadioConfigurationRequest{Mode: modeTeamRobotRadio, TeamNumber: 12345, SsidSuffix: "suffix", WpaKey6: "11111111", WpaKey24: "22222222"}
 	assert.Nil(t, radio.handleConfigurationRequest(request))
 	assert.Equal(t, modeTeamRobotRadio, radio.Mode)
 	assert.Equal(t, fakeTree.valuesFromSet["wireless.@wifi-iface[0].ssid"], "FRC-12345-suffix")
@@ -209,15 +211,52 @@ func TestRadio_handleConfigurationRequest(t *testing.T) {
 	assert.Equal(t, "", radio.Channel)
 
 	// Configure to team access point mode with SSID Suffix.
-	request = ConfigurationRequest{Mode: modeTeamAccessPoint, TeamNumber: 12345, SsidSuffix: "suffix", WpaKey6: "11111111"}
+	request = &RobotRadioConfigurationRequest{Mode: modeTeamAccessPoint, TeamNumber: 12345, SsidSuffix: "suffix", WpaKey6: "11111111"}
 	assert.Nil(t, radio.handleConfigurationRequest(request))
 	assert.Equal(t, modeTeamAccessPoint, radio.Mode)
 	assert.Equal(t, fakeTree.valuesFromSet["wireless.@wifi-iface[0].ssid"], "FRC-12345-suffix")
 	assert.Equal(t, fakeTree.valuesFromSet["wireless.@wifi-iface[1].ssid"], "12345-suffix")
 	assert.Equal(t, "auto", radio.Channel)
+
+	// Configure to team radio mode with transmit power.
+	fakeTree.reset()
+	fakeShell.commandOutput["iwinfo ath1 info"] = "ath0\nESSID: \"12345\"\nTx-Power: 18 dBm\n"
+	request = &RobotRadioConfigurationRequest{
+		Mode: modeTeamRobotRadio, TeamNumber: 12345, WpaKey6: "11111111", WpaKey24: "22222222", TxPower: 18,
+	}
+	assert.Nil(t, radio.handleConfigurationRequest(request))
+	assert.Equal(t, "18", fakeTree.valuesFromSet["wireless.wifi1.txpower"])
+	assert.Equal(t, "18", fakeTree.valuesFromSet["wireless.wifi0.txpower"])
+	assert.Equal(t, 18, radio.TxPower)
+
+	// Configure to team access point mode with transmit power, which leaves the disabled 2.4GHz network alone.
+	fakeTree.reset()
+	fakeShell.commandOutput["iwinfo ath1 info"] = "ath0\nESSID: \"12345\"\nTx-Power: 27 dBm\n"
+	request = &RobotRadioConfigurationRequest{
+		Mode: modeTeamAccessPoint, TeamNumber: 12345, WpaKey6: "11111111", TxPower: 27,
+	}
+	assert.Nil(t, radio.handleConfigurationRequest(request))
+	assert.Equal(t, "27", fakeTree.valuesFromSet["wireless.wifi1.txpower"])
+	assert.NotContains(t, fakeTree.valuesFromSet, "wireless.wifi0.txpower")
+	assert.Equal(t, 27, radio.TxPower)
+
+	// Restore the driver's default transmit power on both networks.
+	fakeTree.reset()
+	fakeShell.commandOutput["iwinfo ath1 info"] = "ath0\nESSID: \"12345\"\nTx-Power: 23 dBm\n"
+	request = &RobotRadioConfigurationRequest{
+		Mode:       modeTeamRobotRadio,
+		TeamNumber: 12345,
+		WpaKey6:    "11111111",
+		WpaKey24:   "22222222",
+		TxPower:    TxPowerDefault,
+	}
+	assert.Nil(t, radio.handleConfigurationRequest(request))
+	assert.Equal(t, "***DELETED***", fakeTree.valuesFromSet["wireless.wifi1.txpower"])
+	assert.Equal(t, "***DELETED***", fakeTree.valuesFromSet["wireless.wifi0.txpower"])
+	assert.Equal(t, 23, radio.TxPower)
 }
 
-func TestRadio_handleConfigurationRequestErrors(t *testing.T) {
+func TestRobotRadio_handleConfigurationRequestErrors(t *testing.T) {
 	fakeTree := newFakeUciTree()
 	uciTree = fakeTree
 	fakeShell := newFakeShell(t)
@@ -225,12 +264,21 @@ func TestRadio_handleConfigurationRequestErrors(t *testing.T) {
 	retryBackoffDuration = 10 * time.Millisecond
 	wifiReloadBackoffDuration = 10 * time.Millisecond
 	fakeShell.commandOutput["sh -c source /etc/openwrt_release && echo $DISTRIB_DESCRIPTION"] = ""
-	radio := NewRadio()
+	radio := NewRobotRadio()
 
 	// wifi reload fails.
 	fakeShell.commandErrors["wifi reload"] = errors.New("oops")
-	request := ConfigurationRequest{TeamNumber: 1, WpaKey6: "foo"}
-	assert.Equal(t, "failed to reload Wi-Fi configuration: oops", radio.handleConfigurationRequest(request).Error())
+	request := &RobotRadioConfigurationRequest{TeamNumber: 1, WpaKey6: "foo"}
+	job := radio.QueueConfigurationRequest(request)
+	assert.Equal(
+		t,
+		"failed to reload Wi-Fi configuration: oops",
+		radio.handleConfigurationRequest(<-radio.ConfigurationRequestChannel).Error(),
+	)
+	job, _ = radio.GetConfigurationJob(job.Id)
+	assert.Equal(t, jobFailed, job.Status)
+	assert.Equal(t, 1, job.Attempts)
+	assert.Equal(t, "failed to reload Wi-Fi configuration: oops", job.Error)
 
 	// iwinfo fails.
 	fakeTree.reset()
@@ -254,18 +302,119 @@ func TestRadio_handleConfigurationRequestErrors(t *testing.T) {
 	fakeShell.commandOutput["wifi reload"] = ""
 	fakeShell.commandOutput["iwinfo ath1 info"] = "ath0\nESSID: \"2\"\n"
 	go func() {
-		time.Sleep(100 * time.Millisecond)
+		time.Sleep(150 * time.Millisecond)
 		fakeShell.commandOutput["iwinfo ath1 info"] = "ath0\nESSID: \"1\"\n"
 	}()
-	assert.Nil(t, radio.handleConfigurationRequest(request))
+	job = radio.QueueConfigurationRequest(request)
+	assert.Nil(t, radio.handleConfigurationRequest(<-radio.ConfigurationRequestChannel))
 	assert.Greater(t, fakeTree.commitCount, 5)
+	assert.Equal(t, 4, radio.Counters().ConfigurationAttempts)
+	assert.Greater(t, radio.Counters().ConfigurationRetries, 2)
+	job, _ = radio.GetConfigurationJob(job.Id)
+	assert.Equal(t, jobSucceeded, job.Status)
+	assert.Equal(t, radio.Counters().ConfigurationRetries+1, job.Attempts)
+
+	// Loop gives up after the maximum number of attempts.
+	fakeShell.commandOutput["iwinfo ath1 info"] = "ath0\nESSID: \"2\"\n"
+	radio.MaxConfigurationAttempts = 3
+	job = radio.QueueConfigurationRequest(request)
+	err := radio.handleConfigurationRequest(<-radio.ConfigurationRequestChannel)
+	assert.Equal(t, "wireless configuration still incorrect after 3 attempts; giving up", err.Error())
+	assert.Equal(t, statusError, radio.Status)
+	job, _ = radio.GetConfigurationJob(job.Id)
+	assert.Equal(t, jobFailed, job.Status)
+	assert.Equal(t, 3, job.Attempts)
+
+	// Loop gives up once the timeout elapses.
+	radio.MaxConfigurationAttempts = 0
+	radio.ConfigurationTimeout = 50 * time.Millisecond
+	err = radio.handleConfigurationRequest(request)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.Contains(t, err.Error(), "configuration timed out after 50ms")
+	assert.Equal(t, statusError, radio.Status)
+
+	// Loop is preempted by a newer request.
+	radio.ConfigurationTimeout = DefaultConfigurationTimeout
+	job = radio.QueueConfigurationRequest(request)
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		radio.QueueConfigurationRequest(&RobotRadioConfigurationRequest{TeamNumber: 2})
+	}()
+	err = radio.handleConfigurationRequest(<-radio.ConfigurationRequestChannel)
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.Equal(t, statusConfiguring, radio.Status)
+	job, _ = radio.GetConfigurationJob(job.Id)
+	assert.Equal(t, jobSuperseded, job.Status)
+	if assert.Equal(t, 1, len(radio.ConfigurationRequestChannel)) {
+		assert.Equal(t, 2, (<-radio.ConfigurationRequestChannel).(*RobotRadioConfigurationRequest).TeamNumber)
+	}
 }
 
-func TestRadio_updateMonitoring(t *testing.T) {
+func TestRobotRadio_handleConfigurationRequestRollback(t *testing.T) {
+	fakeTree := newFakeUciTree()
+	uciTree = fakeTree
 	fakeShell := newFakeShell(t)
 	shell = fakeShell
+	wifiReloadBackoffDuration = 10 * time.Millisecond
 	fakeShell.commandOutput["sh -c source /etc/openwrt_release && echo $DISTRIB_DESCRIPTION"] = ""
-	radio := NewRadio()
+	radio := NewRobotRadio()
+
+	// Set up the existing configuration.
+	fakeTree.valuesForGet["wireless.@wifi-iface[1].ssid"] = "254"
+	fakeTree.valuesForGet["wireless.@wifi-iface[1].key"] = "11111111"
+	fakeTree.valuesForGet["wireless.@wifi-iface[1].mode"] = "ap"
+	fakeTree.valuesForGet["wireless.wifi1.channel"] = "37"
+	fakeTree.valuesForGet["network.lan.ipaddr"] = "10.2.54.4"
+	fakeTree.valuesForGet["dhcp.lan.dhcp_option"] = "3,10.2.54.4"
+	fakeTree.valuesForGet["dhcp.@host[0].name"] = "roboRIO-254-FRC"
+	fakeShell.commandOutput["iwinfo ath1 info"] = "ath1\nESSID: \"254\"\nTx-Power: 23 dBm\n"
+	radio.setInitialState()
+	fakeShell.reset()
+
+	// Configuration fails partway through and is rolled back.
+	fakeShell.commandOutput["wifi reload"] = ""
+	fakeShell.commandErrors["iwinfo ath1 info"] = errors.New("oops")
+	request := &RobotRadioConfigurationRequest{
+		Mode: modeTeamRobotRadio, TeamNumber: 1678, WpaKey6: "22222222", WpaKey24: "33333333",
+	}
+	err := radio.handleConfigurationRequest(request)
+	assert.Equal(t, "error getting iwinfo for interface ath1: oops", err.Error())
+	assert.Equal(t, "254", fakeTree.valuesFromSet["wireless.@wifi-iface[1].ssid"])
+	assert.Equal(t, "11111111", fakeTree.valuesFromSet["wireless.@wifi-iface[1].key"])
+	assert.Equal(t, "ap", fakeTree.valuesFromSet["wireless.@wifi-iface[1].mode"])
+	assert.Equal(t, "***DELETED***", fakeTree.valuesFromSet["wireless.@wifi-iface[0].ssid"])
+	assert.Equal(t, "37", fakeTree.valuesFromSet["wireless.wifi1.channel"])
+	assert.Equal(t, "***DELETED***", fakeTree.valuesFromSet["wireless.wifi0.channel"])
+	assert.Equal(t, "10.2.54.4", fakeTree.valuesFromSet["network.lan.ipaddr"])
+	assert.Equal(t, "3,10.2.54.4", fakeTree.valuesFromSet["dhcp.lan.dhcp_option"])
+	assert.Equal(t, "roboRIO-254-FRC", fakeTree.valuesFromSet["dhcp.@host[0].name"])
+	assert.Equal(t, statusError, radio.Status)
+	assert.Equal(t, err.Error(), radio.ConfigurationError)
+	assert.True(t, radio.RolledBack)
+	assert.Equal(t, modeTeamAccessPoint, radio.Mode)
+	assert.Equal(t, "37", radio.Channel)
+	assert.Equal(t, 254, radio.TeamNumber)
+	assert.False(t, radio.NetworkStatus6.IsRobot)
+
+	// Rollback also fails.
+	fakeShell.reset()
+	fakeShell.commandErrors["wifi reload"] = errors.New("oops")
+	err = radio.handleConfigurationRequest(request)
+	assert.Equal(t, "failed to reload Wi-Fi configuration: oops", err.Error())
+	assert.Equal(
+		t,
+		"failed to reload Wi-Fi configuration: oops; rollback also failed: failed to reload Wi-Fi configuration: oops",
+		radio.ConfigurationError,
+	)
+	assert.False(t, radio.RolledBack)
+}
+
+func TestRobotRadio_updateMonitoring(t *testing.T) {
+	fakeShell := newFakeShell(t)
+	shell = fakeShell
+	fakeShell.commandOutput["sh -c source /etc/openwrt_release && echo $DISTRIB_DESCRIPTION"] = ""
+	radio := NewRobotRadio()
+	listener := radio.SubscribeStatusChanges()
 
 	fakeShell.reset()
 	fakeShell.commandErrors["luci-bwc -i ath0"] = errors.New("oops")
@@ -305,4 +454,62 @@ func TestRadio_updateMonitoring(t *testing.T) {
 	assert.Contains(t, fakeShell.commandsRun, "luci-bwc -i ath1")
 	assert.Contains(t, fakeShell.commandsRun, "iwinfo ath1 assoclist")
 	assert.Contains(t, fakeShell.commandsRun, "ifconfig ath1")
+	assert.Equal(t, map[string]int{"luci-bwc": 1}, radio.Counters().MonitoringCommandFailures)
+	assert.Equal(t, 1, len(listener))
+
+	// Link state unchanged.
+	<-listener
+	radio.updateMonitoring()
+	assert.Equal(t, 0, len(listener))
+
+	// Each poll is recorded in the link history of both networks.
+	history, err := radio.LinkHistory("2.4GHz", time.Time{})
+	assert.Nil(t, err)
+	if assert.Equal(t, 2, len(history)) {
+		assert.Equal(t, "48:DA:35:B0:00:CF", history[1].MacAddress)
+	}
+	history, err = radio.LinkHistory("6GHz", time.Time{})
+	assert.Nil(t, err)
+	if assert.Equal(t, 2, len(history)) {
+		assert.Equal(t, 15.324, history[0].BandwidthUsedMbps)
+	}
+	_, err = radio.LinkHistory("red1", time.Time{})
+	assert.EqualError(t, err, "invalid network \"red1\" (expecting one of 6GHz, 2.4GHz)")
+
+	// The association of the 2.4GHz network is recorded as a link event.
+	events, err := radio.LinkEvents("2.4GHz", 0)
+	assert.Nil(t, err)
+	if assert.Equal(t, 1, len(events)) {
+		assert.Equal(t, linkEventAssociated, events[0].Type)
+		assert.Equal(t, LinkState{}, events[0].Before)
+	}
+	events, err = radio.LinkEvents("6GHz", 0)
+	assert.Nil(t, err)
+	assert.Empty(t, events)
+}
+
+func TestRobotRadio_Snapshot(t *testing.T) {
+	radio := &RobotRadio{}
+	radio.ConfigurationRequestChannel = make(chan ConfigurationRequest)
+	radio.Mode = modeTeamAccessPoint
+	radio.Channel = "auto"
+	radio.TeamNumber = 254
+	radio.Status = statusActive
+	radio.NetworkStatus6 = NetworkStatus{
+		Ssid: "254", IsLinked: true, AssociatedClients: []AssociatedClient{{MacAddress: "48:DA:35:B0:00:CF"}},
+	}
+
+	snapshot := radio.Snapshot()
+	assert.Equal(t, modeTeamAccessPoint, snapshot.Mode)
+	assert.Equal(t, "auto", snapshot.Channel)
+	assert.Equal(t, 254, snapshot.TeamNumber)
+	assert.Equal(t, statusActive, snapshot.Status)
+	assert.Equal(t, radio.NetworkStatus6, snapshot.NetworkStatus6)
+	assert.Nil(t, snapshot.ConfigurationRequestChannel)
+
+	// Modifying the radio shouldn't affect the snapshot.
+	radio.NetworkStatus6.IsLinked = false
+	radio.NetworkStatus6.AssociatedClients[0].MacAddress = "5C:DA:35:B0:00:12"
+	assert.True(t, snapshot.NetworkStatus6.IsLinked)
+	assert.Equal(t, "48:DA:35:B0:00:CF", snapshot.NetworkStatus6.AssociatedClients[0].MacAddress)
 }
@@ -0,0 +1,63 @@
+package radio
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestParseRole(t *testing.T) {
+	role, err := ParseRole("access-point")
+	assert.Nil(t, err)
+	assert.Equal(t, RoleAccessPoint, role)
+
+	role, err = ParseRole("robot-radio")
+	assert.Nil(t, err)
+	assert.Equal(t, RoleRobotRadio, role)
+
+	_, err = ParseRole("ROBOT_RADIO")
+	assert.EqualError(t, err, "invalid radio role \"ROBOT_RADIO\" (expecting access-point or robot-radio)")
+}
+
+func TestDetectRole(t *testing.T) {
+	fakeTree := newFakeUciTree()
+	uciTree = fakeTree
+
+	fakeTree.valuesForGet["system.@system[0].model"] = "VH-109(AP)"
+	assert.Equal(t, RoleAccessPoint, DetectRole())
+
+	fakeTree.valuesForGet["system.@system[0].model"] = "VH-113"
+	assert.Equal(t, RoleRobotRadio, DetectRole())
+
+	// The Linksys access point doesn't report a model that can be relied upon.
+	fakeTree.valuesForGet["system.@system[0].model"] = ""
+	assert.Equal(t, RoleAccessPoint, DetectRole())
+}
+
+func TestNewRadio(t *testing.T) {
+	fakeTree := newFakeUciTree()
+	uciTree = fakeTree
+	fakeShell := newFakeShell(t)
+	shell = fakeShell
+	fakeTree.valuesForGet["system.@system[0].model"] = "VH-109(AP)"
+	fakeShell.commandOutput["cat /etc/vh_firmware"] = "VH version 1.2.3"
+
+	radio := NewRadio(RoleAccessPoint)
+	if assert.IsType(t, &AccessPointRadio{}, radio) {
+		assert.Equal(t, RoleAccessPoint, radio.Role())
+		assert.Equal(t, TypeVividHosting, radio.HardwareType())
+		assert.IsType(t, &AccessPointConfigurationRequest{}, radio.NewConfigurationRequest())
+		assert.IsType(t, &AccessPointRadio{}, radio.StatusSnapshot())
+	}
+
+	radio = NewRadio(RoleRobotRadio)
+	if assert.IsType(t, &RobotRadio{}, radio) {
+		assert.Equal(t, RoleRobotRadio, radio.Role())
+		assert.Equal(t, TypeVividHosting, radio.HardwareType())
+		assert.IsType(t, &RobotRadioConfigurationRequest{}, radio.NewConfigurationRequest())
+		assert.IsType(t, &RobotRadio{}, radio.StatusSnapshot())
+	}
+
+	radio.SetConfigurationLimits(3, 0)
+	assert.Equal(t, 3, radio.(*RobotRadio).MaxConfigurationAttempts)
+	assert.Equal(t, 0, int(radio.(*RobotRadio).ConfigurationTimeout))
+}
@@ -0,0 +1,258 @@
+package radio
+
+import (
+	"fmt"
+	"github.com/digineo/go-uci"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// Simulated rate at which each associated remote device sends and receives data, in bytes per second.
+	simulatedBytesPerSec = 250000
+
+	// Simulated firmware version reported by the radio.
+	simulatedVersion = "simulated"
+
+	// Simulated transmit power in dBm that the Wi-Fi driver applies to a device that has none configured.
+	simulatedDefaultTxPower = "20"
+)
+
+// EnableSimulation replaces the radio's UCI configuration and shell with in-memory simulations of the real hardware for
+// the given role, so that the API can be run on a development machine. Remote devices associate with each configured
+// network once the given delay has elapsed after the Wi-Fi is reloaded. Must be called before NewRadio.
+func EnableSimulation(role Role, associationDelay time.Duration) error {
+	configs, interfaces := simulatedAccessPointUciConfigs, simulatedAccessPointInterfaces
+	if role == RoleRobotRadio {
+		configs, interfaces = simulatedRobotRadioUciConfigs, simulatedRobotRadioInterfaces
+	}
+	tree, err := newMemoryUciTree(configs)
+	if err != nil {
+		return fmt.Errorf("error creating simulated UCI configuration: %v", err)
+	}
+	uciTree = tree
+	simShell := newSimulatedShell(interfaces, associationDelay)
+	shell = simShell
+
+	// Bring up the networks from the initial configuration as though the radio had just finished booting.
+	simShell.reloadWifi()
+	return nil
+}
+
+// memoryUciTree wraps a UCI tree that has had all its configs loaded up front, so that changes are retained in memory
+// without ever being written back to disk.
+type memoryUciTree struct {
+	uci.Tree
+}
+
+// newMemoryUciTree creates a UCI tree from the given map of config names to file contents.
+func newMemoryUciTree(configs map[string]string) (*memoryUciTree, error) {
+	// The UCI library can only parse configs from files, so stage them in a temporary directory just long enough to load
+	// them into memory.
+	dir, err := os.MkdirTemp("", "frc-radio-api-uci")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(dir)
+
+	tree := uci.NewTree(dir)
+	for name, contents := range configs {
+		if err = os.WriteFile(filepath.Join(dir, name), []byte(contents), 0644); err != nil {
+			return nil, err
+		}
+		if err = tree.LoadConfig(name, true); err != nil {
+			return nil, err
+		}
+	}
+	return &memoryUciTree{tree}, nil
+}
+
+// Commit is a no-op since the changes are already held in memory and there is no disk to write them back to.
+func (tree *memoryUciTree) Commit() error {
+	return nil
+}
+
+// simulatedShell is an implementation of the shellWrapper interface that emulates the output of the commands run on
+// the real radio.
+type simulatedShell struct {
+	mutex sync.Mutex
+
+	// How long after the Wi-Fi is reloaded that remote devices associate with each configured network.
+	associationDelay time.Duration
+
+	// Map of Wi-Fi interface names to their simulated state.
+	networks map[string]*simulatedNetwork
+}
+
+// simulatedNetwork holds the state of a single simulated Wi-Fi interface.
+type simulatedNetwork struct {
+	// Position of the interface's section among the wifi-iface sections in the wireless UCI config.
+	index int
+
+	// SSID that the interface is currently broadcasting, as of the last Wi-Fi reload.
+	ssid string
+
+	// Transmit power in dBm of the interface's Wi-Fi device, as of the last Wi-Fi reload.
+	txPower string
+
+	// Time at which the interface started broadcasting its current SSID.
+	startTime time.Time
+}
+
+// newSimulatedShell creates a simulated shell having the given map of Wi-Fi interface names to the positions of their
+// sections in the wireless UCI config.
+func newSimulatedShell(interfaces map[string]int, associationDelay time.Duration) *simulatedShell {
+	simShell := simulatedShell{associationDelay: associationDelay, networks: make(map[string]*simulatedNetwork)}
+	for networkInterface, index := range interfaces {
+		simShell.networks[networkInterface] = &simulatedNetwork{index: index}
+	}
+	return &simShell
+}
+
+func (simShell *simulatedShell) runCommand(command string, args ...string) (string, error) {
+	simShell.mutex.Lock()
+	defer simShell.mutex.Unlock()
+
+	fullCommand := strings.Join(append([]string{command}, args...), " ")
+	switch {
+	case command == "wifi" && len(args) > 0 && args[0] == "reload":
+		simShell.reloadWifiLocked()
+		return "", nil
+	case command == "iwinfo" && len(args) == 2 && args[1] == "scan":
+		return simulatedScanResults, nil
+	case command == "iwinfo" && len(args) == 2:
+		network, ok := simShell.networks[args[0]]
+		if !ok {
+			return "", fmt.Errorf("no such wireless device: %s", args[0])
+		}
+		switch args[1] {
+		case "info":
+			return fmt.Sprintf(
+				"%s     ESSID: \"%s\"\n          Mode: Master\n          Tx-Power: %s dBm\n",
+				args[0],
+				network.ssid,
+				network.txPower,
+			), nil
+		case "assoclist":
+			return simShell.assocList(network), nil
+		}
+	case command == "luci-bwc" && len(args) == 2 && args[0] == "-i":
+		if network, ok := simShell.networks[args[1]]; ok {
+			return simShell.bandwidthHistory(network), nil
+		}
+	case command == "ifconfig" && len(args) == 1:
+		if network, ok := simShell.networks[args[0]]; ok {
+			bytes := simShell.bytesTransferred(network, time.Now())
+			return fmt.Sprintf(
+				"%s\tLink encap:Ethernet  HWaddr 00:00:00:00:00:00\n\tRX bytes:%d (%d KiB)  TX bytes:%d (%d KiB)\n",
+				args[0],
+				bytes,
+				bytes/1024,
+				bytes,
+				bytes/1024,
+			), nil
+		}
+	case fullCommand == "cat /etc/vh_firmware":
+		return simulatedVersion, nil
+	case fullCommand == "sh -c source /etc/openwrt_release && echo $DISTRIB_DESCRIPTION":
+		return simulatedVersion, nil
+	case fullCommand == "/etc/init.d/log restart", command == "sh", command == "tc":
+		// Commands that only have side effects on the real radio.
+		return "", nil
+	}
+
+	return "", fmt.Errorf("command not supported by simulation: %s", fullCommand)
+}
+
+func (simShell *simulatedShell) startCommand(command string, args ...string) error {
+	if command == "sysupgrade" {
+		log.Printf("Simulating firmware update with arguments %v; no changes will be made.", args)
+		return nil
+	}
+	return fmt.Errorf("command not supported by simulation: %s", strings.Join(append([]string{command}, args...), " "))
+}
+
+// reloadWifi applies the current wireless UCI configuration to the simulated networks.
+func (simShell *simulatedShell) reloadWifi() {
+	simShell.mutex.Lock()
+	defer simShell.mutex.Unlock()
+	simShell.reloadWifiLocked()
+}
+
+// reloadWifiLocked applies the current wireless UCI configuration to the simulated networks. The caller must hold the
+// mutex.
+func (simShell *simulatedShell) reloadWifiLocked() {
+	for _, network := range simShell.networks {
+		wifiInterface := fmt.Sprintf("@wifi-iface[%d]", network.index)
+		ssid, _ := uciTree.GetLast("wireless", wifiInterface, "ssid")
+		device, _ := uciTree.GetLast("wireless", wifiInterface, "device")
+		var ok bool
+		if network.txPower, ok = uciTree.GetLast("wireless", device, "txpower"); !ok || network.txPower == "" {
+			network.txPower = simulatedDefaultTxPower
+		}
+		if ssid != network.ssid {
+			// Any remote device that was associated with the old network drops off and the new one will take a while to
+			// associate.
+			network.ssid = ssid
+			network.startTime = time.Now()
+		}
+	}
+}
+
+// associationTime returns the time at which a remote device associated with the given network, or the zero time if
+// none has.
+func (simShell *simulatedShell) associationTime(network *simulatedNetwork) time.Time {
+	if network.ssid == "" || strings.HasPrefix(network.ssid, "no-team-") {
+		return time.Time{}
+	}
+	associationTime := network.startTime.Add(simShell.associationDelay)
+	if time.Now().Before(associationTime) {
+		return time.Time{}
+	}
+	return associationTime
+}
+
+// bytesTransferred returns the number of bytes the remote device on the given network has sent (and received) as of
+// the given time.
+func (simShell *simulatedShell) bytesTransferred(network *simulatedNetwork, asOf time.Time) int {
+	associationTime := simShell.associationTime(network)
+	if associationTime.IsZero() || asOf.Before(associationTime) {
+		return 0
+	}
+	return int(asOf.Sub(associationTime).Seconds() * simulatedBytesPerSec)
+}
+
+// assocList returns simulated 'iwinfo assoclist' output for the given network.
+func (simShell *simulatedShell) assocList(network *simulatedNetwork) string {
+	if simShell.associationTime(network).IsZero() {
+		return "No station connected\n"
+	}
+	packets := simShell.bytesTransferred(network, time.Now()) / 1000
+	return fmt.Sprintf(
+		"48:DA:35:B0:00:%02X  -55 dBm / -95 dBm (SNR 40)  0 ms ago\n"+
+			"\tRX: 860.3 MBit/s                                %d Pkts.\n"+
+			"\tTX: 648.5 MBit/s                                %d Pkts.\n"+
+			"\texpected throughput: unknown\n",
+		network.index,
+		packets,
+		packets,
+	)
+}
+
+// bandwidthHistory returns simulated 'luci-bwc' output for the given network, covering the last several seconds.
+func (simShell *simulatedShell) bandwidthHistory(network *simulatedNetwork) string {
+	now := time.Now()
+	var samples []string
+	for secondsAgo := 6; secondsAgo >= 0; secondsAgo-- {
+		sampleTime := now.Add(-time.Duration(secondsAgo) * time.Second)
+		bytes := simShell.bytesTransferred(network, sampleTime)
+		samples = append(
+			samples, fmt.Sprintf("[ %d, %d, %d, %d, %d ]", sampleTime.Unix(), bytes, bytes/1000, bytes, bytes/1000),
+		)
+	}
+	return strings.Join(samples, ",\n")
+}
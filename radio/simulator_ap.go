@@ -0,0 +1,126 @@
+// This file is specific to the access point role of the API.
+
+package radio
+
+// Map of the simulated access point's Wi-Fi interface names to the positions of their sections in the wireless UCI
+// config.
+var simulatedAccessPointInterfaces = map[string]int{"ath1": 1, "ath11": 2, "ath12": 3, "ath13": 4, "ath14": 5, "ath15": 6}
+
+// Initial UCI configuration of the simulated access point, which mimics a Vivid-Hosting VH-109.
+var simulatedAccessPointUciConfigs = map[string]string{
+	"system": `
+config system
+	option hostname 'VH-109'
+	option model 'VH-109(AP)'
+`,
+	"wireless": `
+config wifi-device 'wifi1'
+	option channel '5'
+	option htmode 'HE40'
+	option txpower '23'
+	option disabled '0'
+
+config wifi-iface
+	option device 'wifi1'
+	option mode 'ap'
+	option ssid 'VH-109-admin'
+	option network 'lan'
+
+config wifi-iface
+	option device 'wifi1'
+	option mode 'ap'
+	option ssid 'no-team-1'
+	option key 'no-team-1'
+	option sae_password 'no-team-1'
+	option network 'vlan10'
+
+config wifi-iface
+	option device 'wifi1'
+	option mode 'ap'
+	option ssid 'no-team-2'
+	option key 'no-team-2'
+	option sae_password 'no-team-2'
+	option network 'vlan20'
+
+config wifi-iface
+	option device 'wifi1'
+	option mode 'ap'
+	option ssid 'no-team-3'
+	option key 'no-team-3'
+	option sae_password 'no-team-3'
+	option network 'vlan30'
+
+config wifi-iface
+	option device 'wifi1'
+	option mode 'ap'
+	option ssid 'no-team-4'
+	option key 'no-team-4'
+	option sae_password 'no-team-4'
+	option network 'vlan40'
+
+config wifi-iface
+	option device 'wifi1'
+	option mode 'ap'
+	option ssid 'no-team-5'
+	option key 'no-team-5'
+	option sae_password 'no-team-5'
+	option network 'vlan50'
+
+config wifi-iface
+	option device 'wifi1'
+	option mode 'ap'
+	option ssid 'no-team-6'
+	option key 'no-team-6'
+	option sae_password 'no-team-6'
+	option network 'vlan60'
+`,
+	"network": `
+config interface 'lan'
+	option proto 'static'
+	option ipaddr '10.0.100.2'
+	option netmask '255.255.255.0'
+
+config interface 'vlan10'
+	option proto 'none'
+
+config interface 'vlan20'
+	option proto 'none'
+
+config interface 'vlan30'
+	option proto 'none'
+
+config interface 'vlan40'
+	option proto 'none'
+
+config interface 'vlan50'
+	option proto 'none'
+
+config interface 'vlan60'
+	option proto 'none'
+
+config interface 'vlan70'
+	option proto 'none'
+
+config interface 'vlan80'
+	option proto 'none'
+
+config interface 'vlan90'
+	option proto 'none'
+`,
+}
+
+// Simulated 'iwinfo scan' output, which mimics a pair of neighboring networks such as those of an adjacent practice
+// field.
+const simulatedScanResults = `Cell 01 - Address: 48:DA:35:B0:10:01
+          ESSID: "practice-field"
+          Mode: Master  Channel: 37
+          Signal: -58 dBm  Quality: 52/70
+          Encryption: WPA3 SAE (CCMP)
+
+Cell 02 - Address: 48:DA:35:B0:10:02
+          ESSID: "pit-display"
+          Mode: Master  Channel: 69
+          Signal: -71 dBm  Quality: 39/70
+          Encryption: WPA2 PSK (CCMP)
+
+`
@@ -0,0 +1,60 @@
+// This file is specific to the robot radio role of the API.
+
+package radio
+
+// Map of the simulated robot radio's Wi-Fi interface names to the positions of their sections in the wireless UCI
+// config.
+var simulatedRobotRadioInterfaces = map[string]int{
+	radioInterface24: radioInterfaceIndex24, radioInterface6: radioInterfaceIndex6,
+}
+
+// Initial UCI configuration of the simulated robot radio, which mimics a Vivid-Hosting VH-113 configured as an access
+// point for team 1234.
+var simulatedRobotRadioUciConfigs = map[string]string{
+	"system": `
+config system
+	option hostname 'VH-113'
+	option model 'VH-113'
+`,
+	"wireless": `
+config wifi-device 'wifi0'
+	option channel 'auto'
+	option txpower '17'
+	option disabled '1'
+
+config wifi-device 'wifi1'
+	option channel '5'
+	option txpower '23'
+	option disabled '0'
+
+config wifi-iface
+	option device 'wifi0'
+	option mode 'ap'
+	option ssid 'FRC-1234'
+	option key '12345678'
+
+config wifi-iface
+	option device 'wifi1'
+	option mode 'ap'
+	option ssid '1234'
+	option key '12345678'
+`,
+	"network": `
+config interface 'lan'
+	option proto 'static'
+	option ipaddr '10.12.34.4'
+	option netmask '255.255.255.0'
+	option gateway '10.12.34.4'
+`,
+	"dhcp": `
+config dhcp 'lan'
+	option interface 'lan'
+	option start '20'
+	option limit '180'
+	list dhcp_option '3,10.12.34.4'
+
+config host
+	option name 'roboRIO-1234-FRC'
+	option ip '10.12.34.2'
+`,
+}
@@ -0,0 +1,150 @@
+package radio
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/digineo/go-uci"
+	"github.com/stretchr/testify/assert"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestEnableSimulation(t *testing.T) {
+	t.Run("AccessPoint", func(t *testing.T) {
+		testEnableSimulation(t, RoleAccessPoint, simulatedAccessPointInterfaces)
+	})
+	t.Run("RobotRadio", func(t *testing.T) {
+		testEnableSimulation(t, RoleRobotRadio, simulatedRobotRadioInterfaces)
+	})
+}
+
+func testEnableSimulation(t *testing.T, role Role, interfaces map[string]int) {
+	originalUciTree, originalShell := uciTree, shell
+	defer func() {
+		uciTree, shell = originalUciTree, originalShell
+	}()
+
+	assert.Nil(t, EnableSimulation(role, 50*time.Millisecond))
+	assert.Equal(t, role, DetectRole())
+	radio := NewRadio(role)
+	assert.Equal(t, role, radio.Role())
+	assert.Equal(t, TypeVividHosting, radio.HardwareType())
+	jsonData, err := json.Marshal(radio.StatusSnapshot())
+	assert.Nil(t, err)
+	assert.Contains(t, string(jsonData), fmt.Sprintf("\"version\":\"%s\"", simulatedVersion))
+	assert.True(t, radio.(personality).isStarted())
+
+	// Pick any one of the simulated networks to exercise.
+	var networkInterface string
+	var index int
+	for networkInterface, index = range interfaces {
+		break
+	}
+	wifiInterface := fmt.Sprintf("@wifi-iface[%d]", index)
+
+	// Configuration changes shouldn't take effect until the Wi-Fi is reloaded.
+	assert.True(t, uciTree.SetType("wireless", wifiInterface, "ssid", uci.TypeOption, "254"))
+	assert.Nil(t, uciTree.Commit())
+	ssid, _ := uciTree.GetLast("wireless", wifiInterface, "ssid")
+	assert.Equal(t, "254", ssid)
+	ssid, err = getSsid(networkInterface)
+	assert.Nil(t, err)
+	assert.NotEqual(t, "254", ssid)
+	_, err = shell.runCommand("wifi", "reload")
+	assert.Nil(t, err)
+	ssid, err = getSsid(networkInterface)
+	assert.Nil(t, err)
+	assert.Equal(t, "254", ssid)
+	txPower, err := getTxPower(networkInterface)
+	assert.Nil(t, err)
+	assert.Greater(t, txPower, 0)
+
+	// Remote device hasn't associated yet.
+	var counters counterSet
+	var status NetworkStatus
+	status.updateMonitoring(networkInterface, &counters)
+	assert.False(t, status.IsLinked)
+	assert.Equal(t, 0, status.RxBytes)
+	assert.Equal(t, 0.0, status.BandwidthUsedMbps)
+
+	// Remote device has associated.
+	time.Sleep(100 * time.Millisecond)
+	status.updateMonitoring(networkInterface, &counters)
+	assert.True(t, status.IsLinked)
+	assert.True(t, strings.HasPrefix(status.MacAddress, "48:DA:35:B0:00:"))
+	assert.Equal(t, -55, status.SignalDbm)
+	assert.Equal(t, 860.3, status.RxRateMbps)
+	assert.Greater(t, status.RxBytes, 0)
+	assert.Equal(t, status.RxBytes, status.TxBytes)
+	assert.Empty(t, counters.snapshot().MonitoringCommandFailures)
+
+	// Changing the SSID should drop the remote device.
+	uciTree.SetType("wireless", wifiInterface, "ssid", uci.TypeOption, "no-team-1")
+	_, _ = shell.runCommand("wifi", "reload", "wifi1")
+	status.updateMonitoring(networkInterface, &counters)
+	assert.False(t, status.IsLinked)
+}
+
+func TestEnableSimulation_AccessPointVlans(t *testing.T) {
+	originalUciTree, originalShell := uciTree, shell
+	defer func() {
+		uciTree, shell = originalUciTree, originalShell
+	}()
+
+	assert.Nil(t, EnableSimulation(RoleAccessPoint, 50*time.Millisecond))
+	radio := NewAccessPointRadio()
+	request := AccessPointConfigurationRequest{StationVlans: map[string]int{"red1": 90}}
+	assert.Nil(t, request.Validate(radio))
+	request = AccessPointConfigurationRequest{StationVlans: map[string]int{"red1": 100}}
+	assert.EqualError(t, request.Validate(radio), "invalid VLAN for station red1: 100 (no such network on the radio)")
+}
+
+func TestEnableSimulation_TxPowerDefault(t *testing.T) {
+	originalUciTree, originalShell := uciTree, shell
+	defer func() {
+		uciTree, shell = originalUciTree, originalShell
+	}()
+	wifiReloadBackoffDuration = 10 * time.Millisecond
+
+	// Restoring the driver's default transmit power removes the configured one, which the simulated driver reports as
+	// its own default rather than failing to parse.
+	assert.Nil(t, EnableSimulation(RoleAccessPoint, 0))
+	accessPointRadio := NewAccessPointRadio()
+	accessPointRadio.setInitialState()
+	assert.Equal(t, 23, accessPointRadio.TxPower)
+	assert.Nil(t, accessPointRadio.handleConfigurationRequest(&AccessPointConfigurationRequest{TxPower: TxPowerDefault}))
+	assert.Equal(t, statusActive, accessPointRadio.Status)
+	assert.Equal(t, 20, accessPointRadio.TxPower)
+
+	assert.Nil(t, EnableSimulation(RoleRobotRadio, 0))
+	robotRadio := NewRobotRadio()
+	robotRadio.setInitialState()
+	request := &RobotRadioConfigurationRequest{
+		Mode:       modeTeamRobotRadio,
+		TeamNumber: 254,
+		WpaKey6:    "11111111",
+		WpaKey24:   "22222222",
+		TxPower:    TxPowerDefault,
+	}
+	assert.Nil(t, robotRadio.handleConfigurationRequest(request))
+	assert.Equal(t, statusActive, robotRadio.Status)
+	assert.Equal(t, 20, robotRadio.TxPower)
+}
+
+func TestSimulatedShell(t *testing.T) {
+	simShell := newSimulatedShell(simulatedAccessPointInterfaces, 0)
+
+	output, err := simShell.runCommand("cat", "/etc/vh_firmware")
+	assert.Nil(t, err)
+	assert.Equal(t, simulatedVersion, output)
+	_, err = simShell.runCommand("/etc/init.d/log", "restart")
+	assert.Nil(t, err)
+	assert.Nil(t, simShell.startCommand("sysupgrade", "-n", "/tmp/new-firmware.tar"))
+
+	_, err = simShell.runCommand("iwinfo", "nonexistent", "info")
+	assert.Equal(t, "no such wireless device: nonexistent", err.Error())
+	_, err = simShell.runCommand("reboot")
+	assert.Equal(t, "command not supported by simulation: reboot", err.Error())
+	assert.Equal(t, "command not supported by simulation: reboot now", simShell.startCommand("reboot", "now").Error())
+}
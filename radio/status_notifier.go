@@ -0,0 +1,46 @@
+package radio
+
+import "sync"
+
+// statusNotifier fans out notifications of changes in the radio's state to any number of listeners.
+type statusNotifier struct {
+	mutex     sync.Mutex
+	listeners map[chan struct{}]struct{}
+}
+
+// subscribe registers a new listener and returns the channel on which it will be notified of changes.
+func (notifier *statusNotifier) subscribe() chan struct{} {
+	notifier.mutex.Lock()
+	defer notifier.mutex.Unlock()
+
+	if notifier.listeners == nil {
+		notifier.listeners = make(map[chan struct{}]struct{})
+	}
+
+	// Buffer a single notification so that the notifier never has to block on a slow listener.
+	listener := make(chan struct{}, 1)
+	notifier.listeners[listener] = struct{}{}
+	return listener
+}
+
+// unsubscribe removes the given listener so that it no longer receives notifications.
+func (notifier *statusNotifier) unsubscribe(listener chan struct{}) {
+	notifier.mutex.Lock()
+	defer notifier.mutex.Unlock()
+
+	delete(notifier.listeners, listener)
+}
+
+// notify signals all listeners that the state has changed. Listeners that have yet to consume a previous notification
+// are skipped since they will observe the latest state anyway once they do.
+func (notifier *statusNotifier) notify() {
+	notifier.mutex.Lock()
+	defer notifier.mutex.Unlock()
+
+	for listener := range notifier.listeners {
+		select {
+		case listener <- struct{}{}:
+		default:
+		}
+	}
+}
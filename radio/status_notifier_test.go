@@ -0,0 +1,38 @@
+package radio
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestStatusNotifier(t *testing.T) {
+	var notifier statusNotifier
+
+	// Notifying without any listeners should be a no-op.
+	notifier.notify()
+
+	listener1 := notifier.subscribe()
+	listener2 := notifier.subscribe()
+	assert.Equal(t, 0, len(listener1))
+	assert.Equal(t, 0, len(listener2))
+
+	notifier.notify()
+	assert.Equal(t, 1, len(listener1))
+	assert.Equal(t, 1, len(listener2))
+
+	// Notifications should coalesce rather than block when a listener hasn't consumed the previous one.
+	notifier.notify()
+	assert.Equal(t, 1, len(listener1))
+	<-listener1
+	notifier.notify()
+	assert.Equal(t, 1, len(listener1))
+	assert.Equal(t, 1, len(listener2))
+
+	// Unsubscribed listeners should no longer receive notifications.
+	<-listener1
+	<-listener2
+	notifier.unsubscribe(listener1)
+	notifier.notify()
+	assert.Equal(t, 0, len(listener1))
+	assert.Equal(t, 1, len(listener2))
+}
@@ -0,0 +1,246 @@
+// This file is specific to the access point role of the API.
+
+package radio
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Width of a single channel, in MHz, out of which wider channels are made.
+const baseChannelWidthMhz = 20
+
+var (
+	surveyCellRe       = regexp.MustCompile("^Cell \\d+ - Address: ([0-9A-Fa-f:]+)")
+	surveySsidRe       = regexp.MustCompile("^ESSID: \"(.*)\"")
+	surveyChannelRe    = regexp.MustCompile("(?:^|\\s)Channel: (\\d+)")
+	surveySignalRe     = regexp.MustCompile("Signal: (-?\\d+) dBm")
+	surveyEncryptionRe = regexp.MustCompile("^Encryption: (.*)")
+	surveyWidthRe      = regexp.MustCompile("^Channel Width: (\\d+) MHz")
+	surveyCenterRe     = regexp.MustCompile("^Center Frequency 1: (\\d+)")
+	surveyOffsetRe     = regexp.MustCompile("^Secondary Channel Offset: (above|below)")
+)
+
+// ChannelSurvey holds the neighboring networks found by scanning from the access point, and how congested they make
+// each of the channels that the access point could use.
+type ChannelSurvey struct {
+	// Every network found by the scan, in the order reported by the radio.
+	Networks []SurveyedNetwork `json:"networks"`
+
+	// Congestion of each valid channel for the radio's hardware type, in ascending order of channel number.
+	Channels []ChannelCongestion `json:"channels"`
+
+	// Valid channel having the least congestion among those that can be used at the radio's current channel bandwidth,
+	// which is the one selected when a configuration request asks for the channel to be chosen automatically.
+	LeastCongestedChannel int `json:"leastCongestedChannel"`
+}
+
+// SurveyedNetwork represents a single neighboring network found by scanning.
+type SurveyedNetwork struct {
+	// MAC address of the access point broadcasting the network.
+	MacAddress string `json:"macAddress"`
+
+	// SSID of the network. Blank if hidden.
+	Ssid string `json:"ssid"`
+
+	// Primary channel number of the network.
+	Channel int `json:"channel"`
+
+	// Width of the network's channel, in MHz. 20 unless the network advertises a wider channel.
+	ChannelWidthMhz int `json:"channelWidthMhz"`
+
+	// Signal strength of the network as received by the access point, in decibel-milliwatts.
+	SignalDbm int `json:"signalDbm"`
+
+	// Human-readable description of the network's encryption (e.g. "WPA3 SAE (CCMP)").
+	Encryption string `json:"encryption"`
+
+	// Number of the channel at the center of the network's wide channel. Zero if it wasn't advertised.
+	centerChannel int
+}
+
+// ChannelCongestion summarizes the neighboring networks found on a single channel.
+type ChannelCongestion struct {
+	// Channel number.
+	Channel int `json:"channel"`
+
+	// Number of neighboring networks occupying the channel, either as their primary channel or as part of a wider one.
+	NetworkCount int `json:"networkCount"`
+
+	// Signal strength of the strongest neighboring network occupying the channel, in decibel-milliwatts. Zero if there
+	// are none.
+	StrongestSignalDbm int `json:"strongestSignalDbm"`
+
+	// Combined signal strength of all the neighboring networks occupying the channel, in decibel-milliwatts. This is
+	// only a proxy for congestion, since the radio can't measure how much airtime the networks actually use. Zero if
+	// there are none.
+	TotalSignalDbm int `json:"totalSignalDbm"`
+
+	// Combined signal strength in milliwatts, for comparing channels with more precision than the rounded dBm value.
+	totalSignalMw float64
+}
+
+// Survey scans for neighboring networks using 'iwinfo scan' on the radio's Wi-Fi device and summarizes how congested
+// they make each of the valid channels for the radio's hardware type.
+func (radio *AccessPointRadio) Survey() (*ChannelSurvey, error) {
+	radio.mutex.RLock()
+	channelBandwidth := radio.ChannelBandwidth
+	radio.mutex.RUnlock()
+	return radio.survey(channelBandwidth)
+}
+
+// survey performs a survey in which the least congested channel is chosen from those that can be used at the given
+// channel bandwidth.
+func (radio *AccessPointRadio) survey(channelBandwidth string) (*ChannelSurvey, error) {
+	output, err := shell.runCommand("iwinfo", radio.device, "scan")
+	if err != nil {
+		return nil, fmt.Errorf("error scanning for networks on device %s: %v", radio.device, err)
+	}
+	networks := parseScanResults(output)
+	channels := summarizeChannelCongestion(networks, validChannels(radio.Type))
+
+	// Ties go to the lowest channel number.
+	var leastCongested *ChannelCongestion
+	for i, channel := range channels {
+		if !radio.canUseChannel(channel.Channel, channelBandwidth) {
+			continue
+		}
+		if leastCongested == nil || channel.totalSignalMw < leastCongested.totalSignalMw {
+			leastCongested = &channels[i]
+		}
+	}
+	if leastCongested == nil {
+		return nil, fmt.Errorf("no valid channels for %s at %s", radio.Type.String(), channelBandwidth)
+	}
+	return &ChannelSurvey{Networks: networks, Channels: channels, LeastCongestedChannel: leastCongested.Channel}, nil
+}
+
+// canUseChannel returns true if the given valid channel can be used at the given channel bandwidth. Only wide 6GHz
+// channels are restricted, and an unrecognized bandwidth doesn't restrict anything.
+func (radio *AccessPointRadio) canUseChannel(channel int, channelBandwidth string) bool {
+	if _, ok := channelBandwidthHtmodes[channelBandwidth]; !ok || radio.Type != TypeVividHosting {
+		return true
+	}
+	return isValid6GhzChannelForBandwidth(channel, channelBandwidth)
+}
+
+// parseScanResults extracts the networks from the given 'iwinfo scan' output.
+func parseScanResults(response string) []SurveyedNetwork {
+	networks := []SurveyedNetwork{}
+	var network *SurveyedNetwork
+	for _, line := range strings.Split(response, "\n") {
+		line = strings.TrimSpace(line)
+		if matches := surveyCellRe.FindStringSubmatch(line); len(matches) > 0 {
+			networks = append(
+				networks, SurveyedNetwork{MacAddress: strings.ToUpper(matches[1]), ChannelWidthMhz: baseChannelWidthMhz},
+			)
+			network = &networks[len(networks)-1]
+			continue
+		}
+		if network == nil {
+			continue
+		}
+		if matches := surveySsidRe.FindStringSubmatch(line); len(matches) > 0 {
+			network.Ssid = matches[1]
+		}
+		if matches := surveyChannelRe.FindStringSubmatch(line); len(matches) > 0 && network.Channel == 0 {
+			// Only the first mention is the primary channel; later ones describe the wider channel it belongs to.
+			network.Channel, _ = strconv.Atoi(matches[1])
+		}
+		if matches := surveySignalRe.FindStringSubmatch(line); len(matches) > 0 {
+			network.SignalDbm, _ = strconv.Atoi(matches[1])
+		}
+		if matches := surveyEncryptionRe.FindStringSubmatch(line); len(matches) > 0 {
+			network.Encryption = matches[1]
+		}
+
+		// The HT, VHT and HE operation sections each describe the channel width as far as their standard goes, so the
+		// widest one wins. Only the VHT and HE sections give the center of the channel; the HT section gives which side
+		// of the primary channel a 40MHz channel extends to instead.
+		if matches := surveyWidthRe.FindStringSubmatch(line); len(matches) > 0 {
+			if width, _ := strconv.Atoi(matches[1]); width > network.ChannelWidthMhz {
+				network.ChannelWidthMhz = width
+			}
+		}
+		if matches := surveyCenterRe.FindStringSubmatch(line); len(matches) > 0 {
+			if center, _ := strconv.Atoi(matches[1]); center > 0 {
+				network.centerChannel = center
+			}
+		}
+		if matches := surveyOffsetRe.FindStringSubmatch(line); len(matches) > 0 && network.centerChannel == 0 {
+			if matches[1] == "above" {
+				network.centerChannel = network.Channel + 2
+			} else {
+				network.centerChannel = network.Channel - 2
+			}
+		}
+	}
+	return networks
+}
+
+// occupiedChannels returns the numbers of the 20MHz channels making up the network's full channel width, or just its
+// primary channel if the extent of a wider channel wasn't advertised.
+func (network *SurveyedNetwork) occupiedChannels() []int {
+	// Adjacent 20MHz channels are numbered four apart, so a wide channel extends two channel numbers either side of its
+	// center for each additional 20MHz.
+	count := network.ChannelWidthMhz / baseChannelWidthMhz
+	first := network.centerChannel - 2*(count-1)
+	last := first + 4*(count-1)
+	if count <= 1 || network.Channel < first || network.Channel > last || (network.Channel-first)%4 != 0 {
+		return []int{network.Channel}
+	}
+	channels := make([]int, count)
+	for i := range channels {
+		channels[i] = first + 4*i
+	}
+	return channels
+}
+
+// summarizeChannelCongestion tallies the given networks against each of the given channels that they occupy across
+// their full channel width. Networks on other channels are ignored.
+func summarizeChannelCongestion(networks []SurveyedNetwork, channels []int) []ChannelCongestion {
+	congestion := make([]ChannelCongestion, len(channels))
+	congestionByChannel := make(map[int]*ChannelCongestion)
+	for i, channel := range channels {
+		congestion[i].Channel = channel
+		congestionByChannel[channel] = &congestion[i]
+	}
+
+	for _, network := range networks {
+		for _, occupiedChannel := range network.occupiedChannels() {
+			channel, ok := congestionByChannel[occupiedChannel]
+			if !ok {
+				continue
+			}
+			if channel.NetworkCount == 0 || network.SignalDbm > channel.StrongestSignalDbm {
+				channel.StrongestSignalDbm = network.SignalDbm
+			}
+			channel.NetworkCount++
+			channel.totalSignalMw += math.Pow(10, float64(network.SignalDbm)/10)
+			channel.TotalSignalDbm = int(math.Round(10 * math.Log10(channel.totalSignalMw)))
+		}
+	}
+	return congestion
+}
+
+// validChannels returns the channel numbers that the access point of the given hardware type can use, in ascending
+// order.
+func validChannels(radioType RadioType) []int {
+	switch radioType {
+	case TypeLinksys:
+		return validLinksysChannels
+	case TypeVividHosting:
+		var channels []int
+		for channel := 1; channel <= 233; channel++ {
+			if isValid6GhzChannel(channel) {
+				channels = append(channels, channel)
+			}
+		}
+		return channels
+	default:
+		return nil
+	}
+}
@@ -0,0 +1,213 @@
+// This file is specific to the access point role of the API.
+
+package radio
+
+import (
+	"errors"
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"strings"
+	"testing"
+)
+
+var iwinfoScanOutput = `Cell 01 - Address: 48:da:35:b0:10:01
+          ESSID: "practice-field"
+          Mode: Master  Frequency: 6.135 GHz  Band: 6 GHz  Channel: 37
+          Signal: -58 dBm  Quality: 52/70
+          Encryption: WPA3 SAE (CCMP)
+          HE Operation:
+                    Center Frequency 1: 39
+                    Channel Width: 40 MHz
+
+Cell 02 - Address: 48:DA:35:B0:10:02
+          ESSID: unknown
+          Mode: Master  Channel: 37
+          Signal: -61 dBm  Quality: 49/70
+          Encryption: WPA3 SAE (CCMP)
+
+Cell 03 - Address: 48:DA:35:B0:10:03
+          ESSID: "pit-display"
+          Mode: Master  Channel: 69
+          Signal: -71 dBm  Quality: 39/70
+          Encryption: none
+          HE Operation:
+                    Center Frequency 1: 71
+                    Channel Width: 80 MHz
+
+Cell 04 - Address: 48:DA:35:B0:10:04
+          ESSID: "off-grid"
+          Mode: Master  Channel: 2
+          Signal: -40 dBm  Quality: 70/70
+          Encryption: none
+
+`
+
+func TestParseScanResults(t *testing.T) {
+	networks := parseScanResults(iwinfoScanOutput)
+	if assert.Equal(t, 4, len(networks)) {
+		assert.Equal(
+			t,
+			SurveyedNetwork{
+				MacAddress:      "48:DA:35:B0:10:01",
+				Ssid:            "practice-field",
+				Channel:         37,
+				ChannelWidthMhz: 40,
+				SignalDbm:       -58,
+				Encryption:      "WPA3 SAE (CCMP)",
+				centerChannel:   39,
+			},
+			networks[0],
+		)
+		assert.Equal(
+			t,
+			SurveyedNetwork{
+				MacAddress:      "48:DA:35:B0:10:02",
+				Channel:         37,
+				ChannelWidthMhz: 20,
+				SignalDbm:       -61,
+				Encryption:      "WPA3 SAE (CCMP)",
+			},
+			networks[1],
+		)
+		assert.Equal(t, "pit-display", networks[2].Ssid)
+		assert.Equal(t, 69, networks[2].Channel)
+		assert.Equal(t, 80, networks[2].ChannelWidthMhz)
+		assert.Equal(t, -71, networks[2].SignalDbm)
+		assert.Equal(t, "none", networks[2].Encryption)
+	}
+
+	assert.Equal(t, []SurveyedNetwork{}, parseScanResults("No scan results\n"))
+	assert.Equal(t, []SurveyedNetwork{}, parseScanResults(""))
+}
+
+func TestSurveyedNetwork_occupiedChannels(t *testing.T) {
+	networks := parseScanResults(iwinfoScanOutput)
+	assert.Equal(t, []int{37, 41}, networks[0].occupiedChannels())
+	assert.Equal(t, []int{37}, networks[1].occupiedChannels())
+	assert.Equal(t, []int{65, 69, 73, 77}, networks[2].occupiedChannels())
+
+	// A 5GHz network advertising only an HT operation section, which gives the side of a 40MHz channel but not its
+	// center.
+	networks = parseScanResults(`Cell 01 - Address: 48:DA:35:B0:10:05
+          Mode: Master  Frequency: 5.200 GHz  Band: 5 GHz  Channel: 40
+          HT Operation:
+                    Primary Channel: 40
+                    Secondary Channel Offset: below
+                    Channel Width: 40 MHz or higher
+`)
+	if assert.Equal(t, 1, len(networks)) {
+		assert.Equal(t, 40, networks[0].ChannelWidthMhz)
+		assert.Equal(t, []int{36, 40}, networks[0].occupiedChannels())
+	}
+
+	// The VHT operation section takes precedence for a network that is wider still.
+	networks = parseScanResults(`Cell 01 - Address: 48:DA:35:B0:10:06
+          Mode: Master  Channel: 149
+          HT Operation:
+                    Secondary Channel Offset: above
+                    Channel Width: 40 MHz or higher
+          VHT Operation:
+                    Center Frequency 1: 155
+                    Center Frequency 2: 0
+                    Channel Width: 80 MHz
+`)
+	if assert.Equal(t, 1, len(networks)) {
+		assert.Equal(t, []int{149, 153, 157, 161}, networks[0].occupiedChannels())
+	}
+
+	// A wide channel whose center is missing or inconsistent with the primary channel falls back to the primary.
+	assert.Equal(t, []int{69}, (&SurveyedNetwork{Channel: 69, ChannelWidthMhz: 80}).occupiedChannels())
+	assert.Equal(
+		t, []int{69}, (&SurveyedNetwork{Channel: 69, ChannelWidthMhz: 40, centerChannel: 103}).occupiedChannels(),
+	)
+}
+
+func TestSummarizeChannelCongestion(t *testing.T) {
+	channels := summarizeChannelCongestion(parseScanResults(iwinfoScanOutput), []int{5, 37, 41, 69, 77})
+	if assert.Equal(t, 5, len(channels)) {
+		assert.Equal(t, 5, channels[0].Channel)
+		assert.Equal(t, 0, channels[0].NetworkCount)
+		assert.Equal(t, 0, channels[0].StrongestSignalDbm)
+		assert.Equal(t, 0, channels[0].TotalSignalDbm)
+		assert.Equal(t, 37, channels[1].Channel)
+		assert.Equal(t, 2, channels[1].NetworkCount)
+		assert.Equal(t, -58, channels[1].StrongestSignalDbm)
+		assert.Equal(t, -56, channels[1].TotalSignalDbm)
+		assert.Equal(t, 41, channels[2].Channel)
+		assert.Equal(t, 1, channels[2].NetworkCount)
+		assert.Equal(t, -58, channels[2].StrongestSignalDbm)
+		assert.Equal(t, -58, channels[2].TotalSignalDbm)
+		assert.Equal(t, 69, channels[3].Channel)
+		assert.Equal(t, 1, channels[3].NetworkCount)
+		assert.Equal(t, -71, channels[3].StrongestSignalDbm)
+		assert.Equal(t, -71, channels[3].TotalSignalDbm)
+
+		// A wide network counts against every channel it spans, not just its primary one.
+		assert.Equal(t, 77, channels[4].Channel)
+		assert.Equal(t, 1, channels[4].NetworkCount)
+		assert.Equal(t, -71, channels[4].TotalSignalDbm)
+	}
+}
+
+func TestAccessPointRadio_Survey(t *testing.T) {
+	fakeTree := newFakeUciTree()
+	uciTree = fakeTree
+	fakeTree.valuesForGet["system.@system[0].model"] = "VH-109(AP)"
+	fakeShell := newFakeShell(t)
+	shell = fakeShell
+	fakeShell.commandOutput["cat /etc/vh_firmware"] = ""
+	radio := NewAccessPointRadio()
+
+	// Every channel is empty except for one.
+	fakeShell.commandOutput["iwinfo wifi1 scan"] = "Cell 01 - Address: 48:DA:35:B0:10:01\nMode: Master  Channel: 5\n" +
+		"Signal: -80 dBm  Quality: 20/70\n"
+	survey, err := radio.Survey()
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(survey.Networks))
+	assert.Equal(t, 29, len(survey.Channels))
+	assert.Equal(t, 5, survey.Channels[0].Channel)
+	assert.Equal(t, 229, survey.Channels[28].Channel)
+	assert.Equal(t, 13, survey.LeastCongestedChannel)
+
+	// Networks on channels that aren't valid for the radio are listed but don't count toward congestion.
+	fakeShell.commandOutput["iwinfo wifi1 scan"] = iwinfoScanOutput
+	survey, err = radio.Survey()
+	assert.Nil(t, err)
+	assert.Equal(t, 4, len(survey.Networks))
+	assert.Equal(t, 2, survey.Channels[4].NetworkCount)
+	assert.Equal(t, 77, survey.Channels[9].Channel)
+	assert.Equal(t, 1, survey.Channels[9].NetworkCount)
+	assert.Equal(t, 5, survey.LeastCongestedChannel)
+
+	// Every channel is occupied, so the one with the weakest combined signal wins.
+	var scanOutput strings.Builder
+	for i, channel := range validChannels(TypeVividHosting) {
+		signal := -50
+		if channel == 101 {
+			signal = -85
+		}
+		scanOutput.WriteString(fmt.Sprintf("Cell %02d - Address: 48:DA:35:B0:20:%02X\n", i, i))
+		scanOutput.WriteString(fmt.Sprintf("Channel: %d\nSignal: %d dBm\n", channel, signal))
+	}
+	fakeShell.commandOutput["iwinfo wifi1 scan"] = scanOutput.String()
+	survey, err = radio.Survey()
+	assert.Nil(t, err)
+	assert.Equal(t, 29, len(survey.Networks))
+	assert.Equal(t, 101, survey.LeastCongestedChannel)
+
+	// The scan fails.
+	delete(fakeShell.commandOutput, "iwinfo wifi1 scan")
+	fakeShell.commandErrors["iwinfo wifi1 scan"] = errors.New("oops")
+	_, err = radio.Survey()
+	assert.EqualError(t, err, "error scanning for networks on device wifi1: oops")
+}
+
+func TestValidChannels(t *testing.T) {
+	assert.Equal(t, validLinksysChannels, validChannels(TypeLinksys))
+	channels := validChannels(TypeVividHosting)
+	assert.Equal(t, 29, len(channels))
+	assert.Equal(t, 5, channels[0])
+	assert.Equal(t, 13, channels[1])
+	assert.Equal(t, 229, channels[28])
+	assert.Nil(t, validChannels(TypeUnknown))
+}
@@ -0,0 +1,57 @@
+package radio
+
+import (
+	"fmt"
+	"github.com/digineo/go-uci"
+)
+
+// uciOption identifies a single UCI option that may be changed when configuring the radio.
+type uciOption struct {
+	config     string
+	section    string
+	option     string
+	optionType uci.OptionType
+}
+
+// configurationSnapshot captures the state of the radio prior to applying a configuration request.
+type configurationSnapshot struct {
+	// Values of the UCI options that the configuration request may change.
+	uci uciSnapshot
+
+	// Copy of the in-memory state of the radio, as returned by its StatusSnapshot method.
+	radio any
+}
+
+// uciSnapshot records the values of a set of UCI options at a point in time so that they can later be restored.
+type uciSnapshot struct {
+	options []uciOption
+
+	// Values of each option, in the same order as the options. Nil if the option wasn't set.
+	values [][]string
+}
+
+// takeUciSnapshot records the current values of the given UCI options.
+func takeUciSnapshot(options []uciOption) uciSnapshot {
+	snapshot := uciSnapshot{options: options, values: make([][]string, len(options))}
+	for i, option := range options {
+		if values, ok := uciTree.Get(option.config, option.section, option.option); ok && len(values) > 0 {
+			snapshot.values[i] = values
+		}
+	}
+	return snapshot
+}
+
+// restore sets each of the options in the snapshot back to its recorded value and commits the result.
+func (snapshot uciSnapshot) restore() error {
+	for i, option := range snapshot.options {
+		if snapshot.values[i] == nil {
+			uciTree.Del(option.config, option.section, option.option)
+		} else {
+			uciTree.SetType(option.config, option.section, option.option, option.optionType, snapshot.values[i]...)
+		}
+	}
+	if err := uciTree.Commit(); err != nil {
+		return fmt.Errorf("failed to commit restored configuration: %v", err)
+	}
+	return nil
+}
@@ -0,0 +1,43 @@
+package radio
+
+import (
+	"errors"
+	"github.com/digineo/go-uci"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestUciSnapshot(t *testing.T) {
+	fakeTree := newFakeUciTree()
+	uciTree = fakeTree
+	fakeTree.valuesForGet["wireless.wifi1.channel"] = "93"
+	fakeTree.valuesForGet["dhcp.lan.dhcp_option"] = "3,10.12.34.4"
+	options := []uciOption{
+		{"wireless", "wifi1", "channel", uci.TypeOption},
+		{"wireless", "wifi1", "htmode", uci.TypeOption},
+		{"dhcp", "lan", "dhcp_option", uci.TypeList},
+	}
+
+	snapshot := takeUciSnapshot(options)
+	assert.Equal(t, [][]string{{"93"}, nil, {"3,10.12.34.4"}}, snapshot.values)
+
+	// Changes made after the snapshot is taken shouldn't affect it.
+	fakeTree.valuesForGet["wireless.wifi1.channel"] = "5"
+	fakeTree.valuesForGet["wireless.wifi1.htmode"] = "HT40"
+
+	assert.Nil(t, snapshot.restore())
+	assert.Equal(
+		t,
+		map[string]string{
+			"wireless.wifi1.channel": "93",
+			"wireless.wifi1.htmode":  "***DELETED***",
+			"dhcp.lan.dhcp_option":   "3,10.12.34.4",
+		},
+		fakeTree.valuesFromSet,
+	)
+	assert.Equal(t, 1, fakeTree.commitCount)
+
+	// Commit fails.
+	fakeTree.commitError = errors.New("oops")
+	assert.Equal(t, "failed to commit restored configuration: oops", snapshot.restore().Error())
+}
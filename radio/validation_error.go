@@ -0,0 +1,83 @@
+package radio
+
+import (
+	"fmt"
+	"strings"
+)
+
+// ValidationErrorCode is a stable, machine-readable identifier for the kind of problem found with a configuration
+// request, for clients to act on without parsing the accompanying message.
+type ValidationErrorCode string
+
+const (
+	ValidationEmptyRequest          ValidationErrorCode = "EMPTY_REQUEST"
+	ValidationInvalidMode           ValidationErrorCode = "INVALID_MODE"
+	ValidationInvalidChannel        ValidationErrorCode = "INVALID_CHANNEL"
+	ValidationInvalidBandwidth      ValidationErrorCode = "INVALID_CHANNEL_BANDWIDTH"
+	ValidationInvalidTxPower        ValidationErrorCode = "INVALID_TX_POWER"
+	ValidationInvalidVlan           ValidationErrorCode = "INVALID_VLAN"
+	ValidationInvalidStation        ValidationErrorCode = "INVALID_STATION"
+	ValidationInvalidSsid           ValidationErrorCode = "INVALID_SSID"
+	ValidationInvalidWpaKey         ValidationErrorCode = "INVALID_WPA_KEY"
+	ValidationInvalidBandwidthLimit ValidationErrorCode = "INVALID_BANDWIDTH_LIMIT"
+	ValidationInvalidSyslogIp       ValidationErrorCode = "INVALID_SYSLOG_IP_ADDRESS"
+	ValidationInvalidTeamNumber     ValidationErrorCode = "INVALID_TEAM_NUMBER"
+)
+
+// ValidationError describes a parameter of a configuration request that has an invalid value.
+type ValidationError struct {
+	// Kind of problem found with the parameter.
+	Code ValidationErrorCode
+
+	// Path of the parameter within the JSON request, with nested fields separated by dots (e.g. "channel" or
+	// "stationConfigurations.red1.wpaKey"). Blank if the problem isn't specific to a single parameter.
+	Field string
+
+	// Human-readable description of the problem.
+	Message string
+}
+
+// newValidationError creates a validation error having the given code and field and a message built from the given
+// format and arguments.
+func newValidationError(code ValidationErrorCode, field, format string, args ...any) *ValidationError {
+	return &ValidationError{Code: code, Field: field, Message: fmt.Sprintf(format, args...)}
+}
+
+func (err *ValidationError) Error() string {
+	return err.Message
+}
+
+// ValidationErrors collects every problem found with a configuration request, in the order that the parameters were
+// checked.
+type ValidationErrors []*ValidationError
+
+// add appends a validation error having the given code and field and a message built from the given format and
+// arguments.
+func (errs *ValidationErrors) add(code ValidationErrorCode, field, format string, args ...any) {
+	*errs = append(*errs, newValidationError(code, field, format, args...))
+}
+
+// orNil returns the collected errors as an error, or nil if there are none.
+func (errs ValidationErrors) orNil() error {
+	if len(errs) == 0 {
+		return nil
+	}
+	return errs
+}
+
+func (errs ValidationErrors) Error() string {
+	messages := make([]string, len(errs))
+	for i, err := range errs {
+		messages[i] = err.Message
+	}
+	return strings.Join(messages, "; ")
+}
+
+// Unwrap returns the individual errors, so that errors.As can find the first *ValidationError among them.
+func (errs ValidationErrors) Unwrap() []error {
+	unwrapped := make([]error, len(errs))
+	for i, err := range errs {
+		unwrapped[i] = err
+	}
+	return unwrapped
+}
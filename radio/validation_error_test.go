@@ -0,0 +1,107 @@
+package radio
+
+import (
+	"errors"
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestValidationError(t *testing.T) {
+	err := newValidationError(ValidationInvalidChannel, "channel", "invalid channel for %s: %d", TypeLinksys, 5)
+	assert.EqualError(t, err, "invalid channel for TypeLinksys: 5")
+	assert.Equal(t, ValidationInvalidChannel, err.Code)
+	assert.Equal(t, "channel", err.Field)
+
+	// The details survive being wrapped with more context.
+	var validationErr *ValidationError
+	if assert.True(t, errors.As(fmt.Errorf("invalid configuration: %w", err), &validationErr)) {
+		assert.Same(t, err, validationErr)
+	}
+}
+
+func TestValidationError_Fields(t *testing.T) {
+	linksysRadio := &AccessPointRadio{Type: TypeLinksys}
+	robotRadio := &RobotRadio{}
+
+	cases := []struct {
+		request ConfigurationRequest
+		radio   Radio
+		code    ValidationErrorCode
+		field   string
+	}{
+		{&AccessPointConfigurationRequest{}, linksysRadio, ValidationEmptyRequest, ""},
+		{&AccessPointConfigurationRequest{Channel: 5}, linksysRadio, ValidationInvalidChannel, "channel"},
+		{
+			&AccessPointConfigurationRequest{ChannelBandwidth: "40MHz"},
+			linksysRadio,
+			ValidationInvalidBandwidth,
+			"channelBandwidth",
+		},
+		{&AccessPointConfigurationRequest{RedVlans: Vlans102030}, linksysRadio, ValidationInvalidVlan, "blueVlans"},
+		{
+			&AccessPointConfigurationRequest{
+				StationConfigurations: map[string]StationConfiguration{"red4": {Ssid: "254", WpaKey: "12345678"}},
+			},
+			linksysRadio,
+			ValidationInvalidStation,
+			"stationConfigurations.red4",
+		},
+		{
+			&AccessPointConfigurationRequest{
+				StationConfigurations: map[string]StationConfiguration{"red2": {Ssid: "254", WpaKey: "1234"}},
+			},
+			linksysRadio,
+			ValidationInvalidWpaKey,
+			"stationConfigurations.red2.wpaKey",
+		},
+		{
+			&AccessPointConfigurationRequest{
+				StationConfigurations: map[string]StationConfiguration{"blue3": {Ssid: "", WpaKey: "12345678"}},
+			},
+			linksysRadio,
+			ValidationInvalidSsid,
+			"stationConfigurations.blue3.ssid",
+		},
+		{
+			&AccessPointConfigurationRequest{SyslogIpAddress: "blorpy"},
+			linksysRadio,
+			ValidationInvalidSyslogIp,
+			"syslogIpAddress",
+		},
+		{&RobotRadioConfigurationRequest{Mode: "BLORPY"}, robotRadio, ValidationInvalidMode, "mode"},
+		{
+			&RobotRadioConfigurationRequest{Mode: modeTeamRobotRadio, TeamNumber: 0},
+			robotRadio,
+			ValidationInvalidTeamNumber,
+			"teamNumber",
+		},
+		{
+			&RobotRadioConfigurationRequest{Mode: modeTeamRobotRadio, TeamNumber: 254, WpaKey6: "12345678"},
+			robotRadio,
+			ValidationInvalidWpaKey,
+			"wpaKey24",
+		},
+	}
+	for _, testCase := range cases {
+		var validationErr *ValidationError
+		if assert.True(t, errors.As(testCase.request.Validate(testCase.radio), &validationErr), testCase.field) {
+			assert.Equal(t, testCase.code, validationErr.Code)
+			assert.Equal(t, testCase.field, validationErr.Field)
+		}
+	}
+}
+
+func TestValidationErrors(t *testing.T) {
+	var errs ValidationErrors
+	assert.Nil(t, errs.orNil())
+	errs.add(ValidationInvalidChannel, "channel", "invalid channel: %d", 3)
+	errs.add(ValidationInvalidWpaKey, "wpaKey6", "invalid wpaKey6")
+	assert.EqualError(t, errs.orNil(), "invalid channel: 3; invalid wpaKey6")
+
+	// The first of the individual errors can be extracted, for callers that only expect one.
+	var validationErr *ValidationError
+	if assert.True(t, errors.As(fmt.Errorf("invalid configuration: %w", errs), &validationErr)) {
+		assert.Same(t, errs[0], validationErr)
+	}
+}
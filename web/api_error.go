@@ -0,0 +1,147 @@
+package web
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"github.com/patfair/frc-radio-api/radio"
+	"log"
+	"net/http"
+)
+
+// Machine-readable codes identifying the kind of error in API error responses, in addition to the validation codes
+// defined by the radio package. Clients can rely on these not changing, unlike the accompanying messages.
+const (
+	errorBadRequest          = "BAD_REQUEST"
+	errorUnauthorized        = "UNAUTHORIZED"
+	errorForbidden           = "FORBIDDEN"
+	errorNotFound            = "NOT_FOUND"
+	errorConflict            = "CONFLICT"
+	errorUnprocessableEntity = "UNPROCESSABLE_ENTITY"
+	errorInternal            = "INTERNAL_ERROR"
+	errorInvalidJson         = "INVALID_JSON"
+	errorInvalidFirmwareFile = "INVALID_FIRMWARE_FILE"
+	errorInvalidChecksum     = "INVALID_CHECKSUM"
+	errorChecksumMismatch    = "CHECKSUM_MISMATCH"
+	errorInvalidToken        = "INVALID_TOKEN"
+	errorInvalidWebhook      = "INVALID_WEBHOOK"
+	errorValidationFailed    = "VALIDATION_FAILED"
+)
+
+// errorResponse is the JSON body returned along with every error status.
+type errorResponse struct {
+	// Machine-readable code identifying the kind of error, e.g. "INVALID_CHANNEL" or "UNAUTHORIZED".
+	Code string `json:"code"`
+
+	// Path of the request field that caused the error, e.g. "stationConfigurations.red1.wpaKey", if applicable.
+	Field string `json:"field,omitempty"`
+
+	// Human-readable description of the error.
+	Message string `json:"message"`
+
+	// Every problem found with the fields of a configuration request, grouped by field path in the order they were
+	// found, if the request failed validation.
+	Errors map[string][]fieldError `json:"errors,omitempty"`
+}
+
+// fieldError describes a problem found with a single field of a configuration request.
+type fieldError struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+// apiError is an error annotated with the code and, optionally, the request field to report in the error response.
+type apiError struct {
+	code  string
+	field string
+	err   error
+}
+
+// newApiError wraps the given error with the given code and field.
+func newApiError(code, field string, err error) error {
+	return &apiError{code: code, field: field, err: err}
+}
+
+func (err *apiError) Error() string {
+	return err.err.Error()
+}
+
+func (err *apiError) Unwrap() error {
+	return err.err
+}
+
+// newErrorResponse builds the response describing the given error, taking the code and field from the error if it
+// carries them and otherwise deriving the code from the given HTTP status code. If the error comprises several
+// validation errors, the code is VALIDATION_FAILED and they are each listed under their field instead.
+func newErrorResponse(err error, statusCode int) errorResponse {
+	response := errorResponse{Message: err.Error()}
+	var validationErrs radio.ValidationErrors
+	var validationErr *radio.ValidationError
+	var annotatedErr *apiError
+	if !errors.As(err, &validationErrs) && errors.As(err, &validationErr) {
+		validationErrs = radio.ValidationErrors{validationErr}
+	}
+	switch {
+	case len(validationErrs) > 0:
+		// Validation errors are the most specific, so they take precedence over any annotation wrapping them.
+		if len(validationErrs) == 1 {
+			response.Code, response.Field = string(validationErrs[0].Code), validationErrs[0].Field
+		} else {
+			response.Code = errorValidationFailed
+		}
+		for _, validationErr = range validationErrs {
+			if validationErr.Field == "" {
+				continue
+			}
+			if response.Errors == nil {
+				response.Errors = make(map[string][]fieldError)
+			}
+			response.Errors[validationErr.Field] = append(
+				response.Errors[validationErr.Field],
+				fieldError{Code: string(validationErr.Code), Message: validationErr.Message},
+			)
+		}
+	case errors.As(err, &annotatedErr):
+		response.Code, response.Field = annotatedErr.code, annotatedErr.field
+	default:
+		response.Code = errorCodeForStatus(statusCode)
+	}
+	return response
+}
+
+// errorCodeForStatus returns the generic error code corresponding to the given HTTP status code.
+func errorCodeForStatus(statusCode int) string {
+	switch statusCode {
+	case http.StatusUnauthorized:
+		return errorUnauthorized
+	case http.StatusForbidden:
+		return errorForbidden
+	case http.StatusNotFound:
+		return errorNotFound
+	case http.StatusConflict:
+		return errorConflict
+	case http.StatusUnprocessableEntity:
+		return errorUnprocessableEntity
+	}
+	if statusCode >= 500 {
+		return errorInternal
+	}
+	return errorBadRequest
+}
+
+// handleWebErr writes the given error out as a JSON error response with the given status code.
+func handleWebErr(w http.ResponseWriter, err error, statusCode int) {
+	log.Printf("HTTP request error %d: %v", statusCode, err)
+
+	// Encoding can't fail since the response consists only of strings.
+	var body bytes.Buffer
+	encoder := json.NewEncoder(&body)
+	encoder.SetEscapeHTML(false)
+	encoder.SetIndent("", "  ")
+	_ = encoder.Encode(newErrorResponse(err, statusCode))
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(statusCode)
+	_, _ = w.Write(body.Bytes())
+}
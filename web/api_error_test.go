@@ -0,0 +1,94 @@
+package web
+
+import (
+	"errors"
+	"fmt"
+	"github.com/patfair/frc-radio-api/radio"
+	"github.com/stretchr/testify/assert"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestNewErrorResponse(t *testing.T) {
+	// The code is derived from the status code if the error doesn't carry one.
+	assert.Equal(t, errorResponse{Code: "BAD_REQUEST", Message: "oops"}, newErrorResponse(errors.New("oops"), 400))
+	assert.Equal(t, "UNAUTHORIZED", newErrorResponse(errors.New("oops"), 401).Code)
+	assert.Equal(t, "FORBIDDEN", newErrorResponse(errors.New("oops"), 403).Code)
+	assert.Equal(t, "NOT_FOUND", newErrorResponse(errors.New("oops"), 404).Code)
+	assert.Equal(t, "CONFLICT", newErrorResponse(errors.New("oops"), 409).Code)
+	assert.Equal(t, "UNPROCESSABLE_ENTITY", newErrorResponse(errors.New("oops"), 422).Code)
+	assert.Equal(t, "INTERNAL_ERROR", newErrorResponse(errors.New("oops"), 500).Code)
+	assert.Equal(t, "INTERNAL_ERROR", newErrorResponse(errors.New("oops"), 503).Code)
+
+	// An annotated error carries its own code and field, even when wrapped.
+	err := fmt.Errorf("upload failed: %w", newApiError(errorChecksumMismatch, "checksum", errors.New("mismatch")))
+	assert.Equal(
+		t,
+		errorResponse{Code: "CHECKSUM_MISMATCH", Field: "checksum", Message: "upload failed: mismatch"},
+		newErrorResponse(err, 400),
+	)
+
+	// A validation error takes precedence over a more generic annotation.
+	validationErr := &radio.ValidationError{
+		Code: radio.ValidationInvalidWpaKey, Field: "stationConfigurations.red2.wpaKey", Message: "bad key",
+	}
+	err = newApiError(errorInvalidJson, "", fmt.Errorf("invalid JSON: %w", validationErr))
+	assert.Equal(
+		t,
+		errorResponse{
+			Code:    "INVALID_WPA_KEY",
+			Field:   "stationConfigurations.red2.wpaKey",
+			Message: "invalid JSON: bad key",
+			Errors: map[string][]fieldError{
+				"stationConfigurations.red2.wpaKey": {{Code: "INVALID_WPA_KEY", Message: "bad key"}},
+			},
+		},
+		newErrorResponse(err, 400),
+	)
+
+	// Several validation errors are listed by field, without dropping any that share a field, and a field-less one is
+	// only described by the message.
+	err = fmt.Errorf(
+		"invalid configuration: %w",
+		radio.ValidationErrors{
+			validationErr,
+			&radio.ValidationError{Code: radio.ValidationInvalidChannel, Field: "channel", Message: "bad channel"},
+			&radio.ValidationError{Code: radio.ValidationInvalidChannel, Field: "channel", Message: "worse channel"},
+		},
+	)
+	assert.Equal(
+		t,
+		errorResponse{
+			Code:    "VALIDATION_FAILED",
+			Message: "invalid configuration: bad key; bad channel; worse channel",
+			Errors: map[string][]fieldError{
+				"stationConfigurations.red2.wpaKey": {{Code: "INVALID_WPA_KEY", Message: "bad key"}},
+				"channel": {
+					{Code: "INVALID_CHANNEL", Message: "bad channel"},
+					{Code: "INVALID_CHANNEL", Message: "worse channel"},
+				},
+			},
+		},
+		newErrorResponse(err, 400),
+	)
+	err = &radio.ValidationError{Code: radio.ValidationEmptyRequest, Message: "empty configuration request"}
+	assert.Equal(
+		t,
+		errorResponse{Code: "EMPTY_REQUEST", Message: "empty configuration request"},
+		newErrorResponse(err, 400),
+	)
+}
+
+func TestHandleWebErr(t *testing.T) {
+	recorder := httptest.NewRecorder()
+	handleWebErr(recorder, errors.New("no webhook with ID \"1234\" <here>"), 404)
+	assert.Equal(t, 404, recorder.Code)
+	assert.Equal(t, "nosniff", recorder.Header().Get("X-Content-Type-Options"))
+	// Messages aren't HTML-escaped, since the body is never rendered as HTML.
+	assert.Contains(t, recorder.Body.String(), "<here>")
+	assert.Equal(
+		t,
+		errorResponse{Code: "NOT_FOUND", Message: "no webhook with ID \"1234\" <here>"},
+		decodeErrorResponse(t, recorder),
+	)
+}
@@ -0,0 +1,36 @@
+package web
+
+import (
+	"encoding/json"
+	"net/http"
+)
+
+// auditHandler returns a JSON list of the entries in the audit log, oldest first, optionally limited to those after the
+// time given by the 'since' query parameter.
+func (web *WebServer) auditHandler(w http.ResponseWriter, r *http.Request) {
+	if !web.authorize(w, r, scopeAdmin) {
+		return
+	}
+
+	since, err := parseSinceParam(r.URL.Query().Get("since"))
+	if err != nil {
+		handleWebErr(w, err, http.StatusBadRequest)
+		return
+	}
+	entries, err := web.audit.entries(since)
+	if err != nil {
+		handleWebErr(w, err, http.StatusInternalServerError)
+		return
+	}
+
+	jsonData, err := json.MarshalIndent(entries, "", "  ")
+	if err != nil {
+		handleWebErr(w, err, http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if _, err = w.Write(jsonData); err != nil {
+		handleWebErr(w, err, http.StatusInternalServerError)
+		return
+	}
+}
@@ -0,0 +1,163 @@
+package web
+
+import (
+	"encoding/json"
+	"github.com/patfair/frc-radio-api/radio"
+	"github.com/stretchr/testify/assert"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWeb_auditHandler(t *testing.T) {
+	web := NewWebServer(radio.NewAccessPointRadio())
+	web.audit = newAuditLog(filepath.Join(t.TempDir(), "audit.log"))
+	web.tokens = newTokenStore(filepath.Join(t.TempDir(), "tokens.json"))
+
+	// Nothing is recorded for requests that don't change anything.
+	recorder := web.getHttpResponse("/audit")
+	assert.Equal(t, 200, recorder.Code)
+	assert.Equal(t, "application/json", recorder.Header().Get("Content-Type"))
+	assert.Equal(t, "[]", recorder.Body.String())
+	web.getHttpResponse("/status")
+	assert.Equal(t, "[]", web.getHttpResponse("/audit").Body.String())
+
+	// An accepted configuration request is recorded with its WPA keys redacted.
+	recorder = web.postHttpResponse(
+		"/configuration", `{"channel": 149, "stationConfigurations": {"red1": {"ssid": "254", "wpaKey": "12345678"}}}`,
+	)
+	assert.Equal(t, 202, recorder.Code)
+	var job radio.ConfigurationJob
+	assert.Nil(t, json.Unmarshal(recorder.Body.Bytes(), &job))
+
+	// A rejected configuration request is recorded along with the reason.
+	recorder = web.postHttpResponse("/configuration", `{"channel": 3, "stationConfigurations": {}}`)
+	assert.Equal(t, 400, recorder.Code)
+
+	// Requests to other mutating endpoints are recorded too.
+	_, adminSecret, _ := web.tokens.create("Admin", []tokenScope{scopeAdmin})
+	adminHeaders := map[string]string{"Authorization": "Bearer " + adminSecret}
+	recorder = web.postHttpResponseWithHeaders("/webhooks", `{"url": "http://10.0.100.5/hook"}`, adminHeaders)
+	assert.Equal(t, 201, recorder.Code)
+	recorder = web.postFileHttpResponse("/firmware", "file", []byte("firmware"), map[string]string{"checksum": "1234"})
+	assert.Equal(t, 401, recorder.Code)
+
+	recorder = web.getHttpResponseWithHeaders("/audit", adminHeaders)
+	assert.Equal(t, 200, recorder.Code)
+	assert.NotContains(t, recorder.Body.String(), "12345678")
+	var entries []auditEntry
+	assert.Nil(t, json.Unmarshal(recorder.Body.Bytes(), &entries))
+	if assert.Equal(t, 4, len(entries)) {
+		assert.Equal(t, "POST /configuration", entries[0].Action)
+		assert.Equal(t, "anonymous", entries[0].Identity)
+		assert.Equal(t, 202, entries[0].StatusCode)
+		assert.Equal(t, auditAccepted, entries[0].Outcome)
+		assert.Equal(t, job.Id, entries[0].JobId)
+		assert.Equal(
+			t,
+			map[string]any{
+				"channel": float64(149),
+				"stationConfigurations": map[string]any{
+					"red1": map[string]any{"ssid": "254", "wpaKey": redactedValue},
+				},
+			},
+			entries[0].Request,
+		)
+		assert.Contains(t, web.audit.pendingJobs, job.Id)
+
+		assert.Equal(t, 400, entries[1].StatusCode)
+		assert.Equal(t, auditRejected, entries[1].Outcome)
+		assert.Contains(t, entries[1].Error, "invalid configuration: invalid channel for TypeLinksys: 3")
+		assert.Empty(t, entries[1].JobId)
+
+		assert.Equal(t, "POST /webhooks", entries[2].Action)
+		assert.True(t, strings.HasPrefix(entries[2].Identity, "token Admin ("))
+		assert.Equal(t, map[string]any{"url": "http://10.0.100.5/hook"}, entries[2].Request)
+		assert.Empty(t, entries[2].JobId)
+
+		assert.Equal(t, "POST /firmware", entries[3].Action)
+		assert.Equal(t, auditUnauthorized, entries[3].Outcome)
+		assert.Contains(t, entries[3].Error, "not authorized")
+		// The upload isn't parsed, and so isn't summarized, until the request has been authorized.
+		assert.Nil(t, entries[3].Request)
+	}
+
+	recorder = web.getHttpResponseWithHeaders(
+		"/audit?since="+entries[1].Time.Format(time.RFC3339Nano), adminHeaders,
+	)
+	assert.Equal(t, 200, recorder.Code)
+	assert.Nil(t, json.Unmarshal(recorder.Body.Bytes(), &entries))
+	assert.Equal(t, 2, len(entries))
+
+	recorder = web.getHttpResponseWithHeaders("/audit?since=yesterday", adminHeaders)
+	assert.Equal(t, 400, recorder.Code)
+	assert.Contains(t, decodeErrorResponse(t, recorder).Message, "invalid since time \"yesterday\"")
+}
+
+func TestWeb_auditHandlerTokenRevocation(t *testing.T) {
+	web := NewWebServer(radio.NewAccessPointRadio())
+	web.audit = newAuditLog(filepath.Join(t.TempDir(), "audit.log"))
+	web.tokens = newTokenStore(filepath.Join(t.TempDir(), "tokens.json"))
+	web.password = "mypassword"
+
+	// The creation of a token is recorded without its secret, and a token revoking itself is still identified.
+	recorder := web.postHttpResponseWithHeaders(
+		"/tokens", `{"name": "FMS", "scopes": ["admin"]}`, map[string]string{"Authorization": "Bearer mypassword"},
+	)
+	assert.Equal(t, 201, recorder.Code)
+	var created tokenCreationResponse
+	assert.Nil(t, json.Unmarshal(recorder.Body.Bytes(), &created))
+	recorder = web.deleteHttpResponseWithHeaders(
+		"/tokens/"+created.Id, map[string]string{"Authorization": "Bearer " + created.Token},
+	)
+	assert.Equal(t, 204, recorder.Code)
+
+	entries, err := web.audit.entries(time.Time{})
+	assert.Nil(t, err)
+	if assert.Equal(t, 2, len(entries)) {
+		assert.Equal(t, "POST /tokens", entries[0].Action)
+		assert.Equal(t, "password", entries[0].Identity)
+		assert.Equal(t, map[string]any{"name": "FMS", "scopes": []any{"admin"}}, entries[0].Request)
+		assert.Equal(t, "DELETE /tokens/"+created.Id, entries[1].Action)
+		assert.Equal(t, "token FMS ("+created.Id+")", entries[1].Identity)
+		assert.Nil(t, entries[1].Request)
+		assert.Equal(t, 204, entries[1].StatusCode)
+	}
+}
+
+func TestWeb_auditHandlerUnauthorized(t *testing.T) {
+	web := NewWebServer(radio.NewAccessPointRadio())
+	web.audit = newAuditLog(filepath.Join(t.TempDir(), "audit.log"))
+	web.tokens = newTokenStore(filepath.Join(t.TempDir(), "tokens.json"))
+	web.password = "mypassword"
+
+	recorder := web.getHttpResponse("/audit")
+	assert.Equal(t, 401, recorder.Code)
+
+	// Viewing the audit log requires the admin scope.
+	_, secret, _ := web.tokens.create("Audience display", []tokenScope{scopeReadStatus})
+	recorder = web.getHttpResponseWithHeaders("/audit", map[string]string{"Authorization": "Bearer " + secret})
+	assert.Equal(t, 403, recorder.Code)
+}
+
+func TestWeb_auditHandlerFirmware(t *testing.T) {
+	web := NewWebServer(radio.NewAccessPointRadio())
+	web.audit = newAuditLog(filepath.Join(t.TempDir(), "audit.log"))
+
+	recorder := web.postFileHttpResponse("/firmware", "file", []byte("firmware"), map[string]string{"checksum": "1234"})
+	assert.Equal(t, 400, recorder.Code)
+
+	entries, err := web.audit.entries(time.Time{})
+	assert.Nil(t, err)
+	if assert.Equal(t, 1, len(entries)) {
+		assert.Equal(t, "POST /firmware", entries[0].Action)
+		assert.Equal(t, auditRejected, entries[0].Outcome)
+		assert.Contains(t, entries[0].Error, "missing or invalid checksum")
+		assert.Equal(
+			t,
+			map[string]any{"checksum": "1234", "file": map[string]any{"filename": "file.ext", "sizeBytes": float64(8)}},
+			entries[0].Request,
+		)
+	}
+}
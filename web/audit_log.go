@@ -0,0 +1,413 @@
+package web
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/patfair/frc-radio-api/radio"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// Name of the current audit log file within the data directory.
+	auditLogFileName = "frc-radio-api-audit.log"
+
+	// Maximum size of the current audit log file in bytes, beyond which it is rotated to a file having the same path
+	// plus ".old", replacing any existing one.
+	auditLogMaxSizeBytes = 3 * 1 << 19 // 1.5 MB
+
+	// Maximum number of bytes of each request body to capture for summarizing in the audit log.
+	maxAuditedBodyBytes = 64 * 1024
+
+	// Maximum length of the error message recorded in each audit log entry.
+	maxAuditedErrorLength = 500
+
+	// Value that secrets in audited requests are replaced with.
+	redactedValue = "REDACTED"
+)
+
+// Substrings of the (lowercased) names of request fields whose values are redacted from the audit log.
+var redactedFieldNames = []string{"wpakey", "password", "secret", "token"}
+
+// Outcomes of the actions recorded in the audit log. Configuration jobs that finish are recorded with their final job
+// status (e.g. "SUCCEEDED" or "FAILED") as the outcome.
+const (
+	auditAccepted     = "ACCEPTED"
+	auditRejected     = "REJECTED"
+	auditUnauthorized = "UNAUTHORIZED"
+)
+
+// auditEntry represents a single record in the audit log.
+type auditEntry struct {
+	// Time at which the action occurred.
+	Time time.Time `json:"time"`
+
+	// Address of the client that requested the action, or the URL of the MQTT broker it was received from.
+	RemoteAddress string `json:"remoteAddress"`
+
+	// Identity of the credential that the action was requested with; one of "anonymous", "password", "mqtt" or
+	// "token [name] ([id])".
+	Identity string `json:"identity"`
+
+	// Description of the action, e.g. "POST /configuration" or "CONFIGURATION_COMPLETED".
+	Action string `json:"action"`
+
+	// Summary of the request, with secrets such as WPA keys redacted.
+	Request any `json:"request,omitempty"`
+
+	// HTTP status code of the response, if the action was requested through the API.
+	StatusCode int `json:"statusCode,omitempty"`
+
+	// Outcome of the action; one of "ACCEPTED", "REJECTED" or "UNAUTHORIZED" for requests, or the final status of the
+	// job for completed configuration jobs.
+	Outcome string `json:"outcome"`
+
+	// Error message explaining why the request was rejected or the configuration failed, if it was.
+	Error string `json:"error,omitempty"`
+
+	// ID of the configuration job that the action queued or completed, if any.
+	JobId string `json:"jobId,omitempty"`
+}
+
+// auditLog records mutating actions to an append-only file of JSON lines so that changes to the radio can be
+// reconstructed after the fact.
+type auditLog struct {
+	mutex sync.Mutex
+
+	// Path of the current file. If blank, the audit log is disabled and nothing is recorded.
+	filePath string
+
+	// Size beyond which the current file is rotated.
+	maxSizeBytes int64
+
+	// Entries that queued configuration jobs which have yet to finish, by job ID, for attributing their completion.
+	pendingJobs map[string]auditEntry
+
+	// Notifies the job watcher whenever a queued configuration job starts being tracked, in case it finishes before the
+	// radio's status next changes.
+	pendingJobAdded chan struct{}
+}
+
+// newAuditLog creates an audit log that appends to the given file, or a disabled one if the path is blank.
+func newAuditLog(filePath string) *auditLog {
+	return &auditLog{
+		filePath:        filePath,
+		maxSizeBytes:    auditLogMaxSizeBytes,
+		pendingJobs:     make(map[string]auditEntry),
+		pendingJobAdded: make(chan struct{}, 1),
+	}
+}
+
+// record appends the given entry to the log, rotating the file first if it has grown too big.
+func (audit *auditLog) record(entry auditEntry) {
+	audit.mutex.Lock()
+	defer audit.mutex.Unlock()
+
+	if audit.filePath == "" {
+		return
+	}
+	if entry.JobId != "" && entry.Outcome == auditAccepted {
+		audit.pendingJobs[entry.JobId] = entry
+		select {
+		case audit.pendingJobAdded <- struct{}{}:
+		default:
+			// The watcher is already due to check the pending jobs.
+		}
+	}
+
+	entryJson, err := json.Marshal(entry)
+	if err != nil {
+		log.Printf("Error encoding audit log entry: %v", err)
+		return
+	}
+	if fileInfo, err := os.Stat(audit.filePath); err == nil && fileInfo.Size() >= audit.maxSizeBytes {
+		if err = os.Rename(audit.filePath, audit.oldFilePath()); err != nil {
+			log.Printf("Error rotating audit log file: %v", err)
+		}
+	}
+	file, err := os.OpenFile(audit.filePath, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0600)
+	if err != nil {
+		log.Printf("Error opening audit log file: %v", err)
+		return
+	}
+	defer file.Close()
+	if _, err = file.Write(append(entryJson, '\n')); err != nil {
+		log.Printf("Error writing audit log entry: %v", err)
+	}
+}
+
+// recordConfigurationResult appends an entry describing the final outcome of the given finished configuration job,
+// attributed to whoever queued it if known.
+func (audit *auditLog) recordConfigurationResult(job radio.ConfigurationJob) {
+	audit.mutex.Lock()
+	queuingEntry := audit.pendingJobs[job.Id]
+	delete(audit.pendingJobs, job.Id)
+	audit.mutex.Unlock()
+
+	finishedAt := time.Now()
+	if job.FinishedAt != nil {
+		finishedAt = *job.FinishedAt
+	}
+	audit.record(
+		auditEntry{
+			Time:          finishedAt,
+			RemoteAddress: queuingEntry.RemoteAddress,
+			Identity:      queuingEntry.Identity,
+			Action:        "CONFIGURATION_COMPLETED",
+			Outcome:       string(job.Status),
+			Error:         job.Error,
+			JobId:         job.Id,
+		},
+	)
+}
+
+// pendingJobIds returns the IDs of the queued configuration jobs whose completion has yet to be recorded, in sorted
+// order.
+func (audit *auditLog) pendingJobIds() []string {
+	audit.mutex.Lock()
+	defer audit.mutex.Unlock()
+
+	ids := make([]string, 0, len(audit.pendingJobs))
+	for id := range audit.pendingJobs {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+// forgetPendingJob stops tracking the configuration job having the given ID, since its outcome can no longer be
+// determined.
+func (audit *auditLog) forgetPendingJob(id string) {
+	audit.mutex.Lock()
+	defer audit.mutex.Unlock()
+	delete(audit.pendingJobs, id)
+}
+
+// entries returns the entries in the rotated and current files, oldest first, that occurred after the given time.
+func (audit *auditLog) entries(since time.Time) ([]auditEntry, error) {
+	audit.mutex.Lock()
+	defer audit.mutex.Unlock()
+
+	entries := make([]auditEntry, 0)
+	if audit.filePath == "" {
+		return entries, nil
+	}
+	for _, filePath := range []string{audit.oldFilePath(), audit.filePath} {
+		file, err := os.Open(filePath)
+		if errors.Is(err, os.ErrNotExist) {
+			continue
+		} else if err != nil {
+			return nil, err
+		}
+		scanner := bufio.NewScanner(file)
+		scanner.Buffer(make([]byte, 0, 64*1024), 4*maxAuditedBodyBytes)
+		for scanner.Scan() {
+			var entry auditEntry
+			if err = json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+				// Skip any line left incomplete by a crash rather than hiding the rest of the log.
+				continue
+			}
+			if entry.Time.After(since) {
+				entries = append(entries, entry)
+			}
+		}
+		err = scanner.Err()
+		file.Close()
+		if err != nil {
+			return nil, fmt.Errorf("error reading audit log file %s: %v", filePath, err)
+		}
+	}
+	return entries, nil
+}
+
+// oldFilePath returns the path that the current file is rotated to.
+func (audit *auditLog) oldFilePath() string {
+	return audit.filePath + ".old"
+}
+
+// watchRadioForAudit loops indefinitely, recording the outcome of each audited configuration job once it finishes.
+func (web *WebServer) watchRadioForAudit() {
+	statusListener := web.radio.SubscribeStatusChanges()
+	for {
+		web.checkRadioForAudit()
+		select {
+		case <-statusListener:
+		case <-web.audit.pendingJobAdded:
+		}
+	}
+}
+
+// checkRadioForAudit records the outcome of each audited configuration job that has finished since the last check.
+func (web *WebServer) checkRadioForAudit() {
+	for _, id := range web.audit.pendingJobIds() {
+		job, ok := web.radio.GetConfigurationJob(id)
+		if !ok {
+			// The job has been forgotten about, so its outcome can no longer be determined.
+			web.audit.forgetPendingJob(id)
+		} else if job.FinishedAt != nil {
+			web.audit.recordConfigurationResult(job)
+		}
+	}
+}
+
+// audited wraps the given handler of a mutating endpoint so that every request to it is recorded in the audit log,
+// along with its outcome.
+func (web *WebServer) audited(handler http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		// Identify the requester up front in case the request revokes the very token it was made with.
+		requestTime, identity := time.Now(), web.identify(r)
+		var body bytes.Buffer
+		if !strings.HasPrefix(r.Header.Get("Content-Type"), "multipart/") {
+			r.Body = readCloser{io.TeeReader(r.Body, &limitedWriter{&body, maxAuditedBodyBytes}), r.Body}
+		}
+		recorder := &auditResponseRecorder{ResponseWriter: w, statusCode: http.StatusOK}
+		handler(recorder, r)
+
+		entry := auditEntry{
+			Time:          requestTime,
+			RemoteAddress: r.RemoteAddr,
+			Identity:      identity,
+			Action:        r.Method + " " + r.URL.Path,
+			Request:       summarizeAuditedRequest(r, body.Bytes()),
+			StatusCode:    recorder.statusCode,
+		}
+		switch {
+		case recorder.statusCode < 300:
+			entry.Outcome = auditAccepted
+			var job struct {
+				Id string `json:"id"`
+			}
+			if strings.HasPrefix(r.URL.Path, "/configuration") && json.Unmarshal(recorder.body.Bytes(), &job) == nil {
+				entry.JobId = job.Id
+			}
+		case recorder.statusCode == http.StatusUnauthorized || recorder.statusCode == http.StatusForbidden:
+			entry.Outcome = auditUnauthorized
+			entry.Error = truncateAuditedError(recorder.body.String())
+		default:
+			entry.Outcome = auditRejected
+			entry.Error = truncateAuditedError(recorder.body.String())
+		}
+		web.audit.record(entry)
+	}
+}
+
+// summarizeAuditedRequest returns a summary of the given request for the audit log, with secrets redacted: the decoded
+// JSON body, the form fields and uploaded file sizes of a multipart body, or nil if there is no body to summarize.
+func summarizeAuditedRequest(r *http.Request, body []byte) any {
+	if r.MultipartForm != nil {
+		summary := make(map[string]any)
+		for name, values := range r.MultipartForm.Value {
+			if len(values) > 0 {
+				summary[name] = values[0]
+			}
+		}
+		for name, files := range r.MultipartForm.File {
+			if len(files) > 0 {
+				summary[name] = map[string]any{"filename": files[0].Filename, "sizeBytes": files[0].Size}
+			}
+		}
+		return redactSecrets(summary)
+	}
+	if len(bytes.TrimSpace(body)) == 0 {
+		return nil
+	}
+	var decodedBody any
+	if err := json.Unmarshal(body, &decodedBody); err != nil {
+		return fmt.Sprintf("unparseable %d-byte body", len(body))
+	}
+	return redactSecrets(decodedBody)
+}
+
+// redactSecrets returns the given decoded JSON value with the values of any fields whose names suggest they hold
+// secrets replaced, at any depth.
+func redactSecrets(value any) any {
+	switch typedValue := value.(type) {
+	case map[string]any:
+		for name, fieldValue := range typedValue {
+			if isSecretFieldName(name) && fieldValue != nil && fieldValue != "" {
+				typedValue[name] = redactedValue
+			} else {
+				typedValue[name] = redactSecrets(fieldValue)
+			}
+		}
+	case []any:
+		for i, element := range typedValue {
+			typedValue[i] = redactSecrets(element)
+		}
+	}
+	return value
+}
+
+// isSecretFieldName returns true if the given field name suggests that its value is a secret.
+func isSecretFieldName(name string) bool {
+	lowercaseName := strings.ToLower(name)
+	for _, redactedFieldName := range redactedFieldNames {
+		if strings.Contains(lowercaseName, redactedFieldName) {
+			return true
+		}
+	}
+	return false
+}
+
+// truncateAuditedError returns the given error response body trimmed to a reasonable length for the audit log.
+func truncateAuditedError(message string) string {
+	message = strings.TrimSpace(message)
+	if len(message) > maxAuditedErrorLength {
+		return message[:maxAuditedErrorLength] + "..."
+	}
+	return message
+}
+
+// auditResponseRecorder wraps a response writer to capture the status code and the beginning of the body.
+type auditResponseRecorder struct {
+	http.ResponseWriter
+	statusCode  int
+	wroteHeader bool
+	body        bytes.Buffer
+}
+
+func (recorder *auditResponseRecorder) WriteHeader(statusCode int) {
+	if !recorder.wroteHeader {
+		recorder.statusCode = statusCode
+		recorder.wroteHeader = true
+	}
+	recorder.ResponseWriter.WriteHeader(statusCode)
+}
+
+func (recorder *auditResponseRecorder) Write(data []byte) (int, error) {
+	recorder.wroteHeader = true
+	_, _ = (&limitedWriter{&recorder.body, maxAuditedBodyBytes}).Write(data)
+	return recorder.ResponseWriter.Write(data)
+}
+
+// limitedWriter writes to the underlying buffer until it reaches the given size, silently discarding the rest.
+type limitedWriter struct {
+	buffer   *bytes.Buffer
+	maxBytes int
+}
+
+func (writer *limitedWriter) Write(data []byte) (int, error) {
+	if remaining := writer.maxBytes - writer.buffer.Len(); remaining > 0 {
+		if len(data) > remaining {
+			writer.buffer.Write(data[:remaining])
+		} else {
+			writer.buffer.Write(data)
+		}
+	}
+	return len(data), nil
+}
+
+// readCloser combines a reader with the closer of the request body that it reads from.
+type readCloser struct {
+	io.Reader
+	io.Closer
+}
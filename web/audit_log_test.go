@@ -0,0 +1,201 @@
+package web
+
+import (
+	"encoding/json"
+	"github.com/patfair/frc-radio-api/radio"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestAuditLog(t *testing.T) {
+	filePath := filepath.Join(t.TempDir(), "audit.log")
+	audit := newAuditLog(filePath)
+
+	entries, err := audit.entries(time.Time{})
+	assert.Nil(t, err)
+	assert.Equal(t, []auditEntry{}, entries)
+
+	startTime := time.Date(2024, 4, 20, 12, 0, 0, 0, time.UTC)
+	audit.record(
+		auditEntry{
+			Time:          startTime,
+			RemoteAddress: "10.0.100.5:51234",
+			Identity:      "password",
+			Action:        "POST /configuration",
+			StatusCode:    202,
+			Outcome:       auditAccepted,
+			JobId:         "1",
+		},
+	)
+	audit.record(
+		auditEntry{
+			Time:          startTime.Add(time.Second),
+			RemoteAddress: "10.0.100.6:40000",
+			Identity:      "anonymous",
+			Action:        "POST /firmware",
+			StatusCode:    401,
+			Outcome:       auditUnauthorized,
+		},
+	)
+
+	// The completion of a job is attributed to whoever queued it.
+	finishedAt := startTime.Add(2 * time.Second)
+	audit.recordConfigurationResult(
+		radio.ConfigurationJob{Id: "1", Status: "FAILED", Error: "oops", FinishedAt: &finishedAt},
+	)
+	entries, err = audit.entries(time.Time{})
+	assert.Nil(t, err)
+	if assert.Equal(t, 3, len(entries)) {
+		assert.Equal(t, "POST /configuration", entries[0].Action)
+		assert.Equal(t, "POST /firmware", entries[1].Action)
+		assert.Equal(
+			t,
+			auditEntry{
+				Time:          finishedAt,
+				RemoteAddress: "10.0.100.5:51234",
+				Identity:      "password",
+				Action:        "CONFIGURATION_COMPLETED",
+				Outcome:       "FAILED",
+				Error:         "oops",
+				JobId:         "1",
+			},
+			entries[2],
+		)
+	}
+	assert.Empty(t, audit.pendingJobs)
+
+	entries, err = audit.entries(startTime)
+	assert.Nil(t, err)
+	assert.Equal(t, 2, len(entries))
+
+	// The file is readable only by its owner and holds one JSON object per line.
+	info, err := os.Stat(filePath)
+	assert.Nil(t, err)
+	assert.Equal(t, os.FileMode(0600), info.Mode().Perm())
+	contents, _ := os.ReadFile(filePath)
+	lines := strings.Split(strings.TrimSpace(string(contents)), "\n")
+	assert.Equal(t, 3, len(lines))
+	assert.True(t, json.Valid([]byte(lines[0])))
+
+	// Incomplete lines are skipped.
+	file, _ := os.OpenFile(filePath, os.O_APPEND|os.O_WRONLY, 0600)
+	_, _ = file.WriteString("{\"time\": \"2024-04\n")
+	file.Close()
+	entries, err = audit.entries(time.Time{})
+	assert.Nil(t, err)
+	assert.Equal(t, 3, len(entries))
+}
+
+func TestAuditLogRotation(t *testing.T) {
+	filePath := filepath.Join(t.TempDir(), "audit.log")
+	audit := newAuditLog(filePath)
+	audit.maxSizeBytes = 200
+
+	startTime := time.Date(2024, 4, 20, 12, 0, 0, 0, time.UTC)
+	for i := 0; i < 6; i++ {
+		audit.record(
+			auditEntry{
+				Time:     startTime.Add(time.Duration(i) * time.Second),
+				Identity: "password",
+				Action:   "DELETE /webhooks/abcdef0123456789",
+				Outcome:  auditAccepted,
+			},
+		)
+	}
+
+	// Both the current and rotated files are read, but anything rotated out of those is gone.
+	_, err := os.Stat(filePath + ".old")
+	assert.Nil(t, err)
+	entries, err := audit.entries(time.Time{})
+	assert.Nil(t, err)
+	if assert.Equal(t, 4, len(entries)) {
+		assert.Equal(t, startTime.Add(2*time.Second), entries[0].Time)
+		assert.Equal(t, startTime.Add(5*time.Second), entries[3].Time)
+	}
+}
+
+func TestAuditLogDisabled(t *testing.T) {
+	audit := newAuditLog("")
+	audit.record(auditEntry{Time: time.Now(), Action: "POST /configuration", Outcome: auditAccepted, JobId: "1"})
+	entries, err := audit.entries(time.Time{})
+	assert.Nil(t, err)
+	assert.Equal(t, []auditEntry{}, entries)
+	assert.Empty(t, audit.pendingJobs)
+}
+
+func TestWeb_checkRadioForAudit(t *testing.T) {
+	fakeRadio := &fakeWebhookRadio{snapshot: &radio.AccessPointRadio{}, jobs: make(map[string]radio.ConfigurationJob)}
+	web := NewWebServer(fakeRadio)
+	web.audit = newAuditLog(filepath.Join(t.TempDir(), "audit.log"))
+
+	// Queuing a job wakes the watcher up, independently of any webhooks.
+	startTime := time.Date(2024, 4, 20, 12, 0, 0, 0, time.UTC)
+	fakeRadio.jobs["abc"] = radio.ConfigurationJob{Id: "abc", Status: "APPLYING"}
+	for _, id := range []string{"abc", "forgotten"} {
+		web.audit.record(
+			auditEntry{Time: startTime, Identity: "mqtt", Action: "MQTT configure", Outcome: auditAccepted, JobId: id},
+		)
+	}
+	assert.Equal(t, 1, len(web.audit.pendingJobAdded))
+	web.checkRadioForAudit()
+	assert.Equal(t, []string{"abc"}, web.audit.pendingJobIds())
+	entries, _ := web.audit.entries(time.Time{})
+	assert.Equal(t, 2, len(entries))
+
+	// The outcome is recorded once the job finishes, and only once.
+	finishedAt := startTime.Add(time.Minute)
+	fakeRadio.jobs["abc"] = radio.ConfigurationJob{Id: "abc", Status: "SUCCEEDED", FinishedAt: &finishedAt}
+	web.checkRadioForAudit()
+	web.checkRadioForAudit()
+	assert.Empty(t, web.audit.pendingJobIds())
+	entries, _ = web.audit.entries(time.Time{})
+	if assert.Equal(t, 3, len(entries)) {
+		assert.Equal(t, "CONFIGURATION_COMPLETED", entries[2].Action)
+		assert.Equal(t, "mqtt", entries[2].Identity)
+		assert.Equal(t, "SUCCEEDED", entries[2].Outcome)
+		assert.Equal(t, finishedAt, entries[2].Time)
+	}
+}
+
+func TestSummarizeAuditedRequest(t *testing.T) {
+	body := `{"channel": 93, "stationConfigurations": {"red1": {"ssid": "254", "wpaKey": "12345678"},
+		"blue1": {"ssid": "1678", "wpaKey": ""}}, "wpaKey6": "87654321", "scopes": ["admin"]}`
+	assert.Equal(
+		t,
+		map[string]any{
+			"channel": float64(93),
+			"stationConfigurations": map[string]any{
+				"red1":  map[string]any{"ssid": "254", "wpaKey": redactedValue},
+				"blue1": map[string]any{"ssid": "1678", "wpaKey": ""},
+			},
+			"wpaKey6": redactedValue,
+			"scopes":  []any{"admin"},
+		},
+		summarizeAuditedRequest(&http.Request{}, []byte(body)),
+	)
+
+	assert.Nil(t, summarizeAuditedRequest(&http.Request{}, nil))
+	assert.Nil(t, summarizeAuditedRequest(&http.Request{}, []byte("  \n")))
+	assert.Equal(t, "unparseable 6-byte body", summarizeAuditedRequest(&http.Request{}, []byte("blorpy")))
+}
+
+func TestIsSecretFieldName(t *testing.T) {
+	for _, name := range []string{"wpaKey", "wpaKey24", "wpakey6", "password", "secret", "token"} {
+		assert.True(t, isSecretFieldName(name), name)
+	}
+	for _, name := range []string{"ssid", "channel", "name", "scopes", "checksum"} {
+		assert.False(t, isSecretFieldName(name), name)
+	}
+}
+
+func TestTruncateAuditedError(t *testing.T) {
+	assert.Equal(t, "HTTP request error 400: oops", truncateAuditedError("HTTP request error 400: oops\n"))
+	truncated := truncateAuditedError(strings.Repeat("x", 600))
+	assert.Equal(t, maxAuditedErrorLength+3, len(truncated))
+	assert.True(t, strings.HasSuffix(truncated, "..."))
+}
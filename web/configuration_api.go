@@ -2,36 +2,85 @@ package web
 
 import (
 	"encoding/json"
-	"errors"
 	"fmt"
+	"github.com/gorilla/mux"
 	"github.com/patfair/frc-radio-api/radio"
+	"io"
 	"log"
 	"net/http"
 )
 
+// configurationResponse is the body returned when a configuration request is accepted into the queue.
+type configurationResponse struct {
+	radio.ConfigurationJob
+	Message string `json:"message"`
+}
+
 // configurationHandler receives a JSON request to configure the radio and adds it to the asynchronous queue.
 func (web *WebServer) configurationHandler(w http.ResponseWriter, r *http.Request) {
-	if !web.isAuthorized(r) {
-		handleWebErr(
-			w,
-			errors.New("not authorized; must provide 'Authorization: Bearer [password]' header"),
-			http.StatusUnauthorized,
-		)
+	if !web.authorize(w, r, scopeConfigure) {
 		return
 	}
 
-	var request radio.ConfigurationRequest
-	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
-		handleWebErr(w, fmt.Errorf("invalid JSON: %v", err), http.StatusBadRequest)
+	job, err := web.queueConfigurationRequest(r.Body)
+	if err != nil {
+		handleWebErr(w, err, http.StatusBadRequest)
 		return
 	}
-	if err := request.Validate(web.radio); err != nil {
-		handleWebErr(w, fmt.Errorf("invalid configuration: %v", err), http.StatusBadRequest)
+	response := configurationResponse{
+		ConfigurationJob: job, Message: "New configuration received and will be applied asynchronously.",
+	}
+	jsonData, err := json.MarshalIndent(response, "", "  ")
+	if err != nil {
+		handleWebErr(w, err, http.StatusInternalServerError)
 		return
 	}
 
-	log.Printf("Received configuration request: %+v", request)
-	web.radio.ConfigurationRequestChannel <- request
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Location", fmt.Sprintf("/configuration/%s", job.Id))
 	w.WriteHeader(http.StatusAccepted)
-	_, _ = fmt.Fprintln(w, "New configuration received and will be applied asynchronously.")
+	_, _ = w.Write(jsonData)
+}
+
+// queueConfigurationRequest decodes and validates the given JSON configuration request and, if it is valid, adds it to
+// the asynchronous queue. Returns a copy of the job tracking its progress.
+func (web *WebServer) queueConfigurationRequest(body io.Reader) (radio.ConfigurationJob, error) {
+	request := web.radio.NewConfigurationRequest()
+	if err := json.NewDecoder(body).Decode(request); err != nil {
+		return radio.ConfigurationJob{}, newApiError(errorInvalidJson, "", fmt.Errorf("invalid JSON: %w", err))
+	}
+	if err := request.Validate(web.radio); err != nil {
+		return radio.ConfigurationJob{}, fmt.Errorf("invalid configuration: %w", err)
+	}
+
+	log.Printf("Received configuration request: %+v", request)
+	job := web.radio.QueueConfigurationRequest(request)
+	web.webhookTrigger.addPendingJob(job.Id)
+	return job, nil
+}
+
+// configurationJobHandler returns the progress and outcome of a previously accepted configuration request.
+func (web *WebServer) configurationJobHandler(w http.ResponseWriter, r *http.Request) {
+	if !web.authorize(w, r, scopeConfigure) {
+		return
+	}
+
+	id := mux.Vars(r)["id"]
+	job, ok := web.radio.GetConfigurationJob(id)
+	if !ok {
+		handleWebErr(w, fmt.Errorf("no configuration job with ID %q", id), http.StatusNotFound)
+		return
+	}
+
+	jsonData, err := json.MarshalIndent(job, "", "  ")
+	if err != nil {
+		handleWebErr(w, err, http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if _, err = w.Write(jsonData); err != nil {
+		handleWebErr(w, err, http.StatusInternalServerError)
+		return
+	}
 }
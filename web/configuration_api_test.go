@@ -1,16 +1,16 @@
-// This file is specific to the access point version of the API.
-//go:build !robot
+// This file is specific to the access point role of the API.
 
 package web
 
 import (
+	"encoding/json"
 	"github.com/patfair/frc-radio-api/radio"
 	"github.com/stretchr/testify/assert"
 	"testing"
 )
 
 func TestWeb_configurationHandler(t *testing.T) {
-	ap := radio.NewRadio()
+	ap := radio.NewAccessPointRadio()
 	ap.Type = radio.TypeVividHosting
 	web := NewWebServer(ap)
 
@@ -26,9 +26,14 @@ func TestWeb_configurationHandler(t *testing.T) {
 	)
 	assert.Equal(t, 202, recorder.Code)
 	assert.Contains(t, recorder.Body.String(), "configuration received")
+	var response map[string]any
+	assert.Nil(t, json.Unmarshal(recorder.Body.Bytes(), &response))
+	assert.Equal(t, "QUEUED", response["status"])
+	assert.NotEmpty(t, response["id"])
+	assert.Equal(t, "/configuration/"+response["id"].(string), recorder.Header().Get("Location"))
 	if assert.Equal(t, 1, len(ap.ConfigurationRequestChannel)) {
-		request := <-ap.ConfigurationRequestChannel
-		assert.Equal(t, 0, request.Channel)
+		request := (<-ap.ConfigurationRequestChannel).(*radio.AccessPointConfigurationRequest)
+		assert.Equal(t, radio.AccessPointChannel(0), request.Channel)
 		assert.Equal(t, 1, len(request.StationConfigurations))
 		assert.Equal(
 			t, radio.StationConfiguration{Ssid: "254", WpaKey: "12345678"}, request.StationConfigurations["blue1"],
@@ -56,8 +61,8 @@ func TestWeb_configurationHandler(t *testing.T) {
 	assert.Equal(t, 202, recorder.Code)
 	assert.Contains(t, recorder.Body.String(), "configuration received")
 	if assert.Equal(t, 1, len(ap.ConfigurationRequestChannel)) {
-		request := <-ap.ConfigurationRequestChannel
-		assert.Equal(t, 149, request.Channel)
+		request := (<-ap.ConfigurationRequestChannel).(*radio.AccessPointConfigurationRequest)
+		assert.Equal(t, radio.AccessPointChannel(149), request.Channel)
 		assert.Equal(t, "20MHz", request.ChannelBandwidth)
 		assert.Equal(t, 6, len(request.StationConfigurations))
 		assert.Equal(
@@ -79,16 +84,25 @@ func TestWeb_configurationHandler(t *testing.T) {
 			t, radio.StationConfiguration{Ssid: "9996", WpaKey: "66666666"}, request.StationConfigurations["blue3"],
 		)
 	}
+
+	// Request to select the channel automatically.
+	recorder = web.postHttpResponse("/configuration", `{"channel": "auto"}`)
+	assert.Equal(t, 202, recorder.Code)
+	if assert.Equal(t, 1, len(ap.ConfigurationRequestChannel)) {
+		request := (<-ap.ConfigurationRequestChannel).(*radio.AccessPointConfigurationRequest)
+		assert.Equal(t, radio.AutoChannel, request.Channel)
+	}
 }
 
 func TestWeb_configurationHandlerInvalidInput(t *testing.T) {
-	ap := radio.NewRadio()
+	ap := radio.NewAccessPointRadio()
 	web := NewWebServer(ap)
 
 	// Invalid JSON.
 	recorder := web.postHttpResponse("/configuration", "not JSON")
 	assert.Equal(t, 400, recorder.Code)
 	assert.Contains(t, recorder.Body.String(), "invalid JSON")
+	assert.Equal(t, "INVALID_JSON", decodeErrorResponse(t, recorder).Code)
 	assert.Equal(t, 0, len(ap.ConfigurationRequestChannel))
 
 	// Empty request.
@@ -96,10 +110,66 @@ func TestWeb_configurationHandlerInvalidInput(t *testing.T) {
 	assert.Equal(t, 400, recorder.Code)
 	assert.Contains(t, recorder.Body.String(), "empty configuration request")
 	assert.Equal(t, 0, len(ap.ConfigurationRequestChannel))
+
+	// VLAN that doesn't exist on the radio.
+	recorder = web.postHttpResponse("/configuration", `{"stationVlans": {"red1": 110}}`)
+	assert.Equal(t, 400, recorder.Code)
+	assert.Equal(
+		t,
+		errorResponse{
+			Code:    "INVALID_VLAN",
+			Field:   "stationVlans.red1",
+			Message: "invalid configuration: invalid VLAN for station red1: 110 (no such network on the radio)",
+			Errors: map[string][]fieldError{
+				"stationVlans.red1": {
+					{Code: "INVALID_VLAN", Message: "invalid VLAN for station red1: 110 (no such network on the radio)"},
+				},
+			},
+		},
+		decodeErrorResponse(t, recorder),
+	)
+	assert.Equal(t, 0, len(ap.ConfigurationRequestChannel))
+
+	// Several problems at once, which are all reported together.
+	recorder = web.postHttpResponse(
+		"/configuration",
+		`{"channel": 3, "stationConfigurations": {"red1": {"ssid": "254", "wpaKey": "12345678"},
+			"red2": {"ssid": "1114", "wpaKey": "1234"}, "blue3": {"ssid": "", "wpaKey": "abc_defgh"}}}`,
+	)
+	assert.Equal(t, 400, recorder.Code)
+	response := decodeErrorResponse(t, recorder)
+	assert.Equal(t, "VALIDATION_FAILED", response.Code)
+	assert.Empty(t, response.Field)
+	assert.Equal(
+		t,
+		map[string][]fieldError{
+			"channel": {{Code: "INVALID_CHANNEL", Message: "invalid channel for TypeLinksys: 3"}},
+			"stationConfigurations.blue3.ssid": {
+				{Code: "INVALID_SSID", Message: "SSID for station blue3 cannot be blank"},
+			},
+			"stationConfigurations.blue3.wpaKey": {
+				{Code: "INVALID_WPA_KEY", Message: "invalid WPA key for station blue3 (expecting alphanumeric)"},
+			},
+			"stationConfigurations.red2.wpaKey": {
+				{Code: "INVALID_WPA_KEY", Message: "invalid WPA key length for station red2: 4 (expecting 8-16)"},
+			},
+		},
+		response.Errors,
+	)
+	assert.Equal(t, 0, len(ap.ConfigurationRequestChannel))
+
+	// Channel that is neither a number nor "auto".
+	recorder = web.postHttpResponse("/configuration", `{"channel": "best"}`)
+	assert.Equal(t, 400, recorder.Code)
+	response = decodeErrorResponse(t, recorder)
+	assert.Contains(t, response.Message, "invalid channel \"best\"")
+	assert.Equal(t, "INVALID_CHANNEL", response.Code)
+	assert.Equal(t, "channel", response.Field)
+	assert.Equal(t, 0, len(ap.ConfigurationRequestChannel))
 }
 
 func TestWeb_configurationHandlerAuthorization(t *testing.T) {
-	ap := radio.NewRadio()
+	ap := radio.NewAccessPointRadio()
 	web := NewWebServer(ap)
 	web.password = "mypassword"
 
@@ -119,3 +189,43 @@ func TestWeb_configurationHandlerAuthorization(t *testing.T) {
 	)
 	assert.Equal(t, 202, recorder.Code)
 }
+
+func TestWeb_configurationJobHandler(t *testing.T) {
+	ap := radio.NewAccessPointRadio()
+	web := NewWebServer(ap)
+
+	recorder := web.postHttpResponse("/configuration", `{"channel": 149}`)
+	assert.Equal(t, 202, recorder.Code)
+	location := recorder.Header().Get("Location")
+
+	recorder = web.getHttpResponse(location)
+	assert.Equal(t, 200, recorder.Code)
+	assert.Equal(t, "application/json", recorder.Header().Get("Content-Type"))
+	var job radio.ConfigurationJob
+	assert.Nil(t, json.Unmarshal(recorder.Body.Bytes(), &job))
+	assert.Equal(t, "/configuration/"+job.Id, location)
+	assert.Equal(t, "QUEUED", string(job.Status))
+	assert.Equal(t, 0, job.Attempts)
+	assert.Nil(t, job.StartedAt)
+
+	// Unknown job.
+	recorder = web.getHttpResponse("/configuration/abcdef")
+	assert.Equal(t, 404, recorder.Code)
+	assert.Contains(t, decodeErrorResponse(t, recorder).Message, "no configuration job with ID \"abcdef\"")
+}
+
+func TestWeb_configurationJobHandlerAuthorization(t *testing.T) {
+	ap := radio.NewAccessPointRadio()
+	web := NewWebServer(ap)
+	job := ap.QueueConfigurationRequest(&radio.AccessPointConfigurationRequest{Channel: 149})
+	web.password = "mypassword"
+
+	recorder := web.getHttpResponse("/configuration/" + job.Id)
+	assert.Equal(t, 401, recorder.Code)
+
+	recorder = web.getHttpResponseWithHeaders(
+		"/configuration/"+job.Id, map[string]string{"Authorization": "Bearer mypassword"},
+	)
+	assert.Equal(t, 200, recorder.Code)
+	assert.Contains(t, recorder.Body.String(), job.Id)
+}
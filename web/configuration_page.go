@@ -1,11 +1,9 @@
-// This file is specific to the robot radio version of the API.
-//go:build robot
+// This file is specific to the robot radio role of the API.
 
 package web
 
 import (
 	_ "embed"
-	"errors"
 	"fmt"
 	"net/http"
 )
@@ -15,12 +13,7 @@ var htmlContents string
 
 // configPageHandler receives a GET request and returns the radio configuration html page.
 func (web *WebServer) configurationPageHandler(w http.ResponseWriter, r *http.Request) {
-	if !web.isAuthorized(r) {
-		handleWebErr(
-			w,
-			errors.New("not authorized; must provide 'Authorization: Bearer [password]' header"),
-			http.StatusUnauthorized,
-		)
+	if !web.authorize(w, r, scopeConfigure) {
 		return
 	}
 
@@ -1,5 +1,4 @@
-// This file is specific to the robot radio version of the API.
-//go:build robot
+// This file is specific to the robot radio role of the API.
 
 package web
 
@@ -11,8 +10,7 @@ import (
 )
 
 func TestWeb_configurationPageHandler(t *testing.T) {
-	ap := radio.NewRadio()
-	web := NewWebServer(ap)
+	web := NewWebServer(radio.NewRobotRadio())
 
 	// Ensure request results in html returned
 	recorder := web.getHttpResponse("/configuration")
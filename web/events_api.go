@@ -0,0 +1,132 @@
+package web
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/patfair/frc-radio-api/radio"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// eventsHandler returns a JSON list of the recent link events of the radio's networks.
+func (web *WebServer) eventsHandler(w http.ResponseWriter, r *http.Request) {
+	if !web.authorize(w, r, scopeReadStatus) {
+		return
+	}
+
+	query := r.URL.Query()
+	since, err := parseSinceParam(query.Get("since"))
+	if err != nil {
+		handleWebErr(w, err, http.StatusBadRequest)
+		return
+	}
+	events, err := web.radio.LinkEvents(query.Get("station"), 0)
+	if err != nil {
+		handleWebErr(w, err, http.StatusBadRequest)
+		return
+	}
+	filteredEvents := make([]radio.LinkEvent, 0)
+	for _, event := range events {
+		if event.Time.After(since) {
+			filteredEvents = append(filteredEvents, event)
+		}
+	}
+
+	jsonData, err := json.MarshalIndent(filteredEvents, "", "  ")
+	if err != nil {
+		handleWebErr(w, err, http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if _, err = w.Write(jsonData); err != nil {
+		handleWebErr(w, err, http.StatusInternalServerError)
+		return
+	}
+}
+
+// eventsStreamHandler streams link events as Server-Sent Events as they are recorded. If the client is reconnecting and
+// provides the ID of the last event it received, any events it missed in the meantime are sent first.
+func (web *WebServer) eventsStreamHandler(w http.ResponseWriter, r *http.Request) {
+	if !web.authorize(w, r, scopeReadStatus) {
+		return
+	}
+
+	station := r.URL.Query().Get("station")
+	if _, err := web.radio.LinkEvents(station, 0); err != nil {
+		handleWebErr(w, err, http.StatusBadRequest)
+		return
+	}
+
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		handleWebErr(w, errors.New("streaming is not supported by the connection"), http.StatusInternalServerError)
+		return
+	}
+
+	listener := web.radio.SubscribeLinkEvents()
+	defer web.radio.UnsubscribeLinkEvents(listener)
+
+	// Only send events recorded from now on, unless the client asks to resume from an earlier one.
+	var lastEventId int
+	if lastEventIdHeader := r.Header.Get("Last-Event-ID"); lastEventIdHeader != "" {
+		lastEventId, _ = strconv.Atoi(lastEventIdHeader)
+	} else if events, _ := web.radio.LinkEvents("", 0); len(events) > 0 {
+		lastEventId = events[len(events)-1].Id
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+
+	lastEventId, err := web.writeLinkEvents(w, station, lastEventId)
+	if err != nil {
+		return
+	}
+	flusher.Flush()
+
+	keepaliveTicker := time.NewTicker(statusStreamKeepaliveInterval)
+	defer keepaliveTicker.Stop()
+	for {
+		select {
+		case <-listener:
+			if lastEventId, err = web.writeLinkEvents(w, station, lastEventId); err != nil {
+				return
+			}
+		case <-keepaliveTicker.C:
+			if _, err = fmt.Fprint(w, ": keepalive\n\n"); err != nil {
+				return
+			}
+		case <-r.Context().Done():
+			return
+		}
+		flusher.Flush()
+	}
+}
+
+// writeLinkEvents writes the link events of the given network recorded after the one having the given ID to the given
+// stream, and returns the ID of the last event written.
+func (web *WebServer) writeLinkEvents(w http.ResponseWriter, station string, lastEventId int) (int, error) {
+	events, _ := web.radio.LinkEvents(station, lastEventId)
+	for _, event := range events {
+		if err := writeLinkEvent(w, event); err != nil {
+			return lastEventId, err
+		}
+		lastEventId = event.Id
+	}
+	return lastEventId, nil
+}
+
+// writeLinkEvent writes the given link event to the given stream as a single Server-Sent Event.
+func writeLinkEvent(w http.ResponseWriter, event radio.LinkEvent) error {
+	jsonData, err := json.Marshal(event)
+	if err != nil {
+		log.Printf("Error marshalling link event for stream: %v", err)
+		return err
+	}
+	_, err = fmt.Fprintf(w, "id: %d\nevent: link\ndata: %s\n\n", event.Id, jsonData)
+	return err
+}
@@ -0,0 +1,85 @@
+package web
+
+import (
+	"github.com/patfair/frc-radio-api/radio"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestWeb_eventsHandler(t *testing.T) {
+	web := NewWebServer(radio.NewAccessPointRadio())
+
+	recorder := web.getHttpResponse("/events")
+	assert.Equal(t, 200, recorder.Code)
+	assert.Equal(t, "application/json", recorder.Header().Get("Content-Type"))
+	assert.Equal(t, "[]", recorder.Body.String())
+
+	recorder = web.getHttpResponse("/events?station=blue2&since=2024-04-20T12:00:00Z")
+	assert.Equal(t, 200, recorder.Code)
+	assert.Equal(t, "[]", recorder.Body.String())
+
+	recorder = web.getHttpResponse("/events?station=6GHz")
+	assert.Equal(t, 400, recorder.Code)
+	assert.Contains(t, decodeErrorResponse(t, recorder).Message, "invalid network \"6GHz\"")
+
+	recorder = web.getHttpResponse("/events?since=yesterday")
+	assert.Equal(t, 400, recorder.Code)
+	assert.Contains(t, decodeErrorResponse(t, recorder).Message, "invalid since time \"yesterday\"")
+}
+
+func TestWeb_eventsHandlerAuthorization(t *testing.T) {
+	web := NewWebServer(radio.NewRobotRadio())
+	web.password = "mypassword"
+
+	recorder := web.getHttpResponse("/events")
+	assert.Equal(t, 401, recorder.Code)
+	recorder = web.getHttpResponse("/events/stream")
+	assert.Equal(t, 401, recorder.Code)
+
+	recorder = web.getHttpResponseWithHeaders("/events", map[string]string{"Authorization": "Bearer mypassword"})
+	assert.Equal(t, 200, recorder.Code)
+}
+
+func TestWeb_eventsStreamHandler(t *testing.T) {
+	web := NewWebServer(radio.NewRobotRadio())
+	server := httptest.NewServer(web.newRouter())
+	defer server.Close()
+
+	response, err := http.Get(server.URL + "/events/stream?station=red1")
+	if assert.Nil(t, err) {
+		assert.Equal(t, 400, response.StatusCode)
+		response.Body.Close()
+	}
+
+	response, err = http.Get(server.URL + "/events/stream?station=6GHz")
+	if assert.Nil(t, err) {
+		assert.Equal(t, 200, response.StatusCode)
+		assert.Equal(t, "text/event-stream", response.Header.Get("Content-Type"))
+		response.Body.Close()
+	}
+}
+
+func TestWriteLinkEvent(t *testing.T) {
+	event := radio.LinkEvent{
+		Id:      7,
+		Time:    time.Date(2024, 4, 20, 12, 0, 0, 0, time.UTC),
+		Station: "blue2",
+		Ssid:    "1678",
+		Type:    "DISASSOCIATED",
+		Before:  radio.LinkState{IsLinked: true, MacAddress: "48:DA:35:B0:00:CF", ConnectionQuality: "good"},
+	}
+
+	recorder := httptest.NewRecorder()
+	assert.Nil(t, writeLinkEvent(recorder, event))
+	assert.Equal(
+		t,
+		"id: 7\nevent: link\ndata: {\"id\":7,\"time\":\"2024-04-20T12:00:00Z\",\"station\":\"blue2\",\"ssid\":\"1678\","+
+			"\"type\":\"DISASSOCIATED\",\"before\":{\"isLinked\":true,\"macAddress\":\"48:DA:35:B0:00:CF\","+
+			"\"connectionQuality\":\"good\"},\"after\":{\"isLinked\":false,\"macAddress\":\"\",\"connectionQuality\":\"\"}}"+
+			"\n\n",
+		recorder.Body.String(),
+	)
+}
@@ -23,8 +23,8 @@ const (
 	// Maximum size of the firmware file that can be held in memory at once (based on device memory limitations).
 	maxMemorySizeBytes = 2 * 1024 * 1024 // 2 MB
 
-	// Path to the optional file containing the private key for decrypting new firmware.
-	firmwareDecryptionKeyFilePath = "/root/frc-radio-api-firmware-key.txt"
+	// Name of the optional file within the data directory containing the private key for decrypting new firmware.
+	firmwareDecryptionKeyFileName = "frc-radio-api-firmware-key.txt"
 
 	// Path where new firmware files are saved after being decrypted.
 	firmwarePath = "/tmp/new-firmware.tar"
@@ -32,27 +32,36 @@ const (
 
 var checksumRe = regexp.MustCompile(`^[0-9a-f]{64}$`)
 
+// firmwareUpdate is the data of a webhook payload describing the start of a firmware update.
+type firmwareUpdate struct {
+	// Hexadecimal-encoded SHA-256 hash of the decrypted firmware file.
+	Checksum string `json:"checksum"`
+}
+
 // firmwareHandler handles requests to update the radio firmware.
 func (web *WebServer) firmwareHandler(w http.ResponseWriter, r *http.Request) {
-	if !web.isAuthorized(r) {
-		handleWebErr(
-			w,
-			errors.New("not authorized; must provide 'Authorization: Bearer [password]' header"),
-			http.StatusUnauthorized,
-		)
+	if !web.authorize(w, r, scopeFirmware) {
 		return
 	}
 
 	// Prevent a malicious client from uploading a huge file and filling up the disk.
 	r.Body = http.MaxBytesReader(w, r.Body, maxRequestSizeBytes)
 	if err := r.ParseMultipartForm(maxMemorySizeBytes); err != nil {
-		handleWebErr(w, fmt.Errorf("error parsing multipart form: %v", err), http.StatusBadRequest)
+		handleWebErr(
+			w,
+			newApiError(errorInvalidFirmwareFile, "", fmt.Errorf("error parsing multipart form: %v", err)),
+			http.StatusBadRequest,
+		)
 		return
 	}
 
 	file, _, err := r.FormFile("file")
 	if err != nil {
-		handleWebErr(w, fmt.Errorf("missing or invalid firmware file: %v", err), http.StatusBadRequest)
+		handleWebErr(
+			w,
+			newApiError(errorInvalidFirmwareFile, "file", fmt.Errorf("missing or invalid firmware file: %v", err)),
+			http.StatusBadRequest,
+		)
 		return
 	}
 
@@ -60,9 +69,13 @@ func (web *WebServer) firmwareHandler(w http.ResponseWriter, r *http.Request) {
 	if !checksumRe.MatchString(checksum) {
 		handleWebErr(
 			w,
-			errors.New(
-				"missing or invalid checksum; expecting a 64-character hexadecimal-encoded SHA-256 hash of the "+
-					"decrypted firmware file",
+			newApiError(
+				errorInvalidChecksum,
+				"checksum",
+				errors.New(
+					"missing or invalid checksum; expecting a 64-character hexadecimal-encoded SHA-256 hash of the "+
+						"decrypted firmware file",
+				),
 			),
 			http.StatusBadRequest,
 		)
@@ -70,7 +83,11 @@ func (web *WebServer) firmwareHandler(w http.ResponseWriter, r *http.Request) {
 	}
 
 	if err = web.decryptAndSaveFirmwareFile(file); err != nil {
-		handleWebErr(w, fmt.Errorf("error saving firmware file: %v", err), http.StatusUnprocessableEntity)
+		handleWebErr(
+			w,
+			newApiError(errorInvalidFirmwareFile, "file", fmt.Errorf("error saving firmware file: %v", err)),
+			http.StatusUnprocessableEntity,
+		)
 		return
 	}
 
@@ -82,11 +99,20 @@ func (web *WebServer) firmwareHandler(w http.ResponseWriter, r *http.Request) {
 	}
 	if fileChecksum != checksum {
 		handleWebErr(
-			w, fmt.Errorf("checksum mismatch; expected %s, got %s", checksum, fileChecksum), http.StatusBadRequest,
+			w,
+			newApiError(
+				errorChecksumMismatch,
+				"checksum",
+				fmt.Errorf("checksum mismatch; expected %s, got %s", checksum, fileChecksum),
+			),
+			http.StatusBadRequest,
 		)
 		return
 	}
 
+	// Webhook delivery is best-effort since the process is liable to be terminated by the update before it completes.
+	web.webhooks.publish(webhookFirmwareUpdateStarted, time.Now(), firmwareUpdate{Checksum: checksum})
+
 	// Initiate the firmware update process; the radio will reboot automatically after this.
 	go func() {
 		// Add a short delay to give the HTTP response time to be sent.
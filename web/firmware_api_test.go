@@ -13,7 +13,7 @@ const encryptedBase64 = "YWdlLWVuY3J5cHRpb24ub3JnL3YxCi0+IFgyNTUxOSB4V0dLRzJWeHl
 	"UE8xRGh3QlhKa05HaTAKkImLt8n/HK5tNDObg/rBSkniuquU0M/1zfor20Rbx0svTIbqgWZ06lmt2H4HSGOdn+EJsWGmNOGccj5Cig=="
 
 func TestWeb_firmwareHandler(t *testing.T) {
-	ap := radio.NewRadio()
+	ap := radio.NewAccessPointRadio()
 	web := NewWebServer(ap)
 
 	// Decryption not enabled.
@@ -42,7 +42,7 @@ func TestWeb_firmwareHandler(t *testing.T) {
 }
 
 func TestWeb_firmwareHandlerInvalidInput(t *testing.T) {
-	ap := radio.NewRadio()
+	ap := radio.NewAccessPointRadio()
 	web := NewWebServer(ap)
 
 	// Wrong content type.
@@ -69,6 +69,7 @@ func TestWeb_firmwareHandlerInvalidInput(t *testing.T) {
 	)
 	assert.Equal(t, 400, recorder.Code)
 	assert.Contains(t, recorder.Body.String(), "missing or invalid checksum")
+	assert.Equal(t, "INVALID_CHECKSUM", decodeErrorResponse(t, recorder).Code)
 
 	// Wrong decryption key.
 	web.firmwareDecryptionKey, _ = age.ParseX25519Identity(
@@ -107,10 +108,11 @@ func TestWeb_firmwareHandlerInvalidInput(t *testing.T) {
 	)
 	assert.Equal(t, 400, recorder.Code)
 	assert.Contains(t, recorder.Body.String(), "checksum mismatch")
+	assert.Equal(t, "CHECKSUM_MISMATCH", decodeErrorResponse(t, recorder).Code)
 }
 
 func TestWeb_firmwareHandlerAuthorization(t *testing.T) {
-	ap := radio.NewRadio()
+	ap := radio.NewAccessPointRadio()
 	web := NewWebServer(ap)
 	web.password = "mypassword"
 
@@ -0,0 +1,120 @@
+package web
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"github.com/patfair/frc-radio-api/radio"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// Column headings of the CSV format of the link history, which match the JSON field names except for the derived
+// associatedClientCount column, which counts the entries of the JSON associatedClients array instead of listing them.
+var historyCsvHeader = []string{
+	"time",
+	"ssid",
+	"isLinked",
+	"macAddress",
+	"signalDbm",
+	"noiseDbm",
+	"signalNoiseRatio",
+	"rxRateMbps",
+	"rxPackets",
+	"rxBytes",
+	"txRateMbps",
+	"txPackets",
+	"txBytes",
+	"bandwidthUsedMbps",
+	"bandwidthLimitMbps",
+	"connectionQuality",
+	"associatedClientCount",
+}
+
+// statusHistoryHandler returns the recent link metric samples of a single network as JSON or CSV.
+func (web *WebServer) statusHistoryHandler(w http.ResponseWriter, r *http.Request) {
+	if !web.authorize(w, r, scopeReadStatus) {
+		return
+	}
+
+	query := r.URL.Query()
+	since, err := parseSinceParam(query.Get("since"))
+	if err != nil {
+		handleWebErr(w, err, http.StatusBadRequest)
+		return
+	}
+	format := query.Get("format")
+	if format != "" && format != "json" && format != "csv" {
+		handleWebErr(w, fmt.Errorf("invalid format %q (expecting json or csv)", format), http.StatusBadRequest)
+		return
+	}
+	samples, err := web.radio.LinkHistory(query.Get("station"), since)
+	if err != nil {
+		handleWebErr(w, err, http.StatusBadRequest)
+		return
+	}
+
+	if format == "csv" {
+		w.Header().Set("Content-Type", "text/csv")
+		if err = writeHistoryCsv(w, samples); err != nil {
+			handleWebErr(w, err, http.StatusInternalServerError)
+		}
+		return
+	}
+
+	jsonData, err := json.MarshalIndent(samples, "", "  ")
+	if err != nil {
+		handleWebErr(w, err, http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if _, err = w.Write(jsonData); err != nil {
+		handleWebErr(w, err, http.StatusInternalServerError)
+		return
+	}
+}
+
+// parseSinceParam parses the given value of a "since" query parameter as an RFC 3339 time, treating a blank value as
+// the zero time.
+func parseSinceParam(sinceParam string) (time.Time, error) {
+	if sinceParam == "" {
+		return time.Time{}, nil
+	}
+	since, err := time.Parse(time.RFC3339, sinceParam)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid since time %q (expecting RFC 3339 format)", sinceParam)
+	}
+	return since, nil
+}
+
+// writeHistoryCsv writes the given samples to the response as CSV, with one row per sample.
+func writeHistoryCsv(w http.ResponseWriter, samples []radio.LinkSample) error {
+	writer := csv.NewWriter(w)
+	_ = writer.Write(historyCsvHeader)
+	for _, sample := range samples {
+		_ = writer.Write(
+			[]string{
+				sample.Time.Format(time.RFC3339Nano),
+				sample.Ssid,
+				strconv.FormatBool(sample.IsLinked),
+				sample.MacAddress,
+				strconv.Itoa(sample.SignalDbm),
+				strconv.Itoa(sample.NoiseDbm),
+				strconv.Itoa(sample.SignalNoiseRatio),
+				strconv.FormatFloat(sample.RxRateMbps, 'f', -1, 64),
+				strconv.Itoa(sample.RxPackets),
+				strconv.Itoa(sample.RxBytes),
+				strconv.FormatFloat(sample.TxRateMbps, 'f', -1, 64),
+				strconv.Itoa(sample.TxPackets),
+				strconv.Itoa(sample.TxBytes),
+				strconv.FormatFloat(sample.BandwidthUsedMbps, 'f', -1, 64),
+				strconv.FormatFloat(sample.BandwidthLimitMbps, 'f', -1, 64),
+				sample.ConnectionQuality,
+				strconv.Itoa(len(sample.AssociatedClients)),
+			},
+		)
+	}
+	writer.Flush()
+	return writer.Error()
+}
@@ -0,0 +1,105 @@
+package web
+
+import (
+	"github.com/patfair/frc-radio-api/radio"
+	"github.com/stretchr/testify/assert"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestWeb_statusHistoryHandler(t *testing.T) {
+	web := NewWebServer(radio.NewAccessPointRadio())
+
+	recorder := web.getHttpResponse("/status/history?station=red1")
+	assert.Equal(t, 200, recorder.Code)
+	assert.Equal(t, "application/json", recorder.Header().Get("Content-Type"))
+	assert.Equal(t, "[]", recorder.Body.String())
+
+	recorder = web.getHttpResponse("/status/history?station=blue3&since=2024-04-20T12:00:00Z&format=csv")
+	assert.Equal(t, 200, recorder.Code)
+	assert.Equal(t, "text/csv", recorder.Header().Get("Content-Type"))
+	assert.Equal(
+		t,
+		"time,ssid,isLinked,macAddress,signalDbm,noiseDbm,signalNoiseRatio,rxRateMbps,rxPackets,rxBytes,txRateMbps,"+
+			"txPackets,txBytes,bandwidthUsedMbps,bandwidthLimitMbps,connectionQuality,associatedClientCount\n",
+		recorder.Body.String(),
+	)
+
+	recorder = web.getHttpResponse("/status/history")
+	assert.Equal(t, 400, recorder.Code)
+	assert.Contains(t, decodeErrorResponse(t, recorder).Message, "invalid network \"\" (expecting one of red1, red2")
+
+	recorder = web.getHttpResponse("/status/history?station=6GHz")
+	assert.Equal(t, 400, recorder.Code)
+	assert.Contains(t, decodeErrorResponse(t, recorder).Message, "invalid network \"6GHz\"")
+
+	recorder = web.getHttpResponse("/status/history?station=red1&since=yesterday")
+	assert.Equal(t, 400, recorder.Code)
+	assert.Contains(
+		t, decodeErrorResponse(t, recorder).Message, "invalid since time \"yesterday\" (expecting RFC 3339 format)",
+	)
+
+	recorder = web.getHttpResponse("/status/history?station=red1&format=xml")
+	assert.Equal(t, 400, recorder.Code)
+	assert.Contains(t, decodeErrorResponse(t, recorder).Message, "invalid format \"xml\" (expecting json or csv)")
+
+	// The robot radio identifies its networks by band.
+	web = NewWebServer(radio.NewRobotRadio())
+	recorder = web.getHttpResponse("/status/history?station=2.4GHz")
+	assert.Equal(t, 200, recorder.Code)
+	recorder = web.getHttpResponse("/status/history?station=red1")
+	assert.Equal(t, 400, recorder.Code)
+}
+
+func TestWeb_statusHistoryHandlerAuthorization(t *testing.T) {
+	web := NewWebServer(radio.NewAccessPointRadio())
+	web.password = "mypassword"
+
+	recorder := web.getHttpResponse("/status/history?station=red1")
+	assert.Equal(t, 401, recorder.Code)
+
+	recorder = web.getHttpResponseWithHeaders(
+		"/status/history?station=red1", map[string]string{"Authorization": "Bearer mypassword"},
+	)
+	assert.Equal(t, 200, recorder.Code)
+}
+
+func TestWriteHistoryCsv(t *testing.T) {
+	samples := []radio.LinkSample{
+		{
+			Time: time.Date(2024, 4, 20, 12, 0, 0, 500000000, time.UTC),
+			NetworkStatus: radio.NetworkStatus{
+				Ssid:               "254",
+				IsLinked:           true,
+				MacAddress:         "48:DA:35:B0:00:CF",
+				SignalDbm:          -53,
+				NoiseDbm:           -95,
+				SignalNoiseRatio:   42,
+				RxRateMbps:         550.6,
+				RxPackets:          4095,
+				RxBytes:            12345,
+				TxRateMbps:         254,
+				TxPackets:          123,
+				TxBytes:            98765,
+				BandwidthUsedMbps:  4.102,
+				BandwidthLimitMbps: 2.5,
+				ConnectionQuality:  "excellent",
+				AssociatedClients:  []radio.AssociatedClient{{MacAddress: "48:DA:35:B0:00:CF"}},
+			},
+		},
+		{Time: time.Date(2024, 4, 20, 12, 0, 5, 0, time.UTC), NetworkStatus: radio.NetworkStatus{Ssid: "254"}},
+	}
+
+	recorder := httptest.NewRecorder()
+	assert.Nil(t, writeHistoryCsv(recorder, samples))
+	assert.Equal(
+		t,
+		"time,ssid,isLinked,macAddress,signalDbm,noiseDbm,signalNoiseRatio,rxRateMbps,rxPackets,rxBytes,txRateMbps,"+
+			"txPackets,txBytes,bandwidthUsedMbps,bandwidthLimitMbps,connectionQuality,associatedClientCount\n"+
+			"2024-04-20T12:00:00.5Z,254,true,48:DA:35:B0:00:CF,-53,-95,42,550.6,4095,12345,254,123,98765,4.102,2.5,"+
+			"excellent,1\n"+
+			"2024-04-20T12:00:05Z,254,false,,0,0,0,0,0,0,0,0,0,0,0,,0\n",
+		recorder.Body.String(),
+	)
+}
@@ -0,0 +1,228 @@
+package web
+
+import (
+	"fmt"
+	"github.com/patfair/frc-radio-api/radio"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// monitoredNetwork associates the status of a single Wi-Fi network with the labels that identify it in the metrics.
+type monitoredNetwork struct {
+	labels []metricLabel
+	status *radio.NetworkStatus
+}
+
+// metricLabel represents a single name/value pair used to distinguish metrics having the same name.
+type metricLabel struct {
+	name  string
+	value string
+}
+
+// networkMetric describes how a single field of the network status is exported as a metric.
+type networkMetric struct {
+	name       string
+	metricType string
+	help       string
+	value      func(status *radio.NetworkStatus) float64
+}
+
+var networkMetrics = []networkMetric{
+	{
+		"frc_radio_network_linked",
+		"gauge",
+		"Whether the network is associated with a remote device (1) or not (0).",
+		func(status *radio.NetworkStatus) float64 { return boolToFloat(status.IsLinked) },
+	},
+	{
+		"frc_radio_network_signal_dbm",
+		"gauge",
+		"Signal strength of the link to the remote device, in decibel-milliwatts.",
+		func(status *radio.NetworkStatus) float64 { return float64(status.SignalDbm) },
+	},
+	{
+		"frc_radio_network_noise_dbm",
+		"gauge",
+		"Noise level of the link to the remote device, in decibel-milliwatts.",
+		func(status *radio.NetworkStatus) float64 { return float64(status.NoiseDbm) },
+	},
+	{
+		"frc_radio_network_signal_noise_ratio_db",
+		"gauge",
+		"Signal-to-noise ratio of the link to the remote device, in decibels.",
+		func(status *radio.NetworkStatus) float64 { return float64(status.SignalNoiseRatio) },
+	},
+	{
+		"frc_radio_network_rx_rate_mbps",
+		"gauge",
+		"Upper-bound link receive rate from the remote device, in megabits per second.",
+		func(status *radio.NetworkStatus) float64 { return status.RxRateMbps },
+	},
+	{
+		"frc_radio_network_rx_packets_total",
+		"counter",
+		"Number of packets received from the remote device.",
+		func(status *radio.NetworkStatus) float64 { return float64(status.RxPackets) },
+	},
+	{
+		"frc_radio_network_rx_bytes_total",
+		"counter",
+		"Number of bytes received on the network interface.",
+		func(status *radio.NetworkStatus) float64 { return float64(status.RxBytes) },
+	},
+	{
+		"frc_radio_network_tx_rate_mbps",
+		"gauge",
+		"Upper-bound link transmit rate to the remote device, in megabits per second.",
+		func(status *radio.NetworkStatus) float64 { return status.TxRateMbps },
+	},
+	{
+		"frc_radio_network_tx_packets_total",
+		"counter",
+		"Number of packets transmitted to the remote device.",
+		func(status *radio.NetworkStatus) float64 { return float64(status.TxPackets) },
+	},
+	{
+		"frc_radio_network_tx_bytes_total",
+		"counter",
+		"Number of bytes transmitted on the network interface.",
+		func(status *radio.NetworkStatus) float64 { return float64(status.TxBytes) },
+	},
+	{
+		"frc_radio_network_bandwidth_used_mbps",
+		"gauge",
+		"Five-second average total (rx + tx) bandwidth used, in megabits per second.",
+		func(status *radio.NetworkStatus) float64 { return status.BandwidthUsedMbps },
+	},
+	{
+		"frc_radio_network_bandwidth_limit_mbps",
+		"gauge",
+		"Throughput limit applied to the network in each direction, in megabits per second, or 0 if unlimited.",
+		func(status *radio.NetworkStatus) float64 { return status.BandwidthLimitMbps },
+	},
+}
+
+// metricsHandler returns the network telemetry and event counters in the Prometheus text exposition format.
+func (web *WebServer) metricsHandler(w http.ResponseWriter, r *http.Request) {
+	if !web.authorize(w, r, scopeReadStatus) {
+		return
+	}
+
+	var metrics metricsWriter
+	networks := getMonitoredNetworks(web.radio.StatusSnapshot())
+	for _, metric := range networkMetrics {
+		metrics.writeHeader(metric.name, metric.metricType, metric.help)
+		for _, network := range networks {
+			value := metric.value(network.status)
+			if value == radio.MonitoringErrorCode {
+				// Omit values that couldn't be determined rather than exporting the sentinel.
+				continue
+			}
+			metrics.writeSample(metric.name, network.labels, value)
+		}
+	}
+
+	metrics.writeHeader(
+		"frc_radio_network_info", "gauge", "Identifying details of the remote device associated with the network.",
+	)
+	for _, network := range networks {
+		if network.status.MacAddress != "" {
+			labels := append(network.labels, metricLabel{"mac_address", network.status.MacAddress})
+			metrics.writeSample("frc_radio_network_info", labels, 1)
+		}
+	}
+
+	metrics.writeHeader(
+		"frc_radio_network_connection_quality", "gauge", "Connection quality of the link to the remote device.",
+	)
+	for _, network := range networks {
+		if network.status.ConnectionQuality != "" {
+			labels := append(network.labels, metricLabel{"quality", network.status.ConnectionQuality})
+			metrics.writeSample("frc_radio_network_connection_quality", labels, 1)
+		}
+	}
+
+	counters := web.radio.Counters()
+	metrics.writeHeader(
+		"frc_radio_configuration_attempts_total", "counter", "Number of configuration requests the radio has applied.",
+	)
+	metrics.writeSample("frc_radio_configuration_attempts_total", nil, float64(counters.ConfigurationAttempts))
+	metrics.writeHeader(
+		"frc_radio_configuration_retries_total",
+		"counter",
+		"Number of times the Wi-Fi configuration had to be reapplied because the radio didn't yet reflect it.",
+	)
+	metrics.writeSample("frc_radio_configuration_retries_total", nil, float64(counters.ConfigurationRetries))
+	metrics.writeHeader(
+		"frc_radio_monitoring_command_failures_total", "counter", "Number of monitoring commands that have failed.",
+	)
+	var commands []string
+	for command := range counters.MonitoringCommandFailures {
+		commands = append(commands, command)
+	}
+	sort.Strings(commands)
+	for _, command := range commands {
+		metrics.writeSample(
+			"frc_radio_monitoring_command_failures_total",
+			[]metricLabel{{"command", command}},
+			float64(counters.MonitoringCommandFailures[command]),
+		)
+	}
+
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	if _, err := w.Write([]byte(metrics.String())); err != nil {
+		handleWebErr(w, err, http.StatusInternalServerError)
+		return
+	}
+}
+
+// getMonitoredNetworks returns the status of each network in the given radio status snapshot that is worth exporting,
+// along with the labels that identify it.
+func getMonitoredNetworks(snapshot any) []monitoredNetwork {
+	switch r := snapshot.(type) {
+	case *radio.AccessPointRadio:
+		return getAccessPointMonitoredNetworks(r)
+	case *radio.RobotRadio:
+		return getRobotRadioMonitoredNetworks(r)
+	default:
+		return nil
+	}
+}
+
+// metricsWriter accumulates metrics in the Prometheus text exposition format.
+type metricsWriter struct {
+	strings.Builder
+}
+
+// writeHeader writes the help text and type for the given metric, which must precede all of its samples.
+func (writer *metricsWriter) writeHeader(name, metricType, help string) {
+	_, _ = fmt.Fprintf(writer, "# HELP %s %s\n# TYPE %s %s\n", name, help, name, metricType)
+}
+
+// writeSample writes a single value of the given metric with the given labels.
+func (writer *metricsWriter) writeSample(name string, labels []metricLabel, value float64) {
+	writer.WriteString(name)
+	if len(labels) > 0 {
+		labelStrings := make([]string, len(labels))
+		for i, label := range labels {
+			labelStrings[i] = fmt.Sprintf("%s=\"%s\"", label.name, escapeLabelValue(label.value))
+		}
+		_, _ = fmt.Fprintf(writer, "{%s}", strings.Join(labelStrings, ","))
+	}
+	_, _ = fmt.Fprintf(writer, " %s\n", strconv.FormatFloat(value, 'g', -1, 64))
+}
+
+// escapeLabelValue escapes the characters that have special meaning within a quoted label value.
+func escapeLabelValue(value string) string {
+	return strings.NewReplacer("\\", "\\\\", "\"", "\\\"", "\n", "\\n").Replace(value)
+}
+
+// boolToFloat converts the given boolean to a metric value of 1 or 0.
+func boolToFloat(value bool) float64 {
+	if value {
+		return 1
+	}
+	return 0
+}
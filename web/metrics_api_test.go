@@ -0,0 +1,126 @@
+// This file is specific to the access point role of the API.
+
+package web
+
+import (
+	"github.com/patfair/frc-radio-api/radio"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestWeb_metricsHandler(t *testing.T) {
+	ap := radio.NewAccessPointRadio()
+	ap.Type = radio.TypeVividHosting
+	web := NewWebServer(ap)
+
+	ap.StationStatuses["red2"] = &radio.NetworkStatus{
+		Ssid:               "254",
+		IsLinked:           true,
+		MacAddress:         "48:DA:35:B0:00:CF",
+		SignalDbm:          -53,
+		NoiseDbm:           -95,
+		SignalNoiseRatio:   42,
+		RxRateMbps:         550.6,
+		RxPackets:          4095,
+		RxBytes:            12345,
+		TxRateMbps:         254,
+		TxPackets:          10,
+		TxBytes:            98765,
+		BandwidthUsedMbps:  15.324,
+		BandwidthLimitMbps: 4,
+		ConnectionQuality:  "excellent",
+	}
+	ap.StationStatuses["blue1"] = &radio.NetworkStatus{
+		Ssid:              "1114",
+		RxBytes:           radio.MonitoringErrorCode,
+		TxBytes:           radio.MonitoringErrorCode,
+		BandwidthUsedMbps: 0.5,
+	}
+
+	recorder := web.getHttpResponse("/metrics")
+	assert.Equal(t, 200, recorder.Code)
+	assert.Equal(t, "text/plain; version=0.0.4; charset=utf-8", recorder.Header().Get("Content-Type"))
+	body := recorder.Body.String()
+	assert.Contains(
+		t,
+		body,
+		"# HELP frc_radio_network_linked Whether the network is associated with a remote device (1) or not (0).\n"+
+			"# TYPE frc_radio_network_linked gauge\n"+
+			"frc_radio_network_linked{station=\"blue1\",ssid=\"1114\",radio_type=\"VividHosting\"} 0\n"+
+			"frc_radio_network_linked{station=\"red2\",ssid=\"254\",radio_type=\"VividHosting\"} 1\n",
+	)
+	assert.Contains(
+		t, body, "frc_radio_network_signal_dbm{station=\"red2\",ssid=\"254\",radio_type=\"VividHosting\"} -53\n",
+	)
+	assert.Contains(
+		t, body, "frc_radio_network_rx_rate_mbps{station=\"red2\",ssid=\"254\",radio_type=\"VividHosting\"} 550.6\n",
+	)
+	assert.Contains(t, body, "# TYPE frc_radio_network_tx_bytes_total counter\n")
+	assert.Contains(
+		t,
+		body,
+		"frc_radio_network_tx_bytes_total{station=\"red2\",ssid=\"254\",radio_type=\"VividHosting\"} 98765\n",
+	)
+	assert.NotContains(t, body, "frc_radio_network_tx_bytes_total{station=\"blue1\"")
+	assert.Contains(
+		t,
+		body,
+		"frc_radio_network_bandwidth_used_mbps{station=\"blue1\",ssid=\"1114\",radio_type=\"VividHosting\"} 0.5\n",
+	)
+	assert.Contains(t, body, "# TYPE frc_radio_network_bandwidth_limit_mbps gauge\n")
+	assert.Contains(
+		t,
+		body,
+		"frc_radio_network_bandwidth_limit_mbps{station=\"red2\",ssid=\"254\",radio_type=\"VividHosting\"} 4\n",
+	)
+	assert.Contains(
+		t,
+		body,
+		"frc_radio_network_bandwidth_limit_mbps{station=\"blue1\",ssid=\"1114\",radio_type=\"VividHosting\"} 0\n",
+	)
+	assert.Contains(
+		t,
+		body,
+		"frc_radio_network_info{station=\"red2\",ssid=\"254\",radio_type=\"VividHosting\","+
+			"mac_address=\"48:DA:35:B0:00:CF\"} 1\n",
+	)
+	assert.Contains(
+		t,
+		body,
+		"frc_radio_network_connection_quality{station=\"red2\",ssid=\"254\",radio_type=\"VividHosting\","+
+			"quality=\"excellent\"} 1\n",
+	)
+	assert.NotContains(t, body, "station=\"red1\"")
+	assert.Contains(t, body, "frc_radio_configuration_attempts_total 0\n")
+	assert.Contains(t, body, "frc_radio_configuration_retries_total 0\n")
+	assert.Contains(t, body, "# TYPE frc_radio_monitoring_command_failures_total counter\n")
+}
+
+func TestWeb_metricsHandlerAuthorization(t *testing.T) {
+	ap := radio.NewAccessPointRadio()
+	web := NewWebServer(ap)
+	web.password = "mypassword"
+
+	// Without password.
+	recorder := web.getHttpResponse("/metrics")
+	assert.Equal(t, 401, recorder.Code)
+
+	// With correct password.
+	recorder = web.getHttpResponseWithHeaders("/metrics", map[string]string{"Authorization": "Bearer mypassword"})
+	assert.Equal(t, 200, recorder.Code)
+}
+
+func TestMetricsWriter(t *testing.T) {
+	var metrics metricsWriter
+	metrics.writeHeader("my_metric", "gauge", "Some help text.")
+	metrics.writeSample("my_metric", nil, 1.5)
+	metrics.writeSample("my_metric", []metricLabel{{"name", "with \"quotes\"\nand \\backslash"}, {"other", "x"}}, -2)
+	assert.Equal(
+		t,
+		"# HELP my_metric Some help text.\n"+
+			"# TYPE my_metric gauge\n"+
+			"my_metric 1.5\n"+
+			"my_metric{name=\"with \\\"quotes\\\"\\nand \\\\backslash\",other=\"x\"} -2\n",
+		metrics.String(),
+	)
+}
@@ -0,0 +1,210 @@
+package web
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	mqtt "github.com/eclipse/paho.mqtt.golang"
+	"github.com/patfair/frc-radio-api/radio"
+	"log"
+	"net/http"
+	"sort"
+	"time"
+)
+
+const (
+	// Interval at which to republish the radio status to the MQTT broker even if it hasn't changed, so that subscribers
+	// see up-to-date link metrics.
+	mqttPublishInterval = 5 * time.Second
+
+	// Interval between attempts to connect to the MQTT broker if it is unreachable.
+	mqttConnectRetryInterval = 10 * time.Second
+
+	// Maximum amount of time to wait for the MQTT broker to acknowledge each message published.
+	mqttPublishTimeout = 5 * time.Second
+)
+
+// MqttOptions holds the settings for publishing the radio's status to an MQTT broker.
+type MqttOptions struct {
+	// URL of the broker to connect to (e.g. "tcp://10.0.100.5:1883"). If blank, MQTT is disabled.
+	BrokerUrl string
+
+	// Client ID to connect to the broker with. If blank, a random one is generated.
+	ClientId string
+
+	// Credentials to connect to the broker with. If blank, no credentials are sent.
+	Username string
+	Password string
+
+	// Prefix of the topics to publish and subscribe to. If blank, "frc/ap" is used for the access point and
+	// "frc/robot" for the robot radio.
+	TopicPrefix string
+}
+
+// mqttError is the payload published in place of the job when a configuration request received over MQTT is rejected.
+type mqttError struct {
+	Error  string                  `json:"error"`
+	Code   string                  `json:"code"`
+	Field  string                  `json:"field,omitempty"`
+	Errors map[string][]fieldError `json:"errors,omitempty"`
+}
+
+// runMqtt connects to the MQTT broker, if one is configured, and then publishes the radio status to it whenever it
+// changes and at a regular interval. Blocks until the process terminates.
+func (web *WebServer) runMqtt() {
+	if web.Mqtt.BrokerUrl == "" {
+		return
+	}
+
+	listener := web.radio.SubscribeStatusChanges()
+	defer web.radio.UnsubscribeStatusChanges(listener)
+
+	client := web.newMqttClient()
+	log.Printf("Connecting to MQTT broker at %s", web.Mqtt.BrokerUrl)
+	// The connection is retried in the background until it succeeds, so there is no need to wait for it here.
+	client.Connect()
+
+	publishTicker := time.NewTicker(mqttPublishInterval)
+	defer publishTicker.Stop()
+	for {
+		select {
+		case <-listener:
+		case <-publishTicker.C:
+		}
+		if client.IsConnectionOpen() {
+			web.publishMqttStatus(client)
+		}
+	}
+}
+
+// newMqttClient returns a client for the configured MQTT broker that, upon each connection or reconnection, subscribes
+// to the configuration topic and publishes the current status.
+func (web *WebServer) newMqttClient() mqtt.Client {
+	clientId := web.Mqtt.ClientId
+	if clientId == "" {
+		clientId = fmt.Sprintf("frc-radio-api-%s", newRandomId())
+	}
+	options := mqtt.NewClientOptions().
+		AddBroker(web.Mqtt.BrokerUrl).
+		SetClientID(clientId).
+		SetUsername(web.Mqtt.Username).
+		SetPassword(web.Mqtt.Password).
+		SetAutoReconnect(true).
+		SetConnectRetry(true).
+		SetConnectRetryInterval(mqttConnectRetryInterval).
+		// Handle each message in its own goroutine so that publishing from within a handler can't deadlock.
+		SetOrderMatters(false).
+		SetOnConnectHandler(
+			func(client mqtt.Client) {
+				log.Printf("Connected to MQTT broker at %s", web.Mqtt.BrokerUrl)
+				topic := web.mqttTopic("configuration")
+				token := client.Subscribe(topic, 1, web.handleMqttConfiguration)
+				if token.Wait() && token.Error() != nil {
+					log.Printf("Error subscribing to MQTT topic %s: %v", topic, token.Error())
+				}
+				web.publishMqttStatus(client)
+			},
+		).
+		SetConnectionLostHandler(
+			func(client mqtt.Client, err error) {
+				log.Printf("Lost connection to MQTT broker at %s; will reconnect: %v", web.Mqtt.BrokerUrl, err)
+			},
+		)
+	return mqtt.NewClient(options)
+}
+
+// publishMqttStatus publishes the radio status as a whole, as well as that of each of its networks individually, as
+// retained messages so that new subscribers receive the latest values immediately.
+func (web *WebServer) publishMqttStatus(client mqtt.Client) {
+	snapshot := web.radio.StatusSnapshot()
+	web.publishMqttJson(client, web.mqttTopic("status"), true, snapshot)
+
+	networkStatuses := getNetworkStatuses(snapshot)
+	var networkNames []string
+	for networkName := range networkStatuses {
+		networkNames = append(networkNames, networkName)
+	}
+	sort.Strings(networkNames)
+	for _, networkName := range networkNames {
+		web.publishMqttJson(client, web.mqttTopic("stations/"+networkName), true, networkStatuses[networkName])
+	}
+}
+
+// handleMqttConfiguration receives a JSON request to configure the radio over MQTT and adds it to the asynchronous
+// queue in the same manner as the /configuration endpoint, then publishes the resulting job or error.
+func (web *WebServer) handleMqttConfiguration(client mqtt.Client, message mqtt.Message) {
+	resultTopic := web.mqttTopic("configuration/result")
+	auditedRequest := auditEntry{
+		Time:          time.Now(),
+		RemoteAddress: web.Mqtt.BrokerUrl,
+		Identity:      "mqtt",
+		Action:        "MQTT " + message.Topic(),
+		Request:       summarizeAuditedRequest(&http.Request{}, message.Payload()),
+	}
+	job, err := web.queueConfigurationRequest(bytes.NewReader(message.Payload()))
+	if err != nil {
+		log.Printf("Rejected configuration request from MQTT topic %s: %v", message.Topic(), err)
+		auditedRequest.Outcome, auditedRequest.Error = auditRejected, err.Error()
+		web.audit.record(auditedRequest)
+		response := newErrorResponse(err, http.StatusBadRequest)
+		web.publishMqttJson(
+			client,
+			resultTopic,
+			false,
+			mqttError{Error: response.Message, Code: response.Code, Field: response.Field, Errors: response.Errors},
+		)
+		return
+	}
+	auditedRequest.Outcome, auditedRequest.JobId = auditAccepted, job.Id
+	web.audit.record(auditedRequest)
+	web.publishMqttJson(
+		client,
+		resultTopic,
+		false,
+		configurationResponse{
+			ConfigurationJob: job, Message: "New configuration received and will be applied asynchronously.",
+		},
+	)
+}
+
+// publishMqttJson publishes the given value as JSON to the given topic, logging any error.
+func (web *WebServer) publishMqttJson(client mqtt.Client, topic string, retained bool, value any) {
+	jsonData, err := json.Marshal(value)
+	if err != nil {
+		log.Printf("Error marshalling MQTT payload for topic %s: %v", topic, err)
+		return
+	}
+	token := client.Publish(topic, 1, retained, jsonData)
+	if !token.WaitTimeout(mqttPublishTimeout) {
+		log.Printf("Timed out publishing to MQTT topic %s", topic)
+	} else if token.Error() != nil {
+		log.Printf("Error publishing to MQTT topic %s: %v", topic, token.Error())
+	}
+}
+
+// mqttTopic returns the full name of the given MQTT topic, under the configured prefix or the default for the radio's
+// role.
+func (web *WebServer) mqttTopic(topic string) string {
+	prefix := web.Mqtt.TopicPrefix
+	if prefix == "" {
+		if web.radio.Role() == radio.RoleRobotRadio {
+			prefix = "frc/robot"
+		} else {
+			prefix = "frc/ap"
+		}
+	}
+	return prefix + "/" + topic
+}
+
+// getNetworkStatuses returns the status of each network in the given radio status snapshot, keyed by the name of its
+// station or band. The status of an access point station that has no team assigned is nil.
+func getNetworkStatuses(snapshot any) map[string]*radio.NetworkStatus {
+	switch r := snapshot.(type) {
+	case *radio.AccessPointRadio:
+		return r.StationStatuses
+	case *radio.RobotRadio:
+		return map[string]*radio.NetworkStatus{"2.4GHz": &r.NetworkStatus24, "6GHz": &r.NetworkStatus6}
+	default:
+		return nil
+	}
+}
@@ -0,0 +1,397 @@
+package web
+
+import (
+	"bufio"
+	"encoding/binary"
+	"encoding/json"
+	mqtt "github.com/eclipse/paho.mqtt.golang"
+	"github.com/patfair/frc-radio-api/radio"
+	"github.com/stretchr/testify/assert"
+	"io"
+	"net"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestWeb_publishMqttStatus(t *testing.T) {
+	broker := newFakeMqttBroker(t)
+	ap := radio.NewAccessPointRadio()
+	web := NewWebServer(ap)
+	web.Mqtt = MqttOptions{BrokerUrl: broker.url(), Username: "fms", Password: "secret"}
+
+	client := web.newMqttClient()
+	if token := client.Connect(); !assert.True(t, token.WaitTimeout(time.Second)) || !assert.Nil(t, token.Error()) {
+		return
+	}
+	defer client.Disconnect(100)
+
+	// The status should be published as soon as the connection is established, with the stations in sorted order.
+	assert.Eventually(
+		t, func() bool { return broker.retained("frc/ap/stations/red3") != nil }, time.Second, time.Millisecond,
+	)
+	assert.Equal(t, "fms", broker.username)
+	assert.Equal(t, "secret", broker.password)
+	var status map[string]any
+	assert.Nil(t, json.Unmarshal(broker.retained("frc/ap/status"), &status))
+	assert.Equal(t, "BOOTING", status["status"])
+	for _, station := range []string{"red1", "red2", "red3", "blue1", "blue2", "blue3"} {
+		assert.Equal(t, "null", string(broker.retained("frc/ap/stations/"+station)))
+	}
+	assert.Contains(t, broker.subscriptions(), "frc/ap/configuration")
+}
+
+func TestWeb_handleMqttConfiguration(t *testing.T) {
+	broker := newFakeMqttBroker(t)
+	ap := radio.NewAccessPointRadio()
+	ap.Type = radio.TypeVividHosting
+	web := NewWebServer(ap)
+	web.Mqtt = MqttOptions{BrokerUrl: broker.url(), TopicPrefix: "field"}
+	web.audit = newAuditLog(filepath.Join(t.TempDir(), "audit.log"))
+
+	client := web.newMqttClient()
+	if token := client.Connect(); !assert.True(t, token.WaitTimeout(time.Second)) || !assert.Nil(t, token.Error()) {
+		return
+	}
+	defer client.Disconnect(100)
+	assert.Eventually(
+		t, func() bool { return broker.retained("field/stations/red3") != nil }, time.Second, time.Millisecond,
+	)
+	assert.Equal(t, []string{"field/configuration"}, broker.subscriptions())
+
+	// Send a valid request from a separate client, as the field management system would.
+	sender := mqtt.NewClient(mqtt.NewClientOptions().AddBroker(broker.url()).SetClientID("fms"))
+	if token := sender.Connect(); !assert.True(t, token.WaitTimeout(time.Second)) || !assert.Nil(t, token.Error()) {
+		return
+	}
+	defer sender.Disconnect(100)
+	sender.Publish(
+		"field/configuration", 1, false, `{"stationConfigurations": {"blue1": {"ssid": "254", "wpaKey": "12345678"}}}`,
+	).Wait()
+
+	result := broker.waitForMessage(t, "field/configuration/result")
+	var response map[string]any
+	assert.Nil(t, json.Unmarshal(result, &response))
+	assert.Equal(t, "QUEUED", response["status"])
+	assert.NotEmpty(t, response["id"])
+	if assert.Equal(t, 1, len(ap.ConfigurationRequestChannel)) {
+		request := (<-ap.ConfigurationRequestChannel).(*radio.AccessPointConfigurationRequest)
+		assert.Equal(
+			t, radio.StationConfiguration{Ssid: "254", WpaKey: "12345678"}, request.StationConfigurations["blue1"],
+		)
+	}
+	_, ok := ap.GetConfigurationJob(response["id"].(string))
+	assert.True(t, ok)
+
+	// Send an invalid request.
+	sender.Publish("field/configuration", 1, false, `{"stationConfigurations": {"orange1": {"ssid": "254"}}}`).Wait()
+	result = broker.waitForMessage(t, "field/configuration/result")
+	var rejection mqttError
+	assert.Nil(t, json.Unmarshal(result, &rejection))
+	assert.Equal(
+		t,
+		mqttError{
+			Error: "invalid configuration: invalid station: orange1",
+			Code:  "INVALID_STATION",
+			Field: "stationConfigurations.orange1",
+			Errors: map[string][]fieldError{
+				"stationConfigurations.orange1": {{Code: "INVALID_STATION", Message: "invalid station: orange1"}},
+			},
+		},
+		rejection,
+	)
+	assert.Equal(t, 0, len(ap.ConfigurationRequestChannel))
+
+	sender.Publish("field/configuration", 1, false, "blorpy").Wait()
+	result = broker.waitForMessage(t, "field/configuration/result")
+	assert.Contains(t, string(result), `{"error":"invalid JSON:`)
+	assert.Contains(t, string(result), `"code":"INVALID_JSON"`)
+	assert.Equal(t, 0, len(ap.ConfigurationRequestChannel))
+
+	// Each request is recorded in the audit log, with the WPA keys redacted.
+	entries, err := web.audit.entries(time.Time{})
+	assert.Nil(t, err)
+	if assert.Equal(t, 3, len(entries)) {
+		assert.Equal(t, "MQTT field/configuration", entries[0].Action)
+		assert.Equal(t, "mqtt", entries[0].Identity)
+		assert.Equal(t, broker.url(), entries[0].RemoteAddress)
+		assert.Equal(t, auditAccepted, entries[0].Outcome)
+		assert.Equal(t, response["id"], entries[0].JobId)
+		assert.Equal(
+			t,
+			map[string]any{
+				"stationConfigurations": map[string]any{
+					"blue1": map[string]any{"ssid": "254", "wpaKey": redactedValue},
+				},
+			},
+			entries[0].Request,
+		)
+		assert.Equal(t, auditRejected, entries[1].Outcome)
+		assert.Equal(t, "invalid configuration: invalid station: orange1", entries[1].Error)
+		assert.Equal(t, "unparseable 6-byte body", entries[2].Request)
+	}
+}
+
+func TestWeb_mqttTopic(t *testing.T) {
+	web := NewWebServer(radio.NewAccessPointRadio())
+	assert.Equal(t, "frc/ap/status", web.mqttTopic("status"))
+	web.Mqtt.TopicPrefix = "field/ap"
+	assert.Equal(t, "field/ap/stations/red1", web.mqttTopic("stations/red1"))
+
+	web = NewWebServer(radio.NewRobotRadio())
+	assert.Equal(t, "frc/robot/stations/6GHz", web.mqttTopic("stations/6GHz"))
+}
+
+func TestGetNetworkStatuses(t *testing.T) {
+	ap := radio.NewAccessPointRadio()
+	ap.StationStatuses["red2"] = &radio.NetworkStatus{Ssid: "254", IsLinked: true}
+	statuses := getNetworkStatuses(ap)
+	assert.Equal(t, 6, len(statuses))
+	assert.Equal(t, &radio.NetworkStatus{Ssid: "254", IsLinked: true}, statuses["red2"])
+	assert.Nil(t, statuses["blue1"])
+
+	robotRadio := radio.NewRobotRadio()
+	robotRadio.NetworkStatus6.Ssid = "1234"
+	robotRadio.NetworkStatus24.Ssid = "FRC-1234"
+	statuses = getNetworkStatuses(robotRadio)
+	assert.Equal(t, 2, len(statuses))
+	assert.Equal(t, "1234", statuses["6GHz"].Ssid)
+	assert.Equal(t, "FRC-1234", statuses["2.4GHz"].Ssid)
+
+	assert.Nil(t, getNetworkStatuses("not a radio"))
+}
+
+// fakeMqttBroker is a minimal in-process MQTT 3.1.1 broker for testing, which supports only what the API uses: a single
+// level of subscription without wildcards, retained messages, and acknowledgement of QoS 1 publishes. Messages are
+// forwarded to subscribers at QoS 0.
+type fakeMqttBroker struct {
+	listener net.Listener
+	mutex    sync.Mutex
+
+	// Credentials presented by the most recent client to connect.
+	username string
+	password string
+
+	// Map of topics to the connections subscribed to them.
+	subscribers map[string][]net.Conn
+
+	// Map of topics to their retained message.
+	retainedMessages map[string][]byte
+
+	// Messages published to each topic, oldest first, which are consumed by waitForMessage.
+	messages map[string][][]byte
+}
+
+// newFakeMqttBroker starts a broker listening on a random local port, which is shut down at the end of the test.
+func newFakeMqttBroker(t *testing.T) *fakeMqttBroker {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	broker := &fakeMqttBroker{
+		listener:         listener,
+		subscribers:      make(map[string][]net.Conn),
+		retainedMessages: make(map[string][]byte),
+		messages:         make(map[string][][]byte),
+	}
+	t.Cleanup(func() { _ = listener.Close() })
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			t.Cleanup(func() { _ = conn.Close() })
+			go broker.serve(conn)
+		}
+	}()
+	return broker
+}
+
+// url returns the URL to connect to the broker at.
+func (broker *fakeMqttBroker) url() string {
+	return "tcp://" + broker.listener.Addr().String()
+}
+
+// retained returns the retained message of the given topic, or nil if there is none.
+func (broker *fakeMqttBroker) retained(topic string) []byte {
+	broker.mutex.Lock()
+	defer broker.mutex.Unlock()
+	return broker.retainedMessages[topic]
+}
+
+// subscriptions returns the topics that any client has subscribed to.
+func (broker *fakeMqttBroker) subscriptions() []string {
+	broker.mutex.Lock()
+	defer broker.mutex.Unlock()
+	var topics []string
+	for topic := range broker.subscribers {
+		topics = append(topics, topic)
+	}
+	return topics
+}
+
+// waitForMessage waits for a message to be published to the given topic and returns its payload, failing the test if
+// none arrives.
+func (broker *fakeMqttBroker) waitForMessage(t *testing.T, topic string) []byte {
+	var payload []byte
+	assert.Eventually(
+		t,
+		func() bool {
+			broker.mutex.Lock()
+			defer broker.mutex.Unlock()
+			if len(broker.messages[topic]) == 0 {
+				return false
+			}
+			payload = broker.messages[topic][0]
+			broker.messages[topic] = broker.messages[topic][1:]
+			return true
+		},
+		time.Second,
+		time.Millisecond,
+	)
+	return payload
+}
+
+// serve handles the packets sent by a single client until it disconnects.
+func (broker *fakeMqttBroker) serve(conn net.Conn) {
+	reader := bufio.NewReader(conn)
+	for {
+		header, err := reader.ReadByte()
+		if err != nil {
+			return
+		}
+		body, err := readMqttPacketBody(reader)
+		if err != nil {
+			return
+		}
+
+		switch header >> 4 {
+		case 1: // CONNECT
+			broker.handleConnect(body)
+			_, _ = conn.Write([]byte{0x20, 2, 0, 0})
+		case 3: // PUBLISH
+			broker.handlePublish(conn, header, body)
+		case 8: // SUBSCRIBE
+			packetId, topics := parseMqttSubscribe(body)
+			broker.mutex.Lock()
+			for _, topic := range topics {
+				broker.subscribers[topic] = append(broker.subscribers[topic], conn)
+			}
+			broker.mutex.Unlock()
+			response := []byte{0x90, byte(2 + len(topics)), byte(packetId >> 8), byte(packetId)}
+			_, _ = conn.Write(append(response, make([]byte, len(topics))...))
+		case 12: // PINGREQ
+			_, _ = conn.Write([]byte{0xd0, 0})
+		case 14: // DISCONNECT
+			return
+		}
+	}
+}
+
+// handleConnect records the credentials presented in the given CONNECT packet.
+func (broker *fakeMqttBroker) handleConnect(body []byte) {
+	_, rest := readMqttString(body)
+	flags := rest[1]
+	_, rest = readMqttString(rest[4:]) // Client ID
+	if flags&0x04 != 0 {
+		_, rest = readMqttString(rest) // Will topic
+		_, rest = readMqttString(rest) // Will message
+	}
+	broker.mutex.Lock()
+	defer broker.mutex.Unlock()
+	broker.username, broker.password = "", ""
+	if flags&0x80 != 0 {
+		broker.username, rest = readMqttString(rest)
+	}
+	if flags&0x40 != 0 {
+		broker.password, _ = readMqttString(rest)
+	}
+}
+
+// handlePublish records the message in the given PUBLISH packet, acknowledges it if needed and forwards it to any
+// subscribers.
+func (broker *fakeMqttBroker) handlePublish(conn net.Conn, header byte, body []byte) {
+	topic, rest := readMqttString(body)
+	qos := (header >> 1) & 0x03
+	if qos > 0 {
+		_, _ = conn.Write([]byte{0x40, 2, rest[0], rest[1]})
+		rest = rest[2:]
+	}
+	payload := append([]byte{}, rest...)
+
+	broker.mutex.Lock()
+	broker.messages[topic] = append(broker.messages[topic], payload)
+	if header&0x01 != 0 {
+		broker.retainedMessages[topic] = payload
+	}
+	subscribers := append([]net.Conn{}, broker.subscribers[topic]...)
+	broker.mutex.Unlock()
+
+	packet := append(encodeMqttString(topic), payload...)
+	for _, subscriber := range subscribers {
+		_, _ = subscriber.Write(append(append([]byte{0x30}, encodeMqttLength(len(packet))...), packet...))
+	}
+}
+
+// readMqttPacketBody reads the remaining length of an MQTT packet and then the rest of the packet.
+func readMqttPacketBody(reader *bufio.Reader) ([]byte, error) {
+	length, multiplier := 0, 1
+	for {
+		digit, err := reader.ReadByte()
+		if err != nil {
+			return nil, err
+		}
+		length += int(digit&0x7f) * multiplier
+		if digit&0x80 == 0 {
+			break
+		}
+		multiplier *= 128
+	}
+	body := make([]byte, length)
+	_, err := io.ReadFull(reader, body)
+	return body, err
+}
+
+// parseMqttSubscribe returns the packet ID and the topics subscribed to in the given SUBSCRIBE packet.
+func parseMqttSubscribe(body []byte) (uint16, []string) {
+	packetId := binary.BigEndian.Uint16(body)
+	var topics []string
+	rest := body[2:]
+	for len(rest) > 0 {
+		var topic string
+		topic, rest = readMqttString(rest)
+		topics = append(topics, topic)
+		rest = rest[1:] // Requested QoS
+	}
+	return packetId, topics
+}
+
+// readMqttString reads a length-prefixed string from the start of the given data and returns it along with the
+// remaining data.
+func readMqttString(data []byte) (string, []byte) {
+	length := int(binary.BigEndian.Uint16(data))
+	return string(data[2 : 2+length]), data[2+length:]
+}
+
+// encodeMqttString returns the given string prefixed with its length.
+func encodeMqttString(value string) []byte {
+	return append([]byte{byte(len(value) >> 8), byte(len(value))}, value...)
+}
+
+// encodeMqttLength returns the given remaining length of an MQTT packet in its variable-length encoding.
+func encodeMqttLength(length int) []byte {
+	var encoded []byte
+	for {
+		digit := byte(length % 128)
+		length /= 128
+		if length > 0 {
+			digit |= 0x80
+		}
+		encoded = append(encoded, digit)
+		if length == 0 {
+			return encoded
+		}
+	}
+}
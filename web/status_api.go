@@ -3,21 +3,22 @@ package web
 import (
 	"encoding/json"
 	"errors"
+	"fmt"
+	"log"
 	"net/http"
+	"time"
 )
 
+// Interval at which to send a comment on an otherwise idle status stream to keep the connection alive.
+const statusStreamKeepaliveInterval = 30 * time.Second
+
 // statusHandler returns a JSON dump of the radio status.
 func (web *WebServer) statusHandler(w http.ResponseWriter, r *http.Request) {
-	if !web.isAuthorized(r) {
-		handleWebErr(
-			w,
-			errors.New("not authorized; must provide 'Authorization: Bearer [password]' header"),
-			http.StatusUnauthorized,
-		)
+	if !web.authorize(w, r, scopeReadStatus) {
 		return
 	}
 
-	jsonData, err := json.MarshalIndent(web.radio, "", "  ")
+	jsonData, err := json.MarshalIndent(web.radio.StatusSnapshot(), "", "  ")
 	if err != nil {
 		handleWebErr(w, err, http.StatusInternalServerError)
 		return
@@ -30,3 +31,59 @@ func (web *WebServer) statusHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 }
+
+// statusStreamHandler streams the radio status as Server-Sent Events, sending one event upon connection and another
+// whenever the status changes.
+func (web *WebServer) statusStreamHandler(w http.ResponseWriter, r *http.Request) {
+	if !web.authorize(w, r, scopeReadStatus) {
+		return
+	}
+
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		handleWebErr(w, errors.New("streaming is not supported by the connection"), http.StatusInternalServerError)
+		return
+	}
+
+	listener := web.radio.SubscribeStatusChanges()
+	defer web.radio.UnsubscribeStatusChanges(listener)
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+
+	if err := web.writeStatusEvent(w); err != nil {
+		return
+	}
+	flusher.Flush()
+
+	keepaliveTicker := time.NewTicker(statusStreamKeepaliveInterval)
+	defer keepaliveTicker.Stop()
+	for {
+		select {
+		case <-listener:
+			if err := web.writeStatusEvent(w); err != nil {
+				return
+			}
+		case <-keepaliveTicker.C:
+			if _, err := fmt.Fprint(w, ": keepalive\n\n"); err != nil {
+				return
+			}
+		case <-r.Context().Done():
+			return
+		}
+		flusher.Flush()
+	}
+}
+
+// writeStatusEvent writes the current radio status to the given stream as a single Server-Sent Event.
+func (web *WebServer) writeStatusEvent(w http.ResponseWriter) error {
+	jsonData, err := json.Marshal(web.radio.StatusSnapshot())
+	if err != nil {
+		log.Printf("Error marshalling radio status for stream: %v", err)
+		return err
+	}
+	_, err = fmt.Fprintf(w, "event: status\ndata: %s\n\n", jsonData)
+	return err
+}
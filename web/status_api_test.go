@@ -1,17 +1,20 @@
-// This file is specific to the access point version of the API.
-//go:build !robot
+// This file is specific to the access point role of the API.
 
 package web
 
 import (
+	"bufio"
 	"encoding/json"
 	"github.com/patfair/frc-radio-api/radio"
 	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"strings"
 	"testing"
 )
 
 func TestWeb_statusHandler(t *testing.T) {
-	ap := radio.NewRadio()
+	ap := radio.NewAccessPointRadio()
 	web := NewWebServer(ap)
 
 	ap.Channel = 136
@@ -31,7 +34,7 @@ func TestWeb_statusHandler(t *testing.T) {
 	recorder := web.getHttpResponse("/status")
 	assert.Equal(t, 200, recorder.Code)
 
-	var actualAp radio.Radio
+	var actualAp radio.AccessPointRadio
 	assert.Nil(t, json.Unmarshal(recorder.Body.Bytes(), &actualAp))
 	assert.Equal(t, ap.Status, actualAp.Status)
 	assert.Equal(t, ap.Status, actualAp.Status)
@@ -39,7 +42,7 @@ func TestWeb_statusHandler(t *testing.T) {
 }
 
 func TestWeb_statusHandlerAuthorization(t *testing.T) {
-	ap := radio.NewRadio()
+	ap := radio.NewAccessPointRadio()
 	web := NewWebServer(ap)
 	web.password = "mypassword"
 
@@ -55,3 +58,41 @@ func TestWeb_statusHandlerAuthorization(t *testing.T) {
 	recorder = web.getHttpResponseWithHeaders("/status", map[string]string{"Authorization": "Bearer mypassword"})
 	assert.Equal(t, 200, recorder.Code)
 }
+
+func TestWeb_statusStreamHandler(t *testing.T) {
+	ap := radio.NewAccessPointRadio()
+	web := NewWebServer(ap)
+	web.password = "mypassword"
+	ap.Status = "CONFIGURING"
+
+	server := httptest.NewServer(web.newRouter())
+	defer server.Close()
+
+	// Without password.
+	response, err := http.Get(server.URL + "/status/stream")
+	if assert.Nil(t, err) {
+		assert.Equal(t, 401, response.StatusCode)
+		response.Body.Close()
+	}
+
+	// With correct password.
+	request, _ := http.NewRequest("GET", server.URL+"/status/stream", nil)
+	request.Header.Set("Authorization", "Bearer mypassword")
+	response, err = http.DefaultClient.Do(request)
+	if assert.Nil(t, err) {
+		defer response.Body.Close()
+		assert.Equal(t, 200, response.StatusCode)
+		assert.Equal(t, "text/event-stream", response.Header.Get("Content-Type"))
+
+		// The current status should be sent immediately upon connection.
+		reader := bufio.NewReader(response.Body)
+		line, _ := reader.ReadString('\n')
+		assert.Equal(t, "event: status\n", line)
+		line, _ = reader.ReadString('\n')
+		if assert.True(t, strings.HasPrefix(line, "data: ")) {
+			var actualAp radio.AccessPointRadio
+			assert.Nil(t, json.Unmarshal([]byte(strings.TrimPrefix(line, "data: ")), &actualAp))
+			assert.Equal(t, ap.Status, actualAp.Status)
+		}
+	}
+}
@@ -0,0 +1,43 @@
+// This file is specific to the access point role of the API.
+
+package web
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/patfair/frc-radio-api/radio"
+	"net/http"
+)
+
+// surveyHandler scans for neighboring networks and returns them as JSON, along with how congested they make each of
+// the channels that the access point could use. The scan takes the radio off its channel for a moment, so it requires
+// the same scope as changing the configuration.
+func (web *WebServer) surveyHandler(w http.ResponseWriter, r *http.Request) {
+	if !web.authorize(w, r, scopeConfigure) {
+		return
+	}
+
+	accessPoint, ok := web.radio.(*radio.AccessPointRadio)
+	if !ok {
+		handleWebErr(w, errors.New("surveys are only supported by the access point"), http.StatusNotFound)
+		return
+	}
+	survey, err := accessPoint.Survey()
+	if err != nil {
+		handleWebErr(w, err, http.StatusInternalServerError)
+		return
+	}
+
+	jsonData, err := json.MarshalIndent(survey, "", "  ")
+	if err != nil {
+		handleWebErr(w, err, http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_, err = w.Write(jsonData)
+	if err != nil {
+		handleWebErr(w, err, http.StatusInternalServerError)
+		return
+	}
+}
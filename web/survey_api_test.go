@@ -0,0 +1,46 @@
+// This file is specific to the access point role of the API.
+
+package web
+
+import (
+	"github.com/patfair/frc-radio-api/radio"
+	"github.com/stretchr/testify/assert"
+	"path/filepath"
+	"testing"
+)
+
+func TestWeb_surveyHandler(t *testing.T) {
+	web := NewWebServer(radio.NewAccessPointRadio())
+
+	// The scan can't be run outside of a real radio.
+	recorder := web.getHttpResponse("/survey")
+	assert.Equal(t, 500, recorder.Code)
+	assert.Contains(t, recorder.Body.String(), "error scanning for networks on device radio0")
+
+	// The robot radio has no such endpoint.
+	web = NewWebServer(radio.NewRobotRadio())
+	recorder = web.getHttpResponse("/survey")
+	assert.Equal(t, 404, recorder.Code)
+}
+
+func TestWeb_surveyHandlerAuthorization(t *testing.T) {
+	web := NewWebServer(radio.NewAccessPointRadio())
+	web.password = "mypassword"
+
+	recorder := web.getHttpResponse("/survey")
+	assert.Equal(t, 401, recorder.Code)
+	response := decodeErrorResponse(t, recorder)
+	assert.Equal(t, "UNAUTHORIZED", response.Code)
+	assert.Contains(t, response.Message, "not authorized")
+
+	// Since scanning interrupts service, a token that can only read the status isn't enough.
+	web.tokens = newTokenStore(filepath.Join(t.TempDir(), "tokens.json"))
+	_, statusSecret, _ := web.tokens.create("Audience display", []tokenScope{scopeReadStatus})
+	_, configureSecret, _ := web.tokens.create("FMS", []tokenScope{scopeConfigure})
+	recorder = web.getHttpResponseWithHeaders("/survey", map[string]string{"Authorization": "Bearer " + statusSecret})
+	assert.Equal(t, 403, recorder.Code)
+	assert.Contains(t, decodeErrorResponse(t, recorder).Message, "lacks the \"configure\" scope")
+	recorder = web.getHttpResponseWithHeaders("/survey", map[string]string{"Authorization": "Bearer " + configureSecret})
+	assert.Equal(t, 500, recorder.Code)
+	assert.Contains(t, decodeErrorResponse(t, recorder).Message, "error scanning for networks")
+}
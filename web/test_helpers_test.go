@@ -2,11 +2,14 @@ package web
 
 import (
 	"bytes"
+	"encoding/json"
+	"github.com/stretchr/testify/assert"
 	"io"
 	"mime/multipart"
 	"net/http"
 	"net/http/httptest"
 	"strings"
+	"testing"
 )
 
 // getHttpResponse stubs the webserver, sends a GET request to the given path, and returns the response, for use in
@@ -74,3 +77,24 @@ func (web *WebServer) postFileHttpResponse(
 	web.newRouter().ServeHTTP(recorder, req)
 	return recorder
 }
+
+// deleteHttpResponseWithHeaders stubs the webserver, sends a DELETE request to the given path with the given headers,
+// and returns the response, for use in testing.
+func (web *WebServer) deleteHttpResponseWithHeaders(path string, headers map[string]string) *httptest.ResponseRecorder {
+	recorder := httptest.NewRecorder()
+	req, _ := http.NewRequest("DELETE", path, nil)
+	for key, value := range headers {
+		req.Header.Set(key, value)
+	}
+	web.newRouter().ServeHTTP(recorder, req)
+	return recorder
+}
+
+// decodeErrorResponse checks that the given response carries a JSON error body and returns it decoded, for use in
+// testing.
+func decodeErrorResponse(t *testing.T, recorder *httptest.ResponseRecorder) errorResponse {
+	assert.Equal(t, "application/json", recorder.Header().Get("Content-Type"))
+	var response errorResponse
+	assert.Nil(t, json.Unmarshal(recorder.Body.Bytes(), &response))
+	return response
+}
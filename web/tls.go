@@ -0,0 +1,151 @@
+package web
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/hex"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"log"
+	"math/big"
+	"net"
+	"os"
+	"time"
+)
+
+const (
+	// Names of the files within the data directory holding the self-signed certificate and its private key, which are
+	// generated on first boot if no certificate is provided.
+	selfSignedCertFileName = "frc-radio-api-cert.pem"
+	selfSignedKeyFileName  = "frc-radio-api-key.pem"
+
+	// TCP port that the HTTPS listener uses if no address is given.
+	portTls = 8443
+
+	// How long the self-signed certificate is valid for.
+	selfSignedCertValidity = 20 * 365 * 24 * time.Hour
+
+	// Header carrying the fingerprint of the server's TLS certificate in responses from the /health endpoint.
+	tlsFingerprintHeader = "X-FRC-Radio-TLS-Fingerprint"
+)
+
+// TlsOptions holds the settings for serving the API over HTTPS.
+type TlsOptions struct {
+	// Whether to serve the API over HTTPS in addition to (or instead of) plain HTTP.
+	Enabled bool
+
+	// Address and port for the HTTPS listener. If blank, the host of the HTTP listener is used with port 8443.
+	ListenAddress string
+
+	// Paths to an operator-provided PEM certificate and private key. If both are blank, a self-signed certificate is
+	// generated on first boot and reused thereafter.
+	CertFile string
+	KeyFile  string
+
+	// Whether to stop serving plain HTTP once HTTPS is enabled. HTTP is kept by default for backward compatibility.
+	DisableHttp bool
+}
+
+// loadTlsCertificate returns the certificate configured in the TLS options, generating and persisting a self-signed
+// one to the given paths if none is provided and none has been generated yet.
+func (options *TlsOptions) loadTlsCertificate(selfSignedCertFile, selfSignedKeyFile string) (tls.Certificate, error) {
+	if options.CertFile != "" || options.KeyFile != "" {
+		if options.CertFile == "" || options.KeyFile == "" {
+			return tls.Certificate{}, errors.New("both a TLS certificate and key must be provided, or neither")
+		}
+		certificate, err := tls.LoadX509KeyPair(options.CertFile, options.KeyFile)
+		if err != nil {
+			return tls.Certificate{}, fmt.Errorf("error loading TLS certificate: %v", err)
+		}
+		return certificate, nil
+	}
+
+	certificate, err := tls.LoadX509KeyPair(selfSignedCertFile, selfSignedKeyFile)
+	if err == nil {
+		return certificate, nil
+	}
+	if _, statErr := os.Stat(selfSignedCertFile); !errors.Is(statErr, os.ErrNotExist) {
+		return tls.Certificate{}, fmt.Errorf("error loading self-signed TLS certificate: %v", err)
+	}
+	log.Printf("Generating self-signed TLS certificate at %s", selfSignedCertFile)
+	return generateSelfSignedCertificate(selfSignedCertFile, selfSignedKeyFile)
+}
+
+// generateSelfSignedCertificate creates a new self-signed certificate and private key, writes them to the given paths
+// in PEM format, and returns them.
+func generateSelfSignedCertificate(certFile, keyFile string) (tls.Certificate, error) {
+	privateKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+	serialNumber, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+	hostname, _ := os.Hostname()
+	now := time.Now()
+	template := x509.Certificate{
+		SerialNumber:          serialNumber,
+		Subject:               pkix.Name{CommonName: "frc-radio-api", Organization: []string{"FRC Radio API"}},
+		NotBefore:             now.Add(-time.Hour),
+		NotAfter:              now.Add(selfSignedCertValidity),
+		KeyUsage:              x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		BasicConstraintsValid: true,
+		DNSNames:              []string{"localhost"},
+		IPAddresses:           []net.IP{net.IPv4(127, 0, 0, 1)},
+	}
+	if hostname != "" && hostname != "localhost" {
+		template.DNSNames = append(template.DNSNames, hostname)
+	}
+	if ipAddress, err := getVlan100IpAddress(); err == nil {
+		template.IPAddresses = append(template.IPAddresses, net.ParseIP(ipAddress))
+	}
+	certDer, err := x509.CreateCertificate(rand.Reader, &template, &template, &privateKey.PublicKey, privateKey)
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+	keyDer, err := x509.MarshalECPrivateKey(privateKey)
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+
+	certPem := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: certDer})
+	keyPem := pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDer})
+	if err = os.WriteFile(keyFile, keyPem, 0600); err != nil {
+		return tls.Certificate{}, fmt.Errorf("error saving TLS key: %v", err)
+	}
+	if err = os.WriteFile(certFile, certPem, 0644); err != nil {
+		return tls.Certificate{}, fmt.Errorf("error saving TLS certificate: %v", err)
+	}
+	return tls.X509KeyPair(certPem, keyPem)
+}
+
+// tlsFingerprint returns the hex-encoded SHA-256 hash of the given certificate's leaf in DER form, which clients can
+// pin in place of validating a self-signed certificate against a certificate authority.
+func tlsFingerprint(certificate tls.Certificate) string {
+	if len(certificate.Certificate) == 0 {
+		return ""
+	}
+	hash := sha256.Sum256(certificate.Certificate[0])
+	return hex.EncodeToString(hash[:])
+}
+
+// getTlsListenAddress returns the address and port for the HTTPS listener, which defaults to the host of the given HTTP
+// listen address with the HTTPS port.
+func (options *TlsOptions) getTlsListenAddress(httpListenAddress string) string {
+	if options.ListenAddress != "" {
+		return options.ListenAddress
+	}
+	host, _, err := net.SplitHostPort(httpListenAddress)
+	if err != nil {
+		host = ""
+	}
+	return net.JoinHostPort(host, fmt.Sprint(portTls))
+}
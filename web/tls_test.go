@@ -0,0 +1,109 @@
+package web
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"github.com/patfair/frc-radio-api/radio"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestTlsOptions_loadTlsCertificateSelfSigned(t *testing.T) {
+	dir := t.TempDir()
+	certFile, keyFile := filepath.Join(dir, "cert.pem"), filepath.Join(dir, "key.pem")
+	options := TlsOptions{Enabled: true}
+
+	// The certificate is generated on first use.
+	certificate, err := options.loadTlsCertificate(certFile, keyFile)
+	assert.Nil(t, err)
+	fingerprint := tlsFingerprint(certificate)
+	assert.Len(t, fingerprint, 64)
+	info, err := os.Stat(keyFile)
+	assert.Nil(t, err)
+	assert.Equal(t, os.FileMode(0600), info.Mode().Perm())
+	leaf, err := x509.ParseCertificate(certificate.Certificate[0])
+	assert.Nil(t, err)
+	assert.Equal(t, "frc-radio-api", leaf.Subject.CommonName)
+	assert.Contains(t, leaf.DNSNames, "localhost")
+	assert.Nil(t, leaf.CheckSignature(leaf.SignatureAlgorithm, leaf.RawTBSCertificate, leaf.Signature))
+
+	// The same certificate is reused thereafter.
+	certificate, err = options.loadTlsCertificate(certFile, keyFile)
+	assert.Nil(t, err)
+	assert.Equal(t, fingerprint, tlsFingerprint(certificate))
+
+	// A corrupted certificate is reported rather than silently replaced, which would break clients that pinned it.
+	assert.Nil(t, os.WriteFile(certFile, []byte("blorpy"), 0644))
+	_, err = options.loadTlsCertificate(certFile, keyFile)
+	if assert.NotNil(t, err) {
+		assert.Contains(t, err.Error(), "error loading self-signed TLS certificate")
+	}
+}
+
+func TestTlsOptions_loadTlsCertificateProvided(t *testing.T) {
+	dir := t.TempDir()
+	certFile, keyFile := filepath.Join(dir, "cert.pem"), filepath.Join(dir, "key.pem")
+	generatedCertificate, err := generateSelfSignedCertificate(certFile, keyFile)
+	assert.Nil(t, err)
+
+	// The provided certificate takes precedence over the self-signed one.
+	options := TlsOptions{Enabled: true, CertFile: certFile, KeyFile: keyFile}
+	certificate, err := options.loadTlsCertificate(filepath.Join(dir, "other.pem"), filepath.Join(dir, "other-key.pem"))
+	assert.Nil(t, err)
+	assert.Equal(t, tlsFingerprint(generatedCertificate), tlsFingerprint(certificate))
+	_, err = os.Stat(filepath.Join(dir, "other.pem"))
+	assert.True(t, os.IsNotExist(err))
+
+	options = TlsOptions{Enabled: true, CertFile: certFile}
+	_, err = options.loadTlsCertificate(certFile, keyFile)
+	assert.EqualError(t, err, "both a TLS certificate and key must be provided, or neither")
+
+	options = TlsOptions{Enabled: true, CertFile: certFile, KeyFile: filepath.Join(dir, "missing.pem")}
+	_, err = options.loadTlsCertificate(certFile, keyFile)
+	if assert.NotNil(t, err) {
+		assert.Contains(t, err.Error(), "error loading TLS certificate")
+	}
+}
+
+func TestTlsFingerprint(t *testing.T) {
+	assert.Equal(t, "", tlsFingerprint(tls.Certificate{}))
+	certificate := tls.Certificate{Certificate: [][]byte{[]byte("leaf"), []byte("intermediate")}}
+	assert.Equal(t, "9f91161f43433e49a6de6db680d79f60159f2e4ac9172621a12846428158440b", tlsFingerprint(certificate))
+}
+
+func TestTlsOptions_getTlsListenAddress(t *testing.T) {
+	options := TlsOptions{}
+	assert.Equal(t, "10.0.100.2:8443", options.getTlsListenAddress("10.0.100.2:80"))
+	assert.Equal(t, "localhost:8443", options.getTlsListenAddress("localhost:8081"))
+	assert.Equal(t, ":8443", options.getTlsListenAddress(":80"))
+	options.ListenAddress = "10.0.100.2:443"
+	assert.Equal(t, "10.0.100.2:443", options.getTlsListenAddress("10.0.100.2:80"))
+}
+
+func TestWeb_healthHandlerOverTls(t *testing.T) {
+	certificate, err := generateSelfSignedCertificate(
+		filepath.Join(t.TempDir(), "cert.pem"), filepath.Join(t.TempDir(), "key.pem"),
+	)
+	assert.Nil(t, err)
+	web := NewWebServer(radio.NewAccessPointRadio())
+	web.tlsFingerprint = tlsFingerprint(certificate)
+	server := httptest.NewUnstartedServer(web.newRouter())
+	server.TLS = &tls.Config{Certificates: []tls.Certificate{certificate}}
+	server.StartTLS()
+	defer server.Close()
+
+	// The fingerprint sent in the header matches the certificate that the client actually sees.
+	httpClient := &http.Client{Transport: &http.Transport{TLSClientConfig: &tls.Config{InsecureSkipVerify: true}}}
+	response, err := httpClient.Get(server.URL + "/health")
+	if assert.Nil(t, err) {
+		defer response.Body.Close()
+		assert.Equal(t, 200, response.StatusCode)
+		assert.Equal(t, web.tlsFingerprint, response.Header.Get(tlsFingerprintHeader))
+		peerCertificate := tls.Certificate{Certificate: [][]byte{response.TLS.PeerCertificates[0].Raw}}
+		assert.Equal(t, web.tlsFingerprint, tlsFingerprint(peerCertificate))
+	}
+}
@@ -0,0 +1,114 @@
+package web
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/gorilla/mux"
+	"net/http"
+	"strings"
+)
+
+// tokenCreationRequest is the JSON body of a request to create an API token.
+type tokenCreationRequest struct {
+	// Human-readable name describing who or what will use the token.
+	Name string `json:"name"`
+
+	// Scopes that the token grants access to.
+	Scopes []tokenScope `json:"scopes"`
+}
+
+// tokenCreationResponse is the JSON body of the response to a successful request to create an API token.
+type tokenCreationResponse struct {
+	apiToken
+
+	// Secret to provide in the 'Authorization: Bearer [token]' header. It is not stored and can't be retrieved again.
+	Token string `json:"token"`
+}
+
+// tokensHandler returns a JSON list of the API tokens, without their secrets.
+func (web *WebServer) tokensHandler(w http.ResponseWriter, r *http.Request) {
+	if !web.authorize(w, r, scopeAdmin) {
+		return
+	}
+
+	jsonData, err := json.MarshalIndent(web.tokens.list(), "", "  ")
+	if err != nil {
+		handleWebErr(w, err, http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if _, err = w.Write(jsonData); err != nil {
+		handleWebErr(w, err, http.StatusInternalServerError)
+		return
+	}
+}
+
+// tokenCreationHandler receives a JSON request to create a new API token and returns it along with its secret.
+func (web *WebServer) tokenCreationHandler(w http.ResponseWriter, r *http.Request) {
+	if !web.authorize(w, r, scopeAdmin) {
+		return
+	}
+
+	var request tokenCreationRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		handleWebErr(w, newApiError(errorInvalidJson, "", fmt.Errorf("invalid JSON: %v", err)), http.StatusBadRequest)
+		return
+	}
+	if err := request.validate(); err != nil {
+		handleWebErr(w, newApiError(errorInvalidToken, "", fmt.Errorf("invalid token: %v", err)), http.StatusBadRequest)
+		return
+	}
+
+	token, secret, err := web.tokens.create(strings.TrimSpace(request.Name), request.Scopes)
+	if err != nil {
+		handleWebErr(w, err, http.StatusConflict)
+		return
+	}
+	jsonData, err := json.MarshalIndent(tokenCreationResponse{apiToken: token, Token: secret}, "", "  ")
+	if err != nil {
+		handleWebErr(w, err, http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Location", fmt.Sprintf("/tokens/%s", token.Id))
+	w.WriteHeader(http.StatusCreated)
+	_, _ = w.Write(jsonData)
+}
+
+// tokenRevocationHandler deletes a previously created API token, after which it can no longer be used.
+func (web *WebServer) tokenRevocationHandler(w http.ResponseWriter, r *http.Request) {
+	if !web.authorize(w, r, scopeAdmin) {
+		return
+	}
+
+	id := mux.Vars(r)["id"]
+	found, err := web.tokens.revoke(id)
+	if !found {
+		handleWebErr(w, fmt.Errorf("no token with ID %q", id), http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		handleWebErr(w, err, http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// validate checks that the token creation request has a name and at least one scope, all of which are known.
+func (request *tokenCreationRequest) validate() error {
+	if strings.TrimSpace(request.Name) == "" {
+		return errors.New("name must not be blank")
+	}
+	if len(request.Scopes) == 0 {
+		return fmt.Errorf("at least one scope is required (expecting any of %v)", tokenScopes)
+	}
+	for _, scope := range request.Scopes {
+		if !isValidTokenScope(scope) {
+			return fmt.Errorf("invalid scope %q (expecting any of %v)", scope, tokenScopes)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,106 @@
+package web
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/patfair/frc-radio-api/radio"
+	"github.com/stretchr/testify/assert"
+	"path/filepath"
+	"testing"
+)
+
+func TestWeb_tokenHandlers(t *testing.T) {
+	web := NewWebServer(radio.NewAccessPointRadio())
+	web.tokens = newTokenStore(filepath.Join(t.TempDir(), "tokens.json"))
+	web.password = "mypassword"
+	headers := map[string]string{"Authorization": "Bearer mypassword"}
+
+	recorder := web.getHttpResponseWithHeaders("/tokens", headers)
+	assert.Equal(t, 200, recorder.Code)
+	assert.Equal(t, "application/json", recorder.Header().Get("Content-Type"))
+	assert.Equal(t, "[]", recorder.Body.String())
+
+	recorder = web.postHttpResponseWithHeaders(
+		"/tokens", `{"name": "FMS", "scopes": ["read-status", "configure"]}`, headers,
+	)
+	assert.Equal(t, 201, recorder.Code)
+	var created tokenCreationResponse
+	assert.Nil(t, json.Unmarshal(recorder.Body.Bytes(), &created))
+	assert.Len(t, created.Id, 16)
+	assert.Equal(t, "FMS", created.Name)
+	assert.Equal(t, []tokenScope{scopeReadStatus, scopeConfigure}, created.Scopes)
+	assert.Len(t, created.Token, 64)
+	assert.Equal(t, "/tokens/"+created.Id, recorder.Header().Get("Location"))
+
+	// The secret is never listed.
+	recorder = web.getHttpResponseWithHeaders("/tokens", headers)
+	assert.Equal(t, 200, recorder.Code)
+	assert.NotContains(t, recorder.Body.String(), created.Token)
+	assert.NotContains(t, recorder.Body.String(), "hashedSecret")
+	var tokens []apiToken
+	assert.Nil(t, json.Unmarshal(recorder.Body.Bytes(), &tokens))
+	assert.Equal(t, []apiToken{created.apiToken}, tokens)
+
+	// The new token can be used for the endpoints in its scopes, but not to manage tokens.
+	tokenHeaders := map[string]string{"Authorization": "Bearer " + created.Token}
+	recorder = web.getHttpResponseWithHeaders("/status", tokenHeaders)
+	assert.Equal(t, 200, recorder.Code)
+	recorder = web.getHttpResponseWithHeaders("/tokens", tokenHeaders)
+	assert.Equal(t, 403, recorder.Code)
+
+	recorder = web.deleteHttpResponseWithHeaders("/tokens/"+created.Id, headers)
+	assert.Equal(t, 204, recorder.Code)
+	assert.Empty(t, recorder.Body.String())
+	assert.True(t, web.tokens.isEmpty())
+
+	// The revoked token no longer works.
+	recorder = web.getHttpResponseWithHeaders("/status", tokenHeaders)
+	assert.Equal(t, 401, recorder.Code)
+
+	recorder = web.deleteHttpResponseWithHeaders("/tokens/"+created.Id, headers)
+	assert.Equal(t, 404, recorder.Code)
+	assert.Contains(t, decodeErrorResponse(t, recorder).Message, fmt.Sprintf("no token with ID %q", created.Id))
+}
+
+func TestWeb_tokenCreationHandlerErrors(t *testing.T) {
+	web := NewWebServer(radio.NewAccessPointRadio())
+	web.tokens = newTokenStore(filepath.Join(t.TempDir(), "tokens.json"))
+
+	recorder := web.postHttpResponse("/tokens", "blorpy")
+	assert.Equal(t, 400, recorder.Code)
+	assert.Contains(t, recorder.Body.String(), "invalid JSON")
+
+	recorder = web.postHttpResponse("/tokens", `{"name": " ", "scopes": ["admin"]}`)
+	assert.Equal(t, 400, recorder.Code)
+	assert.Contains(t, recorder.Body.String(), "invalid token: name must not be blank")
+
+	recorder = web.postHttpResponse("/tokens", `{"name": "FMS"}`)
+	assert.Equal(t, 400, recorder.Code)
+	assert.Contains(t, recorder.Body.String(), "invalid token: at least one scope is required")
+
+	recorder = web.postHttpResponse("/tokens", `{"name": "FMS", "scopes": ["read-status", "superuser"]}`)
+	assert.Equal(t, 400, recorder.Code)
+	assert.Contains(t, decodeErrorResponse(t, recorder).Message, "invalid scope \"superuser\" (expecting any of")
+
+	assert.True(t, web.tokens.isEmpty())
+}
+
+func TestWeb_tokenHandlersUnauthorized(t *testing.T) {
+	web := NewWebServer(radio.NewAccessPointRadio())
+	web.tokens = newTokenStore(filepath.Join(t.TempDir(), "tokens.json"))
+	web.password = "mypassword"
+
+	recorder := web.getHttpResponse("/tokens")
+	assert.Equal(t, 401, recorder.Code)
+	recorder = web.postHttpResponse("/tokens", `{"name": "FMS", "scopes": ["admin"]}`)
+	assert.Equal(t, 401, recorder.Code)
+	recorder = web.deleteHttpResponseWithHeaders("/tokens/1234", nil)
+	assert.Equal(t, 401, recorder.Code)
+	assert.True(t, web.tokens.isEmpty())
+
+	// A token having the admin scope can manage other tokens.
+	token, secret, _ := web.tokens.create("Admin", []tokenScope{scopeAdmin})
+	recorder = web.getHttpResponseWithHeaders("/tokens", map[string]string{"Authorization": "Bearer " + secret})
+	assert.Equal(t, 200, recorder.Code)
+	assert.Contains(t, recorder.Body.String(), token.Id)
+}
@@ -0,0 +1,228 @@
+package web
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"sync"
+	"time"
+)
+
+const (
+	// Name of the optional file within the data directory containing the hashed API tokens.
+	tokenFileName = "frc-radio-api-tokens.json"
+
+	// Maximum number of API tokens that can exist at once.
+	maxTokens = 50
+)
+
+// tokenScope represents a set of endpoints that an API token grants access to.
+type tokenScope string
+
+const (
+	// Grants read-only access to the status, history, events and metrics endpoints.
+	scopeReadStatus tokenScope = "read-status"
+
+	// Grants access to the endpoints for applying configuration, checking on its progress and surveying channels.
+	scopeConfigure tokenScope = "configure"
+
+	// Grants access to the endpoint for flashing new firmware.
+	scopeFirmware tokenScope = "firmware"
+
+	// Grants access to every endpoint, including those for managing webhooks and API tokens.
+	scopeAdmin tokenScope = "admin"
+)
+
+// All scopes that an API token can be granted.
+var tokenScopes = []tokenScope{scopeReadStatus, scopeConfigure, scopeFirmware, scopeAdmin}
+
+// apiToken represents a named credential for accessing the API, as exposed through the API. The secret itself is only
+// revealed once, when the token is created.
+type apiToken struct {
+	// Unique identifier for the token.
+	Id string `json:"id"`
+
+	// Human-readable name describing who or what uses the token.
+	Name string `json:"name"`
+
+	// Scopes that the token grants access to.
+	Scopes []tokenScope `json:"scopes"`
+
+	// Time at which the token was created.
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+// storedToken represents an API token as persisted to the token file.
+type storedToken struct {
+	apiToken
+
+	// Hex-encoded SHA-256 hash of the token's secret.
+	HashedSecret string `json:"hashedSecret"`
+}
+
+// tokenStore holds the API tokens and persists them to a file so that they survive restarts.
+type tokenStore struct {
+	mutex sync.Mutex
+
+	// Path to the file that the tokens are loaded from and saved to. If blank, they are only kept in memory.
+	filePath string
+
+	// Tokens in the order they were created.
+	tokens []storedToken
+}
+
+// newTokenStore creates an empty store that persists its tokens to the given file (or not at all, if blank).
+func newTokenStore(filePath string) *tokenStore {
+	return &tokenStore{filePath: filePath}
+}
+
+// load replaces the tokens in the store with those in its file. A missing file is treated as having no tokens.
+func (store *tokenStore) load() error {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+
+	if store.filePath == "" {
+		store.tokens = nil
+		return nil
+	}
+	tokenBytes, err := os.ReadFile(store.filePath)
+	if errors.Is(err, os.ErrNotExist) {
+		store.tokens = nil
+		return nil
+	} else if err != nil {
+		return err
+	}
+	var tokens []storedToken
+	if err = json.Unmarshal(tokenBytes, &tokens); err != nil {
+		return fmt.Errorf("error parsing token file %s: %v", store.filePath, err)
+	}
+	store.tokens = tokens
+	return nil
+}
+
+// saveLocked writes the tokens in the store out to its file, if it has one. The caller must hold the mutex.
+func (store *tokenStore) saveLocked() error {
+	if store.filePath == "" {
+		return nil
+	}
+	tokenBytes, err := json.MarshalIndent(store.tokens, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(store.filePath, tokenBytes, 0600)
+}
+
+// create adds a new token having the given name and scopes and returns a copy of it along with its secret, which is not
+// stored anywhere in plain text and so can't be retrieved again.
+func (store *tokenStore) create(name string, scopes []tokenScope) (apiToken, string, error) {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+
+	if len(store.tokens) >= maxTokens {
+		return apiToken{}, "", fmt.Errorf("too many tokens exist (maximum %d)", maxTokens)
+	}
+	secretBytes := make([]byte, 32)
+	if _, err := rand.Read(secretBytes); err != nil {
+		return apiToken{}, "", err
+	}
+	secret := hex.EncodeToString(secretBytes)
+	token := storedToken{
+		apiToken: apiToken{
+			Id: newRandomId(), Name: name, Scopes: scopes, CreatedAt: time.Now().UTC().Truncate(time.Second),
+		},
+		HashedSecret: hashTokenSecret(secret),
+	}
+	store.tokens = append(store.tokens, token)
+	if err := store.saveLocked(); err != nil {
+		store.tokens = store.tokens[:len(store.tokens)-1]
+		return apiToken{}, "", fmt.Errorf("error saving token file: %v", err)
+	}
+	log.Printf("Created API token %s (%s) with scopes %v", token.Id, token.Name, token.Scopes)
+	return token.apiToken, secret, nil
+}
+
+// revoke deletes the token having the given ID. Returns false if there is no such token.
+func (store *tokenStore) revoke(id string) (bool, error) {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+
+	for i, token := range store.tokens {
+		if token.Id == id {
+			remainingTokens := append(append([]storedToken{}, store.tokens[:i]...), store.tokens[i+1:]...)
+			previousTokens := store.tokens
+			store.tokens = remainingTokens
+			if err := store.saveLocked(); err != nil {
+				store.tokens = previousTokens
+				return true, fmt.Errorf("error saving token file: %v", err)
+			}
+			log.Printf("Revoked API token %s (%s)", token.Id, token.Name)
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// list returns copies of the tokens, without their hashed secrets, in the order they were created.
+func (store *tokenStore) list() []apiToken {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+
+	tokens := make([]apiToken, 0, len(store.tokens))
+	for _, token := range store.tokens {
+		tokens = append(tokens, token.apiToken)
+	}
+	return tokens
+}
+
+// isEmpty returns true if no tokens exist.
+func (store *tokenStore) isEmpty() bool {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+	return len(store.tokens) == 0
+}
+
+// lookup returns the token having the given secret, or false if there is none.
+func (store *tokenStore) lookup(secret string) (apiToken, bool) {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+
+	hashedSecret := []byte(hashTokenSecret(secret))
+	for _, token := range store.tokens {
+		if subtle.ConstantTimeCompare(hashedSecret, []byte(token.HashedSecret)) == 1 {
+			return token.apiToken, true
+		}
+	}
+	return apiToken{}, false
+}
+
+// hasScope returns true if the token grants access to endpoints requiring the given scope.
+func (token *apiToken) hasScope(scope tokenScope) bool {
+	for _, tokenScope := range token.Scopes {
+		if tokenScope == scope || tokenScope == scopeAdmin {
+			return true
+		}
+	}
+	return false
+}
+
+// hashTokenSecret returns the hex-encoded SHA-256 hash of the given token secret.
+func hashTokenSecret(secret string) string {
+	hash := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(hash[:])
+}
+
+// isValidTokenScope returns true if the given scope is one that tokens can be granted.
+func isValidTokenScope(scope tokenScope) bool {
+	for _, validScope := range tokenScopes {
+		if scope == validScope {
+			return true
+		}
+	}
+	return false
+}
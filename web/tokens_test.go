@@ -0,0 +1,122 @@
+package web
+
+import (
+	"github.com/stretchr/testify/assert"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestTokenStore(t *testing.T) {
+	filePath := filepath.Join(t.TempDir(), "tokens.json")
+	store := newTokenStore(filePath)
+
+	// A missing file means there are no tokens.
+	assert.Nil(t, store.load())
+	assert.True(t, store.isEmpty())
+	assert.Equal(t, []apiToken{}, store.list())
+
+	fmsToken, fmsSecret, err := store.create("FMS", []tokenScope{scopeConfigure, scopeReadStatus})
+	assert.Nil(t, err)
+	assert.Len(t, fmsToken.Id, 16)
+	assert.Equal(t, "FMS", fmsToken.Name)
+	assert.Equal(t, []tokenScope{scopeConfigure, scopeReadStatus}, fmsToken.Scopes)
+	assert.False(t, fmsToken.CreatedAt.IsZero())
+	assert.Len(t, fmsSecret, 64)
+	displayToken, displaySecret, err := store.create("Audience display", []tokenScope{scopeReadStatus})
+	assert.Nil(t, err)
+	assert.NotEqual(t, fmsSecret, displaySecret)
+	assert.False(t, store.isEmpty())
+	assert.Equal(t, []apiToken{fmsToken, displayToken}, store.list())
+
+	token, ok := store.lookup(fmsSecret)
+	assert.True(t, ok)
+	assert.Equal(t, fmsToken, token)
+	_, ok = store.lookup("wrongsecret")
+	assert.False(t, ok)
+	_, ok = store.lookup("")
+	assert.False(t, ok)
+
+	// The file holds only the hashes of the secrets and is readable only by its owner.
+	info, err := os.Stat(filePath)
+	assert.Nil(t, err)
+	assert.Equal(t, os.FileMode(0600), info.Mode().Perm())
+	contents, _ := os.ReadFile(filePath)
+	assert.False(t, strings.Contains(string(contents), fmsSecret))
+	assert.True(t, strings.Contains(string(contents), hashTokenSecret(fmsSecret)))
+
+	// The tokens survive being loaded into a new store.
+	reloadedStore := newTokenStore(filePath)
+	assert.Nil(t, reloadedStore.load())
+	assert.Equal(t, store.list(), reloadedStore.list())
+	_, ok = reloadedStore.lookup(displaySecret)
+	assert.True(t, ok)
+
+	found, err := store.revoke(fmsToken.Id)
+	assert.True(t, found)
+	assert.Nil(t, err)
+	assert.Equal(t, []apiToken{displayToken}, store.list())
+	_, ok = store.lookup(fmsSecret)
+	assert.False(t, ok)
+	found, err = store.revoke(fmsToken.Id)
+	assert.False(t, found)
+	assert.Nil(t, err)
+	assert.Nil(t, reloadedStore.load())
+	assert.Equal(t, []apiToken{displayToken}, reloadedStore.list())
+}
+
+func TestTokenStoreErrors(t *testing.T) {
+	dir := t.TempDir()
+
+	// The file is malformed.
+	filePath := filepath.Join(dir, "tokens.json")
+	assert.Nil(t, os.WriteFile(filePath, []byte("blorpy"), 0600))
+	store := newTokenStore(filePath)
+	err := store.load()
+	if assert.NotNil(t, err) {
+		assert.Contains(t, err.Error(), "error parsing token file")
+	}
+
+	// The file can't be written, so the token isn't created.
+	store = newTokenStore(filepath.Join(dir, "missing", "tokens.json"))
+	_, _, err = store.create("FMS", []tokenScope{scopeAdmin})
+	if assert.NotNil(t, err) {
+		assert.Contains(t, err.Error(), "error saving token file")
+	}
+	assert.True(t, store.isEmpty())
+
+	// Too many tokens exist.
+	store = newTokenStore(filepath.Join(dir, "full.json"))
+	for i := 0; i < maxTokens; i++ {
+		_, _, err = store.create("FMS", []tokenScope{scopeReadStatus})
+		assert.Nil(t, err)
+	}
+	_, _, err = store.create("FMS", []tokenScope{scopeReadStatus})
+	assert.EqualError(t, err, "too many tokens exist (maximum 50)")
+}
+
+func TestApiToken_hasScope(t *testing.T) {
+	token := apiToken{Scopes: []tokenScope{scopeReadStatus, scopeFirmware}}
+	assert.True(t, token.hasScope(scopeReadStatus))
+	assert.True(t, token.hasScope(scopeFirmware))
+	assert.False(t, token.hasScope(scopeConfigure))
+	assert.False(t, token.hasScope(scopeAdmin))
+
+	// The admin scope implies all others.
+	token = apiToken{Scopes: []tokenScope{scopeAdmin}}
+	for _, scope := range tokenScopes {
+		assert.True(t, token.hasScope(scope))
+	}
+
+	token = apiToken{}
+	assert.False(t, token.hasScope(scopeReadStatus))
+}
+
+func TestIsValidTokenScope(t *testing.T) {
+	for _, scope := range tokenScopes {
+		assert.True(t, isValidTokenScope(scope))
+	}
+	assert.False(t, isValidTokenScope("superuser"))
+	assert.False(t, isValidTokenScope(""))
+}
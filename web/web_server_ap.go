@@ -1,5 +1,4 @@
-// This file is specific to the access point version of the API.
-//go:build !robot
+// This file is specific to the access point role of the API.
 
 package web
 
@@ -11,6 +10,8 @@ import (
 	"net"
 	"net/http"
 	"regexp"
+	"sort"
+	"strings"
 	"time"
 )
 
@@ -20,10 +21,11 @@ const (
 	portVividHosting = 80
 )
 
-// getListenAddress returns the address and port that the web server should listen on.
-func getListenAddress(r *radio.Radio) string {
+// getAccessPointListenAddress returns the address and port that the web server should listen on when running on an
+// access point of the given hardware type.
+func getAccessPointListenAddress(radioType radio.RadioType) string {
 	var port int
-	if r.Type == radio.TypeLinksys {
+	if radioType == radio.TypeLinksys {
 		port = portLinksys
 	} else {
 		port = portVividHosting
@@ -67,10 +69,41 @@ func getVlan100IpAddress() (string, error) {
 	return "", fmt.Errorf("no IP address found on VLAN 100 (i.e. matching %v)", ipRe)
 }
 
-// addRoutes adds additional route handlers to the router if needed.
-func addRoutes(router *mux.Router, web *WebServer) {}
+// getAccessPointMonitoredNetworks returns the status of each team station network that currently has a team assigned,
+// labeled by station name, SSID and radio hardware type.
+func getAccessPointMonitoredNetworks(r *radio.AccessPointRadio) []monitoredNetwork {
+	var stationNames []string
+	for stationName := range r.StationStatuses {
+		stationNames = append(stationNames, stationName)
+	}
+	sort.Strings(stationNames)
+
+	var networks []monitoredNetwork
+	for _, stationName := range stationNames {
+		status := r.StationStatuses[stationName]
+		if status == nil {
+			continue
+		}
+		networks = append(
+			networks,
+			monitoredNetwork{
+				labels: []metricLabel{
+					{"station", stationName}, {"ssid", status.Ssid}, {"radio_type", strings.TrimPrefix(r.Type.String(), "Type")},
+				},
+				status: status,
+			},
+		)
+	}
+	return networks
+}
+
+// addAccessPointRoutes adds the route handlers that are specific to the access point.
+func (web *WebServer) addAccessPointRoutes(router *mux.Router) {
+	router.HandleFunc("/", web.accessPointRootHandler).Methods("GET")
+	router.HandleFunc("/survey", web.surveyHandler).Methods("GET")
+}
 
-// rootHandler redirects the root URL to the status page.
-func (web *WebServer) rootHandler(w http.ResponseWriter, r *http.Request) {
+// accessPointRootHandler redirects the root URL to the status page.
+func (web *WebServer) accessPointRootHandler(w http.ResponseWriter, r *http.Request) {
 	http.Redirect(w, r, "/status", http.StatusFound)
 }
@@ -1,5 +1,4 @@
-// This file is specific to the access point version of the API.
-//go:build !robot
+// This file is specific to the access point role of the API.
 
 package web
 
@@ -11,7 +10,7 @@ import (
 
 func TestGetVlan100IpAddress(t *testing.T) {
 	ipAddress, err := getVlan100IpAddress()
-	r := &radio.Radio{Type: radio.TypeLinksys}
+	r := &radio.AccessPointRadio{Type: radio.TypeLinksys}
 
 	// Branch the test verification logic since it may or may not be Run on a system with a 10.0.100.x interface and
 	// mocking the system calls to be deterministic is onerous.
@@ -32,8 +31,8 @@ func TestGetVlan100IpAddress(t *testing.T) {
 	}
 }
 
-func TestWeb_rootHandler(t *testing.T) {
-	var web WebServer
+func TestWeb_accessPointRootHandler(t *testing.T) {
+	web := NewWebServer(radio.NewAccessPointRadio())
 	recorder := web.getHttpResponse("/")
 	assert.Equal(t, 302, recorder.Code)
 	assert.Equal(t, "/status", recorder.Header().Get("Location"))
@@ -1,6 +1,9 @@
 package web
 
 import (
+	"crypto/subtle"
+	"crypto/tls"
+	"errors"
 	"filippo.io/age"
 	"fmt"
 	"github.com/gorilla/mux"
@@ -8,12 +11,16 @@ import (
 	"log"
 	"net/http"
 	"os"
+	"path/filepath"
 	"strings"
 )
 
 const (
-	// Path to the optional file containing the password for the API.
-	passwordFilePath = "/root/frc-radio-api-password.txt"
+	// Directory that the API reads its secrets from and persists its state to, unless configured otherwise.
+	DefaultDataDir = "/root"
+
+	// Name of the optional file within the data directory containing the password for the API.
+	passwordFileName = "frc-radio-api-password.txt"
 
 	// Interval between attempts to get the IP address of the radio on startup.
 	ipAddressPollIntervalSec = 3
@@ -21,42 +28,111 @@ const (
 
 // WebServer holds shared state across requests to the API.
 type WebServer struct {
-	// Password for authorizing requests to the API. If blank, no authorization is required.
+	// Address and port to listen on. If blank, they are determined from the radio's hardware type and network
+	// configuration.
+	ListenAddress string
+
+	// Settings for publishing the radio's status to an MQTT broker. MQTT is disabled if no broker is configured.
+	Mqtt MqttOptions
+
+	// Settings for serving the API over HTTPS. HTTPS is disabled unless explicitly enabled.
+	Tls TlsOptions
+
+	// Directory containing the files that the API reads its password and firmware decryption key from, and persists its
+	// API tokens, webhooks, audit log and self-signed certificate to. If blank, DefaultDataDir is used.
+	DataDir string
+
+	// Fingerprint of the certificate served over HTTPS, or blank if HTTPS is disabled.
+	tlsFingerprint string
+
+	// Password for authorizing requests to the API, which grants every scope. If blank and there are no API tokens, no
+	// authorization is required.
 	password string
 
+	// Named API tokens, each granting a limited set of scopes. Not persisted until the server is run.
+	tokens *tokenStore
+
+	// Record of the mutating requests made to the API. Disabled until the server is run.
+	audit *auditLog
+
 	// Private key for decrypting new firmware. If nil, only unencrypted firmware can be uploaded.
 	firmwareDecryptionKey *age.X25519Identity
 
 	// Device that the API provides access to.
-	radio *radio.Radio
+	radio radio.Radio
+
+	// Registered webhooks and the payloads waiting to be delivered to them. Not persisted until the server is run.
+	webhooks *webhookDispatcher
+
+	// State of the radio as last observed, for detecting the transitions that webhooks are sent for.
+	webhookTrigger webhookTrigger
 }
 
 // NewWebServer creates a new server instance.
-func NewWebServer(radio *radio.Radio) *WebServer {
-	return &WebServer{radio: radio}
+func NewWebServer(radio radio.Radio) *WebServer {
+	web := &WebServer{radio: radio, tokens: newTokenStore(""), audit: newAuditLog("")}
+	web.webhooks = newWebhookDispatcher("", func() string { return web.password })
+	return web
 }
 
-// Run starts the HTTP server and blocks until the process terminates, serving requests.
+// Run starts the HTTP and/or HTTPS servers and blocks until the process terminates, serving requests.
 func (web *WebServer) Run() {
 	web.setUpSecrets()
+	web.audit = newAuditLog(web.dataFilePath(auditLogFileName))
+	web.webhooks = newWebhookDispatcher(web.dataFilePath(webhookFileName), web.webhooks.signingKey)
+	if err := web.webhooks.load(); err != nil {
+		log.Printf("Error loading webhooks; starting with none registered: %v", err)
+	}
 
-	listenAddress := getListenAddress(web.radio)
-	log.Printf("Server listening on %s\n", listenAddress)
-	if err := http.ListenAndServe(listenAddress, web.newRouter()); err != nil {
-		log.Fatal(err)
+	listenAddress := web.ListenAddress
+	if listenAddress == "" {
+		listenAddress = getListenAddress(web.radio)
+	}
+	router := web.newRouter()
+	serverErrors := make(chan error, 2)
+	if web.Tls.Enabled {
+		certificate, err := web.Tls.loadTlsCertificate(
+			web.dataFilePath(selfSignedCertFileName), web.dataFilePath(selfSignedKeyFileName),
+		)
+		if err != nil {
+			log.Fatal(err)
+		}
+		web.tlsFingerprint = tlsFingerprint(certificate)
+		tlsServer := &http.Server{
+			Addr:      web.Tls.getTlsListenAddress(listenAddress),
+			Handler:   router,
+			TLSConfig: &tls.Config{Certificates: []tls.Certificate{certificate}, MinVersion: tls.VersionTLS12},
+		}
+		log.Printf(
+			"Server listening for HTTPS on %s (certificate fingerprint %s)\n", tlsServer.Addr, web.tlsFingerprint,
+		)
+		go func() { serverErrors <- tlsServer.ListenAndServeTLS("", "") }()
 	}
+	if !web.Tls.Enabled || !web.Tls.DisableHttp {
+		log.Printf("Server listening on %s\n", listenAddress)
+		go func() { serverErrors <- http.ListenAndServe(listenAddress, router) }()
+	}
+	go web.watchRadioForWebhooks()
+	go web.watchRadioForAudit()
+	go web.runMqtt()
+	log.Fatal(<-serverErrors)
 }
 
-// setUpSecrets reads the password and firmware decryption keys from their respective files, if they exist.
+// setUpSecrets reads the password, API tokens and firmware decryption keys from their respective files, if they exist.
 func (web *WebServer) setUpSecrets() {
-	passwordBytes, err := os.ReadFile(passwordFilePath)
+	passwordBytes, err := os.ReadFile(web.dataFilePath(passwordFileName))
 	if err != nil {
-		log.Printf("Error opening password file; authorization disabled: %v", err)
+		log.Printf("Error opening password file; password authorization disabled: %v", err)
 	} else {
 		web.password = strings.TrimSpace(string(passwordBytes))
 	}
 
-	privateKeyBytes, err := os.ReadFile(firmwareDecryptionKeyFilePath)
+	web.tokens = newTokenStore(web.dataFilePath(tokenFileName))
+	if err = web.tokens.load(); err != nil {
+		log.Printf("Error loading API tokens; token authorization disabled: %v", err)
+	}
+
+	privateKeyBytes, err := os.ReadFile(web.dataFilePath(firmwareDecryptionKeyFileName))
 	if err != nil {
 		log.Printf("Error opening encryption key file; firmware decryption disabled: %v", err)
 	} else if len(privateKeyBytes) != 0 {
@@ -68,36 +144,108 @@ func (web *WebServer) setUpSecrets() {
 	}
 }
 
+// dataFilePath returns the path of the file having the given name within the data directory.
+func (web *WebServer) dataFilePath(fileName string) string {
+	dataDir := web.DataDir
+	if dataDir == "" {
+		dataDir = DefaultDataDir
+	}
+	return filepath.Join(dataDir, fileName)
+}
+
 // newRouter sets up the mapping between URLs and handlers.
 func (web *WebServer) newRouter() http.Handler {
 	router := mux.NewRouter()
-	router.HandleFunc("/", web.rootHandler).Methods("GET")
 	router.HandleFunc("/health", web.healthHandler).Methods("GET")
 	router.HandleFunc("/status", web.statusHandler).Methods("GET")
-	router.HandleFunc("/configuration", web.configurationHandler).Methods("POST")
-	router.HandleFunc("/firmware", web.firmwareHandler).Methods("POST")
-	addRoutes(router, web)
+	router.HandleFunc("/status/stream", web.statusStreamHandler).Methods("GET")
+	router.HandleFunc("/status/history", web.statusHistoryHandler).Methods("GET")
+	router.HandleFunc("/events", web.eventsHandler).Methods("GET")
+	router.HandleFunc("/events/stream", web.eventsStreamHandler).Methods("GET")
+	router.HandleFunc("/metrics", web.metricsHandler).Methods("GET")
+	router.HandleFunc("/configuration", web.audited(web.configurationHandler)).Methods("POST")
+	router.HandleFunc("/configuration/{id}", web.configurationJobHandler).Methods("GET")
+	router.HandleFunc("/firmware", web.audited(web.firmwareHandler)).Methods("POST")
+	router.HandleFunc("/webhooks", web.webhooksHandler).Methods("GET")
+	router.HandleFunc("/webhooks", web.audited(web.webhookRegistrationHandler)).Methods("POST")
+	router.HandleFunc("/webhooks/{id}", web.audited(web.webhookDeletionHandler)).Methods("DELETE")
+	router.HandleFunc("/tokens", web.tokensHandler).Methods("GET")
+	router.HandleFunc("/tokens", web.audited(web.tokenCreationHandler)).Methods("POST")
+	router.HandleFunc("/tokens/{id}", web.audited(web.tokenRevocationHandler)).Methods("DELETE")
+	router.HandleFunc("/audit", web.auditHandler).Methods("GET")
+	if web.radio.Role() == radio.RoleRobotRadio {
+		web.addRobotRadioRoutes(router)
+	} else {
+		web.addAccessPointRoutes(router)
+	}
 	return router
 }
 
-// healthHandler returns a simple "OK" response to indicate that the server is running.
+// getListenAddress returns the address and port that the web server should listen on, which depend on the radio's role
+// and hardware type.
+func getListenAddress(r radio.Radio) string {
+	if r.Role() == radio.RoleRobotRadio {
+		return getRobotRadioListenAddress()
+	}
+	return getAccessPointListenAddress(r.HardwareType())
+}
+
+// healthHandler returns a simple "OK" response to indicate that the server is running, along with the fingerprint of
+// the TLS certificate if HTTPS is enabled.
 func (web *WebServer) healthHandler(w http.ResponseWriter, r *http.Request) {
+	if web.tlsFingerprint != "" {
+		w.Header().Set(tlsFingerprintHeader, web.tlsFingerprint)
+	}
 	_, _ = fmt.Fprintln(w, "OK")
 }
 
-// isAuthorized returns true if the request is authorized to access the API.
-func (web *WebServer) isAuthorized(r *http.Request) bool {
-	if web.password == "" {
+// authorize checks that the request carries the password or an API token granting the given scope, writing an error
+// response and returning false if it doesn't. No authorization is required if there is neither a password nor any
+// tokens.
+func (web *WebServer) authorize(w http.ResponseWriter, r *http.Request, scope tokenScope) bool {
+	if web.password == "" && web.tokens.isEmpty() {
+		return true
+	}
+	credential := getBearerCredential(r)
+	if web.isPassword(credential) {
 		return true
 	}
-	var password string
-	_, _ = fmt.Sscanf(r.Header.Get("Authorization"), "Bearer %s", &password)
-	return password == web.password
+	token, ok := web.tokens.lookup(credential)
+	if !ok {
+		handleWebErr(
+			w,
+			errors.New("not authorized; must provide 'Authorization: Bearer [token]' header"),
+			http.StatusUnauthorized,
+		)
+		return false
+	}
+	if !token.hasScope(scope) {
+		handleWebErr(w, fmt.Errorf("forbidden; token %q lacks the %q scope", token.Name, scope), http.StatusForbidden)
+		return false
+	}
+	return true
+}
+
+// identify returns a description of the credential that the request was made with, for the audit log.
+func (web *WebServer) identify(r *http.Request) string {
+	credential := getBearerCredential(r)
+	if web.isPassword(credential) {
+		return "password"
+	}
+	if token, ok := web.tokens.lookup(credential); ok {
+		return fmt.Sprintf("token %s (%s)", token.Name, token.Id)
+	}
+	return "anonymous"
+}
+
+// isPassword returns true if the API is protected by a password and the given credential matches it.
+func (web *WebServer) isPassword(credential string) bool {
+	return web.password != "" && subtle.ConstantTimeCompare([]byte(credential), []byte(web.password)) == 1
 }
 
-// handleWebErr writes the given error out as plain text with the given status code.
-func handleWebErr(w http.ResponseWriter, err error, statusCode int) {
-	message := fmt.Sprintf("HTTP request error %d: %v", statusCode, err)
-	log.Println(message)
-	http.Error(w, message, statusCode)
+// getBearerCredential returns the password or token given in the request's 'Authorization: Bearer' header, if any.
+func getBearerCredential(r *http.Request) string {
+	var credential string
+	_, _ = fmt.Sscanf(r.Header.Get("Authorization"), "Bearer %s", &credential)
+	return credential
 }
@@ -1,20 +1,117 @@
 package web
 
 import (
+	"github.com/patfair/frc-radio-api/radio"
 	"github.com/stretchr/testify/assert"
+	"os"
+	"path/filepath"
 	"testing"
 )
 
 func TestWeb_healthHandler(t *testing.T) {
-	var web WebServer
+	for _, r := range []radio.Radio{radio.NewAccessPointRadio(), radio.NewRobotRadio()} {
+		web := NewWebServer(r)
+		recorder := web.getHttpResponse("/health")
+		assert.Equal(t, 200, recorder.Code)
+		assert.Equal(t, recorder.Body.String(), "OK\n")
+		assert.Empty(t, recorder.Header().Get(tlsFingerprintHeader))
+	}
+
+	// The certificate fingerprint is included once HTTPS is enabled.
+	web := NewWebServer(radio.NewAccessPointRadio())
+	web.tlsFingerprint = "0123456789abcdef"
 	recorder := web.getHttpResponse("/health")
 	assert.Equal(t, 200, recorder.Code)
-	assert.Equal(t, recorder.Body.String(), "OK\n")
+	assert.Equal(t, "0123456789abcdef", recorder.Header().Get(tlsFingerprintHeader))
 }
 
 func TestWebNotFound(t *testing.T) {
-	var web WebServer
-	recorder := web.getHttpResponse("/foo")
-	assert.Equal(t, 404, recorder.Code)
-	assert.Contains(t, recorder.Body.String(), "404 page not found")
+	for _, r := range []radio.Radio{radio.NewAccessPointRadio(), radio.NewRobotRadio()} {
+		web := NewWebServer(r)
+		recorder := web.getHttpResponse("/foo")
+		assert.Equal(t, 404, recorder.Code)
+		assert.Contains(t, recorder.Body.String(), "404 page not found")
+	}
+
+	// The configuration page only exists on the robot radio.
+	web := NewWebServer(radio.NewAccessPointRadio())
+	recorder := web.getHttpResponse("/configuration")
+	assert.Equal(t, 405, recorder.Code)
+}
+
+func TestWeb_dataFilePath(t *testing.T) {
+	web := NewWebServer(radio.NewAccessPointRadio())
+	assert.Equal(t, "/root/frc-radio-api-tokens.json", web.dataFilePath(tokenFileName))
+	web.DataDir = "/tmp/frc-radio-api"
+	assert.Equal(t, "/tmp/frc-radio-api/frc-radio-api-tokens.json", web.dataFilePath(tokenFileName))
+}
+
+func TestWeb_setUpSecrets(t *testing.T) {
+	web := NewWebServer(radio.NewAccessPointRadio())
+	web.DataDir = t.TempDir()
+
+	// Nothing is set up if the data directory holds no files.
+	web.setUpSecrets()
+	assert.Empty(t, web.password)
+	assert.True(t, web.tokens.isEmpty())
+	assert.Nil(t, web.firmwareDecryptionKey)
+
+	// The secrets are read from the data directory, and new tokens are saved there.
+	assert.Nil(t, os.WriteFile(filepath.Join(web.DataDir, passwordFileName), []byte("mypassword\n"), 0600))
+	web.setUpSecrets()
+	assert.Equal(t, "mypassword", web.password)
+	_, _, err := web.tokens.create("FMS", []tokenScope{scopeConfigure})
+	assert.Nil(t, err)
+	_, err = os.Stat(filepath.Join(web.DataDir, tokenFileName))
+	assert.Nil(t, err)
+	web.setUpSecrets()
+	assert.Equal(t, 1, len(web.tokens.list()))
+}
+
+func TestWeb_authorizeScopes(t *testing.T) {
+	web := NewWebServer(radio.NewAccessPointRadio())
+	web.tokens = newTokenStore(filepath.Join(t.TempDir(), "tokens.json"))
+
+	// Everything is open when there is neither a password nor any tokens.
+	recorder := web.getHttpResponse("/webhooks")
+	assert.Equal(t, 200, recorder.Code)
+
+	// Creating a token locks down the API even without a password.
+	_, statusSecret, _ := web.tokens.create("Audience display", []tokenScope{scopeReadStatus})
+	_, firmwareSecret, _ := web.tokens.create("Firmware updater", []tokenScope{scopeFirmware})
+	recorder = web.getHttpResponse("/status")
+	assert.Equal(t, 401, recorder.Code)
+	assert.Contains(t, recorder.Body.String(), "not authorized; must provide 'Authorization: Bearer [token]' header")
+	recorder = web.getHttpResponseWithHeaders("/status", map[string]string{"Authorization": "Bearer wrongsecret"})
+	assert.Equal(t, 401, recorder.Code)
+
+	statusHeaders := map[string]string{"Authorization": "Bearer " + statusSecret}
+	for _, path := range []string{"/status", "/status/history?station=red1", "/events", "/metrics"} {
+		recorder = web.getHttpResponseWithHeaders(path, statusHeaders)
+		assert.Equal(t, 200, recorder.Code, path)
+	}
+	recorder = web.getHttpResponseWithHeaders("/webhooks", statusHeaders)
+	assert.Equal(t, 403, recorder.Code)
+	assert.Contains(
+		t, decodeErrorResponse(t, recorder).Message, "forbidden; token \"Audience display\" lacks the \"admin\" scope",
+	)
+	recorder = web.postHttpResponseWithHeaders("/configuration", `{"channel": 149}`, statusHeaders)
+	assert.Equal(t, 403, recorder.Code)
+	assert.Contains(t, decodeErrorResponse(t, recorder).Message, "lacks the \"configure\" scope")
+	recorder = web.postHttpResponseWithHeaders("/firmware", "", statusHeaders)
+	assert.Equal(t, 403, recorder.Code)
+	assert.Contains(t, decodeErrorResponse(t, recorder).Message, "lacks the \"firmware\" scope")
+
+	firmwareHeaders := map[string]string{"Authorization": "Bearer " + firmwareSecret}
+	recorder = web.getHttpResponseWithHeaders("/status", firmwareHeaders)
+	assert.Equal(t, 403, recorder.Code)
+	recorder = web.postHttpResponseWithHeaders("/firmware", "", firmwareHeaders)
+	assert.Equal(t, 400, recorder.Code)
+
+	// The password grants every scope.
+	web.password = "mypassword"
+	recorder = web.getHttpResponseWithHeaders("/webhooks", map[string]string{"Authorization": "Bearer mypassword"})
+	assert.Equal(t, 200, recorder.Code)
+	recorder = web.getHttpResponseWithHeaders("/status", statusHeaders)
+	assert.Equal(t, 200, recorder.Code)
 }
@@ -1,5 +1,4 @@
-// This file is specific to the robot radio version of the API.
-//go:build robot
+// This file is specific to the robot radio role of the API.
 
 package web
 
@@ -8,22 +7,44 @@ import (
 	"github.com/gorilla/mux"
 	"github.com/patfair/frc-radio-api/radio"
 	"net/http"
+	"strings"
 )
 
 // TCP port that the web server listens on.
-const port = 80
+const portRobotRadio = 80
 
-// getListenAddress returns the address and port that the web server should listen on.
-func getListenAddress(r *radio.Radio) string {
-	return fmt.Sprintf(":%d", port)
+// getRobotRadioListenAddress returns the address and port that the web server should listen on when running on a robot
+// radio.
+func getRobotRadioListenAddress() string {
+	return fmt.Sprintf(":%d", portRobotRadio)
 }
 
-// addRoutes adds additional route handlers to the router if needed.
-func addRoutes(router *mux.Router, web *WebServer) {
+// getRobotRadioMonitoredNetworks returns the status of each of the robot radio's two networks, labeled by band and SSID.
+func getRobotRadioMonitoredNetworks(r *radio.RobotRadio) []monitoredNetwork {
+	radioType := strings.TrimPrefix(r.HardwareType().String(), "Type")
+	return []monitoredNetwork{
+		{
+			labels: []metricLabel{
+				{"network", "2.4GHz"}, {"ssid", r.NetworkStatus24.Ssid}, {"radio_type", radioType},
+			},
+			status: &r.NetworkStatus24,
+		},
+		{
+			labels: []metricLabel{
+				{"network", "6GHz"}, {"ssid", r.NetworkStatus6.Ssid}, {"radio_type", radioType},
+			},
+			status: &r.NetworkStatus6,
+		},
+	}
+}
+
+// addRobotRadioRoutes adds the route handlers that are specific to the robot radio.
+func (web *WebServer) addRobotRadioRoutes(router *mux.Router) {
+	router.HandleFunc("/", web.robotRadioRootHandler).Methods("GET")
 	router.HandleFunc("/configuration", web.configurationPageHandler).Methods("GET")
 }
 
-// rootHandler redirects the root URL to the configuration page.
-func (web *WebServer) rootHandler(w http.ResponseWriter, r *http.Request) {
+// robotRadioRootHandler redirects the root URL to the configuration page.
+func (web *WebServer) robotRadioRootHandler(w http.ResponseWriter, r *http.Request) {
 	http.Redirect(w, r, "/configuration", http.StatusFound)
 }
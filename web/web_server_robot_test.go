@@ -1,22 +1,68 @@
-// This file is specific to the robot radio version of the API.
-//go:build robot
+// This file is specific to the robot radio role of the API.
 
 package web
 
 import (
+	"encoding/json"
 	"github.com/patfair/frc-radio-api/radio"
 	"github.com/stretchr/testify/assert"
 	"testing"
 )
 
-func TestGetListenAddress(t *testing.T) {
-	r := &radio.Radio{}
-	assert.Equal(t, ":80", getListenAddress(r))
+func TestGetRobotRadioListenAddress(t *testing.T) {
+	assert.Equal(t, ":80", getListenAddress(&radio.RobotRadio{}))
 }
 
-func TestWeb_rootHandler(t *testing.T) {
-	var web WebServer
+func TestGetRobotRadioMonitoredNetworks(t *testing.T) {
+	r := &radio.RobotRadio{}
+	r.NetworkStatus24.Ssid = "FRC-254"
+	r.NetworkStatus6.Ssid = "254"
+	networks := getMonitoredNetworks(r)
+	if assert.Equal(t, 2, len(networks)) {
+		assert.Equal(
+			t,
+			[]metricLabel{{"network", "2.4GHz"}, {"ssid", "FRC-254"}, {"radio_type", "VividHosting"}},
+			networks[0].labels,
+		)
+		assert.Same(t, &r.NetworkStatus24, networks[0].status)
+		assert.Equal(
+			t, []metricLabel{{"network", "6GHz"}, {"ssid", "254"}, {"radio_type", "VividHosting"}}, networks[1].labels,
+		)
+		assert.Same(t, &r.NetworkStatus6, networks[1].status)
+	}
+}
+
+func TestWeb_robotRadioRootHandler(t *testing.T) {
+	web := NewWebServer(radio.NewRobotRadio())
 	recorder := web.getHttpResponse("/")
 	assert.Equal(t, 302, recorder.Code)
 	assert.Equal(t, "/configuration", recorder.Header().Get("Location"))
 }
+
+func TestWeb_robotRadioConfigurationAndStatus(t *testing.T) {
+	robotRadio := radio.NewRobotRadio()
+	robotRadio.TeamNumber = 1114
+	web := NewWebServer(robotRadio)
+
+	recorder := web.postHttpResponse(
+		"/configuration",
+		`{"mode": "TEAM_ROBOT_RADIO", "teamNumber": 254, "wpaKey6": "12345678", "wpaKey24": "87654321"}`,
+	)
+	assert.Equal(t, 202, recorder.Code)
+	if assert.Equal(t, 1, len(robotRadio.ConfigurationRequestChannel)) {
+		request := (<-robotRadio.ConfigurationRequestChannel).(*radio.RobotRadioConfigurationRequest)
+		assert.Equal(t, 254, request.TeamNumber)
+		assert.Equal(t, "12345678", request.WpaKey6)
+	}
+
+	// Fields of the access point's configuration request aren't accepted.
+	recorder = web.postHttpResponse("/configuration", `{"stationConfigurations": {}}`)
+	assert.Equal(t, 400, recorder.Code)
+	assert.Contains(t, recorder.Body.String(), "invalid configuration: invalid operation mode")
+
+	recorder = web.getHttpResponse("/status")
+	assert.Equal(t, 200, recorder.Code)
+	var status radio.RobotRadio
+	assert.Nil(t, json.Unmarshal(recorder.Body.Bytes(), &status))
+	assert.Equal(t, 1114, status.TeamNumber)
+}
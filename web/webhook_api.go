@@ -0,0 +1,115 @@
+package web
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/gorilla/mux"
+	"net/http"
+	"net/url"
+)
+
+// webhookRegistration is the JSON body of a request to register a webhook.
+type webhookRegistration struct {
+	// URL to POST payloads to.
+	Url string `json:"url"`
+
+	// Types of event to send payloads for. Empty means all types.
+	Events []webhookEventType `json:"events"`
+}
+
+// webhooksHandler returns a JSON list of the registered webhooks.
+func (web *WebServer) webhooksHandler(w http.ResponseWriter, r *http.Request) {
+	if !web.authorize(w, r, scopeAdmin) {
+		return
+	}
+
+	jsonData, err := json.MarshalIndent(web.webhooks.list(), "", "  ")
+	if err != nil {
+		handleWebErr(w, err, http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if _, err = w.Write(jsonData); err != nil {
+		handleWebErr(w, err, http.StatusInternalServerError)
+		return
+	}
+}
+
+// webhookRegistrationHandler receives a JSON request to register a new webhook.
+func (web *WebServer) webhookRegistrationHandler(w http.ResponseWriter, r *http.Request) {
+	if !web.authorize(w, r, scopeAdmin) {
+		return
+	}
+
+	var registration webhookRegistration
+	if err := json.NewDecoder(r.Body).Decode(&registration); err != nil {
+		handleWebErr(w, newApiError(errorInvalidJson, "", fmt.Errorf("invalid JSON: %v", err)), http.StatusBadRequest)
+		return
+	}
+	if err := registration.validate(); err != nil {
+		handleWebErr(
+			w, newApiError(errorInvalidWebhook, "", fmt.Errorf("invalid webhook: %v", err)), http.StatusBadRequest,
+		)
+		return
+	}
+
+	hook, err := web.webhooks.register(registration.Url, registration.Events)
+	if err != nil {
+		handleWebErr(w, err, http.StatusConflict)
+		return
+	}
+	jsonData, err := json.MarshalIndent(hook, "", "  ")
+	if err != nil {
+		handleWebErr(w, err, http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Location", fmt.Sprintf("/webhooks/%s", hook.Id))
+	w.WriteHeader(http.StatusCreated)
+	_, _ = w.Write(jsonData)
+}
+
+// webhookDeletionHandler unregisters a previously registered webhook.
+func (web *WebServer) webhookDeletionHandler(w http.ResponseWriter, r *http.Request) {
+	if !web.authorize(w, r, scopeAdmin) {
+		return
+	}
+
+	id := mux.Vars(r)["id"]
+	found, err := web.webhooks.unregister(id)
+	if !found {
+		handleWebErr(w, fmt.Errorf("no webhook with ID %q", id), http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		handleWebErr(w, err, http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// validate checks that the webhook registration has an absolute HTTP or HTTPS URL and only known event types.
+func (registration *webhookRegistration) validate() error {
+	webhookUrl, err := url.Parse(registration.Url)
+	if err != nil || (webhookUrl.Scheme != "http" && webhookUrl.Scheme != "https") || webhookUrl.Host == "" {
+		return fmt.Errorf("invalid URL %q (expecting an absolute http or https URL)", registration.Url)
+	}
+	for _, event := range registration.Events {
+		if !isValidWebhookEventType(event) {
+			return fmt.Errorf("invalid event type %q (expecting one of %v)", event, webhookEventTypes)
+		}
+	}
+	return nil
+}
+
+// isValidWebhookEventType returns true if the given event type is one that webhooks can be sent for.
+func isValidWebhookEventType(eventType webhookEventType) bool {
+	for _, validType := range webhookEventTypes {
+		if eventType == validType {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,108 @@
+package web
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/patfair/frc-radio-api/radio"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestWeb_webhookHandlers(t *testing.T) {
+	web := NewWebServer(radio.NewAccessPointRadio())
+
+	recorder := web.getHttpResponse("/webhooks")
+	assert.Equal(t, 200, recorder.Code)
+	assert.Equal(t, "application/json", recorder.Header().Get("Content-Type"))
+	assert.Equal(t, "[]", recorder.Body.String())
+
+	// Register a webhook for only some types of event.
+	recorder = web.postHttpResponse(
+		"/webhooks", `{"url": "http://10.0.100.5:8080/hook", "events": ["LINK_UP", "LINK_DOWN"]}`,
+	)
+	assert.Equal(t, 201, recorder.Code)
+	var hook webhook
+	assert.Nil(t, json.Unmarshal(recorder.Body.Bytes(), &hook))
+	assert.Len(t, hook.Id, 16)
+	assert.Equal(t, "http://10.0.100.5:8080/hook", hook.Url)
+	assert.Equal(t, []webhookEventType{webhookLinkUp, webhookLinkDown}, hook.Events)
+	assert.Equal(t, "/webhooks/"+hook.Id, recorder.Header().Get("Location"))
+
+	// Register a webhook for all types of event.
+	recorder = web.postHttpResponse("/webhooks", `{"url": "https://scoring.example.com/radio"}`)
+	assert.Equal(t, 201, recorder.Code)
+	var otherHook webhook
+	assert.Nil(t, json.Unmarshal(recorder.Body.Bytes(), &otherHook))
+	assert.Equal(t, []webhookEventType{}, otherHook.Events)
+
+	recorder = web.getHttpResponse("/webhooks")
+	assert.Equal(t, 200, recorder.Code)
+	var hooks []webhook
+	assert.Nil(t, json.Unmarshal(recorder.Body.Bytes(), &hooks))
+	if assert.Equal(t, 2, len(hooks)) {
+		assert.Equal(t, hook.Id, hooks[0].Id)
+		assert.Equal(t, otherHook.Id, hooks[1].Id)
+	}
+
+	recorder = web.deleteHttpResponseWithHeaders("/webhooks/"+hook.Id, nil)
+	assert.Equal(t, 204, recorder.Code)
+	assert.Empty(t, recorder.Body.String())
+	assert.Equal(t, 1, len(web.webhooks.list()))
+
+	recorder = web.deleteHttpResponseWithHeaders("/webhooks/"+hook.Id, nil)
+	assert.Equal(t, 404, recorder.Code)
+	assert.Contains(t, decodeErrorResponse(t, recorder).Message, fmt.Sprintf("no webhook with ID %q", hook.Id))
+}
+
+func TestWeb_webhookRegistrationHandlerErrors(t *testing.T) {
+	web := NewWebServer(radio.NewAccessPointRadio())
+
+	recorder := web.postHttpResponse("/webhooks", "blorpy")
+	assert.Equal(t, 400, recorder.Code)
+	assert.Contains(t, recorder.Body.String(), "invalid JSON")
+
+	recorder = web.postHttpResponse("/webhooks", `{"events": ["LINK_UP"]}`)
+	assert.Equal(t, 400, recorder.Code)
+	assert.Contains(
+		t, decodeErrorResponse(t, recorder).Message, "invalid URL \"\" (expecting an absolute http or https URL)",
+	)
+
+	recorder = web.postHttpResponse("/webhooks", `{"url": "ftp://10.0.100.5/hook"}`)
+	assert.Equal(t, 400, recorder.Code)
+	assert.Contains(t, decodeErrorResponse(t, recorder).Message, "invalid URL \"ftp://10.0.100.5/hook\"")
+
+	recorder = web.postHttpResponse("/webhooks", `{"url": "/hook"}`)
+	assert.Equal(t, 400, recorder.Code)
+	assert.Contains(t, decodeErrorResponse(t, recorder).Message, "invalid URL \"/hook\"")
+
+	recorder = web.postHttpResponse("/webhooks", `{"url": "http://10.0.100.5/hook", "events": ["LINK_SIDEWAYS"]}`)
+	assert.Equal(t, 400, recorder.Code)
+	assert.Contains(
+		t, decodeErrorResponse(t, recorder).Message, "invalid event type \"LINK_SIDEWAYS\" (expecting one of",
+	)
+
+	assert.Equal(t, 0, len(web.webhooks.list()))
+}
+
+func TestWeb_webhookHandlersAuthorization(t *testing.T) {
+	web := NewWebServer(radio.NewAccessPointRadio())
+	web.password = "mypassword"
+	body := `{"url": "http://10.0.100.5/hook"}`
+
+	recorder := web.getHttpResponse("/webhooks")
+	assert.Equal(t, 401, recorder.Code)
+	assert.Contains(t, recorder.Body.String(), "not authorized")
+	recorder = web.postHttpResponse("/webhooks", body)
+	assert.Equal(t, 401, recorder.Code)
+	recorder = web.deleteHttpResponseWithHeaders("/webhooks/abc", nil)
+	assert.Equal(t, 401, recorder.Code)
+	assert.Equal(t, 0, len(web.webhooks.list()))
+
+	headers := map[string]string{"Authorization": "Bearer mypassword"}
+	recorder = web.postHttpResponseWithHeaders("/webhooks", body, headers)
+	assert.Equal(t, 201, recorder.Code)
+	recorder = web.getHttpResponseWithHeaders("/webhooks", headers)
+	assert.Equal(t, 200, recorder.Code)
+	recorder = web.deleteHttpResponseWithHeaders("/webhooks/"+web.webhooks.list()[0].Id, headers)
+	assert.Equal(t, 204, recorder.Code)
+}
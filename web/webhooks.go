@@ -0,0 +1,511 @@
+package web
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/patfair/frc-radio-api/radio"
+	"log"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+)
+
+const (
+	// Name of the file within the data directory that registered webhooks are persisted to.
+	webhookFileName = "frc-radio-api-webhooks.json"
+
+	// Maximum number of webhooks that can be registered at once.
+	maxWebhooks = 20
+
+	// Number of undelivered payloads to buffer per webhook before new ones are dropped.
+	webhookQueueSize = 100
+
+	// Maximum number of attempts to make at delivering each payload.
+	webhookMaxAttempts = 5
+
+	// How long to wait before the first retry of a failed delivery; each subsequent retry waits twice as long.
+	webhookInitialRetryBackoff = 1 * time.Second
+
+	// Timeout for each attempt at delivering a payload.
+	webhookRequestTimeout = 5 * time.Second
+
+	// Header carrying the HMAC-SHA256 signature of the payload, keyed with the API password.
+	webhookSignatureHeader = "X-FRC-Radio-Signature"
+
+	// Header carrying the type of event that the payload describes.
+	webhookEventHeader = "X-FRC-Radio-Event"
+
+	// Header carrying the unique ID of the payload, which stays the same across retries.
+	webhookDeliveryHeader = "X-FRC-Radio-Delivery"
+)
+
+// webhookEventType represents the kind of event that a webhook payload describes.
+type webhookEventType string
+
+const (
+	webhookStatusChanged          webhookEventType = "STATUS_CHANGED"
+	webhookLinkUp                 webhookEventType = "LINK_UP"
+	webhookLinkDown               webhookEventType = "LINK_DOWN"
+	webhookConfigurationCompleted webhookEventType = "CONFIGURATION_COMPLETED"
+	webhookFirmwareUpdateStarted  webhookEventType = "FIRMWARE_UPDATE_STARTED"
+)
+
+// All types of event that webhooks can be sent for.
+var webhookEventTypes = []webhookEventType{
+	webhookStatusChanged,
+	webhookLinkUp,
+	webhookLinkDown,
+	webhookConfigurationCompleted,
+	webhookFirmwareUpdateStarted,
+}
+
+// webhook represents a URL registered to receive payloads describing events on the radio.
+type webhook struct {
+	// Unique identifier for the webhook.
+	Id string `json:"id"`
+
+	// URL to POST payloads to.
+	Url string `json:"url"`
+
+	// Types of event to send payloads for. Empty means all types.
+	Events []webhookEventType `json:"events"`
+
+	// Queue of payloads waiting to be delivered.
+	queue chan webhookPayload
+
+	// Closed when the webhook is unregistered, to stop its delivery goroutine.
+	stop chan struct{}
+}
+
+// webhookPayload is the JSON body POSTed to a webhook to describe a single event.
+type webhookPayload struct {
+	// Unique identifier for the payload, which stays the same across retries.
+	Id string `json:"id"`
+
+	// Enum representing the kind of event.
+	Type webhookEventType `json:"type"`
+
+	// Time at which the event occurred.
+	Time time.Time `json:"time"`
+
+	// Details of the event, whose structure depends on its type.
+	Data any `json:"data"`
+}
+
+// statusChange is the data of a webhook payload describing a change in the radio's status.
+type statusChange struct {
+	// Status of the radio before the change.
+	PreviousStatus string `json:"previousStatus"`
+
+	// Status of the radio after the change.
+	Status string `json:"status"`
+
+	// Error encountered while applying the most recent configuration request. Blank if it succeeded.
+	ConfigurationError string `json:"configurationError"`
+
+	// Whether the radio was restored to its previous configuration after the most recent configuration request failed.
+	RolledBack bool `json:"rolledBack"`
+}
+
+// webhookDispatcher keeps track of the registered webhooks and delivers payloads to them in the background, in a manner
+// that is safe to access from multiple goroutines. Payloads are delivered to each webhook one at a time, in the order
+// in which the events occurred. The webhooks are persisted to a file so that they survive restarts, whereas payloads
+// yet to be delivered are not.
+type webhookDispatcher struct {
+	mutex sync.Mutex
+
+	// Path to the file that the webhooks are loaded from and saved to. If blank, they are only kept in memory.
+	filePath string
+
+	// Registered webhooks, in the order they were registered.
+	webhooks []*webhook
+
+	// Returns the key with which to sign payloads; payloads are left unsigned if it is blank.
+	signingKey func() string
+
+	// Client for delivering payloads.
+	httpClient *http.Client
+
+	// How long to wait before the first retry of a failed delivery.
+	retryBackoff time.Duration
+}
+
+// newWebhookDispatcher creates a dispatcher that persists its webhooks to the given file (or not at all, if blank) and
+// signs payloads with the key returned by the given function.
+func newWebhookDispatcher(filePath string, signingKey func() string) *webhookDispatcher {
+	return &webhookDispatcher{
+		filePath:     filePath,
+		signingKey:   signingKey,
+		httpClient:   &http.Client{Timeout: webhookRequestTimeout},
+		retryBackoff: webhookInitialRetryBackoff,
+	}
+}
+
+// load replaces the webhooks in the dispatcher with those in its file and starts delivering payloads to them. A missing
+// file is treated as having no webhooks.
+func (dispatcher *webhookDispatcher) load() error {
+	dispatcher.mutex.Lock()
+	defer dispatcher.mutex.Unlock()
+
+	webhooks, err := dispatcher.readFileLocked()
+	if err != nil {
+		return err
+	}
+
+	for _, hook := range dispatcher.webhooks {
+		close(hook.stop)
+	}
+	dispatcher.webhooks = webhooks
+	for _, hook := range webhooks {
+		if hook.Events == nil {
+			hook.Events = []webhookEventType{}
+		}
+		dispatcher.startLocked(hook)
+	}
+	return nil
+}
+
+// readFileLocked returns the webhooks saved in the dispatcher's file, or none if it doesn't have one or the file is
+// missing. The caller must hold the mutex.
+func (dispatcher *webhookDispatcher) readFileLocked() ([]*webhook, error) {
+	if dispatcher.filePath == "" {
+		return nil, nil
+	}
+	webhookBytes, err := os.ReadFile(dispatcher.filePath)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	var webhooks []*webhook
+	if err = json.Unmarshal(webhookBytes, &webhooks); err != nil {
+		return nil, fmt.Errorf("error parsing webhook file %s: %v", dispatcher.filePath, err)
+	}
+	return webhooks, nil
+}
+
+// saveLocked writes the webhooks out to the dispatcher's file, if it has one. The caller must hold the mutex.
+func (dispatcher *webhookDispatcher) saveLocked() error {
+	if dispatcher.filePath == "" {
+		return nil
+	}
+	webhookBytes, err := json.MarshalIndent(dispatcher.webhooks, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(dispatcher.filePath, webhookBytes, 0600)
+}
+
+// register starts sending payloads for the given types of event (or all types, if empty) to the given URL, and returns
+// a copy of the new webhook.
+func (dispatcher *webhookDispatcher) register(url string, events []webhookEventType) (webhook, error) {
+	dispatcher.mutex.Lock()
+	defer dispatcher.mutex.Unlock()
+
+	if len(dispatcher.webhooks) >= maxWebhooks {
+		return webhook{}, fmt.Errorf("too many webhooks registered (maximum %d)", maxWebhooks)
+	}
+	if events == nil {
+		events = []webhookEventType{}
+	}
+	hook := &webhook{Id: newRandomId(), Url: url, Events: events}
+	previousWebhooks := dispatcher.webhooks
+	dispatcher.webhooks = append(dispatcher.webhooks, hook)
+	if err := dispatcher.saveLocked(); err != nil {
+		dispatcher.webhooks = previousWebhooks
+		return webhook{}, fmt.Errorf("error saving webhook file: %v", err)
+	}
+	dispatcher.startLocked(hook)
+	log.Printf("Registered webhook %s for %s (events %v)", hook.Id, hook.Url, hook.Events)
+	return *hook, nil
+}
+
+// startLocked starts delivering payloads to the given webhook, warning if they will go out unsigned. The caller must
+// hold the mutex.
+func (dispatcher *webhookDispatcher) startLocked(hook *webhook) {
+	hook.queue = make(chan webhookPayload, webhookQueueSize)
+	hook.stop = make(chan struct{})
+	go dispatcher.runDeliveries(hook)
+	if dispatcher.signingKey() == "" {
+		log.Printf(
+			"Warning: payloads for webhook %s will be unsigned, since the API has no password to sign them with; "+
+				"receivers can't verify that they came from the radio",
+			hook.Url,
+		)
+	}
+}
+
+// unregister stops sending payloads to the webhook having the given ID, discarding any it has yet to receive. Returns
+// false if there is no such webhook, and an error if the change couldn't be saved, in which case the webhook remains.
+func (dispatcher *webhookDispatcher) unregister(id string) (bool, error) {
+	dispatcher.mutex.Lock()
+	defer dispatcher.mutex.Unlock()
+
+	for i, hook := range dispatcher.webhooks {
+		if hook.Id == id {
+			remainingWebhooks := append(append([]*webhook{}, dispatcher.webhooks[:i]...), dispatcher.webhooks[i+1:]...)
+			previousWebhooks := dispatcher.webhooks
+			dispatcher.webhooks = remainingWebhooks
+			if err := dispatcher.saveLocked(); err != nil {
+				dispatcher.webhooks = previousWebhooks
+				return true, fmt.Errorf("error saving webhook file: %v", err)
+			}
+			close(hook.stop)
+			log.Printf("Unregistered webhook %s for %s", hook.Id, hook.Url)
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// list returns copies of the registered webhooks, in the order they were registered.
+func (dispatcher *webhookDispatcher) list() []webhook {
+	dispatcher.mutex.Lock()
+	defer dispatcher.mutex.Unlock()
+
+	webhooks := make([]webhook, 0, len(dispatcher.webhooks))
+	for _, hook := range dispatcher.webhooks {
+		webhooks = append(webhooks, *hook)
+	}
+	return webhooks
+}
+
+// publish queues a payload describing the given event for delivery to every webhook interested in its type.
+func (dispatcher *webhookDispatcher) publish(eventType webhookEventType, eventTime time.Time, data any) {
+	dispatcher.mutex.Lock()
+	defer dispatcher.mutex.Unlock()
+
+	payload := webhookPayload{Id: newRandomId(), Type: eventType, Time: eventTime, Data: data}
+	for _, hook := range dispatcher.webhooks {
+		if !hook.wants(eventType) {
+			continue
+		}
+		select {
+		case hook.queue <- payload:
+		default:
+			log.Printf("Dropping %s payload %s for webhook %s since its queue is full", eventType, payload.Id, hook.Url)
+		}
+	}
+}
+
+// runDeliveries loops until the given webhook is unregistered, delivering the payloads queued for it.
+func (dispatcher *webhookDispatcher) runDeliveries(hook *webhook) {
+	for {
+		select {
+		case payload := <-hook.queue:
+			dispatcher.deliver(hook, payload)
+		case <-hook.stop:
+			return
+		}
+	}
+}
+
+// deliver POSTs the given payload to the given webhook, retrying with exponential backoff if it fails.
+func (dispatcher *webhookDispatcher) deliver(hook *webhook, payload webhookPayload) {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		log.Printf("Error marshalling webhook payload: %v", err)
+		return
+	}
+	signature := signWebhookPayload(body, dispatcher.signingKey())
+
+	backoff := dispatcher.retryBackoff
+	for attempt := 1; ; attempt++ {
+		retryable, err := dispatcher.deliverOnce(hook.Url, payload, body, signature)
+		if err == nil {
+			return
+		}
+		if !retryable || attempt >= webhookMaxAttempts {
+			log.Printf(
+				"Giving up on delivering %s payload %s to webhook %s after %d attempts: %v",
+				payload.Type,
+				payload.Id,
+				hook.Url,
+				attempt,
+				err,
+			)
+			return
+		}
+		log.Printf(
+			"Error delivering %s payload %s to webhook %s; will retry: %v", payload.Type, payload.Id, hook.Url, err,
+		)
+
+		select {
+		case <-time.After(backoff):
+			backoff *= 2
+		case <-hook.stop:
+			return
+		}
+	}
+}
+
+// deliverOnce makes a single attempt at POSTing the given payload to the given URL. Returns an error if it failed, and
+// whether the failure is one that is worth retrying.
+func (dispatcher *webhookDispatcher) deliverOnce(
+	url string, payload webhookPayload, body []byte, signature string,
+) (bool, error) {
+	request, err := http.NewRequest(http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return false, err
+	}
+	request.Header.Set("Content-Type", "application/json")
+	request.Header.Set(webhookEventHeader, string(payload.Type))
+	request.Header.Set(webhookDeliveryHeader, payload.Id)
+	if signature != "" {
+		request.Header.Set(webhookSignatureHeader, signature)
+	}
+
+	response, err := dispatcher.httpClient.Do(request)
+	if err != nil {
+		return true, err
+	}
+	_ = response.Body.Close()
+	if response.StatusCode >= 200 && response.StatusCode < 300 {
+		return false, nil
+	}
+	// Only server-side errors and rate limiting are likely to go away by themselves.
+	retryable := response.StatusCode >= 500 || response.StatusCode == http.StatusTooManyRequests
+	return retryable, fmt.Errorf("webhook returned status %d", response.StatusCode)
+}
+
+// wants returns true if the webhook is interested in events of the given type.
+func (hook *webhook) wants(eventType webhookEventType) bool {
+	if len(hook.Events) == 0 {
+		return true
+	}
+	for _, event := range hook.Events {
+		if event == eventType {
+			return true
+		}
+	}
+	return false
+}
+
+// signWebhookPayload returns the signature of the given payload body as the hexadecimal-encoded HMAC-SHA256 of it keyed
+// with the given key, prefixed with the name of the algorithm. Returns a blank signature if the key is blank.
+func signWebhookPayload(body []byte, key string) string {
+	if key == "" {
+		return ""
+	}
+	mac := hmac.New(sha256.New, []byte(key))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// newRandomId returns a random 16-character hexadecimal string for identifying webhooks and payloads.
+func newRandomId() string {
+	idBytes := make([]byte, 8)
+	_, _ = rand.Read(idBytes)
+	return hex.EncodeToString(idBytes)
+}
+
+// webhookTrigger tracks the state of the radio as last observed, in order to detect the transitions that webhook
+// payloads are sent for.
+type webhookTrigger struct {
+	mutex sync.Mutex
+
+	// Whether the radio has been observed at least once.
+	initialized bool
+
+	// Status of the radio as of the last observation.
+	lastStatus string
+
+	// ID of the last link event already considered.
+	lastLinkEventId int
+
+	// IDs of the configuration jobs queued through the API that have yet to finish.
+	pendingJobIds []string
+}
+
+// addPendingJob starts watching the configuration job having the given ID for its completion.
+func (trigger *webhookTrigger) addPendingJob(id string) {
+	trigger.mutex.Lock()
+	defer trigger.mutex.Unlock()
+
+	trigger.pendingJobIds = append(trigger.pendingJobIds, id)
+}
+
+// watchRadioForWebhooks loops indefinitely, publishing webhook payloads whenever the radio's status or link state
+// changes.
+func (web *WebServer) watchRadioForWebhooks() {
+	statusListener := web.radio.SubscribeStatusChanges()
+	linkEventListener := web.radio.SubscribeLinkEvents()
+	for {
+		web.checkRadioForWebhooks()
+		select {
+		case <-statusListener:
+		case <-linkEventListener:
+		}
+	}
+}
+
+// checkRadioForWebhooks compares the current state of the radio with that last observed and publishes a webhook
+// payload for each transition.
+func (web *WebServer) checkRadioForWebhooks() {
+	trigger := &web.webhookTrigger
+	trigger.mutex.Lock()
+	defer trigger.mutex.Unlock()
+	now := time.Now()
+
+	status, configurationError, rolledBack := getRadioStatus(web.radio.StatusSnapshot())
+	if trigger.initialized && status != trigger.lastStatus {
+		web.webhooks.publish(
+			webhookStatusChanged,
+			now,
+			statusChange{
+				PreviousStatus:     trigger.lastStatus,
+				Status:             status,
+				ConfigurationError: configurationError,
+				RolledBack:         rolledBack,
+			},
+		)
+	}
+	trigger.lastStatus = status
+	trigger.initialized = true
+
+	events, _ := web.radio.LinkEvents("", trigger.lastLinkEventId)
+	for _, event := range events {
+		switch event.Type {
+		case "ASSOCIATED":
+			web.webhooks.publish(webhookLinkUp, event.Time, event)
+		case "DISASSOCIATED":
+			web.webhooks.publish(webhookLinkDown, event.Time, event)
+		}
+		trigger.lastLinkEventId = event.Id
+	}
+
+	var stillPendingJobIds []string
+	for _, id := range trigger.pendingJobIds {
+		job, ok := web.radio.GetConfigurationJob(id)
+		if !ok {
+			// The job has been forgotten about, so its outcome can no longer be determined.
+			continue
+		}
+		if job.FinishedAt == nil {
+			stillPendingJobIds = append(stillPendingJobIds, id)
+			continue
+		}
+		web.webhooks.publish(webhookConfigurationCompleted, *job.FinishedAt, job)
+	}
+	trigger.pendingJobIds = stillPendingJobIds
+}
+
+// getRadioStatus returns the status, configuration error and rollback outcome from the given status snapshot of either
+// role of radio.
+func getRadioStatus(snapshot any) (string, string, bool) {
+	switch radioSnapshot := snapshot.(type) {
+	case *radio.AccessPointRadio:
+		return string(radioSnapshot.Status), radioSnapshot.ConfigurationError, radioSnapshot.RolledBack
+	case *radio.RobotRadio:
+		return string(radioSnapshot.Status), radioSnapshot.ConfigurationError, radioSnapshot.RolledBack
+	default:
+		return "", "", false
+	}
+}
@@ -0,0 +1,372 @@
+package web
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"github.com/patfair/frc-radio-api/radio"
+	"github.com/stretchr/testify/assert"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// receivedWebhook captures a single request received by a test webhook server.
+type receivedWebhook struct {
+	header  http.Header
+	body    []byte
+	payload webhookPayload
+}
+
+// newWebhookReceiver starts a server that records the webhook requests it receives on the returned channel and
+// responds to them with the given status codes in turn, repeating the last one once they run out.
+func newWebhookReceiver(t *testing.T, statusCodes ...int) (*httptest.Server, chan receivedWebhook) {
+	received := make(chan receivedWebhook, 10)
+	requestCount := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		var payload webhookPayload
+		_ = json.Unmarshal(body, &payload)
+		received <- receivedWebhook{header: r.Header, body: body, payload: payload}
+
+		statusCode := http.StatusOK
+		if len(statusCodes) > 0 {
+			statusCode = statusCodes[min(requestCount, len(statusCodes)-1)]
+		}
+		requestCount++
+		w.WriteHeader(statusCode)
+	}))
+	t.Cleanup(server.Close)
+	return server, received
+}
+
+// receiveWebhook waits for the next request to the test webhook server, failing the test if none arrives in time.
+func receiveWebhook(t *testing.T, received chan receivedWebhook) receivedWebhook {
+	select {
+	case webhook := <-received:
+		return webhook
+	case <-time.After(time.Second):
+		assert.Fail(t, "timed out waiting for webhook")
+		return receivedWebhook{}
+	}
+}
+
+func min(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+func TestWebhookDispatcher_publish(t *testing.T) {
+	server, received := newWebhookReceiver(t)
+	dispatcher := newWebhookDispatcher("", func() string { return "mypassword" })
+	hook, err := dispatcher.register(server.URL, nil)
+	assert.Nil(t, err)
+	assert.Equal(t, 16, len(hook.Id))
+	assert.Equal(t, []webhookEventType{}, hook.Events)
+
+	eventTime := time.Date(2024, 4, 20, 12, 0, 0, 0, time.UTC)
+	dispatcher.publish(webhookFirmwareUpdateStarted, eventTime, firmwareUpdate{Checksum: "0123"})
+	webhook := receiveWebhook(t, received)
+	assert.Equal(t, "application/json", webhook.header.Get("Content-Type"))
+	assert.Equal(t, "FIRMWARE_UPDATE_STARTED", webhook.header.Get(webhookEventHeader))
+	assert.Equal(t, webhook.payload.Id, webhook.header.Get(webhookDeliveryHeader))
+	assert.Equal(t, webhookFirmwareUpdateStarted, webhook.payload.Type)
+	assert.Equal(t, eventTime, webhook.payload.Time)
+	assert.Equal(t, map[string]any{"checksum": "0123"}, webhook.payload.Data)
+
+	// The signature is the HMAC of the body keyed with the password.
+	mac := hmac.New(sha256.New, []byte("mypassword"))
+	mac.Write(webhook.body)
+	assert.Equal(t, "sha256="+hex.EncodeToString(mac.Sum(nil)), webhook.header.Get(webhookSignatureHeader))
+
+	// Payloads are unsigned if there is no password.
+	dispatcher.signingKey = func() string { return "" }
+	dispatcher.publish(webhookStatusChanged, eventTime, statusChange{PreviousStatus: "ACTIVE", Status: "CONFIGURING"})
+	webhook = receiveWebhook(t, received)
+	assert.Equal(t, webhookStatusChanged, webhook.payload.Type)
+	assert.Equal(t, "", webhook.header.Get(webhookSignatureHeader))
+}
+
+func TestWebhookDispatcher_eventFilter(t *testing.T) {
+	server, received := newWebhookReceiver(t)
+	dispatcher := newWebhookDispatcher("", func() string { return "" })
+	_, err := dispatcher.register(server.URL, []webhookEventType{webhookLinkUp, webhookLinkDown})
+	assert.Nil(t, err)
+
+	dispatcher.publish(webhookStatusChanged, time.Now(), statusChange{})
+	dispatcher.publish(webhookLinkDown, time.Now(), radio.LinkEvent{Station: "blue2"})
+	webhook := receiveWebhook(t, received)
+	assert.Equal(t, webhookLinkDown, webhook.payload.Type)
+	assert.Equal(t, "blue2", webhook.payload.Data.(map[string]any)["station"])
+	assert.Equal(t, 0, len(received))
+}
+
+func TestWebhookDispatcher_retries(t *testing.T) {
+	server, received := newWebhookReceiver(t, 500, 429, 200)
+	dispatcher := newWebhookDispatcher("", func() string { return "" })
+	dispatcher.retryBackoff = time.Millisecond
+	_, err := dispatcher.register(server.URL, nil)
+	assert.Nil(t, err)
+
+	// Failed deliveries are retried with the same payload until they succeed.
+	dispatcher.publish(webhookStatusChanged, time.Now(), statusChange{})
+	firstAttempt := receiveWebhook(t, received)
+	assert.Equal(t, firstAttempt.payload.Id, receiveWebhook(t, received).payload.Id)
+	assert.Equal(t, firstAttempt.payload.Id, receiveWebhook(t, received).payload.Id)
+	time.Sleep(10 * time.Millisecond)
+	assert.Equal(t, 0, len(received))
+
+	// Deliveries are given up on after the maximum number of attempts.
+	server, received = newWebhookReceiver(t, 503)
+	dispatcher = newWebhookDispatcher("", func() string { return "" })
+	dispatcher.retryBackoff = time.Millisecond
+	_, err = dispatcher.register(server.URL, nil)
+	assert.Nil(t, err)
+	dispatcher.publish(webhookStatusChanged, time.Now(), statusChange{})
+	for i := 0; i < webhookMaxAttempts; i++ {
+		receiveWebhook(t, received)
+	}
+	time.Sleep(50 * time.Millisecond)
+	assert.Equal(t, 0, len(received))
+
+	// Client errors other than rate limiting aren't retried.
+	server, received = newWebhookReceiver(t, 404)
+	dispatcher = newWebhookDispatcher("", func() string { return "" })
+	dispatcher.retryBackoff = time.Millisecond
+	_, err = dispatcher.register(server.URL, nil)
+	assert.Nil(t, err)
+	dispatcher.publish(webhookStatusChanged, time.Now(), statusChange{})
+	receiveWebhook(t, received)
+	time.Sleep(10 * time.Millisecond)
+	assert.Equal(t, 0, len(received))
+}
+
+func TestWebhookDispatcher_registration(t *testing.T) {
+	server, received := newWebhookReceiver(t)
+	dispatcher := newWebhookDispatcher("", func() string { return "" })
+	assert.Equal(t, []webhook{}, dispatcher.list())
+
+	hook1, _ := dispatcher.register(server.URL+"/one", nil)
+	hook2, _ := dispatcher.register(server.URL+"/two", []webhookEventType{webhookLinkUp})
+	hooks := dispatcher.list()
+	if assert.Equal(t, 2, len(hooks)) {
+		assert.Equal(t, hook1.Id, hooks[0].Id)
+		assert.Equal(t, hook2.Id, hooks[1].Id)
+		assert.Equal(t, server.URL+"/two", hooks[1].Url)
+	}
+
+	// Unregistered webhooks no longer receive payloads.
+	found, err := dispatcher.unregister(hook1.Id)
+	assert.True(t, found)
+	assert.Nil(t, err)
+	found, err = dispatcher.unregister(hook1.Id)
+	assert.False(t, found)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(dispatcher.list()))
+	dispatcher.publish(webhookStatusChanged, time.Now(), statusChange{})
+	time.Sleep(10 * time.Millisecond)
+	assert.Equal(t, 0, len(received))
+
+	for i := 1; i < maxWebhooks; i++ {
+		_, err := dispatcher.register(server.URL, nil)
+		assert.Nil(t, err)
+	}
+	_, err = dispatcher.register(server.URL, nil)
+	assert.EqualError(t, err, "too many webhooks registered (maximum 20)")
+}
+
+func TestWebhookDispatcher_persistence(t *testing.T) {
+	server, received := newWebhookReceiver(t)
+	filePath := filepath.Join(t.TempDir(), "webhooks.json")
+	dispatcher := newWebhookDispatcher(filePath, func() string { return "mypassword" })
+
+	// A missing file means there are no webhooks.
+	assert.Nil(t, dispatcher.load())
+	assert.Equal(t, []webhook{}, dispatcher.list())
+
+	hook1, err := dispatcher.register(server.URL+"/one", nil)
+	assert.Nil(t, err)
+	hook2, err := dispatcher.register(server.URL+"/two", []webhookEventType{webhookLinkUp})
+	assert.Nil(t, err)
+
+	// The file is readable only by its owner.
+	info, err := os.Stat(filePath)
+	assert.Nil(t, err)
+	assert.Equal(t, os.FileMode(0600), info.Mode().Perm())
+
+	// The webhooks survive being loaded into a new dispatcher, which delivers payloads to them.
+	reloadedDispatcher := newWebhookDispatcher(filePath, func() string { return "mypassword" })
+	assert.Nil(t, reloadedDispatcher.load())
+	if assert.Equal(t, 2, len(reloadedDispatcher.list())) {
+		assert.Equal(t, hook1.Id, reloadedDispatcher.list()[0].Id)
+		assert.Equal(t, []webhookEventType{}, reloadedDispatcher.list()[0].Events)
+		assert.Equal(t, hook2.Url, reloadedDispatcher.list()[1].Url)
+		assert.Equal(t, []webhookEventType{webhookLinkUp}, reloadedDispatcher.list()[1].Events)
+	}
+	reloadedDispatcher.publish(webhookLinkUp, time.Now(), radio.LinkEvent{Station: "red1"})
+	receiveWebhook(t, received)
+	receiveWebhook(t, received)
+
+	found, err := dispatcher.unregister(hook1.Id)
+	assert.True(t, found)
+	assert.Nil(t, err)
+	assert.Nil(t, reloadedDispatcher.load())
+	if assert.Equal(t, 1, len(reloadedDispatcher.list())) {
+		assert.Equal(t, hook2.Id, reloadedDispatcher.list()[0].Id)
+	}
+}
+
+func TestWebhookDispatcher_persistenceErrors(t *testing.T) {
+	dir := t.TempDir()
+
+	// The file is malformed.
+	filePath := filepath.Join(dir, "webhooks.json")
+	assert.Nil(t, os.WriteFile(filePath, []byte("blorpy"), 0600))
+	dispatcher := newWebhookDispatcher(filePath, func() string { return "" })
+	err := dispatcher.load()
+	if assert.NotNil(t, err) {
+		assert.Contains(t, err.Error(), "error parsing webhook file")
+	}
+
+	// The file can't be written, so the webhook isn't registered.
+	dispatcher = newWebhookDispatcher(filepath.Join(dir, "missing", "webhooks.json"), func() string { return "" })
+	_, err = dispatcher.register("http://10.0.100.5/webhook", nil)
+	if assert.NotNil(t, err) {
+		assert.Contains(t, err.Error(), "error saving webhook file")
+	}
+	assert.Equal(t, []webhook{}, dispatcher.list())
+}
+
+func TestWebhookDispatcher_unsignedWarning(t *testing.T) {
+	var logOutput bytes.Buffer
+	log.SetOutput(&logOutput)
+	defer log.SetOutput(os.Stderr)
+
+	signingKey := "mypassword"
+	dispatcher := newWebhookDispatcher("", func() string { return signingKey })
+	_, err := dispatcher.register("http://10.0.100.5/signed", nil)
+	assert.Nil(t, err)
+	assert.NotContains(t, logOutput.String(), "unsigned")
+
+	// Without a password to sign with, registration still succeeds but warns that payloads will be unsigned.
+	signingKey = ""
+	_, err = dispatcher.register("http://10.0.100.5/unsigned", nil)
+	assert.Nil(t, err)
+	assert.Contains(
+		t, logOutput.String(), "Warning: payloads for webhook http://10.0.100.5/unsigned will be unsigned",
+	)
+}
+
+// fakeWebhookRadio stands in for the parts of the radio that webhook payloads are triggered by.
+type fakeWebhookRadio struct {
+	radio.Radio
+	snapshot   any
+	linkEvents []radio.LinkEvent
+	jobs       map[string]radio.ConfigurationJob
+}
+
+func (fakeRadio *fakeWebhookRadio) StatusSnapshot() any {
+	return fakeRadio.snapshot
+}
+
+func (fakeRadio *fakeWebhookRadio) LinkEvents(_ string, afterId int) ([]radio.LinkEvent, error) {
+	var events []radio.LinkEvent
+	for _, event := range fakeRadio.linkEvents {
+		if event.Id > afterId {
+			events = append(events, event)
+		}
+	}
+	return events, nil
+}
+
+func (fakeRadio *fakeWebhookRadio) GetConfigurationJob(id string) (radio.ConfigurationJob, bool) {
+	job, ok := fakeRadio.jobs[id]
+	return job, ok
+}
+
+func TestWeb_checkRadioForWebhooks(t *testing.T) {
+	server, received := newWebhookReceiver(t)
+	ap := &radio.AccessPointRadio{}
+	ap.Status = "BOOTING"
+	fakeRadio := &fakeWebhookRadio{snapshot: ap, jobs: make(map[string]radio.ConfigurationJob)}
+	web := NewWebServer(fakeRadio)
+	_, err := web.webhooks.register(server.URL, nil)
+	assert.Nil(t, err)
+
+	// Nothing is sent for the state in which the radio is first observed.
+	web.checkRadioForWebhooks()
+	time.Sleep(10 * time.Millisecond)
+	assert.Equal(t, 0, len(received))
+
+	ap.Status = "ERROR"
+	ap.ConfigurationError = "oops"
+	ap.RolledBack = true
+	web.checkRadioForWebhooks()
+	webhook := receiveWebhook(t, received)
+	assert.Equal(t, webhookStatusChanged, webhook.payload.Type)
+	assert.Equal(
+		t,
+		map[string]any{
+			"previousStatus": "BOOTING", "status": "ERROR", "configurationError": "oops", "rolledBack": true,
+		},
+		webhook.payload.Data,
+	)
+
+	// Only associations and disassociations are sent, and only once each.
+	eventTime := time.Date(2024, 4, 20, 12, 0, 0, 0, time.UTC)
+	fakeRadio.linkEvents = []radio.LinkEvent{
+		{Id: 1, Time: eventTime, Station: "red1", Type: "ASSOCIATED"},
+		{Id: 2, Time: eventTime, Station: "red1", Type: "QUALITY_CHANGED"},
+		{Id: 3, Time: eventTime, Station: "red1", Type: "DISASSOCIATED"},
+	}
+	web.checkRadioForWebhooks()
+	webhook = receiveWebhook(t, received)
+	assert.Equal(t, webhookLinkUp, webhook.payload.Type)
+	assert.Equal(t, eventTime, webhook.payload.Time)
+	assert.Equal(t, 1.0, webhook.payload.Data.(map[string]any)["id"])
+	webhook = receiveWebhook(t, received)
+	assert.Equal(t, webhookLinkDown, webhook.payload.Type)
+	assert.Equal(t, 3.0, webhook.payload.Data.(map[string]any)["id"])
+	web.checkRadioForWebhooks()
+	time.Sleep(10 * time.Millisecond)
+	assert.Equal(t, 0, len(received))
+
+	// Configuration jobs queued through the API are sent once they finish.
+	fakeRadio.jobs["abc"] = radio.ConfigurationJob{Id: "abc", Status: "APPLYING"}
+	web.webhookTrigger.addPendingJob("abc")
+	web.webhookTrigger.addPendingJob("forgotten")
+	web.checkRadioForWebhooks()
+	time.Sleep(10 * time.Millisecond)
+	assert.Equal(t, 0, len(received))
+	assert.Equal(t, []string{"abc"}, web.webhookTrigger.pendingJobIds)
+
+	finishedAt := eventTime.Add(time.Minute)
+	fakeRadio.jobs["abc"] = radio.ConfigurationJob{Id: "abc", Status: "SUCCEEDED", FinishedAt: &finishedAt}
+	web.checkRadioForWebhooks()
+	webhook = receiveWebhook(t, received)
+	assert.Equal(t, webhookConfigurationCompleted, webhook.payload.Type)
+	assert.Equal(t, finishedAt, webhook.payload.Time)
+	assert.Equal(t, "SUCCEEDED", webhook.payload.Data.(map[string]any)["status"])
+	assert.Empty(t, web.webhookTrigger.pendingJobIds)
+}
+
+func TestGetRadioStatus(t *testing.T) {
+	robotRadio := &radio.RobotRadio{}
+	robotRadio.Status = "CONFIGURING"
+	status, configurationError, rolledBack := getRadioStatus(robotRadio)
+	assert.Equal(t, "CONFIGURING", status)
+	assert.Equal(t, "", configurationError)
+	assert.False(t, rolledBack)
+
+	status, _, _ = getRadioStatus(nil)
+	assert.Equal(t, "", status)
+}